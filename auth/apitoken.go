@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"time"
+)
+
+// APIToken is a personal access token, created by a user for their scripts and
+// integrations. It only gives access to the endpoints accepting one of its
+// scopes, until it expires or is revoked.
+type APIToken struct {
+	ID     int      `json:"id"`
+	UserID int      `json:"userID"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Token is the signed token. It is only returned when the API token is
+	// created and is never stored.
+	Token string `json:"token,omitempty"`
+}
+
+type APITokenRepository interface {
+	Get(int) (APIToken, error)
+	ListForUser(int) ([]APIToken, error)
+	Upsert(*APIToken) error
+	Delete(int) error
+}
@@ -0,0 +1,151 @@
+package cayley
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+var (
+	maxAPITokenIDNode = quad.Raw("maxAPITokenID")
+	maxAPITokenIDEdge = quad.Raw("value")
+
+	allAPITokensNode = quad.Raw("allAPITokens")
+	allAPITokensEdge = quad.Raw("apiToken")
+)
+
+type APITokenRepository struct {
+	store *Store
+}
+
+// NewAPITokenRepository creates a new API token repository based on a store.
+func NewAPITokenRepository(store *Store) *APITokenRepository {
+	return &APITokenRepository{
+		store: store,
+	}
+}
+
+// Get retrieves an API token from its id.
+func (r *APITokenRepository) Get(id int) (auth.APIToken, error) {
+	startingPoint := cayley.StartPath(r.store, apiTokenQuad(id)).HasReverse(allAPITokensEdge, allAPITokensNode)
+	startingPoint = startingPoint.Except(startingPoint.HasReverse(deletedEdge, deletedNode))
+	p := startingPoint.
+		SaveOptional(tokenOfEdge, "user").
+		SaveOptional(nameEdge, "name").
+		SaveOptional(scopesEdge, "scopes").
+		SaveOptional(createdAtEdge, "createdAt").
+		SaveOptional(expiresAtEdge, "expiresAt")
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	var token auth.APIToken
+	for it.Next() {
+		tokenID, err := r.store.entity(it.Result(), "apiToken")
+		if err != nil {
+			return auth.APIToken{}, err
+		}
+		token.ID = tokenID
+
+		m := make(map[string]graph.Value)
+		it.TagResults(m)
+		for tag, value := range m {
+			switch tag {
+			case "user":
+				token.UserID, err = r.store.entity(value, "user")
+			case "name":
+				token.Name, err = r.store.string(value)
+			case "scopes":
+				var scopes string
+				scopes, err = r.store.string(value)
+				token.Scopes = strings.Fields(scopes)
+			case "createdAt":
+				token.CreatedAt, err = r.store.time(value)
+			case "expiresAt":
+				token.ExpiresAt, err = r.store.time(value)
+			default:
+				// Do nothing
+				fmt.Println("unsupported tag", tag)
+			}
+			if err != nil {
+				return auth.APIToken{}, err
+			}
+		}
+	}
+
+	return token, nil
+}
+
+// ListForUser retrieves all the API tokens of a user, sorted by id.
+func (r *APITokenRepository) ListForUser(userID int) ([]auth.APIToken, error) {
+	p := cayley.StartPath(r.store, userQuad(userID)).In(tokenOfEdge).HasReverse(allAPITokensEdge, allAPITokensNode)
+	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	ids := make([]int, 0)
+	for it.Next() {
+		tokenID, err := r.store.entity(it.Result(), "apiToken")
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, tokenID)
+	}
+
+	sort.Ints(ids)
+	tokens := make([]auth.APIToken, len(ids))
+	for i, id := range ids {
+		token, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = token
+	}
+
+	return tokens, nil
+}
+
+// Upsert updates the API token passed as argument in the database. If the token has
+// no ID, this method sets it before inserting. The signed token is not stored.
+func (r *APITokenRepository) Upsert(token *auth.APIToken) error {
+	if token.ID == 0 {
+		id, err := r.store.incrementMaxID(maxAPITokenIDNode, maxAPITokenIDEdge)
+		if err != nil {
+			return err
+		}
+
+		token.ID = id
+	}
+
+	old, err := r.Get(token.ID)
+	if err != nil {
+		return err
+	}
+
+	node := apiTokenQuad(token.ID)
+	tx := graph.NewTransaction()
+	replaceTarget(tx, node, tokenOfEdge, userQuad(old.UserID), userQuad(token.UserID))
+	replaceTarget(tx, node, nameEdge, quad.Raw(old.Name), quad.Raw(token.Name))
+	replaceTarget(tx, node, scopesEdge, quad.Raw(strings.Join(old.Scopes, " ")), quad.Raw(strings.Join(token.Scopes, " ")))
+	replaceTarget(tx, node, createdAtEdge, unixRaw(old.CreatedAt), unixRaw(token.CreatedAt))
+	replaceTarget(tx, node, expiresAtEdge, unixRaw(old.ExpiresAt), unixRaw(token.ExpiresAt))
+
+	// Add token to all API tokens
+	addQuad(tx, allAPITokensNode, allAPITokensEdge, node)
+
+	return r.store.ApplyTransaction(tx)
+}
+
+// Delete marks an API token as deleted.
+func (r *APITokenRepository) Delete(id int) error {
+	tx := graph.NewTransaction()
+	addQuad(tx, deletedNode, deletedEdge, apiTokenQuad(id))
+	return r.store.ApplyTransaction(tx)
+}
@@ -0,0 +1,33 @@
+package cayley
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func createAPITokenRepository(t *testing.T) (*APITokenRepository, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err, "could not create tmp file")
+
+	filename := tmpFile.Name()
+	store, err := NewStore(filename)
+	require.NoError(t, err, "could not create store")
+
+	repo := NewAPITokenRepository(store)
+	return repo, func() {
+		store.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestAPITokenRepository(t *testing.T) {
+	repo, tearDown := createAPITokenRepository(t)
+	defer tearDown()
+
+	testutil.TestAPITokenRepository(t, repo)
+}
@@ -0,0 +1,145 @@
+package cayley
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+var (
+	maxInvitationIDNode = quad.Raw("maxInvitationID")
+	maxInvitationIDEdge = quad.Raw("value")
+
+	allInvitationsNode = quad.Raw("allInvitations")
+	allInvitationsEdge = quad.Raw("invitation")
+)
+
+type InvitationRepository struct {
+	store *Store
+}
+
+// NewInvitationRepository creates a new invitation repository based on a store.
+func NewInvitationRepository(store *Store) *InvitationRepository {
+	return &InvitationRepository{
+		store: store,
+	}
+}
+
+// Get retrieves an invitation from its id.
+func (r *InvitationRepository) Get(id int) (auth.Invitation, error) {
+	p := cayley.StartPath(r.store, invitationQuad(id)).
+		HasReverse(allInvitationsEdge, allInvitationsNode).
+		SaveOptional(invitedToEdge, "team").
+		SaveOptional(invitedEmailEdge, "email").
+		SaveOptional(invitedByEdge, "inviter").
+		SaveOptional(stateEdge, "state").
+		SaveOptional(createdAtEdge, "createdAt").
+		SaveOptional(expiresAtEdge, "expiresAt")
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	var invitation auth.Invitation
+	for it.Next() {
+		invitationID, err := r.store.entity(it.Result(), "invitation")
+		if err != nil {
+			return auth.Invitation{}, err
+		}
+		invitation.ID = invitationID
+
+		m := make(map[string]graph.Value)
+		it.TagResults(m)
+		for tag, token := range m {
+			switch tag {
+			case "team":
+				invitation.TeamID, err = r.store.entity(token, "team")
+			case "email":
+				invitation.Email, err = r.store.string(token)
+			case "inviter":
+				invitation.InviterID, err = r.store.entity(token, "user")
+			case "state":
+				var state string
+				state, err = r.store.string(token)
+				invitation.State = auth.InvitationState(state)
+			case "createdAt":
+				invitation.CreatedAt, err = r.store.time(token)
+			case "expiresAt":
+				invitation.ExpiresAt, err = r.store.time(token)
+			default:
+				// Do nothing
+				fmt.Println("unsupported tag", tag)
+			}
+			if err != nil {
+				return auth.Invitation{}, err
+			}
+		}
+	}
+
+	return invitation, nil
+}
+
+// ListForEmail retrieves all the invitations sent to an email, sorted by id.
+func (r *InvitationRepository) ListForEmail(email string) ([]auth.Invitation, error) {
+	p := cayley.StartPath(r.store, quad.Raw(email)).In(invitedEmailEdge).HasReverse(allInvitationsEdge, allInvitationsNode)
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	ids := make([]int, 0)
+	for it.Next() {
+		invitationID, err := r.store.entity(it.Result(), "invitation")
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, invitationID)
+	}
+
+	sort.Ints(ids)
+	invitations := make([]auth.Invitation, len(ids))
+	for i, id := range ids {
+		invitation, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		invitations[i] = invitation
+	}
+
+	return invitations, nil
+}
+
+// Upsert updates the invitation passed as argument in the database. If the invitation has
+// no ID, this method sets it before inserting. The token of the invitation is not stored.
+func (r *InvitationRepository) Upsert(invitation *auth.Invitation) error {
+	if invitation.ID == 0 {
+		id, err := r.store.incrementMaxID(maxInvitationIDNode, maxInvitationIDEdge)
+		if err != nil {
+			return err
+		}
+
+		invitation.ID = id
+	}
+
+	old, err := r.Get(invitation.ID)
+	if err != nil {
+		return err
+	}
+
+	node := invitationQuad(invitation.ID)
+	tx := graph.NewTransaction()
+	replaceTarget(tx, node, invitedToEdge, teamQuad(old.TeamID), teamQuad(invitation.TeamID))
+	replaceTarget(tx, node, invitedEmailEdge, quad.Raw(old.Email), quad.Raw(invitation.Email))
+	replaceTarget(tx, node, invitedByEdge, userQuad(old.InviterID), userQuad(invitation.InviterID))
+	replaceTarget(tx, node, stateEdge, quad.Raw(old.State), quad.Raw(invitation.State))
+	replaceTarget(tx, node, createdAtEdge, unixRaw(old.CreatedAt), unixRaw(invitation.CreatedAt))
+	replaceTarget(tx, node, expiresAtEdge, unixRaw(old.ExpiresAt), unixRaw(invitation.ExpiresAt))
+
+	// Add invitation to all invitations
+	addQuad(tx, allInvitationsNode, allInvitationsEdge, node)
+
+	return r.store.ApplyTransaction(tx)
+}
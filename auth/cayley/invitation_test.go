@@ -0,0 +1,33 @@
+package cayley
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func createInvitationRepository(t *testing.T) (*InvitationRepository, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err, "could not create tmp file")
+
+	filename := tmpFile.Name()
+	store, err := NewStore(filename)
+	require.NoError(t, err, "could not create store")
+
+	repo := NewInvitationRepository(store)
+	return repo, func() {
+		store.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestInvitationRepository(t *testing.T) {
+	repo, tearDown := createInvitationRepository(t)
+	defer tearDown()
+
+	testutil.TestInvitationRepository(t, repo)
+}
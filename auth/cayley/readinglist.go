@@ -0,0 +1,210 @@
+package cayley
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+var (
+	maxReadingListIDNode = quad.Raw("maxReadingListID")
+	maxReadingListIDEdge = quad.Raw("value")
+
+	allReadingListsNode = quad.Raw("allReadingLists")
+	allReadingListsEdge = quad.Raw("readingList")
+)
+
+type ReadingListRepository struct {
+	store *Store
+}
+
+// NewReadingListRepository creates a new reading list repository based on a
+// store.
+func NewReadingListRepository(store *Store) *ReadingListRepository {
+	return &ReadingListRepository{
+		store: store,
+	}
+}
+
+// Get retrieves a reading list from its id.
+func (r *ReadingListRepository) Get(id int) (auth.ReadingList, error) {
+	startingPoint := cayley.StartPath(r.store, readingListQuad(id)).HasReverse(allReadingListsEdge, allReadingListsNode)
+	startingPoint = startingPoint.Except(startingPoint.HasReverse(deletedEdge, deletedNode))
+	p := startingPoint.Clone().
+		SaveOptional(listOfEdge, "owner").
+		SaveOptional(nameEdge, "name").
+		SaveOptional(papersEdge, "papers")
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	list := auth.ReadingList{
+		Papers: make([]int, 0),
+		Teams:  make([]int, 0),
+	}
+	for it.Next() {
+		listID, err := r.store.entity(it.Result(), "readingList")
+		if err != nil {
+			return auth.ReadingList{}, err
+		}
+		list.ID = listID
+
+		m := make(map[string]graph.Value)
+		it.TagResults(m)
+		for tag, value := range m {
+			switch tag {
+			case "owner":
+				list.OwnerID, err = r.store.entity(value, "user")
+			case "name":
+				list.Name, err = r.store.string(value)
+			case "papers":
+				var papers string
+				papers, err = r.store.string(value)
+				if err == nil {
+					list.Papers, err = parseIDs(papers)
+				}
+			default:
+				// Do nothing
+				fmt.Println("unsupported tag", tag)
+			}
+			if err != nil {
+				return auth.ReadingList{}, err
+			}
+		}
+	}
+
+	if list.ID == 0 {
+		return auth.ReadingList{}, nil
+	}
+
+	teams, err := r.ids(startingPoint.Out(sharedWithEdge), "team")
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+	list.Teams = teams
+
+	return list, nil
+}
+
+// ListForUser retrieves the reading lists owned by a user, sorted by id.
+func (r *ReadingListRepository) ListForUser(userID int) ([]auth.ReadingList, error) {
+	return r.list(cayley.StartPath(r.store, userQuad(userID)).In(listOfEdge))
+}
+
+// ListForTeam retrieves the reading lists shared with a team, sorted by id.
+func (r *ReadingListRepository) ListForTeam(teamID int) ([]auth.ReadingList, error) {
+	return r.list(cayley.StartPath(r.store, teamQuad(teamID)).In(sharedWithEdge))
+}
+
+// Upsert updates the reading list passed as argument in the database. If the
+// list has no ID, this method sets it before inserting.
+func (r *ReadingListRepository) Upsert(list *auth.ReadingList) error {
+	if list.ID == 0 {
+		id, err := r.store.incrementMaxID(maxReadingListIDNode, maxReadingListIDEdge)
+		if err != nil {
+			return err
+		}
+
+		list.ID = id
+	}
+
+	old, err := r.Get(list.ID)
+	if err != nil {
+		return err
+	}
+
+	node := readingListQuad(list.ID)
+	tx := graph.NewTransaction()
+	replaceTarget(tx, node, listOfEdge, userQuad(old.OwnerID), userQuad(list.OwnerID))
+	replaceTarget(tx, node, nameEdge, quad.Raw(old.Name), quad.Raw(list.Name))
+	// The papers are stored in a single value to keep their order
+	replaceTarget(tx, node, papersEdge, quad.Raw(formatIDs(old.Papers)), quad.Raw(formatIDs(list.Papers)))
+
+	for _, teamID := range old.Teams {
+		removeQuad(tx, node, sharedWithEdge, teamQuad(teamID))
+	}
+	for _, teamID := range list.Teams {
+		addQuad(tx, node, sharedWithEdge, teamQuad(teamID))
+	}
+
+	// Add list to all reading lists
+	addQuad(tx, allReadingListsNode, allReadingListsEdge, node)
+
+	return r.store.ApplyTransaction(tx)
+}
+
+// Delete marks a reading list as deleted.
+func (r *ReadingListRepository) Delete(id int) error {
+	tx := graph.NewTransaction()
+	addQuad(tx, deletedNode, deletedEdge, readingListQuad(id))
+	return r.store.ApplyTransaction(tx)
+}
+
+// list retrieves the reading lists reached by a path, sorted by id.
+func (r *ReadingListRepository) list(p *path.Path) ([]auth.ReadingList, error) {
+	p = p.HasReverse(allReadingListsEdge, allReadingListsNode)
+	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
+
+	ids, err := r.ids(p, "readingList")
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]auth.ReadingList, len(ids))
+	for i, id := range ids {
+		list, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = list
+	}
+
+	return lists, nil
+}
+
+// ids returns the sorted ids of the entities reached by a path.
+func (r *ReadingListRepository) ids(p *path.Path, entityType string) ([]int, error) {
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	ids := make([]int, 0)
+	for it.Next() {
+		id, err := r.store.entity(it.Result(), entityType)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func formatIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, " ")
+}
+
+func parseIDs(s string) ([]int, error) {
+	fields := strings.Fields(s)
+	ids := make([]int, len(fields))
+	for i, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
@@ -0,0 +1,33 @@
+package cayley
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func createReadingListRepository(t *testing.T) (*ReadingListRepository, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err, "could not create tmp file")
+
+	filename := tmpFile.Name()
+	store, err := NewStore(filename)
+	require.NoError(t, err, "could not create store")
+
+	repo := NewReadingListRepository(store)
+	return repo, func() {
+		store.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestReadingListRepository(t *testing.T) {
+	repo, tearDown := createReadingListRepository(t)
+	defer tearDown()
+
+	testutil.TestReadingListRepository(t, repo)
+}
@@ -3,6 +3,8 @@ package cayley
 import (
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
@@ -14,6 +16,11 @@ import (
 
 type Store struct {
 	*cayley.Handle
+
+	// mu serializes the updates that have to check the current state first.
+	// Bolt only lets one process open the database, so a lock is enough to
+	// make them atomic.
+	mu sync.Locker
 }
 
 // NewStore creates a new store based on a cayley graph db
@@ -31,7 +38,8 @@ func NewStore(dbpath string) (*Store, error) {
 	}
 
 	return &Store{
-		store,
+		Handle: store,
+		mu:     &sync.Mutex{},
 	}, nil
 }
 
@@ -100,6 +108,15 @@ func (s *Store) string(token graph.Value) (string, error) {
 	return "", errors.New(fmt.Sprintf("invalid type %T for string node", nativeValue))
 }
 
+// time reads a time stored as a number of seconds, see unixRaw.
+func (s *Store) time(token graph.Value) (time.Time, error) {
+	sec, err := s.int(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(sec), 0).UTC(), nil
+}
+
 func (s *Store) entity(token graph.Value, entityType string) (int, error) {
 	value := s.NameOf(token)            // get the value in the node (RDF)
 	nativeValue := quad.NativeOf(value) // convert value to normal Go type
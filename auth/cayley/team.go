@@ -9,6 +9,7 @@ import (
 	"github.com/cayleygraph/cayley/quad"
 
 	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/errors"
 )
 
 var (
@@ -33,13 +34,14 @@ func NewTeamRepository(store *Store) *TeamRepository {
 func (r *TeamRepository) Get(id int) (auth.Team, error) {
 	startingPoint := cayley.StartPath(r.store, teamQuad(id))
 	startingPoint = startingPoint.Except(startingPoint.HasReverse(deletedEdge, deletedNode))
-	p := startingPoint.SaveOptional(nameEdge, "name")
+	p := startingPoint.SaveOptional(nameEdge, "name").SaveOptional(descriptionEdge, "description")
 
 	it := r.store.buildIterator(p)
 	defer it.Close()
 
 	team := auth.Team{
 		Members: make([]auth.TeamMember, 0),
+		Owns:    make([]int, 0),
 		CanSee:  make([]int, 0),
 		CanEdit: make([]int, 0),
 	}
@@ -64,6 +66,12 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 					return auth.Team{}, err
 				}
 				team.Name = name
+			case "description":
+				description, err := r.store.string(token)
+				if err != nil {
+					return auth.Team{}, err
+				}
+				team.Description = description
 			default:
 				// Do nothing
 				fmt.Println("unsupported tag", tag)
@@ -76,11 +84,16 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 		[]string{"isAdminOf"},
 		isAdminOfEdge,
 	).SaveOptional(nameEdge, "name").SaveOptional(emailEdge, "email")
-	members := startingPoint.In(
+	editors := startingPoint.InWithTags(
+		[]string{"isMemberOf"},
 		isMemberOfEdge,
 	).SaveOptional(nameEdge, "name").SaveOptional(emailEdge, "email")
+	viewers := startingPoint.InWithTags(
+		[]string{"isViewerOf"},
+		isViewerOfEdge,
+	).SaveOptional(nameEdge, "name").SaveOptional(emailEdge, "email")
 
-	p = admins.Or(members)
+	p = admins.Or(editors).Or(viewers)
 	it = r.store.buildIterator(p)
 	defer it.Close()
 
@@ -103,7 +116,11 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 		for tag, token := range m {
 			switch tag {
 			case "isAdminOf":
-				member.IsTeamAdmin = true
+				member.Role = auth.TeamAdmin
+			case "isMemberOf":
+				member.Role = auth.TeamEditor
+			case "isViewerOf":
+				member.Role = auth.TeamViewer
 			case "name":
 				name, err := r.store.string(token)
 				if err != nil {
@@ -124,10 +141,11 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 		team.Members = append(team.Members, member)
 	}
 
+	owns := startingPoint.OutWithTags([]string{"owns"}, ownsEdge)
 	canSee := startingPoint.OutWithTags([]string{"canSee"}, canSeeEdge)
 	canEdit := startingPoint.OutWithTags([]string{"canEdit"}, canEditEdge)
 
-	p = canSee.Or(canEdit)
+	p = owns.Or(canSee).Or(canEdit)
 	it = r.store.buildIterator(p)
 	defer it.Close()
 
@@ -145,6 +163,8 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 
 		for tag, _ := range m {
 			switch tag {
+			case "owns":
+				team.Owns = append(team.Owns, paperID)
 			case "canSee":
 				team.CanSee = append(team.CanSee, paperID)
 			case "canEdit":
@@ -159,7 +179,7 @@ func (r *TeamRepository) Get(id int) (auth.Team, error) {
 }
 
 func (r *TeamRepository) GetForUser(userID int) ([]auth.Team, error) {
-	p := cayley.StartPath(r.store, userQuad(userID)).Out(isAdminOfEdge, isMemberOfEdge)
+	p := cayley.StartPath(r.store, userQuad(userID)).Out(isAdminOfEdge, isMemberOfEdge, isViewerOfEdge)
 	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
 	it := r.store.buildIterator(p)
 	defer it.Close()
@@ -207,23 +227,29 @@ func (r *TeamRepository) Upsert(team *auth.Team) error {
 
 	tx := graph.NewTransaction()
 	replaceTarget(tx, teamQuad(team.ID), nameEdge, quad.Raw(oldTeam.Name), quad.Raw(team.Name))
+	replaceTarget(tx, teamQuad(team.ID), descriptionEdge, quad.Raw(oldTeam.Description), quad.Raw(team.Description))
 
 	// Remove old members
 	for _, m := range oldTeam.Members {
-		if m.IsTeamAdmin {
-			removeQuad(tx, userQuad(m.ID), isAdminOfEdge, teamQuad(team.ID))
-		} else {
-			removeQuad(tx, userQuad(m.ID), isMemberOfEdge, teamQuad(team.ID))
-		}
+		removeQuad(tx, userQuad(m.ID), roleEdges[m.Role], teamQuad(team.ID))
 	}
 
 	// Add new members
 	for _, m := range team.Members {
-		if m.IsTeamAdmin {
-			addQuad(tx, userQuad(m.ID), isAdminOfEdge, teamQuad(team.ID))
-		} else {
-			addQuad(tx, userQuad(m.ID), isMemberOfEdge, teamQuad(team.ID))
+		edge, ok := roleEdges[m.Role]
+		if !ok {
+			return errors.New(fmt.Sprintf("invalid role %q for member %d", m.Role, m.ID), errors.BadRequest())
 		}
+		addQuad(tx, userQuad(m.ID), edge, teamQuad(team.ID))
+	}
+
+	// Update owned papers
+	for _, paperID := range oldTeam.Owns {
+		removeQuad(tx, teamQuad(team.ID), ownsEdge, paperQuad(paperID))
+	}
+
+	for _, paperID := range team.Owns {
+		addQuad(tx, teamQuad(team.ID), ownsEdge, paperQuad(paperID))
 	}
 
 	// Remove old permissions
@@ -250,6 +276,22 @@ func (r *TeamRepository) Upsert(team *auth.Team) error {
 	return r.store.ApplyTransaction(tx)
 }
 
+// PaperOwner retrieves the id of the team owning a paper, or 0 if the paper is not
+// owned by a team.
+func (r *TeamRepository) PaperOwner(paperID int) (int, error) {
+	p := cayley.StartPath(r.store, paperQuad(paperID)).In(ownsEdge).HasReverse(allTeamsEdge, allTeamsNode)
+	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	if !it.Next() {
+		return 0, nil
+	}
+
+	return r.store.entity(it.Result(), "team")
+}
+
 func (r *TeamRepository) Delete(id int) error {
 	tx := graph.NewTransaction()
 	addQuad(tx, deletedNode, deletedEdge, teamQuad(id))
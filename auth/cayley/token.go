@@ -0,0 +1,200 @@
+package cayley
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/graph/path"
+	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+var (
+	allUserTokensNode = quad.Raw("allUserTokens")
+	allUserTokensEdge = quad.Raw("userToken")
+)
+
+type UserTokenRepository struct {
+	store *Store
+}
+
+// NewUserTokenRepository creates a new user token repository based on a store.
+func NewUserTokenRepository(store *Store) *UserTokenRepository {
+	return &UserTokenRepository{
+		store: store,
+	}
+}
+
+// Get retrieves a token from its hash.
+func (r *UserTokenRepository) Get(hash string) (auth.UserToken, error) {
+	p := cayley.StartPath(r.store, userTokenQuad(hash)).
+		HasReverse(allUserTokensEdge, allUserTokensNode).
+		SaveOptional(tokenKindEdge, "kind").
+		SaveOptional(tokenOfEdge, "user").
+		SaveOptional(usedEdge, "used").
+		SaveOptional(tokenEmailEdge, "email").
+		SaveOptional(createdAtEdge, "createdAt").
+		SaveOptional(expiresAtEdge, "expiresAt")
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	var token auth.UserToken
+	for it.Next() {
+		token.Hash = hash
+
+		m := make(map[string]graph.Value)
+		it.TagResults(m)
+		for tag, value := range m {
+			var err error
+			switch tag {
+			case "kind":
+				var kind string
+				kind, err = r.store.string(value)
+				token.Kind = auth.UserTokenKind(kind)
+			case "user":
+				token.UserID, err = r.store.entity(value, "user")
+			case "used":
+				var used string
+				used, err = r.store.string(value)
+				token.Used = used == "true"
+			case "email":
+				token.Email, err = r.store.string(value)
+			case "createdAt":
+				token.CreatedAt, err = r.store.time(value)
+			case "expiresAt":
+				token.ExpiresAt, err = r.store.time(value)
+			default:
+				// Do nothing
+				fmt.Println("unsupported tag", tag)
+			}
+			if err != nil {
+				return auth.UserToken{}, err
+			}
+		}
+	}
+
+	return token, nil
+}
+
+// Use marks a token as used if it has the right kind and was not used yet. The
+// token is returned as it was before.
+func (r *UserTokenRepository) Use(hash string, kind auth.UserTokenKind) (auth.UserToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, err := r.Get(hash)
+	if err != nil {
+		return auth.UserToken{}, err
+	} else if token.Hash == "" || token.Kind != kind || token.Used {
+		return token, nil
+	}
+
+	tx := graph.NewTransaction()
+	replaceTarget(tx, userTokenQuad(hash), usedEdge, strconv.FormatBool(false), strconv.FormatBool(true))
+	err = r.store.ApplyTransaction(tx)
+	if err != nil {
+		return auth.UserToken{}, err
+	}
+
+	return token, nil
+}
+
+// DeleteExpired deletes all the tokens expired before a date.
+func (r *UserTokenRepository) DeleteExpired(before time.Time) (int, error) {
+	p := cayley.StartPath(r.store, allUserTokensNode).Out(allUserTokensEdge)
+
+	hashes := r.hashes(p)
+
+	tx := graph.NewTransaction()
+	deleted := 0
+	for _, hash := range hashes {
+		token, err := r.Get(hash)
+		if err != nil {
+			return 0, err
+		} else if !token.ExpiresAt.Before(before) {
+			continue
+		}
+
+		node := userTokenQuad(hash)
+		removeQuad(tx, node, tokenKindEdge, quad.Raw(token.Kind))
+		removeQuad(tx, node, tokenOfEdge, userQuad(token.UserID))
+		removeQuad(tx, node, usedEdge, strconv.FormatBool(token.Used))
+		removeQuad(tx, node, tokenEmailEdge, quad.Raw(token.Email))
+		removeQuad(tx, node, createdAtEdge, unixRaw(token.CreatedAt))
+		removeQuad(tx, node, expiresAtEdge, unixRaw(token.ExpiresAt))
+		removeQuad(tx, allUserTokensNode, allUserTokensEdge, node)
+		deleted++
+	}
+
+	err := r.store.ApplyTransaction(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// ListForUser retrieves the tokens of a given kind handed to a user.
+func (r *UserTokenRepository) ListForUser(userID int, kind auth.UserTokenKind) ([]auth.UserToken, error) {
+	p := cayley.StartPath(r.store, userQuad(userID)).
+		In(tokenOfEdge).
+		HasReverse(allUserTokensEdge, allUserTokensNode).
+		Has(tokenKindEdge, quad.Raw(kind))
+
+	hashes := r.hashes(p)
+
+	tokens := make([]auth.UserToken, len(hashes))
+	for i, hash := range hashes {
+		token, err := r.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = token
+	}
+
+	return tokens, nil
+}
+
+// Upsert inserts or updates the token passed as argument in the database.
+func (r *UserTokenRepository) Upsert(token *auth.UserToken) error {
+	old, err := r.Get(token.Hash)
+	if err != nil {
+		return err
+	}
+
+	node := userTokenQuad(token.Hash)
+	tx := graph.NewTransaction()
+	replaceTarget(tx, node, tokenKindEdge, quad.Raw(old.Kind), quad.Raw(token.Kind))
+	replaceTarget(tx, node, tokenOfEdge, userQuad(old.UserID), userQuad(token.UserID))
+	replaceTarget(tx, node, usedEdge, strconv.FormatBool(old.Used), strconv.FormatBool(token.Used))
+	replaceTarget(tx, node, tokenEmailEdge, quad.Raw(old.Email), quad.Raw(token.Email))
+	replaceTarget(tx, node, createdAtEdge, unixRaw(old.CreatedAt), unixRaw(token.CreatedAt))
+	replaceTarget(tx, node, expiresAtEdge, unixRaw(old.ExpiresAt), unixRaw(token.ExpiresAt))
+
+	// Add token to all tokens
+	addQuad(tx, allUserTokensNode, allUserTokensEdge, node)
+
+	return r.store.ApplyTransaction(tx)
+}
+
+// hashes returns the hashes of the tokens a path leads to.
+func (r *UserTokenRepository) hashes(p *path.Path) []string {
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	hashes := make([]string, 0)
+	for it.Next() {
+		iri, ok := quad.NativeOf(r.store.NameOf(it.Result())).(quad.IRI)
+		if !ok {
+			continue
+		}
+		_, hash := splitIRI(iri)
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
@@ -0,0 +1,33 @@
+package cayley
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func createUserTokenRepository(t *testing.T) (*UserTokenRepository, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err, "could not create tmp file")
+
+	filename := tmpFile.Name()
+	store, err := NewStore(filename)
+	require.NoError(t, err, "could not create store")
+
+	repo := NewUserTokenRepository(store)
+	return repo, func() {
+		store.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestUserTokenRepository(t *testing.T) {
+	repo, tearDown := createUserTokenRepository(t)
+	defer tearDown()
+
+	testutil.TestUserTokenRepository(t, repo)
+}
@@ -53,6 +53,18 @@ func (r *UserRepository) GetByEmail(email string) (auth.User, error) {
 	return r.userFromStartingPoint(startingPoint)
 }
 
+// GetByGoogleID retrieves the user a Google account is linked to.
+func (r *UserRepository) GetByGoogleID(googleID string) (auth.User, error) {
+	if googleID == "" {
+		return auth.User{}, nil
+	}
+
+	startingPoint := cayley.StartPath(r.store, quad.Raw(googleID)).In(googleIDEdge)
+	startingPoint = startingPoint.Except(startingPoint.HasReverse(deletedEdge, deletedNode))
+
+	return r.userFromStartingPoint(startingPoint)
+}
+
 // Upsert updates the user passed as argument in the database. If the user has no ID (i.e. user.ID == 0),
 // this method sets the user ID before inserting.
 func (r *UserRepository) Upsert(user *auth.User) error {
@@ -76,8 +88,19 @@ func (r *UserRepository) Upsert(user *auth.User) error {
 	replaceTarget(tx, userQuad(user.ID), nameEdge, quad.Raw(oldUser.Name), quad.Raw(user.Name))
 	replaceTarget(tx, userQuad(user.ID), emailEdge, quad.Raw(oldUser.Email), quad.Raw(user.Email))
 	replaceTarget(tx, userQuad(user.ID), isAdminEdge, strconv.FormatBool(oldUser.IsAdmin), strconv.FormatBool(user.IsAdmin))
+	replaceTarget(tx, userQuad(user.ID), verifiedEdge, strconv.FormatBool(oldUser.EmailVerified), strconv.FormatBool(user.EmailVerified))
 	replaceTarget(tx, userQuad(user.ID), saltEdge, quad.Raw(oldUser.Salt), quad.Raw(user.Salt))
 	replaceTarget(tx, userQuad(user.ID), passwordEdge, quad.Raw(oldUser.PasswordHash), quad.Raw(user.PasswordHash))
+	replaceTarget(tx, userQuad(user.ID), revokedAtEdge, unixRaw(oldUser.SessionsRevokedAt), unixRaw(user.SessionsRevokedAt))
+
+	// No Google account is stored as no edge, so that the users without one
+	// are not all found by the empty id.
+	if oldUser.GoogleID != "" {
+		removeQuad(tx, userQuad(user.ID), googleIDEdge, quad.Raw(oldUser.GoogleID))
+	}
+	if user.GoogleID != "" {
+		addQuad(tx, userQuad(user.ID), googleIDEdge, quad.Raw(user.GoogleID))
+	}
 
 	// Update user owned papers
 	for _, paperID := range oldUser.Owns {
@@ -97,6 +120,15 @@ func (r *UserRepository) Upsert(user *auth.User) error {
 		addQuad(tx, userQuad(user.ID), bookmarksEdge, paperQuad(paperID))
 	}
 
+	// Update user ownership offers
+	for _, paperID := range oldUser.OwnershipOffers {
+		removeQuad(tx, userQuad(user.ID), offeredEdge, paperQuad(paperID))
+	}
+
+	for _, paperID := range user.OwnershipOffers {
+		addQuad(tx, userQuad(user.ID), offeredEdge, paperQuad(paperID))
+	}
+
 	// Add user to all users
 	addQuad(tx, allUsersNode, allUsersEdge, userQuad(user.ID))
 
@@ -115,7 +147,7 @@ func (r *UserRepository) Delete(id int) error {
 // the caller to ensure that by checking if a paper already has an owner before adding a
 // new link (for now).
 func (r *UserRepository) PaperOwner(paperID int) (int, error) {
-	p := cayley.StartPath(r.store, paperQuad(paperID)).In(quad.Raw("owns"))
+	p := cayley.StartPath(r.store, paperQuad(paperID)).In(ownsEdge).HasReverse(allUsersEdge, allUsersNode)
 	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
 
 	it := r.store.buildIterator(p)
@@ -133,6 +165,107 @@ func (r *UserRepository) PaperOwner(paperID int) (int, error) {
 	return userID, nil
 }
 
+// TransferOwnership moves the ownership of a paper from a user to another in a single
+// transaction. The pending ownership offers for the paper are removed, whoever they
+// were made to.
+func (r *UserRepository) TransferOwnership(paperID, fromID, toID int) error {
+	p := cayley.StartPath(r.store, paperQuad(paperID)).In(offeredEdge)
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	tx := graph.NewTransaction()
+	for it.Next() {
+		userID, err := r.store.entity(it.Result(), "user")
+		if err != nil {
+			return err
+		}
+		removeQuad(tx, userQuad(userID), offeredEdge, paperQuad(paperID))
+	}
+
+	removeQuad(tx, userQuad(fromID), ownsEdge, paperQuad(paperID))
+	addQuad(tx, userQuad(toID), ownsEdge, paperQuad(paperID))
+
+	return r.store.ApplyTransaction(tx)
+}
+
+// Grant gives a user access to a paper, without going through a team. If canEdit
+// is false and the user could previously edit the paper, the edit right is removed.
+func (r *UserRepository) Grant(userID, paperID int, canEdit bool) error {
+	tx := graph.NewTransaction()
+	addQuad(tx, userQuad(userID), canSeeEdge, paperQuad(paperID))
+	if canEdit {
+		addQuad(tx, userQuad(userID), canEditEdge, paperQuad(paperID))
+	} else {
+		removeQuad(tx, userQuad(userID), canEditEdge, paperQuad(paperID))
+	}
+	return r.store.ApplyTransaction(tx)
+}
+
+// Revoke removes the access granted to a user on a paper. Access obtained through
+// teams or ownership is not affected.
+func (r *UserRepository) Revoke(userID, paperID int) error {
+	tx := graph.NewTransaction()
+	removeQuad(tx, userQuad(userID), canSeeEdge, paperQuad(paperID))
+	removeQuad(tx, userQuad(userID), canEditEdge, paperQuad(paperID))
+	return r.store.ApplyTransaction(tx)
+}
+
+// PaperGrantees retrieves the users that have been granted access to a paper, sorted
+// by id.
+func (r *UserRepository) PaperGrantees(paperID int) ([]auth.Grantee, error) {
+	canEdit, err := r.granteeIDs(paperID, canEditEdge)
+	if err != nil {
+		return nil, err
+	}
+
+	canSee, err := r.granteeIDs(paperID, canSeeEdge)
+	if err != nil {
+		return nil, err
+	}
+
+	grantees := make([]auth.Grantee, 0, len(canSee))
+	for userID := range canSee {
+		user, err := r.Get(userID)
+		if err != nil {
+			return nil, err
+		} else if user.ID == 0 {
+			continue
+		}
+
+		_, edit := canEdit[userID]
+		grantees = append(grantees, auth.Grantee{
+			ID:      user.ID,
+			Name:    user.Name,
+			Email:   user.Email,
+			CanEdit: edit,
+		})
+	}
+
+	sort.Sort(granteesByID(grantees))
+	return grantees, nil
+}
+
+// granteeIDs returns the ids of the users linked to a paper by edge, indexed by id.
+func (r *UserRepository) granteeIDs(paperID int, edge quad.Value) (map[int]struct{}, error) {
+	p := cayley.StartPath(r.store, paperQuad(paperID)).In(edge).HasReverse(allUsersEdge, allUsersNode)
+	p = p.Except(p.HasReverse(deletedEdge, deletedNode))
+
+	it := r.store.buildIterator(p)
+	defer it.Close()
+
+	ids := make(map[int]struct{})
+	for it.Next() {
+		userID, err := r.store.entity(it.Result(), "user")
+		if err != nil {
+			return nil, err
+		}
+		ids[userID] = struct{}{}
+	}
+
+	return ids, nil
+}
+
 // List returns all the user in the database
 func (r *UserRepository) List() ([]auth.User, error) {
 	p := cayley.StartPath(r.store, allUsersNode).Out(allUsersEdge)
@@ -161,22 +294,34 @@ func (r *UserRepository) List() ([]auth.User, error) {
 // -----------------------------------------------------------------------------
 // Helpers
 
+type granteesByID []auth.Grantee
+
+func (g granteesByID) Len() int           { return len(g) }
+func (g granteesByID) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+func (g granteesByID) Less(i, j int) bool { return g[i].ID < g[j].ID }
+
 func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.User, error) {
 	p := startingPoint.Clone().
 		SaveOptional(nameEdge, "name").
 		SaveOptional(emailEdge, "email").
 		SaveOptional(isAdminEdge, "isAdmin").
+		SaveOptional(verifiedEdge, "emailVerified").
 		SaveOptional(saltEdge, "salt").
-		SaveOptional(passwordEdge, "password")
+		SaveOptional(passwordEdge, "password").
+		SaveOptional(revokedAtEdge, "sessionsRevokedAt").
+		SaveOptional(googleIDEdge, "googleID")
 
 	it := r.store.buildIterator(p)
 	defer it.Close()
 
 	user := auth.User{
 		Owns:      make([]int, 0),
+		TeamOwns:  make([]int, 0),
 		CanSee:    make([]int, 0),
 		CanEdit:   make([]int, 0),
 		Bookmarks: make([]int, 0),
+
+		OwnershipOffers: make([]int, 0),
 	}
 	for it.Next() {
 		userID, err := r.store.entity(it.Result(), "user")
@@ -205,6 +350,12 @@ func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.U
 					return auth.User{}, err
 				}
 				user.IsAdmin = isAdmin == "true"
+			case "emailVerified":
+				verified, err := r.store.string(token)
+				if err != nil {
+					return auth.User{}, err
+				}
+				user.EmailVerified = verified == "true"
 			case "salt":
 				user.Salt, err = r.store.string(token)
 				if err != nil {
@@ -215,6 +366,16 @@ func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.U
 				if err != nil {
 					return auth.User{}, err
 				}
+			case "sessionsRevokedAt":
+				user.SessionsRevokedAt, err = r.store.time(token)
+				if err != nil {
+					return auth.User{}, err
+				}
+			case "googleID":
+				user.GoogleID, err = r.store.string(token)
+				if err != nil {
+					return auth.User{}, err
+				}
 			default:
 				// Do nothing
 				fmt.Println("unsupported tag", tag)
@@ -232,22 +393,53 @@ func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.U
 		[]string{"bookmarks"},
 		bookmarksEdge,
 	)
-	canSeePath := startingPoint.Clone().Out(
+	// The papers owned by a team are seen by all its members. Deleted teams do
+	// not grant anything anymore.
+	teamsPath := startingPoint.Clone().Out(
 		isAdminOfEdge,
 		isMemberOfEdge,
-	).OutWithTags(
+		isViewerOfEdge,
+	)
+	canSeePath := teamsPath.Except(teamsPath.HasReverse(deletedEdge, deletedNode)).OutWithTags(
 		[]string{"canSee"},
 		canSeeEdge,
+		ownsEdge,
 	)
-	canEditPath := startingPoint.Clone().Out(
+	// Viewers cannot edit the papers of their teams
+	editorTeamsPath := startingPoint.Clone().Out(
 		isAdminOfEdge,
 		isMemberOfEdge,
-	).OutWithTags(
+	)
+	canEditPath := editorTeamsPath.Except(editorTeamsPath.HasReverse(deletedEdge, deletedNode)).OutWithTags(
+		[]string{"canEdit"},
+		canEditEdge,
+		ownsEdge,
+	)
+	// Team admins hold the owner rights on the papers of the team
+	adminTeamsPath := startingPoint.Clone().Out(
+		isAdminOfEdge,
+	)
+	teamOwnsPath := adminTeamsPath.Except(adminTeamsPath.HasReverse(deletedEdge, deletedNode)).OutWithTags(
+		[]string{"teamOwns"},
+		ownsEdge,
+	)
+
+	offersPath := startingPoint.Clone().OutWithTags(
+		[]string{"ownershipOffers"},
+		offeredEdge,
+	)
+	grantedCanSeePath := startingPoint.Clone().OutWithTags(
+		[]string{"canSee"},
+		canSeeEdge,
+	)
+	grantedCanEditPath := startingPoint.Clone().OutWithTags(
 		[]string{"canEdit"},
 		canEditEdge,
 	)
 
-	p = ownsPath.Or(bookmarksPath).Or(canSeePath).Or(canEditPath)
+	p = ownsPath.Or(bookmarksPath).Or(offersPath).
+		Or(canSeePath).Or(canEditPath).Or(teamOwnsPath).
+		Or(grantedCanSeePath).Or(grantedCanEditPath)
 	it = r.store.buildIterator(p)
 	defer it.Close()
 
@@ -278,8 +470,12 @@ func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.U
 			user.Owns = append(user.Owns, paperID)
 			canSee[paperID] = struct{}{}
 			canEdit[paperID] = struct{}{}
+		case "teamOwns":
+			user.TeamOwns = append(user.TeamOwns, paperID)
 		case "bookmarks":
 			user.Bookmarks = append(user.Bookmarks, paperID)
+		case "ownershipOffers":
+			user.OwnershipOffers = append(user.OwnershipOffers, paperID)
 		default:
 			fmt.Println("unsupported tag", tag)
 		}
@@ -295,9 +491,11 @@ func (r *UserRepository) userFromStartingPoint(startingPoint *path.Path) (auth.U
 
 	// Sort the paper ids to look normal
 	sort.Ints(user.Owns)
+	sort.Ints(user.TeamOwns)
 	sort.Ints(user.CanSee)
 	sort.Ints(user.CanEdit)
 	sort.Ints(user.Bookmarks)
+	sort.Ints(user.OwnershipOffers)
 
 	return user, nil
 }
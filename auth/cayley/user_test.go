@@ -5,8 +5,10 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/bobinette/papernet/auth"
 	"github.com/bobinette/papernet/auth/testutil"
 )
 
@@ -32,3 +34,36 @@ func TestUserRepository(t *testing.T) {
 
 	testutil.TestUserRepository(t, repo)
 }
+
+func TestUserRepository_DeletedTeam(t *testing.T) {
+	repo, tearDown := createRepository(t)
+	defer tearDown()
+	teamRepo := NewTeamRepository(repo.store)
+
+	admin := auth.User{Email: "admin@paper.net"}
+	require.NoError(t, repo.Upsert(&admin), "inserting admin must not fail")
+	editor := auth.User{Email: "editor@paper.net"}
+	require.NoError(t, repo.Upsert(&editor), "inserting editor must not fail")
+
+	team := auth.Team{
+		Name: "Pizza",
+		Members: []auth.TeamMember{
+			{ID: admin.ID, Role: auth.TeamAdmin},
+			{ID: editor.ID, Role: auth.TeamEditor},
+		},
+		Owns:    []int{1},
+		CanSee:  []int{2, 3},
+		CanEdit: []int{3},
+	}
+	require.NoError(t, teamRepo.Upsert(&team), "inserting team must not fail")
+	require.NoError(t, teamRepo.Delete(team.ID), "deleting team must not fail")
+
+	// A deleted team does not grant any right anymore
+	for _, userID := range []int{admin.ID, editor.ID} {
+		user, err := repo.Get(userID)
+		require.NoError(t, err, "getting user %d must not fail", userID)
+		assert.Empty(t, user.CanSee, "user %d should not see the papers of the deleted team", userID)
+		assert.Empty(t, user.CanEdit, "user %d should not edit the papers of the deleted team", userID)
+		assert.Empty(t, user.TeamOwns, "user %d should not own the papers of the deleted team", userID)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/bobinette/papernet/auth"
 )
 
 var (
@@ -16,17 +19,49 @@ var (
 	nameEdge      = quad.Raw("name")
 	emailEdge     = quad.Raw("email")
 	isAdminEdge   = quad.Raw("isAdmin")
+	verifiedEdge  = quad.Raw("emailVerified")
 	ownsEdge      = quad.Raw("owns")
 	bookmarksEdge = quad.Raw("bookmarks")
 	saltEdge      = quad.Raw("salt")
 	passwordEdge  = quad.Raw("password")
+	offeredEdge   = quad.Raw("isOfferedOwnershipOf")
+	revokedAtEdge = quad.Raw("sessionsRevokedAt")
+	googleIDEdge  = quad.Raw("googleID")
+
+	descriptionEdge = quad.Raw("description")
 
 	isAdminOfEdge  = quad.Raw("isAdminOf")
-	isMemberOfEdge = quad.Raw("isMemberOf")
+	isMemberOfEdge = quad.Raw("isMemberOf") // editors, named after the original member role
+	isViewerOfEdge = quad.Raw("isViewerOf")
 	canSeeEdge     = quad.Raw("canSee")
 	canEditEdge    = quad.Raw("canEdit")
+
+	invitedToEdge    = quad.Raw("invitedTo")
+	invitedEmailEdge = quad.Raw("invitedEmail")
+	invitedByEdge    = quad.Raw("invitedBy")
+	stateEdge        = quad.Raw("state")
+	createdAtEdge    = quad.Raw("createdAt")
+	expiresAtEdge    = quad.Raw("expiresAt")
+
+	tokenKindEdge  = quad.Raw("tokenKind")
+	tokenOfEdge    = quad.Raw("isTokenOf")
+	usedEdge       = quad.Raw("used")
+	tokenEmailEdge = quad.Raw("tokenEmail")
+
+	scopesEdge = quad.Raw("scopes")
+
+	listOfEdge     = quad.Raw("isListOf")
+	papersEdge     = quad.Raw("papers")
+	sharedWithEdge = quad.Raw("isSharedWith")
 )
 
+// roleEdges maps the team roles to the edges linking the members to the team.
+var roleEdges = map[auth.TeamRole]quad.Raw{
+	auth.TeamAdmin:  isAdminOfEdge,
+	auth.TeamEditor: isMemberOfEdge,
+	auth.TeamViewer: isViewerOfEdge,
+}
+
 // userQuad crafts a user quad.IRI from an id: <user:id>
 func userQuad(id int) quad.IRI {
 	return quad.IRI(fmt.Sprintf("user:%d", id))
@@ -37,11 +72,36 @@ func teamQuad(id int) quad.IRI {
 	return quad.IRI(fmt.Sprintf("team:%d", id))
 }
 
+// invitationQuad crafts an invitation quad.IRI from an id: <invitation:id>
+func invitationQuad(id int) quad.IRI {
+	return quad.IRI(fmt.Sprintf("invitation:%d", id))
+}
+
 // paperQuad crafts a paper quad.IRI from an id: <paper:id>
 func paperQuad(id int) quad.IRI {
 	return quad.IRI(fmt.Sprintf("paper:%d", id))
 }
 
+// userTokenQuad crafts a user token quad.IRI from the hash of the token: <token:hash>
+func userTokenQuad(hash string) quad.IRI {
+	return quad.IRI(fmt.Sprintf("token:%s", hash))
+}
+
+// apiTokenQuad crafts an API token quad.IRI from an id: <apiToken:id>
+func apiTokenQuad(id int) quad.IRI {
+	return quad.IRI(fmt.Sprintf("apiToken:%d", id))
+}
+
+// readingListQuad crafts a reading list quad.IRI from an id: <readingList:id>
+func readingListQuad(id int) quad.IRI {
+	return quad.IRI(fmt.Sprintf("readingList:%d", id))
+}
+
+// unixRaw stores a time as a number of seconds.
+func unixRaw(t time.Time) quad.Raw {
+	return quad.Raw(strconv.FormatInt(t.Unix(), 10))
+}
+
 // splitIRI splits the iri into prefix and data, both as strings.
 func splitIRI(iri quad.IRI) (string, string) {
 	iriString := iri.String()
@@ -1,16 +1,19 @@
 package auth
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"time"
 
 	"github.com/bobinette/papernet/auth/cayley"
 	"github.com/bobinette/papernet/auth/http"
 	"github.com/bobinette/papernet/auth/services"
 	"github.com/bobinette/papernet/jwt"
 	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/mail"
 )
 
+// tokenCleanupInterval is the time between two deletions of the expired tokens.
+const tokenCleanupInterval = time.Hour
+
 type Configuration struct {
 	KeyPath string `toml:"key"`
 	Cayley  struct {
@@ -18,23 +21,15 @@ type Configuration struct {
 	} `toml:"cayley"`
 }
 
-// Start registers
-func Start(srv http.Server, conf Configuration, logger log.Logger) *services.UserService {
-	// Load key from file
-	keyData, err := ioutil.ReadFile(conf.KeyPath)
-	if err != nil {
-		logger.Fatal("could not open key file:", err)
-	}
-
-	// Extract key from data
-	var key struct {
-		Key string `json:"k"`
-	}
-	err = json.Unmarshal(keyData, &key)
+// Start registers the auth endpoints. The emails of the account flows are sent
+// with transport, from the address from.
+func Start(srv http.Server, conf Configuration, logger log.Logger, transport mail.Transport, from string) *services.UserService {
+	// Load keys from file
+	keys, err := jwt.LoadKeySet(conf.KeyPath)
 	if err != nil {
-		logger.Fatal("could not read key file:", err)
+		logger.Fatal("could not load key file:", err)
 	}
-	tokenEncoder := jwt.NewEncodeDecoder([]byte(key.Key))
+	tokenEncoder := jwt.NewKeySetEncodeDecoder(keys)
 
 	// Create repositories
 	store, err := cayley.NewStore(conf.Cayley.Store)
@@ -43,14 +38,48 @@ func Start(srv http.Server, conf Configuration, logger log.Logger) *services.Use
 	}
 	userRepository := cayley.NewUserRepository(store)
 	teamRepository := cayley.NewTeamRepository(store)
+	invitationRepository := cayley.NewInvitationRepository(store)
+	userTokenRepository := cayley.NewUserTokenRepository(store)
+	apiTokenRepository := cayley.NewAPITokenRepository(store)
+	readingListRepository := cayley.NewReadingListRepository(store)
+
+	// Start session endpoint
+	sessionService := services.NewSessionService(userRepository, userTokenRepository, tokenEncoder)
+	http.RegisterSessionEndpoints(srv, sessionService, keys)
+	go deleteExpiredTokens(sessionService, logger)
 
 	// Start user endpoint
-	userService := services.NewUserService(userRepository, tokenEncoder)
-	http.RegisterUserEndpoints(srv, userService, []byte(key.Key))
+	userService := services.NewUserService(userRepository, teamRepository, invitationRepository, sessionService)
+	http.RegisterUserEndpoints(srv, userService, keys, sessionService)
 
 	// Start team endpoint
-	teamService := services.NewTeamService(teamRepository, userRepository)
-	http.RegisterTeamEndpoints(srv, teamService, []byte(key.Key))
+	teamService := services.NewTeamService(teamRepository, userRepository, invitationRepository, tokenEncoder)
+	http.RegisterTeamEndpoints(srv, teamService, keys, sessionService)
+
+	// Start account endpoint
+	accountService := services.NewAccountService(userRepository, userTokenRepository, teamRepository, invitationRepository, sessionService, transport, from)
+	http.RegisterAccountEndpoints(srv, accountService, keys, sessionService)
+
+	// Start API token endpoint
+	apiTokenService := services.NewAPITokenService(apiTokenRepository, userRepository, tokenEncoder)
+	http.RegisterAPITokenEndpoints(srv, apiTokenService, keys, sessionService)
+
+	// Start reading list endpoint
+	readingListService := services.NewReadingListService(readingListRepository, userRepository, teamRepository)
+	http.RegisterReadingListEndpoints(srv, readingListService, keys, sessionService)
 
 	return userService
 }
+
+// deleteExpiredTokens regularly deletes the expired tokens, so that the refresh
+// tokens and the revoked access tokens do not pile up forever.
+func deleteExpiredTokens(sessionService *services.SessionService, logger log.Logger) {
+	for range time.Tick(tokenCleanupInterval) {
+		deleted, err := sessionService.DeleteExpiredTokens()
+		if err != nil {
+			logger.Error("could not delete expired tokens:", err)
+			continue
+		}
+		logger.Printf("deleted %d expired tokens", deleted)
+	}
+}
@@ -0,0 +1,67 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bobinette/papernet/auth/services"
+)
+
+type AccountEndpoint struct {
+	service *services.AccountService
+}
+
+func NewAccountEndpoint(s *services.AccountService) AccountEndpoint {
+	return AccountEndpoint{
+		service: s,
+	}
+}
+
+func (ep AccountEndpoint) ForgotPassword(ctx context.Context, r interface{}) (interface{}, error) {
+	req, ok := r.(EmailPasswordRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err := ep.service.RequestPasswordReset(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (ep AccountEndpoint) ResetPassword(ctx context.Context, r interface{}) (interface{}, error) {
+	req, ok := r.(ResetPasswordRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.ResetPassword(req.Token, req.Password)
+}
+
+func (ep AccountEndpoint) RequestEmailVerification(ctx context.Context, _ interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ep.service.RequestEmailVerification(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
+func (ep AccountEndpoint) VerifyEmail(ctx context.Context, r interface{}) (interface{}, error) {
+	token, ok := r.(string)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.VerifyEmail(token)
+}
@@ -0,0 +1,84 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/errors"
+)
+
+type APITokenEndpoint struct {
+	service *services.APITokenService
+}
+
+func NewAPITokenEndpoint(s *services.APITokenService) APITokenEndpoint {
+	return APITokenEndpoint{
+		service: s,
+	}
+}
+
+func (ep APITokenEndpoint) Create(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := r.(auth.APIToken)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Create(callerID, token)
+}
+
+func (ep APITokenEndpoint) List(ctx context.Context, _ interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ep.service.List(callerID)
+}
+
+func (ep APITokenEndpoint) Revoke(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err = ep.service.Revoke(callerID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
+type APITokenRequest struct {
+	UserID  int
+	TokenID int
+}
+
+// Get is used by the other services to check that an API token has not been
+// revoked.
+func (ep APITokenEndpoint) Get(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin only", errors.WithCode(http.StatusForbidden))
+	}
+
+	req, ok := r.(APITokenRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Get(req.UserID, req.TokenID)
+}
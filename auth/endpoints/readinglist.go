@@ -0,0 +1,187 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/errors"
+)
+
+type ReadingListEndpoint struct {
+	service *services.ReadingListService
+}
+
+func NewReadingListEndpoint(s *services.ReadingListService) ReadingListEndpoint {
+	return ReadingListEndpoint{
+		service: s,
+	}
+}
+
+// ReadingListRequest is used by all the routes acting on a reading list. The
+// fields not used by a route are ignored.
+type ReadingListRequest struct {
+	UserID  int    `json:"-"`
+	ListID  int    `json:"-"`
+	Name    string `json:"name"`
+	PaperID int    `json:"paperID"`
+	Papers  []int  `json:"papers"`
+	TeamID  int    `json:"teamID"`
+}
+
+func (ep ReadingListEndpoint) List(ctx context.Context, _ interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ep.service.List(callerID)
+}
+
+func (ep ReadingListEndpoint) Get(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Get(callerID, req.ListID)
+}
+
+func (ep ReadingListEndpoint) Create(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Create(callerID, req.Name)
+}
+
+func (ep ReadingListEndpoint) Rename(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Rename(callerID, req.ListID, req.Name)
+}
+
+func (ep ReadingListEndpoint) Delete(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err = ep.service.Delete(callerID, req.ListID)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
+func (ep ReadingListEndpoint) AddPaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.AddPaper(callerID, req.ListID, req.PaperID)
+}
+
+func (ep ReadingListEndpoint) RemovePaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.RemovePaper(callerID, req.ListID, req.PaperID)
+}
+
+func (ep ReadingListEndpoint) Reorder(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Reorder(callerID, req.ListID, req.Papers)
+}
+
+func (ep ReadingListEndpoint) Share(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Share(callerID, req.ListID, req.TeamID)
+}
+
+func (ep ReadingListEndpoint) Unshare(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Unshare(callerID, req.ListID, req.TeamID)
+}
+
+// UserList is used by the other services to get a reading list as seen by a
+// user.
+func (ep ReadingListEndpoint) UserList(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin only", errors.WithCode(http.StatusForbidden))
+	}
+
+	req, ok := r.(ReadingListRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Get(req.UserID, req.ListID)
+}
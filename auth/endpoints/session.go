@@ -0,0 +1,66 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+type SessionEndpoint struct {
+	service *services.SessionService
+}
+
+func NewSessionEndpoint(s *services.SessionService) SessionEndpoint {
+	return SessionEndpoint{
+		service: s,
+	}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (ep SessionEndpoint) Refresh(ctx context.Context, r interface{}) (interface{}, error) {
+	req, ok := r.(RefreshRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Refresh(req.RefreshToken)
+}
+
+func (ep SessionEndpoint) Logout(ctx context.Context, r interface{}) (interface{}, error) {
+	claims, ok := ctx.Value(kitjwt.JWTClaimsContextKey).(*jwt.Claims)
+	if !ok {
+		return nil, errors.New("no user", errors.WithCode(http.StatusUnauthorized))
+	}
+
+	req, ok := r.(RefreshRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err := ep.service.Logout(claims, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
+func (ep SessionEndpoint) LogoutAll(ctx context.Context, _ interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ep.service.LogoutAll(callerID)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bobinette/papernet/auth"
 	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/errors"
 )
 
 type TeamEndpoint struct {
@@ -60,6 +61,39 @@ func (ep TeamEndpoint) Invite(ctx context.Context, r interface{}) (interface{},
 	return ep.service.Invite(callerID, req.TeamID, req.Email)
 }
 
+type InvitationRequest struct {
+	InvitationID int
+	Token        string
+}
+
+func (ep TeamEndpoint) AcceptInvitation(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(InvitationRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.AcceptInvitation(callerID, req.InvitationID, req.Token)
+}
+
+func (ep TeamEndpoint) DeclineInvitation(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(InvitationRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.DeclineInvitation(callerID, req.InvitationID, req.Token)
+}
+
 type KickRequest struct {
 	TeamID   int
 	MemberID int
@@ -99,6 +133,106 @@ func (ep TeamEndpoint) Share(ctx context.Context, r interface{}) (interface{}, e
 	return ep.service.Share(callerID, req.TeamID, req.PaperID, req.CanEdit)
 }
 
+type UnshareRequest struct {
+	TeamID   int
+	PaperID  int
+	EditOnly bool
+}
+
+func (ep TeamEndpoint) Unshare(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(UnshareRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Unshare(callerID, req.TeamID, req.PaperID, req.EditOnly)
+}
+
+type TeamPaperCreateRequest struct {
+	TeamID  int
+	UserID  int
+	PaperID int
+}
+
+func (ep TeamEndpoint) CreatePaper(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin route", errors.Forbidden())
+	}
+
+	req, ok := r.(TeamPaperCreateRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.CreatePaper(req.UserID, req.TeamID, req.PaperID)
+}
+
+type UpdateTeamRequest struct {
+	TeamID      int
+	Name        string
+	Description string
+}
+
+func (ep TeamEndpoint) Update(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(UpdateTeamRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Update(callerID, req.TeamID, req.Name, req.Description)
+}
+
+type SetRoleRequest struct {
+	TeamID   int
+	MemberID int
+	Role     auth.TeamRole
+}
+
+func (ep TeamEndpoint) SetRole(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(SetRoleRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.SetRole(callerID, req.TeamID, req.MemberID, req.Role)
+}
+
+func (ep TeamEndpoint) Leave(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(DeleteTeamRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err = ep.service.Leave(callerID, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusNoContent}, nil
+}
+
 type DeleteTeamRequest struct {
 	TeamID int
 }
@@ -120,3 +254,43 @@ func (ep TeamEndpoint) Delete(ctx context.Context, r interface{}) (interface{},
 	}
 	return statusCoder{code: http.StatusNoContent}, nil
 }
+
+// TeamsOfUser is used by the other services to get the teams of a user.
+func (ep TeamEndpoint) TeamsOfUser(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin only", errors.WithCode(http.StatusForbidden))
+	}
+
+	userID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.GetForUser(userID)
+}
+
+type UserTeamRequest struct {
+	UserID int
+	TeamID int
+}
+
+// UserTeam is used by the other services to get a team a user is a member
+// of.
+func (ep TeamEndpoint) UserTeam(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin only", errors.WithCode(http.StatusForbidden))
+	}
+
+	req, ok := r.(UserTeamRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.Get(req.UserID, req.TeamID)
+}
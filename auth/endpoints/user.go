@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/bobinette/papernet/errors"
 
@@ -19,13 +20,30 @@ func NewUserEndpoint(s *services.UserService) UserEndpoint {
 	}
 }
 
+// MeResponse is the user as seen by themselves, along with their pending
+// team invitations.
+type MeResponse struct {
+	auth.User
+	Invitations []auth.Invitation `json:"invitations"`
+}
+
 func (ep UserEndpoint) Me(ctx context.Context, _ interface{}) (interface{}, error) {
 	callerID, _, err := extractUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return ep.service.Get(callerID)
+	user, err := ep.service.Get(callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := ep.service.PendingInvitations(callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return MeResponse{User: user, Invitations: invitations}, nil
 }
 
 func (ep UserEndpoint) User(ctx context.Context, r interface{}) (interface{}, error) {
@@ -60,6 +78,43 @@ func (ep UserEndpoint) Upsert(ctx context.Context, r interface{}) (interface{},
 	return ep.service.Upsert(user)
 }
 
+type GoogleLinkRequest struct {
+	UserID   int    `json:"-"`
+	GoogleID string `json:"googleId"`
+}
+
+func (ep UserEndpoint) LinkGoogle(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin route", errors.Forbidden())
+	}
+
+	req, ok := r.(GoogleLinkRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.LinkGoogle(req.UserID, req.GoogleID)
+}
+
+func (ep UserEndpoint) UnlinkGoogle(ctx context.Context, r interface{}) (interface{}, error) {
+	_, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	} else if !isAdmin {
+		return nil, errors.New("admin route", errors.Forbidden())
+	}
+
+	userID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.UnlinkGoogle(userID)
+}
+
 type EmailPasswordRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -71,11 +126,7 @@ func (ep UserEndpoint) SignUp(ctx context.Context, r interface{}) (interface{},
 		return nil, errInvalidRequest
 	}
 
-	token, err := ep.service.SignUp(req.Email, req.Password)
-	if err != nil {
-		return nil, err
-	}
-	return map[string]string{"access_token": token}, nil
+	return ep.service.SignUp(req.Email, req.Password)
 }
 
 func (ep UserEndpoint) Login(ctx context.Context, r interface{}) (interface{}, error) {
@@ -84,11 +135,13 @@ func (ep UserEndpoint) Login(ctx context.Context, r interface{}) (interface{}, e
 		return nil, errInvalidRequest
 	}
 
-	token, err := ep.service.Login(req.Email, req.Password)
-	if err != nil {
-		return nil, err
-	}
-	return map[string]string{"access_token": token}, nil
+	return ep.service.Login(req.Email, req.Password)
+}
+
+type TokenRequest struct {
+	UserID int
+	// Session is set to also issue a refresh token, when logging the user in.
+	Session bool
 }
 
 func (ep UserEndpoint) Token(ctx context.Context, r interface{}) (interface{}, error) {
@@ -99,16 +152,12 @@ func (ep UserEndpoint) Token(ctx context.Context, r interface{}) (interface{}, e
 		return nil, errors.New("admin route", errors.Forbidden())
 	}
 
-	userID, ok := r.(int)
+	req, ok := r.(TokenRequest)
 	if !ok {
 		return nil, errInvalidRequest
 	}
 
-	token, err := ep.service.Token(userID)
-	if err != nil {
-		return nil, err
-	}
-	return map[string]string{"access_token": token}, nil
+	return ep.service.Token(req.UserID, req.Session)
 }
 
 type PaperCreateRequest struct {
@@ -150,3 +199,117 @@ func (ep UserEndpoint) Bookmark(ctx context.Context, r interface{}) (interface{}
 
 	return ep.service.Bookmark(userID, req.PaperID, req.Bookmark)
 }
+
+type SharePaperRequest struct {
+	PaperID int
+	Email   string
+	CanEdit bool
+}
+
+func (ep UserEndpoint) SharePaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(SharePaperRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.SharePaper(callerID, req.PaperID, req.Email, req.CanEdit)
+}
+
+type UnsharePaperRequest struct {
+	PaperID int
+	UserID  int
+}
+
+func (ep UserEndpoint) UnsharePaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(UnsharePaperRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.UnsharePaper(callerID, req.PaperID, req.UserID)
+}
+
+func (ep UserEndpoint) PaperGrantees(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.PaperGrantees(callerID, paperID)
+}
+
+type TransferPaperRequest struct {
+	PaperID int
+	Email   string
+	Force   bool
+}
+
+// TransferPaper offers the ownership of a paper to a user. Admins can force the
+// transfer, in which case the recipient does not have to accept it.
+func (ep UserEndpoint) TransferPaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, isAdmin, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(TransferPaperRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	if req.Force {
+		if !isAdmin {
+			return nil, errors.New("only admins can force a transfer", errors.Forbidden())
+		}
+		return ep.service.TransferPaper(req.PaperID, req.Email)
+	}
+
+	err = ep.service.OfferPaper(callerID, req.PaperID, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	return statusCoder{code: http.StatusAccepted}, nil
+}
+
+func (ep UserEndpoint) AcceptPaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.AcceptPaper(callerID, paperID)
+}
+
+func (ep UserEndpoint) DeclinePaper(ctx context.Context, r interface{}) (interface{}, error) {
+	callerID, _, err := extractUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	return ep.service.DeclinePaper(callerID, paperID)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/auth/endpoints"
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func RegisterAccountEndpoints(srv Server, service *services.AccountService, keys *jwt.KeySet, revoker jwt.Revoker) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+	authenticationMiddleware := jwt.RevocableMiddleware(keys, revoker)
+
+	// Create endpoint
+	ep := endpoints.NewAccountEndpoint(service)
+
+	// Forgot password handler
+	forgotPasswordHandler := kithttp.NewServer(
+		ep.ForgotPassword,
+		decodeEmailPasswordRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Reset password handler
+	resetPasswordHandler := kithttp.NewServer(
+		ep.ResetPassword,
+		decodeResetPasswordRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Request email verification handler
+	requestVerificationHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.RequestEmailVerification),
+		decodeRequestEmailVerificationRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Verify email handler
+	verifyEmailHandler := kithttp.NewServer(
+		ep.VerifyEmail,
+		decodeVerifyEmailRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/auth/v2/password/forgot", "POST", forgotPasswordHandler)
+	srv.RegisterHandler("/auth/v2/password/reset", "POST", resetPasswordHandler)
+	srv.RegisterHandler("/auth/v2/me/verify", "POST", requestVerificationHandler)
+	srv.RegisterHandler("/auth/v2/email/verify", "POST", verifyEmailHandler)
+}
+
+func decodeResetPasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var req endpoints.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeRequestEmailVerificationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return nil, nil
+}
+
+func decodeVerifyEmailRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Token, nil
+}
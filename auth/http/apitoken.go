@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/endpoints"
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func RegisterAPITokenEndpoints(srv Server, service *services.APITokenService, keys *jwt.KeySet, revoker jwt.Revoker) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	// API tokens cannot be used to manage the API tokens
+	jwtMiddleware := jwt.RevocableMiddleware(keys, revoker)
+
+	// Create endpoint
+	ep := endpoints.NewAPITokenEndpoint(service)
+
+	listHandler := kithttp.NewServer(
+		jwtMiddleware(ep.List),
+		decodeListAPITokensRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	createHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Create),
+		decodeCreateAPITokenRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	revokeHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Revoke),
+		decodeRevokeAPITokenRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Get),
+		decodeGetAPITokenRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Routes
+	srv.RegisterHandler("/auth/v2/tokens", "GET", listHandler)
+	srv.RegisterHandler("/auth/v2/tokens", "POST", createHandler)
+	srv.RegisterHandler("/auth/v2/tokens/:id", "DELETE", revokeHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/tokens/:tokenID", "GET", getHandler)
+}
+
+func decodeListAPITokensRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return nil, nil
+}
+
+func decodeCreateAPITokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var token auth.APIToken
+	err := json.NewDecoder(r.Body).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func decodeRevokeAPITokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	tokenID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenID, nil
+}
+
+func decodeGetAPITokenRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, err := strconv.Atoi(params["tokenID"])
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints.APITokenRequest{UserID: userID, TokenID: tokenID}, nil
+}
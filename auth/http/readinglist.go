@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/auth/endpoints"
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func RegisterReadingListEndpoints(srv Server, service *services.ReadingListService, keys *jwt.KeySet, revoker jwt.Revoker) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	jwtMiddleware := jwt.RevocableMiddleware(keys, revoker)
+
+	// Create endpoint
+	ep := endpoints.NewReadingListEndpoint(service)
+
+	listHandler := kithttp.NewServer(
+		jwtMiddleware(ep.List),
+		decodeListReadingListsRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	createHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Create),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Get),
+		decodeReadingListRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	renameHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Rename),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	deleteHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Delete),
+		decodeReadingListRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	addPaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.AddPaper),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	reorderHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Reorder),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	removePaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.RemovePaper),
+		decodeRemoveReadingListPaperRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	shareHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Share),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	unshareHandler := kithttp.NewServer(
+		jwtMiddleware(ep.Unshare),
+		decodeReadingListBodyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	userListHandler := kithttp.NewServer(
+		jwtMiddleware(ep.UserList),
+		decodeUserReadingListRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Routes
+	srv.RegisterHandler("/auth/v2/lists", "GET", listHandler)
+	srv.RegisterHandler("/auth/v2/lists", "POST", createHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id", "GET", getHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id", "PUT", renameHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id", "DELETE", deleteHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id/papers", "POST", addPaperHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id/papers", "PUT", reorderHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id/papers/:paperID", "DELETE", removePaperHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id/share", "POST", shareHandler)
+	srv.RegisterHandler("/auth/v2/lists/:id/unshare", "POST", unshareHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/lists/:listID", "GET", userListHandler)
+}
+
+func decodeListReadingListsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return nil, nil
+}
+
+// decodeReadingListRequest decodes the id of the list from the url.
+func decodeReadingListRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	listID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, errors.New("invalid list id", errors.WithCause(err), errors.BadRequest())
+	}
+
+	return endpoints.ReadingListRequest{ListID: listID}, nil
+}
+
+// decodeReadingListBodyRequest decodes the body and, when there is one, the
+// id of the list from the url.
+func decodeReadingListBodyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var req endpoints.ReadingListRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	params := ctx.Value("params").(map[string]string)
+	if id, ok := params["id"]; ok {
+		req.ListID, err = strconv.Atoi(id)
+		if err != nil {
+			return nil, errors.New("invalid list id", errors.WithCause(err), errors.BadRequest())
+		}
+	}
+
+	return req, nil
+}
+
+func decodeRemoveReadingListPaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	listID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, errors.New("invalid list id", errors.WithCause(err), errors.BadRequest())
+	}
+
+	paperID, err := strconv.Atoi(params["paperID"])
+	if err != nil {
+		return nil, errors.New("invalid paper id", errors.WithCause(err), errors.BadRequest())
+	}
+
+	return endpoints.ReadingListRequest{ListID: listID, PaperID: paperID}, nil
+}
+
+func decodeUserReadingListRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	listID, err := strconv.Atoi(params["listID"])
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints.ReadingListRequest{UserID: userID, ListID: listID}, nil
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/auth/endpoints"
+	"github.com/bobinette/papernet/auth/services"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func RegisterSessionEndpoints(srv Server, service *services.SessionService, keys *jwt.KeySet) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+	authenticationMiddleware := jwt.RevocableMiddleware(keys, service)
+
+	// Create endpoint
+	ep := endpoints.NewSessionEndpoint(service)
+
+	// Refresh handler
+	refreshHandler := kithttp.NewServer(
+		ep.Refresh,
+		decodeRefreshRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Logout handler
+	logoutHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.Logout),
+		decodeLogoutRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Logout of all devices handler
+	logoutAllHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.LogoutAll),
+		decodeLogoutAllRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/auth/v2/refresh", "POST", refreshHandler)
+	srv.RegisterHandler("/auth/v2/logout", "POST", logoutHandler)
+	srv.RegisterHandler("/auth/v2/logout/all", "POST", logoutAllHandler)
+}
+
+func decodeRefreshRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var req endpoints.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeLogoutRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	// The refresh token is optional
+	var req endpoints.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeLogoutAllRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return nil, nil
+}
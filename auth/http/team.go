@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -15,12 +16,12 @@ import (
 	"github.com/bobinette/papernet/jwt"
 )
 
-func RegisterTeamEndpoints(srv Server, service *services.TeamService, jwtKey []byte) {
+func RegisterTeamEndpoints(srv Server, service *services.TeamService, keys *jwt.KeySet, revoker jwt.Revoker) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
-	authenticationMiddleware := jwt.Middleware(jwtKey)
+	authenticationMiddleware := jwt.RevocableMiddleware(keys, revoker)
 
 	// Create endpoint
 	ep := endpoints.NewTeamEndpoint(service)
@@ -65,6 +66,62 @@ func RegisterTeamEndpoints(srv Server, service *services.TeamService, jwtKey []b
 		opts...,
 	)
 
+	// Update team handler
+	updateHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.Update),
+		decodeUpdateTeamRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Set role handler
+	setRoleHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.SetRole),
+		decodeSetRoleRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Leave team handler
+	leaveHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.Leave),
+		decodeDeleteTeamRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Accept invitation handler
+	acceptInvitationHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.AcceptInvitation),
+		decodeInvitationRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Decline invitation handler
+	declineInvitationHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.DeclineInvitation),
+		decodeInvitationRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Unshare paper handler
+	unshareHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.Unshare),
+		decodeUnshareRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Create team paper handler
+	createPaperHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.CreatePaper),
+		decodeCreateTeamPaperRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
 	// Delete team handler
 	deleteHandler := kithttp.NewServer(
 		authenticationMiddleware(ep.Delete),
@@ -73,13 +130,37 @@ func RegisterTeamEndpoints(srv Server, service *services.TeamService, jwtKey []b
 		opts...,
 	)
 
+	// Teams and team of a user handlers, used by the other services
+	teamsOfUserHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.TeamsOfUser),
+		decodeTeamsOfUserRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	userTeamHandler := kithttp.NewServer(
+		authenticationMiddleware(ep.UserTeam),
+		decodeUserTeamRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
 	// Register all handlers
 	srv.RegisterHandler("/auth/v2/teams", "GET", userTeamsHandler)
 	srv.RegisterHandler("/auth/v2/teams", "POST", createTeamHandler)
+	srv.RegisterHandler("/auth/v2/teams/:id", "PUT", updateHandler)
 	srv.RegisterHandler("/auth/v2/teams/:id", "DELETE", deleteHandler)
+	srv.RegisterHandler("/auth/v2/teams/:id/role", "POST", setRoleHandler)
+	srv.RegisterHandler("/auth/v2/teams/:id/leave", "POST", leaveHandler)
 	srv.RegisterHandler("/auth/v2/teams/:id/invite", "POST", inviteHandler)
 	srv.RegisterHandler("/auth/v2/teams/:id/kick", "POST", kickHandler)
 	srv.RegisterHandler("/auth/v2/teams/:id/share", "POST", shareHandler)
+	srv.RegisterHandler("/auth/v2/teams/:id/unshare", "POST", unshareHandler)
+	srv.RegisterHandler("/auth/v2/teams/:id/papers", "POST", createPaperHandler)
+	srv.RegisterHandler("/auth/v2/invitations/:id/accept", "POST", acceptInvitationHandler)
+	srv.RegisterHandler("/auth/v2/invitations/:id/decline", "POST", declineInvitationHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/teams", "GET", teamsOfUserHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/teams/:teamID", "GET", userTeamHandler)
 }
 
 func decodeUserTeamsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -124,6 +205,32 @@ func decodeInviteRequest(ctx context.Context, r *http.Request) (interface{}, err
 	return req, nil
 }
 
+// decodeInvitationRequest reads the invitation id from the url. The token of the
+// invitation link is optional.
+func decodeInvitationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	invitationID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	req := endpoints.InvitationRequest{
+		InvitationID: invitationID,
+		Token:        body.Token,
+	}
+	return req, nil
+}
+
 func decodeKickRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	defer r.Body.Close()
 
@@ -174,6 +281,110 @@ func decodeShareRequest(ctx context.Context, r *http.Request) (interface{}, erro
 	return req, nil
 }
 
+func decodeUnshareRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	teamID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		PaperID  int  `json:"paperID"`
+		EditOnly bool `json:"editOnly"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.UnshareRequest{
+		TeamID:   teamID,
+		PaperID:  body.PaperID,
+		EditOnly: body.EditOnly,
+	}
+	return req, nil
+}
+
+func decodeCreateTeamPaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	teamID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		UserID  int `json:"userID"`
+		PaperID int `json:"paperID"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.TeamPaperCreateRequest{
+		TeamID:  teamID,
+		UserID:  body.UserID,
+		PaperID: body.PaperID,
+	}
+	return req, nil
+}
+
+func decodeUpdateTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	teamID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.UpdateTeamRequest{
+		TeamID:      teamID,
+		Name:        body.Name,
+		Description: body.Description,
+	}
+	return req, nil
+}
+
+func decodeSetRoleRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	teamID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		MemberID int           `json:"memberID"`
+		Role     auth.TeamRole `json:"role"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.SetRoleRequest{
+		TeamID:   teamID,
+		MemberID: body.MemberID,
+		Role:     body.Role,
+	}
+	return req, nil
+}
+
 func decodeDeleteTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	defer r.Body.Close()
 
@@ -188,3 +399,36 @@ func decodeDeleteTeamRequest(ctx context.Context, r *http.Request) (interface{},
 	}
 	return req, nil
 }
+
+func decodeTeamsOfUserRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return userID, nil
+}
+
+func decodeUserTeamRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	teamID, err := strconv.Atoi(params["teamID"])
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.UserTeamRequest{
+		UserID: userID,
+		TeamID: teamID,
+	}
+	return req, nil
+}
@@ -15,13 +15,13 @@ import (
 	"github.com/bobinette/papernet/jwt"
 )
 
-func RegisterUserEndpoints(srv Server, service *services.UserService, jwtKey []byte) {
+func RegisterUserEndpoints(srv Server, service *services.UserService, keys *jwt.KeySet, revoker jwt.Revoker) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
 
-	jwtMiddleware := jwt.Middleware(jwtKey)
+	jwtMiddleware := jwt.RevocableMiddleware(keys, revoker)
 
 	// Create endpoint
 	ep := endpoints.NewUserEndpoint(service)
@@ -48,6 +48,20 @@ func RegisterUserEndpoints(srv Server, service *services.UserService, jwtKey []b
 		opts...,
 	)
 
+	linkGoogleHandler := kithttp.NewServer(
+		jwtMiddleware(ep.LinkGoogle),
+		decodeLinkGoogleRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	unlinkGoogleHandler := kithttp.NewServer(
+		jwtMiddleware(ep.UnlinkGoogle),
+		decodeUserRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
 	signUpHandler := kithttp.NewServer(
 		ep.SignUp,
 		decodeEmailPasswordRequest,
@@ -83,15 +97,65 @@ func RegisterUserEndpoints(srv Server, service *services.UserService, jwtKey []b
 		opts...,
 	)
 
+	sharePaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.SharePaper),
+		decodeSharePaperRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	unsharePaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.UnsharePaper),
+		decodeUnsharePaperRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	paperGranteesHandler := kithttp.NewServer(
+		jwtMiddleware(ep.PaperGrantees),
+		decodePaperIDRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	transferPaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.TransferPaper),
+		decodeTransferPaperRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	acceptPaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.AcceptPaper),
+		decodePaperIDRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	declinePaperHandler := kithttp.NewServer(
+		jwtMiddleware(ep.DeclinePaper),
+		decodePaperIDRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
 	// Routes
 	srv.RegisterHandler("/auth/v2/me", "GET", meHandler)
 	srv.RegisterHandler("/auth/v2/users/:id", "GET", userHandler)
 	srv.RegisterHandler("/auth/v2/users", "POST", userUpsertHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/google", "POST", linkGoogleHandler)
+	srv.RegisterHandler("/auth/v2/users/:id/google", "DELETE", unlinkGoogleHandler)
 	srv.RegisterHandler("/auth/v2/signup", "POST", signUpHandler)
 	srv.RegisterHandler("/auth/v2/login", "POST", loginHandler)
 	srv.RegisterHandler("/auth/v2/users/:id/token", "GET", tokenHandler)
 	srv.RegisterHandler("/auth/v2/users/:id/papers", "POST", createPaperHandler)
 	srv.RegisterHandler("/auth/v2/bookmarks", "POST", bookmarkHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/grantees", "GET", paperGranteesHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/share", "POST", sharePaperHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/unshare", "POST", unsharePaperHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/transfer", "POST", transferPaperHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/transfer/accept", "POST", acceptPaperHandler)
+	srv.RegisterHandler("/auth/v2/papers/:id/transfer/decline", "POST", declinePaperHandler)
 }
 
 func decodeMeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -123,6 +187,25 @@ func decodeUserUpsertRequest(ctx context.Context, r *http.Request) (interface{},
 	return user, nil
 }
 
+func decodeLinkGoogleRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var req endpoints.GoogleLinkRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, err
+	}
+	req.UserID = userID
+
+	return req, nil
+}
+
 func decodeEmailPasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	defer r.Body.Close()
 
@@ -144,7 +227,10 @@ func decodeTokenRequest(ctx context.Context, r *http.Request) (interface{}, erro
 		return nil, err
 	}
 
-	return userID, nil
+	return endpoints.TokenRequest{
+		UserID:  userID,
+		Session: r.URL.Query().Get("session") == "true",
+	}, nil
 }
 
 func decodeCreatePaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
@@ -189,3 +275,91 @@ func decodeBookmarkRequest(ctx context.Context, r *http.Request) (interface{}, e
 	}
 	return req, nil
 }
+
+func decodeSharePaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Email   string `json:"email"`
+		CanEdit bool   `json:"canEdit"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.SharePaperRequest{
+		PaperID: paperID,
+		Email:   body.Email,
+		CanEdit: body.CanEdit,
+	}
+	return req, nil
+}
+
+func decodeUnsharePaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		UserID int `json:"userID"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.UnsharePaperRequest{
+		PaperID: paperID,
+		UserID:  body.UserID,
+	}
+	return req, nil
+}
+
+func decodePaperIDRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close() // Close body
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return paperID, nil
+}
+
+func decodeTransferPaperRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Email string `json:"email"`
+		Force bool   `json:"force"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := endpoints.TransferPaperRequest{
+		PaperID: paperID,
+		Email:   body.Email,
+		Force:   body.Force,
+	}
+	return req, nil
+}
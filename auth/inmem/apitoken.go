@@ -0,0 +1,85 @@
+package inmem
+
+import (
+	"sync"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+type InMemAPITokenRepository struct {
+	mu     sync.Locker
+	tokens []auth.APIToken
+	maxID  int
+}
+
+func NewInMemAPITokenRepository() *InMemAPITokenRepository {
+	return &InMemAPITokenRepository{
+		mu:     &sync.Mutex{},
+		tokens: make([]auth.APIToken, 0),
+		maxID:  0,
+	}
+}
+
+func (r *InMemAPITokenRepository) Get(id int) (auth.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.ID == id {
+			return token, nil
+		}
+	}
+	return auth.APIToken{}, nil
+}
+
+func (r *InMemAPITokenRepository) ListForUser(userID int) ([]auth.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := make([]auth.APIToken, 0)
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *InMemAPITokenRepository) Upsert(token *auth.APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token.ID == 0 {
+		r.maxID++
+		token.ID = r.maxID
+	} else if token.ID > r.maxID {
+		r.maxID = token.ID
+	}
+
+	// The signed token is never stored
+	stored := *token
+	stored.Token = ""
+
+	for i, t := range r.tokens {
+		if t.ID == token.ID {
+			r.tokens[i] = stored
+			return nil
+		}
+	}
+
+	r.tokens = append(r.tokens, stored)
+	return nil
+}
+
+func (r *InMemAPITokenRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, token := range r.tokens {
+		if token.ID == id {
+			r.tokens = append(r.tokens[:i], r.tokens[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func TestInMemAPITokenRepository(t *testing.T) {
+	repo := NewInMemAPITokenRepository()
+	testutil.TestAPITokenRepository(t, repo)
+}
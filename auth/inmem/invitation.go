@@ -0,0 +1,72 @@
+package inmem
+
+import (
+	"sync"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+type InMemInvitationRepository struct {
+	mu          sync.Locker
+	invitations []auth.Invitation
+	maxID       int
+}
+
+func NewInMemInvitationRepository() *InMemInvitationRepository {
+	return &InMemInvitationRepository{
+		mu:          &sync.Mutex{},
+		invitations: make([]auth.Invitation, 0),
+		maxID:       0,
+	}
+}
+
+func (r *InMemInvitationRepository) Get(id int) (auth.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invitation := range r.invitations {
+		if invitation.ID == id {
+			return invitation, nil
+		}
+	}
+	return auth.Invitation{}, nil
+}
+
+func (r *InMemInvitationRepository) ListForEmail(email string) ([]auth.Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitations := make([]auth.Invitation, 0)
+	for _, invitation := range r.invitations {
+		if invitation.Email == email {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *InMemInvitationRepository) Upsert(invitation *auth.Invitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if invitation.ID == 0 {
+		r.maxID++
+		invitation.ID = r.maxID
+	} else if invitation.ID > r.maxID {
+		r.maxID = invitation.ID
+	}
+
+	// The token is never stored
+	stored := *invitation
+	stored.Token = ""
+
+	for i, inv := range r.invitations {
+		if inv.ID == invitation.ID {
+			r.invitations[i] = stored
+			return nil
+		}
+	}
+
+	r.invitations = append(r.invitations, stored)
+	return nil
+}
@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func TestInMemInvitationRepository(t *testing.T) {
+	repo := NewInMemInvitationRepository()
+	testutil.TestInvitationRepository(t, repo)
+}
@@ -0,0 +1,106 @@
+package inmem
+
+import (
+	"sync"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+type InMemReadingListRepository struct {
+	mu    sync.Locker
+	lists []auth.ReadingList
+	maxID int
+}
+
+func NewInMemReadingListRepository() *InMemReadingListRepository {
+	return &InMemReadingListRepository{
+		mu:    &sync.Mutex{},
+		lists: make([]auth.ReadingList, 0),
+		maxID: 0,
+	}
+}
+
+func (r *InMemReadingListRepository) Get(id int) (auth.ReadingList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, list := range r.lists {
+		if list.ID == id {
+			return copyReadingList(list), nil
+		}
+	}
+	return auth.ReadingList{}, nil
+}
+
+func (r *InMemReadingListRepository) ListForUser(userID int) ([]auth.ReadingList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lists := make([]auth.ReadingList, 0)
+	for _, list := range r.lists {
+		if list.OwnerID == userID {
+			lists = append(lists, copyReadingList(list))
+		}
+	}
+	return lists, nil
+}
+
+func (r *InMemReadingListRepository) ListForTeam(teamID int) ([]auth.ReadingList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lists := make([]auth.ReadingList, 0)
+	for _, list := range r.lists {
+		for _, id := range list.Teams {
+			if id == teamID {
+				lists = append(lists, copyReadingList(list))
+				break
+			}
+		}
+	}
+	return lists, nil
+}
+
+func (r *InMemReadingListRepository) Upsert(list *auth.ReadingList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if list.ID == 0 {
+		r.maxID++
+		list.ID = r.maxID
+	} else if list.ID > r.maxID {
+		r.maxID = list.ID
+	}
+
+	stored := copyReadingList(*list)
+	for i, l := range r.lists {
+		if l.ID == list.ID {
+			r.lists[i] = stored
+			return nil
+		}
+	}
+
+	r.lists = append(r.lists, stored)
+	return nil
+}
+
+func (r *InMemReadingListRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, list := range r.lists {
+		if list.ID == id {
+			r.lists = append(r.lists[:i], r.lists[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// copyReadingList copies the slices of a list, so that the lists returned can
+// be modified by the caller.
+func copyReadingList(list auth.ReadingList) auth.ReadingList {
+	list.Papers = append(make([]int, 0, len(list.Papers)), list.Papers...)
+	list.Teams = append(make([]int, 0, len(list.Teams)), list.Teams...)
+	return list
+}
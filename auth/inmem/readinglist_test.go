@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func TestInMemReadingListRepository(t *testing.T) {
+	repo := NewInMemReadingListRepository()
+	testutil.TestReadingListRepository(t, repo)
+}
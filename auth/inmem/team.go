@@ -74,6 +74,21 @@ func (r *InMemTeamRepository) Upsert(team *auth.Team) error {
 	return nil
 }
 
+func (r *InMemTeamRepository) PaperOwner(paperID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, team := range r.teams {
+		for _, ownedID := range team.Owns {
+			if ownedID == paperID {
+				return team.ID, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
 func (r *InMemTeamRepository) Delete(id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -0,0 +1,75 @@
+package inmem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+type InMemUserTokenRepository struct {
+	mu     sync.Locker
+	tokens map[string]auth.UserToken
+}
+
+func NewInMemUserTokenRepository() *InMemUserTokenRepository {
+	return &InMemUserTokenRepository{
+		mu:     &sync.Mutex{},
+		tokens: make(map[string]auth.UserToken),
+	}
+}
+
+func (r *InMemUserTokenRepository) Get(hash string) (auth.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.tokens[hash], nil
+}
+
+func (r *InMemUserTokenRepository) ListForUser(userID int, kind auth.UserTokenKind) ([]auth.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := make([]auth.UserToken, 0)
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.Kind == kind {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *InMemUserTokenRepository) Upsert(token *auth.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.Hash] = *token
+	return nil
+}
+
+func (r *InMemUserTokenRepository) Use(hash string, kind auth.UserTokenKind) (auth.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token := r.tokens[hash]
+	if token.Hash != "" && token.Kind == kind && !token.Used {
+		used := token
+		used.Used = true
+		r.tokens[hash] = used
+	}
+	return token, nil
+}
+
+func (r *InMemUserTokenRepository) DeleteExpired(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for hash, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,12 @@
+package inmem
+
+import (
+	"testing"
+
+	"github.com/bobinette/papernet/auth/testutil"
+)
+
+func TestInMemUserTokenRepository(t *testing.T) {
+	repo := NewInMemUserTokenRepository()
+	testutil.TestUserTokenRepository(t, repo)
+}
@@ -11,6 +11,10 @@ type InMemUserRepository struct {
 	users []auth.User
 	maxID int
 
+	// grants maps user ids to the papers they were granted access to, and
+	// whether they can edit them.
+	grants map[int]map[int]bool
+
 	teamRepository auth.TeamRepository
 }
 
@@ -20,6 +24,8 @@ func NewInMemUserRepository(teamRepo auth.TeamRepository) *InMemUserRepository {
 		users: make([]auth.User, 0),
 		maxID: 0,
 
+		grants: make(map[int]map[int]bool),
+
 		teamRepository: teamRepo,
 	}
 }
@@ -44,6 +50,23 @@ func (r *InMemUserRepository) GetByEmail(email string) (auth.User, error) {
 	return auth.User{}, nil
 }
 
+func (r *InMemUserRepository) GetByGoogleID(googleID string) (auth.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if googleID == "" {
+		return auth.User{}, nil
+	}
+
+	for _, user := range r.users {
+		if user.GoogleID == googleID {
+			return r.get(user.ID)
+		}
+	}
+
+	return auth.User{}, nil
+}
+
 func (r *InMemUserRepository) List() ([]auth.User, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -77,16 +100,43 @@ func (r *InMemUserRepository) get(userID int) (auth.User, error) {
 		canEdit[paperID] = struct{}{}
 	}
 
+	for paperID, edit := range r.grants[user.ID] {
+		canSee[paperID] = struct{}{}
+		if edit {
+			canEdit[paperID] = struct{}{}
+		}
+	}
+
 	teams, err := r.teamRepository.GetForUser(user.ID)
 	if err != nil {
 		return auth.User{}, err
 	}
 
+	user.TeamOwns = make([]int, 0)
 	for _, team := range teams {
+		role := teamRole(user.ID, team)
+
+		// The papers owned by a team are seen by all its members, edited by
+		// its admins and editors, and owned by its admins.
+		for _, paperID := range team.Owns {
+			canSee[paperID] = struct{}{}
+			if role != auth.TeamViewer {
+				canEdit[paperID] = struct{}{}
+			}
+			if role == auth.TeamAdmin {
+				user.TeamOwns = append(user.TeamOwns, paperID)
+			}
+		}
+
 		for _, paperID := range team.CanSee {
 			canSee[paperID] = struct{}{}
 		}
 
+		// Viewers cannot edit the papers of their teams
+		if role == auth.TeamViewer {
+			continue
+		}
+
 		for _, paperID := range team.CanEdit {
 			canEdit[paperID] = struct{}{}
 		}
@@ -169,3 +219,84 @@ func (r *InMemUserRepository) PaperOwner(paperID int) (int, error) {
 
 	return 0, nil
 }
+
+func (r *InMemUserRepository) TransferOwnership(paperID, fromID, toID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, user := range r.users {
+		if user.ID == fromID {
+			r.users[i].Owns = removeInt(paperID, user.Owns)
+		}
+		if user.ID == toID {
+			r.users[i].Owns = append(removeInt(paperID, user.Owns), paperID)
+		}
+		r.users[i].OwnershipOffers = removeInt(paperID, r.users[i].OwnershipOffers)
+	}
+
+	return nil
+}
+
+func (r *InMemUserRepository) Grant(userID, paperID int, canEdit bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.grants[userID] == nil {
+		r.grants[userID] = make(map[int]bool)
+	}
+	r.grants[userID][paperID] = canEdit
+
+	return nil
+}
+
+func (r *InMemUserRepository) Revoke(userID, paperID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.grants[userID], paperID)
+
+	return nil
+}
+
+func (r *InMemUserRepository) PaperGrantees(paperID int) ([]auth.Grantee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	grantees := make([]auth.Grantee, 0)
+	for _, user := range r.users {
+		canEdit, ok := r.grants[user.ID][paperID]
+		if !ok {
+			continue
+		}
+
+		grantees = append(grantees, auth.Grantee{
+			ID:      user.ID,
+			Name:    user.Name,
+			Email:   user.Email,
+			CanEdit: canEdit,
+		})
+	}
+
+	return grantees, nil
+}
+
+// teamRole returns the role of a user in a team.
+func teamRole(userID int, team auth.Team) auth.TeamRole {
+	for _, member := range team.Members {
+		if member.ID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// removeInt returns a copy of ids without v.
+func removeInt(v int, ids []int) []int {
+	res := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id != v {
+			res = append(res, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"time"
+)
+
+type InvitationState string
+
+const (
+	InvitationPending  InvitationState = "pending"
+	InvitationAccepted InvitationState = "accepted"
+	InvitationDeclined InvitationState = "declined"
+	InvitationExpired  InvitationState = "expired"
+)
+
+// Invitation is an invitation to join a team, sent to an email address that
+// may not have an account yet.
+type Invitation struct {
+	ID        int             `json:"id"`
+	TeamID    int             `json:"teamID"`
+	TeamName  string          `json:"teamName"`
+	Email     string          `json:"email"`
+	InviterID int             `json:"inviterID"`
+	State     InvitationState `json:"state"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// Token is the signed token of the invitation link. It is only returned
+	// when the invitation is created and is never stored.
+	Token string `json:"token,omitempty"`
+}
+
+type InvitationRepository interface {
+	Get(int) (Invitation, error)
+	ListForEmail(string) ([]Invitation, error)
+	Upsert(*Invitation) error
+}
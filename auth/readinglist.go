@@ -0,0 +1,24 @@
+package auth
+
+// ReadingList is a named list of papers of a user, in the order they want to
+// read them. The owner can share it, read-only, with their teams.
+type ReadingList struct {
+	ID      int    `json:"id"`
+	OwnerID int    `json:"ownerID"`
+	Name    string `json:"name"`
+
+	Papers []int `json:"papers"`
+
+	// Teams are the teams the list is shared with.
+	Teams []int `json:"teams"`
+}
+
+type ReadingListRepository interface {
+	Get(int) (ReadingList, error)
+	// ListForUser returns the lists owned by a user, ListForTeam the lists
+	// shared with a team.
+	ListForUser(userID int) ([]ReadingList, error)
+	ListForTeam(teamID int) ([]ReadingList, error)
+	Upsert(*ReadingList) error
+	Delete(int) error
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/mail"
+)
+
+const (
+	resetPasswordLink = "https://papernet.bobi.space/reset-password"
+	verifyEmailLink   = "https://papernet.bobi.space/verify-email"
+
+	// passwordResetTTL and emailVerificationTTL are the times after which the
+	// tokens sent by email cannot be used anymore.
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// AccountService handles the flows proving that a user owns their email
+// address: resetting a forgotten password and verifying the email. Both send a
+// single use token by email.
+type AccountService struct {
+	repository           auth.UserRepository
+	tokenRepository      auth.UserTokenRepository
+	teamRepository       auth.TeamRepository
+	invitationRepository auth.InvitationRepository
+
+	sessions  *SessionService
+	transport mail.Transport
+	from      string
+}
+
+func NewAccountService(
+	repo auth.UserRepository,
+	tokenRepo auth.UserTokenRepository,
+	teamRepo auth.TeamRepository,
+	invitationRepo auth.InvitationRepository,
+	sessions *SessionService,
+	transport mail.Transport,
+	from string,
+) *AccountService {
+	return &AccountService{
+		repository:           repo,
+		tokenRepository:      tokenRepo,
+		teamRepository:       teamRepo,
+		invitationRepository: invitationRepo,
+
+		sessions:  sessions,
+		transport: transport,
+		from:      from,
+	}
+}
+
+// RequestPasswordReset sends a password reset link to the user identified by
+// email. It does not fail when no user is found so as not to disclose which
+// emails have an account.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repository.GetByEmail(email)
+	if err != nil {
+		return err
+	} else if user.ID == 0 {
+		return nil
+	}
+
+	token, err := s.issueToken(user, auth.PasswordResetToken, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", resetPasswordLink, url.QueryEscape(token))
+	text := fmt.Sprintf(
+		"Someone asked to reset the password of your Papernet account. Follow the link below to choose a new one:\n\n%s\n\nThe link expires in one hour. If you did not ask for it, you can ignore this email.\n",
+		link,
+	)
+	return s.send(ctx, user.Email, "Reset your Papernet password", text)
+}
+
+// ResetPassword sets a new password for the user the token was sent to, logs
+// them out of all their devices and returns new tokens.
+func (s *AccountService) ResetPassword(token, password string) (Tokens, error) {
+	if password == "" {
+		return Tokens{}, errors.New("password cannot be empty", errors.BadRequest())
+	}
+
+	user, err := s.useToken(token, auth.PasswordResetToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// The other reset links sent cannot be used anymore
+	err = s.revokeTokens(user.ID, auth.PasswordResetToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	user.Salt = randToken(64)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password+user.Salt), bcrypt.DefaultCost)
+	if err != nil {
+		return Tokens{}, err
+	}
+	user.PasswordHash = string(hash)
+
+	// Receiving the email proves that the user owns the address
+	user.EmailVerified = true
+
+	// The password may have been reset because the account was compromised
+	user.SessionsRevokedAt = time.Now()
+
+	err = s.repository.Upsert(&user)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return s.sessions.Issue(user)
+}
+
+// RequestEmailVerification sends a verification link to the email of a user.
+func (s *AccountService) RequestEmailVerification(ctx context.Context, userID int) error {
+	user, err := s.repository.Get(userID)
+	if err != nil {
+		return err
+	} else if user.ID == 0 {
+		return errUserNotFound(userID)
+	}
+
+	if user.EmailVerified {
+		return errors.New("email already verified", errors.BadRequest())
+	} else if user.Email == "" {
+		return errors.New("no email to verify", errors.BadRequest())
+	}
+
+	token, err := s.issueToken(user, auth.EmailVerificationToken, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", verifyEmailLink, url.QueryEscape(token))
+	text := fmt.Sprintf(
+		"Please confirm that %s is the email address of your Papernet account by following the link below:\n\n%s\n\nThe link expires in two days.\n",
+		user.Email,
+		link,
+	)
+	return s.send(ctx, user.Email, "Verify your Papernet email", text)
+}
+
+// VerifyEmail marks the email of the user the token was sent to as verified, and
+// accepts the team invitations sent to it.
+func (s *AccountService) VerifyEmail(token string) (auth.User, error) {
+	user, err := s.useToken(token, auth.EmailVerificationToken)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	user.EmailVerified = true
+	err = s.repository.Upsert(&user)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	err = acceptInvitations(s.teamRepository, s.invitationRepository, user)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	return s.repository.Get(user.ID)
+}
+
+// issueToken generates and stores a new token to be sent to the email of the
+// user. Only the last token sent can be used. Only the hash is stored, the
+// returned token is meant to be sent to the user.
+func (s *AccountService) issueToken(user auth.User, kind auth.UserTokenKind, ttl time.Duration) (string, error) {
+	err := s.revokeTokens(user.ID, kind)
+	if err != nil {
+		return "", err
+	}
+
+	token := randToken(32)
+
+	now := time.Now()
+	userToken := auth.UserToken{
+		Hash:      hashToken(token),
+		Kind:      kind,
+		UserID:    user.ID,
+		Email:     user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	err = s.tokenRepository.Upsert(&userToken)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useToken checks that a token can be used and marks it as used, so it cannot
+// be used a second time. It returns the user the token was sent to.
+func (s *AccountService) useToken(token string, kind auth.UserTokenKind) (auth.User, error) {
+	userToken, err := s.tokenRepository.Use(hashToken(token), kind)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	if userToken.Hash == "" || userToken.Kind != kind {
+		return auth.User{}, errors.New("invalid token", errors.BadRequest())
+	} else if userToken.Used {
+		return auth.User{}, errors.New("token already used", errors.BadRequest())
+	} else if !time.Now().Before(userToken.ExpiresAt) {
+		return auth.User{}, errors.New("token expired", errors.BadRequest())
+	}
+
+	user, err := s.repository.Get(userToken.UserID)
+	if err != nil {
+		return auth.User{}, err
+	} else if user.ID == 0 {
+		return auth.User{}, errUserNotFound(userToken.UserID)
+	}
+
+	// The token only proves that the user owns the address it was sent to
+	if userToken.Email != user.Email {
+		return auth.User{}, errors.New("the email changed since the token was sent", errors.BadRequest())
+	}
+
+	return user, nil
+}
+
+// revokeTokens marks the tokens of a given kind sent to a user and not used
+// yet as used.
+func (s *AccountService) revokeTokens(userID int, kind auth.UserTokenKind) error {
+	tokens, err := s.tokenRepository.ListForUser(userID, kind)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.Used {
+			continue
+		}
+
+		token.Used = true
+		err := s.tokenRepository.Upsert(&token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *AccountService) send(ctx context.Context, to, subject, text string) error {
+	msg := mail.Message{
+		From:    s.from,
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+	}
+	return s.transport.Send(ctx, msg)
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
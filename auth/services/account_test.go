@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/inmem"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/mail"
+)
+
+var tokenRegexp = regexp.MustCompile(`token=(\S+)`)
+
+// tokenFromMail extracts the token from the link of the last email sent.
+func tokenFromMail(t *testing.T, transport *mail.InMemTransport) string {
+	messages := transport.Messages()
+	require.NotEmpty(t, messages, "an email must have been sent")
+
+	match := tokenRegexp.FindStringSubmatch(messages[len(messages)-1].Text)
+	require.Len(t, match, 2, "the email must contain a link with a token")
+
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err, "the token must be escaped")
+	return token
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	transport := mail.NewInMemTransport()
+	sessions := NewSessionService(repo, tokenRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	userService := NewUserService(repo, teamRepo, invitationRepo, sessions)
+	service := NewAccountService(repo, tokenRepo, teamRepo, invitationRepo, sessions, transport, "papernet@paper.net")
+
+	_, err := userService.SignUp("pizza@paper.net", "pizza")
+	require.NoError(t, err, "sign up must not fail")
+
+	// Unknown emails do not fail, but no email is sent
+	err = service.RequestPasswordReset(context.Background(), "unknown@paper.net")
+	assert.NoError(t, err, "requesting a reset for an unknown email should not fail")
+	assert.Empty(t, transport.Messages(), "no email should be sent for an unknown email")
+
+	err = service.RequestPasswordReset(context.Background(), "pizza@paper.net")
+	require.NoError(t, err, "requesting a reset must not fail")
+	messages := transport.Messages()
+	if assert.Len(t, messages, 1, "an email should be sent") {
+		assert.Equal(t, []string{"pizza@paper.net"}, messages[0].To, "email should be sent to the user")
+		assert.Equal(t, "papernet@paper.net", messages[0].From, "email should be sent from the configured address")
+	}
+	token := tokenFromMail(t, transport)
+
+	// Requesting a new link invalidates the previous one
+	err = service.RequestPasswordReset(context.Background(), "pizza@paper.net")
+	require.NoError(t, err, "requesting a second reset must not fail")
+
+	_, err = service.ResetPassword(token, "yolo")
+	if assert.Error(t, err, "resetting with a replaced token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+	token = tokenFromMail(t, transport)
+
+	_, err = service.ResetPassword("invalid", "yolo")
+	if assert.Error(t, err, "resetting with an invalid token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	_, err = service.ResetPassword(token, "")
+	if assert.Error(t, err, "resetting with an empty password should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	tokens, err := service.ResetPassword(token, "yolo")
+	require.NoError(t, err, "resetting the password must not fail")
+	assert.NotEmpty(t, tokens.AccessToken, "resetting the password should log the user in")
+
+	// Resetting the password logs out all the devices
+	user, err := repo.GetByEmail("pizza@paper.net")
+	require.NoError(t, err, "getting user must not fail")
+	assert.False(t, user.SessionsRevokedAt.IsZero(), "sessions should be revoked")
+
+	_, err = userService.Login("pizza@paper.net", "pizza")
+	assert.Error(t, err, "login with the old password should fail")
+	_, err = userService.Login("pizza@paper.net", "yolo")
+	assert.NoError(t, err, "login with the new password should not fail")
+
+	// Tokens are single use
+	_, err = service.ResetPassword(token, "pizza")
+	if assert.Error(t, err, "reusing a token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// Tokens expire
+	hash := hashToken("expired")
+	require.NoError(t, tokenRepo.Upsert(&auth.UserToken{
+		Hash:      hash,
+		Kind:      auth.PasswordResetToken,
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}), "inserting expired token must not fail")
+
+	_, err = service.ResetPassword("expired", "pizza")
+	if assert.Error(t, err, "resetting with an expired token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	transport := mail.NewInMemTransport()
+	sessions := NewSessionService(repo, tokenRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	userService := NewUserService(repo, teamRepo, invitationRepo, sessions)
+	service := NewAccountService(repo, tokenRepo, teamRepo, invitationRepo, sessions, transport, "papernet@paper.net")
+
+	user, err := userService.Upsert(auth.User{Name: "Pizza", Email: "pizza@paper.net"})
+	require.NoError(t, err, "inserting user must not fail")
+	assert.False(t, user.EmailVerified, "email should not be verified on creation")
+
+	err = service.RequestEmailVerification(context.Background(), user.ID+1)
+	if assert.Error(t, err, "requesting verification for an unknown user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	err = service.RequestEmailVerification(context.Background(), user.ID)
+	require.NoError(t, err, "requesting verification must not fail")
+	token := tokenFromMail(t, transport)
+
+	// A password reset token cannot be used to verify an email, and the other
+	// way around
+	_, err = service.ResetPassword(token, "yolo")
+	if assert.Error(t, err, "resetting password with a verification token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	user, err = service.VerifyEmail(token)
+	if assert.NoError(t, err, "verifying email should not fail") {
+		assert.True(t, user.EmailVerified, "email should be verified")
+	}
+
+	_, err = service.VerifyEmail(token)
+	if assert.Error(t, err, "reusing a token should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	err = service.RequestEmailVerification(context.Background(), user.ID)
+	if assert.Error(t, err, "requesting verification of a verified email should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// Changing the email requires a new verification
+	user, err = userService.Upsert(auth.User{ID: user.ID, Name: "Pizza", Email: "pizza@yolo.space"})
+	if assert.NoError(t, err, "updating email should not fail") {
+		assert.False(t, user.EmailVerified, "new email should not be verified")
+	}
+
+	// A token only verifies the address it was sent to
+	err = service.RequestEmailVerification(context.Background(), user.ID)
+	require.NoError(t, err, "requesting verification must not fail")
+	token = tokenFromMail(t, transport)
+
+	_, err = userService.Upsert(auth.User{ID: user.ID, Name: "Pizza", Email: "pizza@other.space"})
+	require.NoError(t, err, "updating email must not fail")
+
+	_, err = service.VerifyEmail(token)
+	if assert.Error(t, err, "verifying with a token sent to another email should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// Identity providers vouch for the emails they verified
+	user, err = userService.Upsert(auth.User{ID: user.ID, Name: "Pizza", Email: "pizza@idp.net", EmailVerified: true})
+	if assert.NoError(t, err, "updating email should not fail") {
+		assert.True(t, user.EmailVerified, "email verified by the caller should be verified")
+	}
+}
+
+func TestAccountService_VerifyEmailAcceptsInvitations(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	transport := mail.NewInMemTransport()
+	encoder := jwt.NewEncodeDecoder([]byte("key"))
+	sessions := NewSessionService(repo, tokenRepo, encoder)
+
+	userService := NewUserService(repo, teamRepo, invitationRepo, sessions)
+	teamService := NewTeamService(teamRepo, repo, invitationRepo, encoder)
+	service := NewAccountService(repo, tokenRepo, teamRepo, invitationRepo, sessions, transport, "papernet@paper.net")
+
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true}
+	require.NoError(t, repo.Upsert(&admin), "inserting admin must not fail")
+	team, err := teamService.Create(admin.ID, auth.Team{Name: "Pizza team"})
+	require.NoError(t, err, "creating team must not fail")
+	_, err = teamService.Invite(admin.ID, team.ID, "newcomer@paper.net")
+	require.NoError(t, err, "inviting newcomer must not fail")
+
+	// The invitation is not accepted on sign up
+	_, err = userService.SignUp("newcomer@paper.net", "password")
+	require.NoError(t, err, "signing up must not fail")
+	newcomer, err := repo.GetByEmail("newcomer@paper.net")
+	require.NoError(t, err, "getting newcomer must not fail")
+
+	team, err = teamRepo.Get(team.ID)
+	require.NoError(t, err, "getting team must not fail")
+	assert.False(t, userIsMemberOfTeam(newcomer.ID, team), "newcomer should not have joined the team on sign up")
+
+	// Verifying the email accepts it
+	err = service.RequestEmailVerification(context.Background(), newcomer.ID)
+	require.NoError(t, err, "requesting verification must not fail")
+	_, err = service.VerifyEmail(tokenFromMail(t, transport))
+	require.NoError(t, err, "verifying email must not fail")
+
+	team, err = teamRepo.Get(team.ID)
+	require.NoError(t, err, "getting team must not fail")
+	assert.True(t, userIsMemberOfTeam(newcomer.ID, team), "newcomer should have joined the team")
+
+	invitations, err := userService.PendingInvitations(newcomer.ID)
+	if assert.NoError(t, err, "listing pending invitations should not fail") {
+		assert.Empty(t, invitations, "invitation should have been accepted")
+	}
+}
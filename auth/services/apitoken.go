@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+const (
+	// defaultAPITokenTTL is the validity of the API tokens created without
+	// expiration date.
+	defaultAPITokenTTL = 90 * 24 * time.Hour
+
+	// maxAPITokenTTL is the longest validity of an API token.
+	maxAPITokenTTL = 365 * 24 * time.Hour
+)
+
+// APITokenEncoder signs the personal API tokens.
+type APITokenEncoder interface {
+	EncodeAPIToken(userID, tokenID int, scopes []string, expiresAt time.Time) (string, error)
+}
+
+// APITokenService manages the personal API tokens of the users.
+type APITokenService struct {
+	repository     auth.APITokenRepository
+	userRepository auth.UserRepository
+
+	encoder APITokenEncoder
+}
+
+func NewAPITokenService(repo auth.APITokenRepository, userRepo auth.UserRepository, encoder APITokenEncoder) *APITokenService {
+	return &APITokenService{
+		repository:     repo,
+		userRepository: userRepo,
+
+		encoder: encoder,
+	}
+}
+
+// Create creates an API token for a user, with the name, scopes and expiration
+// date of token. The signed token is only returned by this method.
+func (s *APITokenService) Create(userID int, token auth.APIToken) (auth.APIToken, error) {
+	user, err := s.userRepository.Get(userID)
+	if err != nil {
+		return auth.APIToken{}, err
+	} else if user.ID == 0 {
+		return auth.APIToken{}, errUserNotFound(userID)
+	}
+
+	name := strings.TrimSpace(token.Name)
+	if name == "" {
+		return auth.APIToken{}, errors.New("token name cannot be empty", errors.BadRequest())
+	}
+
+	scopes, err := validateScopes(token.Scopes)
+	if err != nil {
+		return auth.APIToken{}, err
+	}
+
+	now := time.Now()
+	expiresAt := token.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(defaultAPITokenTTL)
+	} else if !now.Before(expiresAt) {
+		return auth.APIToken{}, errors.New("token expiration date must be in the future", errors.BadRequest())
+	} else if expiresAt.After(now.Add(maxAPITokenTTL)) {
+		return auth.APIToken{}, errors.New("tokens cannot be valid for more than a year", errors.BadRequest())
+	}
+
+	token = auth.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	err = s.repository.Upsert(&token)
+	if err != nil {
+		return auth.APIToken{}, err
+	}
+
+	token.Token, err = s.encoder.EncodeAPIToken(userID, token.ID, token.Scopes, token.ExpiresAt)
+	if err != nil {
+		return auth.APIToken{}, err
+	}
+
+	return token, nil
+}
+
+// List returns the API tokens of a user, including the expired ones.
+func (s *APITokenService) List(userID int) ([]auth.APIToken, error) {
+	return s.repository.ListForUser(userID)
+}
+
+// Get returns an API token of a user, if it can still be used.
+func (s *APITokenService) Get(userID, tokenID int) (auth.APIToken, error) {
+	token, err := s.get(userID, tokenID)
+	if err != nil {
+		return auth.APIToken{}, err
+	} else if !time.Now().Before(token.ExpiresAt) {
+		return auth.APIToken{}, errAPITokenNotFound(tokenID)
+	}
+
+	return token, nil
+}
+
+// Revoke deletes an API token of a user. The token cannot be used anymore.
+func (s *APITokenService) Revoke(userID, tokenID int) error {
+	_, err := s.get(userID, tokenID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Delete(tokenID)
+}
+
+// get returns an API token if it belongs to the user. The tokens of the other
+// users are not found.
+func (s *APITokenService) get(userID, tokenID int) (auth.APIToken, error) {
+	token, err := s.repository.Get(tokenID)
+	if err != nil {
+		return auth.APIToken{}, err
+	} else if token.ID == 0 || token.UserID != userID {
+		return auth.APIToken{}, errAPITokenNotFound(tokenID)
+	}
+
+	return token, nil
+}
+
+// validateScopes checks that all the scopes exist, and removes the duplicates.
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("tokens need at least one scope", errors.BadRequest())
+	}
+
+	validated := make([]string, 0, len(scopes))
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if !jwt.ValidScope(scope) {
+			return nil, errors.New(fmt.Sprintf("invalid scope %q, expected one of %v", scope, jwt.Scopes), errors.BadRequest())
+		} else if seen[scope] {
+			continue
+		}
+
+		seen[scope] = true
+		validated = append(validated, scope)
+	}
+
+	return validated, nil
+}
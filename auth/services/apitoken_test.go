@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/inmem"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func TestAPITokenService(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	tokenRepo := inmem.NewInMemAPITokenRepository()
+	encoder := jwt.NewEncodeDecoder([]byte("key"))
+	service := NewAPITokenService(tokenRepo, repo, encoder)
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+	other := auth.User{Email: "yolo@paper.net"}
+	require.NoError(t, repo.Upsert(&other), "inserting other user must not fail")
+
+	// Invalid tokens
+	tts := map[string]auth.APIToken{
+		"no name":        {Scopes: []string{jwt.ScopePapersRead}},
+		"no scope":       {Name: "script"},
+		"unknown scope":  {Name: "script", Scopes: []string{"admin"}},
+		"expired":        {Name: "script", Scopes: []string{jwt.ScopePapersRead}, ExpiresAt: time.Now().Add(-time.Hour)},
+		"too long lived": {Name: "script", Scopes: []string{jwt.ScopePapersRead}, ExpiresAt: time.Now().Add(2 * maxAPITokenTTL)},
+	}
+	for name, token := range tts {
+		_, err := service.Create(user.ID, token)
+		if assert.Error(t, err, "%s: creating token should fail", name) {
+			errors.AssertCode(t, err, 400)
+		}
+	}
+
+	_, err := service.Create(other.ID+1, auth.APIToken{Name: "script", Scopes: []string{jwt.ScopePapersRead}})
+	if assert.Error(t, err, "creating a token for an unknown user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Create tokens
+	token, err := service.Create(user.ID, auth.APIToken{
+		Name:   " script ",
+		Scopes: []string{jwt.ScopePapersRead, jwt.ScopeImports, jwt.ScopePapersRead},
+	})
+	require.NoError(t, err, "creating token must not fail")
+	assert.Equal(t, "script", token.Name, "name should be trimmed")
+	assert.Equal(t, []string{jwt.ScopePapersRead, jwt.ScopeImports}, token.Scopes, "scopes should be deduplicated")
+	assert.True(t, token.ExpiresAt.After(time.Now().Add(defaultAPITokenTTL-time.Minute)), "token should expire after the default ttl")
+	require.NotEmpty(t, token.Token, "the signed token should be returned on creation")
+
+	// The signed token cannot be used as a session token
+	_, _, err = encoder.Decode(token.Token)
+	assert.Error(t, err, "decoding an api token as an access token should fail")
+
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	crons, err := service.Create(user.ID, auth.APIToken{Name: "crons", Scopes: []string{jwt.ScopeCrons}, ExpiresAt: expiresAt})
+	require.NoError(t, err, "creating token must not fail")
+	assert.True(t, expiresAt.Equal(crons.ExpiresAt), "expiration date should be kept")
+
+	// List
+	tokens, err := service.List(user.ID)
+	if assert.NoError(t, err, "listing tokens should not fail") && assert.Len(t, tokens, 2) {
+		assert.Equal(t, token.ID, tokens[0].ID)
+		assert.Equal(t, "", tokens[0].Token, "the signed token should not be listed")
+		assert.Equal(t, crons.ID, tokens[1].ID)
+	}
+
+	tokens, err = service.List(other.ID)
+	if assert.NoError(t, err, "listing tokens should not fail") {
+		assert.Empty(t, tokens, "other user should not have tokens")
+	}
+
+	// Get
+	_, err = service.Get(user.ID, token.ID)
+	assert.NoError(t, err, "getting token should not fail")
+
+	_, err = service.Get(other.ID, token.ID)
+	if assert.Error(t, err, "getting the token of another user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	expired := auth.APIToken{UserID: user.ID, Name: "expired", CreatedAt: time.Now().Add(-2 * time.Hour), ExpiresAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, tokenRepo.Upsert(&expired), "inserting expired token must not fail")
+	_, err = service.Get(user.ID, expired.ID)
+	if assert.Error(t, err, "getting an expired token should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Revoke
+	err = service.Revoke(other.ID, token.ID)
+	if assert.Error(t, err, "revoking the token of another user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	err = service.Revoke(user.ID, token.ID)
+	require.NoError(t, err, "revoking token must not fail")
+
+	_, err = service.Get(user.ID, token.ID)
+	if assert.Error(t, err, "getting a revoked token should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"time"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+// invitationTTL is the time after which a pending invitation expires.
+const invitationTTL = 7 * 24 * time.Hour
+
+// expireInvitation marks a pending invitation as expired if it is past its
+// expiration date.
+func expireInvitation(repo auth.InvitationRepository, invitation *auth.Invitation, now time.Time) error {
+	if invitation.State != auth.InvitationPending || now.Before(invitation.ExpiresAt) {
+		return nil
+	}
+
+	invitation.State = auth.InvitationExpired
+	return repo.Upsert(invitation)
+}
+
+// joinTeam adds a user to the team of an invitation and marks the invitation as
+// accepted.
+func joinTeam(teamRepo auth.TeamRepository, invitationRepo auth.InvitationRepository, invitation *auth.Invitation, userID int) (auth.Team, error) {
+	team, err := teamRepo.Get(invitation.TeamID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if team.ID == 0 {
+		return auth.Team{}, errTeamNotFound(invitation.TeamID)
+	}
+
+	if !userIsMemberOfTeam(userID, team) {
+		team.Members = append(team.Members, auth.TeamMember{ID: userID, Role: auth.TeamEditor})
+		err = teamRepo.Upsert(&team)
+		if err != nil {
+			return auth.Team{}, err
+		}
+	}
+
+	invitation.State = auth.InvitationAccepted
+	err = invitationRepo.Upsert(invitation)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	return team, nil
+}
+
+// acceptInvitations accepts the pending invitations sent to the email of a user.
+// It must only be called once the email is verified, or anyone could join a
+// team by signing up with the email of someone else. The invitations to teams
+// that do not exist anymore are ignored.
+func acceptInvitations(teamRepo auth.TeamRepository, invitationRepo auth.InvitationRepository, user auth.User) error {
+	if !user.EmailVerified {
+		return nil
+	}
+
+	invitations, err := pendingInvitations(invitationRepo, user.Email)
+	if err != nil {
+		return err
+	}
+
+	for _, invitation := range invitations {
+		team, err := teamRepo.Get(invitation.TeamID)
+		if err != nil {
+			return err
+		} else if team.ID == 0 {
+			continue
+		}
+
+		_, err = joinTeam(teamRepo, invitationRepo, &invitation, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingInvitations returns the invitations sent to an email that can still be
+// accepted, marking the other ones as expired on the way.
+func pendingInvitations(repo auth.InvitationRepository, email string) ([]auth.Invitation, error) {
+	invitations, err := repo.ListForEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pending := make([]auth.Invitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		err := expireInvitation(repo, &invitation, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if invitation.State == auth.InvitationPending {
+			pending = append(pending, invitation)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/errors"
+)
+
+// ReadingListService manages the reading lists of the users. Only the owner of
+// a list can change it, the members of the teams it is shared with can only
+// read it.
+type ReadingListService struct {
+	repository     auth.ReadingListRepository
+	userRepository auth.UserRepository
+	teamRepository auth.TeamRepository
+}
+
+func NewReadingListService(
+	repo auth.ReadingListRepository,
+	userRepo auth.UserRepository,
+	teamRepo auth.TeamRepository,
+) *ReadingListService {
+	return &ReadingListService{
+		repository:     repo,
+		userRepository: userRepo,
+		teamRepository: teamRepo,
+	}
+}
+
+// List returns the reading lists of a user, followed by the lists shared with
+// their teams.
+func (s *ReadingListService) List(callerID int) ([]auth.ReadingList, error) {
+	user, err := s.getUser(callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.repository.ListForUser(callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool, len(lists))
+	for _, list := range lists {
+		seen[list.ID] = true
+	}
+
+	teams, err := s.teamRepository.GetForUser(callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, team := range teams {
+		shared, err := s.repository.ListForTeam(team.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, list := range shared {
+			if seen[list.ID] {
+				continue
+			}
+			seen[list.ID] = true
+			lists = append(lists, visibleTo(user, list))
+		}
+	}
+
+	return lists, nil
+}
+
+// Get returns a reading list the caller owns or that is shared with one of
+// their teams.
+func (s *ReadingListService) Get(callerID, listID int) (auth.ReadingList, error) {
+	_, list, err := s.readableList(callerID, listID)
+	return list, err
+}
+
+// Create creates an empty reading list owned by the caller.
+func (s *ReadingListService) Create(callerID int, name string) (auth.ReadingList, error) {
+	_, err := s.getUser(callerID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	name, err = validateReadingListName(name)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	list := auth.ReadingList{
+		OwnerID: callerID,
+		Name:    name,
+		Papers:  make([]int, 0),
+		Teams:   make([]int, 0),
+	}
+	err = s.repository.Upsert(&list)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	return list, nil
+}
+
+// Rename changes the name of a reading list.
+func (s *ReadingListService) Rename(callerID, listID int, name string) (auth.ReadingList, error) {
+	_, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	list.Name, err = validateReadingListName(name)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	return s.update(list)
+}
+
+// Delete deletes a reading list.
+func (s *ReadingListService) Delete(callerID, listID int) error {
+	_, _, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Delete(listID)
+}
+
+// AddPaper adds a paper the caller can see at the end of a reading list.
+func (s *ReadingListService) AddPaper(callerID, listID, paperID int) (auth.ReadingList, error) {
+	user, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	if indexOf(paperID, user.CanSee) == -1 {
+		return auth.ReadingList{}, errPaperNotFound(paperID)
+	} else if indexOf(paperID, list.Papers) != -1 {
+		return list, nil
+	}
+
+	list.Papers = append(list.Papers, paperID)
+	return s.update(list)
+}
+
+// RemovePaper removes a paper from a reading list.
+func (s *ReadingListService) RemovePaper(callerID, listID, paperID int) (auth.ReadingList, error) {
+	_, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	index := indexOf(paperID, list.Papers)
+	if index == -1 {
+		return list, nil
+	}
+
+	list.Papers = append(list.Papers[:index], list.Papers[index+1:]...)
+	return s.update(list)
+}
+
+// Reorder changes the order of the papers of a reading list. papers must
+// contain the papers of the list, each of them once.
+func (s *ReadingListService) Reorder(callerID, listID int, papers []int) (auth.ReadingList, error) {
+	_, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	errInvalidOrder := errors.New("the new order must contain the papers of the list, each of them once", errors.BadRequest())
+	if len(papers) != len(list.Papers) {
+		return auth.ReadingList{}, errInvalidOrder
+	}
+
+	seen := make(map[int]bool, len(papers))
+	for _, paperID := range papers {
+		if seen[paperID] || indexOf(paperID, list.Papers) == -1 {
+			return auth.ReadingList{}, errInvalidOrder
+		}
+		seen[paperID] = true
+	}
+
+	list.Papers = papers
+	return s.update(list)
+}
+
+// Share shares a reading list, read-only, with a team the caller is a member
+// of.
+func (s *ReadingListService) Share(callerID, listID, teamID int) (auth.ReadingList, error) {
+	_, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	team, err := s.teamRepository.Get(teamID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	} else if team.ID == 0 || !userIsMemberOfTeam(callerID, team) {
+		return auth.ReadingList{}, errTeamNotFound(teamID)
+	}
+
+	if indexOf(teamID, list.Teams) != -1 {
+		return list, nil
+	}
+
+	list.Teams = append(list.Teams, teamID)
+	return s.update(list)
+}
+
+// Unshare stops sharing a reading list with a team. The caller does not need
+// to be a member of the team anymore.
+func (s *ReadingListService) Unshare(callerID, listID, teamID int) (auth.ReadingList, error) {
+	_, list, err := s.ownedList(callerID, listID)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+
+	index := indexOf(teamID, list.Teams)
+	if index == -1 {
+		return list, nil
+	}
+
+	list.Teams = append(list.Teams[:index], list.Teams[index+1:]...)
+	return s.update(list)
+}
+
+func (s *ReadingListService) update(list auth.ReadingList) (auth.ReadingList, error) {
+	err := s.repository.Upsert(&list)
+	if err != nil {
+		return auth.ReadingList{}, err
+	}
+	return list, nil
+}
+
+func (s *ReadingListService) getUser(userID int) (auth.User, error) {
+	user, err := s.userRepository.Get(userID)
+	if err != nil {
+		return auth.User{}, err
+	} else if user.ID == 0 {
+		return auth.User{}, errUserNotFound(userID)
+	}
+	return user, nil
+}
+
+// readableList returns a reading list the caller owns or that is shared with
+// one of their teams, and a 404 otherwise.
+func (s *ReadingListService) readableList(callerID, listID int) (auth.User, auth.ReadingList, error) {
+	user, err := s.getUser(callerID)
+	if err != nil {
+		return auth.User{}, auth.ReadingList{}, err
+	}
+
+	list, err := s.repository.Get(listID)
+	if err != nil {
+		return auth.User{}, auth.ReadingList{}, err
+	} else if list.ID == 0 {
+		return auth.User{}, auth.ReadingList{}, errReadingListNotFound(listID)
+	}
+
+	if list.OwnerID == callerID {
+		return user, list, nil
+	}
+
+	teams, err := s.teamRepository.GetForUser(callerID)
+	if err != nil {
+		return auth.User{}, auth.ReadingList{}, err
+	}
+
+	for _, team := range teams {
+		if indexOf(team.ID, list.Teams) != -1 {
+			return user, visibleTo(user, list), nil
+		}
+	}
+
+	return auth.User{}, auth.ReadingList{}, errReadingListNotFound(listID)
+}
+
+// ownedList returns a 404 if the caller cannot read the reading list, and a
+// 403 if they can read it but do not own it.
+func (s *ReadingListService) ownedList(callerID, listID int) (auth.User, auth.ReadingList, error) {
+	user, list, err := s.readableList(callerID, listID)
+	if err != nil {
+		return auth.User{}, auth.ReadingList{}, err
+	} else if list.OwnerID != callerID {
+		return auth.User{}, auth.ReadingList{}, errors.New(fmt.Sprintf("you are not the owner of reading list %d", listID), errors.Forbidden())
+	}
+
+	return user, list, nil
+}
+
+// visibleTo hides the papers of a shared reading list that a member of the
+// teams cannot see.
+func visibleTo(user auth.User, list auth.ReadingList) auth.ReadingList {
+	papers := make([]int, 0, len(list.Papers))
+	for _, paperID := range list.Papers {
+		if indexOf(paperID, user.CanSee) != -1 {
+			papers = append(papers, paperID)
+		}
+	}
+	list.Papers = papers
+	return list
+}
+
+func validateReadingListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("reading list name cannot be empty", errors.BadRequest())
+	}
+	return name, nil
+}
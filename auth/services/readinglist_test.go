@@ -0,0 +1,185 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/inmem"
+	"github.com/bobinette/papernet/auth/testutil"
+	"github.com/bobinette/papernet/errors"
+)
+
+func TestReadingListService(t *testing.T) {
+	repo := inmem.NewInMemReadingListRepository()
+	teamRepo := inmem.NewInMemTeamRepository()
+	userRepo := inmem.NewInMemUserRepository(teamRepo)
+	service := NewReadingListService(repo, userRepo, teamRepo)
+
+	owner := auth.User{Email: "owner@paper.net", Owns: []int{1, 2, 3}}
+	require.NoError(t, userRepo.Upsert(&owner), "inserting owner must not fail")
+
+	member := auth.User{Email: "member@paper.net", Owns: []int{4}}
+	require.NoError(t, userRepo.Upsert(&member), "inserting member must not fail")
+
+	nonMember := auth.User{Email: "nonMember@paper.net"}
+	require.NoError(t, userRepo.Upsert(&nonMember), "inserting nonMember must not fail")
+
+	// The member can only see paper 2 of the owner
+	team := auth.Team{
+		Name: "Pizza team",
+		Members: []auth.TeamMember{
+			{ID: owner.ID, Role: auth.TeamAdmin},
+			{ID: member.ID, Role: auth.TeamViewer},
+		},
+		CanSee: []int{2},
+	}
+	require.NoError(t, teamRepo.Upsert(&team), "inserting team must not fail")
+
+	otherTeam := auth.Team{
+		Name:    "Yolo team",
+		Members: []auth.TeamMember{{ID: nonMember.ID, Role: auth.TeamAdmin}},
+	}
+	require.NoError(t, teamRepo.Upsert(&otherTeam), "inserting other team must not fail")
+
+	// Create
+	_, err := service.Create(owner.ID, "  ")
+	if assert.Error(t, err, "creating a list without a name should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	list, err := service.Create(owner.ID, " To read ")
+	require.NoError(t, err, "creating a list must not fail")
+	require.NotEqual(t, 0, list.ID, "created list should have an id")
+	expected := auth.ReadingList{
+		ID:      list.ID,
+		OwnerID: owner.ID,
+		Name:    "To read",
+		Papers:  []int{},
+		Teams:   []int{},
+	}
+	testutil.AssertReadingList(t, expected, list, "created list")
+
+	// Add papers. Adding a paper twice does nothing, adding a paper the owner
+	// cannot see is a 404.
+	for _, paperID := range []int{3, 1, 2, 3} {
+		list, err = service.AddPaper(owner.ID, list.ID, paperID)
+		require.NoError(t, err, "adding paper %d must not fail", paperID)
+	}
+	expected.Papers = []int{3, 1, 2}
+	testutil.AssertReadingList(t, expected, list, "list after adding papers")
+
+	_, err = service.AddPaper(owner.ID, list.ID, 4)
+	if assert.Error(t, err, "adding a paper the owner cannot see should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Reorder
+	_, err = service.Reorder(owner.ID, list.ID, []int{1, 2})
+	if assert.Error(t, err, "reordering with missing papers should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	_, err = service.Reorder(owner.ID, list.ID, []int{1, 2, 2})
+	if assert.Error(t, err, "reordering with duplicates should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	list, err = service.Reorder(owner.ID, list.ID, []int{1, 2, 3})
+	require.NoError(t, err, "reordering must not fail")
+	expected.Papers = []int{1, 2, 3}
+	testutil.AssertReadingList(t, expected, list, "list after reorder")
+
+	// Rename
+	list, err = service.Rename(owner.ID, list.ID, "Must read")
+	require.NoError(t, err, "renaming must not fail")
+	expected.Name = "Must read"
+	testutil.AssertReadingList(t, expected, list, "list after rename")
+
+	// The list is not shared yet: 404 for the member
+	_, err = service.Get(member.ID, list.ID)
+	if assert.Error(t, err, "getting a list not shared should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Share. The owner must be a member of the team.
+	_, err = service.Share(owner.ID, list.ID, otherTeam.ID)
+	if assert.Error(t, err, "sharing with a team the owner is not in should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.Share(member.ID, list.ID, team.ID)
+	if assert.Error(t, err, "sharing from a non owner should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	list, err = service.Share(owner.ID, list.ID, team.ID)
+	require.NoError(t, err, "sharing must not fail")
+	expected.Teams = []int{team.ID}
+	testutil.AssertReadingList(t, expected, list, "list after share")
+
+	// The member can read the list, but only sees the papers they can see
+	shared, err := service.Get(member.ID, list.ID)
+	require.NoError(t, err, "getting a shared list must not fail")
+	testutil.AssertReadingList(t, auth.ReadingList{
+		ID:      list.ID,
+		OwnerID: owner.ID,
+		Name:    "Must read",
+		Papers:  []int{2},
+		Teams:   []int{team.ID},
+	}, shared, "shared list")
+
+	lists, err := service.List(member.ID)
+	require.NoError(t, err, "listing must not fail")
+	if assert.Equal(t, 1, len(lists), "member should see the shared list") {
+		testutil.AssertReadingList(t, shared, lists[0], "listed shared list")
+	}
+
+	lists, err = service.List(nonMember.ID)
+	require.NoError(t, err, "listing must not fail")
+	assert.Equal(t, 0, len(lists), "non member should not see any list")
+
+	// The member cannot modify the list
+	_, err = service.AddPaper(member.ID, list.ID, 4)
+	if assert.Error(t, err, "adding a paper from a non owner should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	err = service.Delete(member.ID, list.ID)
+	if assert.Error(t, err, "deleting from a non owner should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	err = service.Delete(nonMember.ID, list.ID)
+	if assert.Error(t, err, "deleting from a user that cannot read the list should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Remove paper
+	list, err = service.RemovePaper(owner.ID, list.ID, 2)
+	require.NoError(t, err, "removing a paper must not fail")
+	expected.Papers = []int{1, 3}
+	testutil.AssertReadingList(t, expected, list, "list after removing paper")
+
+	// Unshare
+	list, err = service.Unshare(owner.ID, list.ID, team.ID)
+	require.NoError(t, err, "unsharing must not fail")
+	expected.Teams = []int{}
+	testutil.AssertReadingList(t, expected, list, "list after unshare")
+
+	_, err = service.Get(member.ID, list.ID)
+	if assert.Error(t, err, "getting an unshared list should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Delete
+	err = service.Delete(owner.ID, list.ID)
+	require.NoError(t, err, "deleting must not fail")
+
+	_, err = service.Get(owner.ID, list.ID)
+	if assert.Error(t, err, "getting a deleted list should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+}
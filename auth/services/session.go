@@ -0,0 +1,200 @@
+package services
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+// refreshTokenTTL is the time after which a user has to log in again if they
+// did not use the application.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// Tokens are returned when a user logs in. The access token authenticates the
+// requests until it expires, in ExpiresIn seconds. The refresh token is then
+// exchanged for new tokens.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// SessionService issues, refreshes and revokes the tokens of the users.
+type SessionService struct {
+	repository      auth.UserRepository
+	tokenRepository auth.UserTokenRepository
+
+	encoder Encoder
+}
+
+func NewSessionService(repo auth.UserRepository, tokenRepo auth.UserTokenRepository, encoder Encoder) *SessionService {
+	return &SessionService{
+		repository:      repo,
+		tokenRepository: tokenRepo,
+
+		encoder: encoder,
+	}
+}
+
+// Issue creates a new session for a user.
+func (s *SessionService) Issue(user auth.User) (Tokens, error) {
+	accessToken, err := s.encoder.Encode(user.ID, user.IsAdmin)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// The refresh tokens outlive the access tokens by far: randToken draws
+	// them from crypto/rand, so that they cannot be guessed
+	refreshToken := randToken(32)
+	now := time.Now()
+	userToken := auth.UserToken{
+		Hash:      hashToken(refreshToken),
+		Kind:      auth.RefreshToken,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}
+	err = s.tokenRepository.Upsert(&userToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(jwt.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// AccessToken issues an access token for a user, without a refresh token. It is
+// meant for the other services acting on behalf of the user: they ask for a new
+// access token when it expires.
+func (s *SessionService) AccessToken(user auth.User) (Tokens, error) {
+	accessToken, err := s.encoder.Encode(user.ID, user.IsAdmin)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken: accessToken,
+		ExpiresIn:   int(jwt.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for new tokens. Refresh tokens are rotated:
+// each of them can only be used once. Using a refresh token a second time means
+// it has been stolen, so all the sessions of the user are revoked.
+func (s *SessionService) Refresh(refreshToken string) (Tokens, error) {
+	// Marking the token as used at once guarantees that two concurrent calls
+	// cannot both exchange it.
+	userToken, err := s.tokenRepository.Use(hashToken(refreshToken), auth.RefreshToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	if userToken.Hash == "" || userToken.Kind != auth.RefreshToken {
+		return Tokens{}, errUnauthorized("invalid refresh token")
+	} else if userToken.Used {
+		err = s.LogoutAll(userToken.UserID)
+		if err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, errUnauthorized("refresh token already used")
+	} else if !time.Now().Before(userToken.ExpiresAt) {
+		return Tokens{}, errUnauthorized("refresh token expired")
+	}
+
+	user, err := s.repository.Get(userToken.UserID)
+	if err != nil {
+		return Tokens{}, err
+	} else if user.ID == 0 || sessionRevoked(user, userToken.CreatedAt) {
+		return Tokens{}, errUnauthorized("refresh token revoked")
+	}
+
+	return s.Issue(user)
+}
+
+// Logout revokes the access token described by claims and, if it belongs to the
+// same user, the refresh token.
+func (s *SessionService) Logout(claims *jwt.Claims, refreshToken string) error {
+	revoked := auth.UserToken{
+		Hash:      hashToken(claims.Id),
+		Kind:      auth.RevokedAccessToken,
+		UserID:    claims.UserID,
+		Used:      true,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}
+	err := s.tokenRepository.Upsert(&revoked)
+	if err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	userToken, err := s.tokenRepository.Get(hashToken(refreshToken))
+	if err != nil {
+		return err
+	} else if userToken.Kind != auth.RefreshToken || userToken.UserID != claims.UserID || userToken.Used {
+		return nil
+	}
+
+	_, err = s.tokenRepository.Use(userToken.Hash, auth.RefreshToken)
+	return err
+}
+
+// LogoutAll revokes all the tokens issued to a user so far.
+func (s *SessionService) LogoutAll(userID int) error {
+	user, err := s.repository.Get(userID)
+	if err != nil {
+		return err
+	} else if user.ID == 0 {
+		return errUserNotFound(userID)
+	}
+
+	user.SessionsRevokedAt = time.Now()
+	return s.repository.Upsert(&user)
+}
+
+// DeleteExpiredTokens deletes the tokens that expired: the refresh and email
+// tokens cannot be used anymore, and the revoked access tokens would be
+// rejected anyway. It returns the number of tokens deleted.
+func (s *SessionService) DeleteExpiredTokens() (int, error) {
+	return s.tokenRepository.DeleteExpired(time.Now())
+}
+
+// IsRevoked tells whether an access token has been revoked, either by logging
+// out or by logging out of all the devices.
+func (s *SessionService) IsRevoked(claims *jwt.Claims) (bool, error) {
+	if claims.Id != "" {
+		userToken, err := s.tokenRepository.Get(hashToken(claims.Id))
+		if err != nil {
+			return false, err
+		} else if userToken.Kind == auth.RevokedAccessToken {
+			return true, nil
+		}
+	}
+
+	user, err := s.repository.Get(claims.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.ID == 0 || sessionRevoked(user, time.Unix(claims.IssuedAt, 0)), nil
+}
+
+// sessionRevoked returns whether a token issued at issuedAt has been revoked by
+// logging out of all the devices. The dates are stored with a precision of one
+// second, the tokens issued in the same second as the revocation are kept.
+func sessionRevoked(user auth.User, issuedAt time.Time) bool {
+	return issuedAt.Unix() < user.SessionsRevokedAt.Unix()
+}
+
+func errUnauthorized(msg string) error {
+	return errors.New(msg, errors.WithCode(http.StatusUnauthorized))
+}
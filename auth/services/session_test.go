@@ -0,0 +1,240 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/inmem"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func accessClaims(userID int, id string, issuedAt time.Time) *jwt.Claims {
+	return &jwt.Claims{
+		UserID: userID,
+		StandardClaims: jwtgo.StandardClaims{
+			Id:        id,
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: issuedAt.Add(jwt.AccessTokenTTL).Unix(),
+		},
+	}
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	service := NewSessionService(repo, tokenRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	tokens, err := service.Issue(user)
+	require.NoError(t, err, "issuing tokens must not fail")
+	assert.NotEmpty(t, tokens.AccessToken, "an access token should be issued")
+	assert.NotEmpty(t, tokens.RefreshToken, "a refresh token should be issued")
+	assert.Equal(t, int(jwt.AccessTokenTTL/time.Second), tokens.ExpiresIn)
+
+	_, err = service.Refresh("invalid")
+	if assert.Error(t, err, "refreshing with an invalid token should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+
+	refreshed, err := service.Refresh(tokens.RefreshToken)
+	require.NoError(t, err, "refreshing must not fail")
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken, "refresh token should be rotated")
+
+	// Reusing a refresh token revokes all the sessions of the user
+	_, err = service.Refresh(tokens.RefreshToken)
+	if assert.Error(t, err, "reusing a refresh token should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+
+	user, err = repo.Get(user.ID)
+	require.NoError(t, err, "getting user must not fail")
+	assert.False(t, user.SessionsRevokedAt.IsZero(), "sessions should be revoked")
+
+	// Refresh tokens issued before the revocation cannot be used anymore
+	require.NoError(t, tokenRepo.Upsert(&auth.UserToken{
+		Hash:      hashToken("old"),
+		Kind:      auth.RefreshToken,
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}), "inserting old refresh token must not fail")
+
+	_, err = service.Refresh("old")
+	if assert.Error(t, err, "refreshing with a revoked token should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+
+	// Refresh tokens expire
+	require.NoError(t, tokenRepo.Upsert(&auth.UserToken{
+		Hash:      hashToken("expired"),
+		Kind:      auth.RefreshToken,
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}), "inserting expired refresh token must not fail")
+
+	_, err = service.Refresh("expired")
+	if assert.Error(t, err, "refreshing with an expired token should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+
+	// Expired tokens are cleaned up
+	deleted, err := service.DeleteExpiredTokens()
+	if assert.NoError(t, err, "deleting expired tokens should not fail") {
+		assert.Equal(t, 1, deleted, "only the expired token should be deleted")
+	}
+
+	token, err := tokenRepo.Get(hashToken("expired"))
+	require.NoError(t, err, "getting token must not fail")
+	assert.Empty(t, token.Hash, "expired token should be deleted")
+}
+
+func TestSessionService_RefreshTokens(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	service := NewSessionService(repo, tokenRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		tokens, err := service.Issue(user)
+		require.NoError(t, err, "issuing tokens must not fail")
+
+		assert.Len(t, tokens.RefreshToken, 43, "refresh token should hold 32 random bytes")
+		assert.Equal(t, tokens.RefreshToken, url.QueryEscape(tokens.RefreshToken), "refresh token should be url safe")
+		assert.False(t, seen[tokens.RefreshToken], "refresh tokens should not repeat")
+		seen[tokens.RefreshToken] = true
+	}
+}
+
+func TestSessionService_ConcurrentRefresh(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	service := NewSessionService(repo, inmem.NewInMemUserTokenRepository(), jwt.NewEncodeDecoder([]byte("key")))
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	tokens, err := service.Issue(user)
+	require.NoError(t, err, "issuing tokens must not fail")
+
+	// Only one of the concurrent calls can exchange the refresh token
+	const calls = 10
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			_, err := service.Refresh(tokens.RefreshToken)
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < calls; i++ {
+		if err := <-errs; err == nil {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 1, succeeded, "the refresh token should be exchanged once")
+}
+
+func TestSessionService_AccessToken(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	tokenRepo := inmem.NewInMemUserTokenRepository()
+	sessions := NewSessionService(repo, tokenRepo, jwt.NewEncodeDecoder([]byte("key")))
+	service := NewUserService(repo, inmem.NewInMemTeamRepository(), inmem.NewInMemInvitationRepository(), sessions)
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	// The services calling on behalf of a user only get an access token
+	tokens, err := service.Token(user.ID, false)
+	require.NoError(t, err, "issuing an access token must not fail")
+	assert.NotEmpty(t, tokens.AccessToken, "an access token should be issued")
+	assert.Empty(t, tokens.RefreshToken, "no refresh token should be issued")
+
+	stored, err := tokenRepo.ListForUser(user.ID, auth.RefreshToken)
+	require.NoError(t, err, "listing tokens must not fail")
+	assert.Empty(t, stored, "no refresh token should be stored")
+
+	tokens, err = service.Token(user.ID, true)
+	require.NoError(t, err, "issuing a session must not fail")
+	assert.NotEmpty(t, tokens.RefreshToken, "a refresh token should be issued for a session")
+}
+
+func TestSessionService_Logout(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	service := NewSessionService(repo, inmem.NewInMemUserTokenRepository(), jwt.NewEncodeDecoder([]byte("key")))
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	tokens, err := service.Issue(user)
+	require.NoError(t, err, "issuing tokens must not fail")
+
+	claims := accessClaims(user.ID, "access", time.Now())
+	other := accessClaims(user.ID, "other", time.Now())
+
+	revoked, err := service.IsRevoked(claims)
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.False(t, revoked, "token should not be revoked before logging out")
+	}
+
+	err = service.Logout(claims, tokens.RefreshToken)
+	require.NoError(t, err, "logging out must not fail")
+
+	revoked, err = service.IsRevoked(claims)
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.True(t, revoked, "token should be revoked after logging out")
+	}
+
+	revoked, err = service.IsRevoked(other)
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.False(t, revoked, "logging out should not revoke the other sessions")
+	}
+
+	_, err = service.Refresh(tokens.RefreshToken)
+	if assert.Error(t, err, "refreshing after logging out should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+}
+
+func TestSessionService_LogoutAll(t *testing.T) {
+	repo := inmem.NewInMemUserRepository(inmem.NewInMemTeamRepository())
+	service := NewSessionService(repo, inmem.NewInMemUserTokenRepository(), jwt.NewEncodeDecoder([]byte("key")))
+
+	user := auth.User{Email: "pizza@paper.net"}
+	require.NoError(t, repo.Upsert(&user), "inserting user must not fail")
+
+	err := service.LogoutAll(user.ID + 1)
+	if assert.Error(t, err, "logging out an unknown user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	old := accessClaims(user.ID, "old", time.Now().Add(-time.Minute))
+	require.NoError(t, service.LogoutAll(user.ID), "logging out of all devices must not fail")
+
+	revoked, err := service.IsRevoked(old)
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.True(t, revoked, "tokens issued before logging out should be revoked")
+	}
+
+	revoked, err = service.IsRevoked(accessClaims(user.ID, "new", time.Now().Add(time.Second)))
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.False(t, revoked, "tokens issued after logging out should not be revoked")
+	}
+
+	revoked, err = service.IsRevoked(accessClaims(user.ID+1, "unknown", time.Now()))
+	if assert.NoError(t, err, "checking revocation should not fail") {
+		assert.True(t, revoked, "tokens of unknown users should be revoked")
+	}
+}
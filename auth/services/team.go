@@ -2,20 +2,38 @@ package services
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/bobinette/papernet/auth"
 	"github.com/bobinette/papernet/errors"
 )
 
+// InvitationEncoder signs the tokens of the invitation links.
+type InvitationEncoder interface {
+	EncodeInvitation(invitationID int, expiresAt time.Time) (string, error)
+	DecodeInvitation(token string) (int, error)
+}
+
 type TeamService struct {
-	repository     auth.TeamRepository
-	userRepository auth.UserRepository
+	repository           auth.TeamRepository
+	userRepository       auth.UserRepository
+	invitationRepository auth.InvitationRepository
+
+	invitationEncoder InvitationEncoder
 }
 
-func NewTeamService(repo auth.TeamRepository, userRepo auth.UserRepository) *TeamService {
+func NewTeamService(
+	repo auth.TeamRepository,
+	userRepo auth.UserRepository,
+	invitationRepo auth.InvitationRepository,
+	invitationEncoder InvitationEncoder,
+) *TeamService {
 	return &TeamService{
-		repository:     repo,
-		userRepository: userRepo,
+		repository:           repo,
+		userRepository:       userRepo,
+		invitationRepository: invitationRepo,
+
+		invitationEncoder: invitationEncoder,
 	}
 }
 
@@ -44,8 +62,9 @@ func (s *TeamService) GetForUser(callerID int) ([]auth.Team, error) {
 
 func (s *TeamService) Create(callerID int, team auth.Team) (auth.Team, error) {
 	team.Members = []auth.TeamMember{
-		{ID: callerID, IsTeamAdmin: true},
+		{ID: callerID, Role: auth.TeamAdmin},
 	}
+	team.Owns = []int{}
 	team.CanSee = []int{}
 	team.CanEdit = []int{}
 
@@ -57,45 +76,148 @@ func (s *TeamService) Create(callerID int, team auth.Team) (auth.Team, error) {
 	return team, nil
 }
 
-func (s *TeamService) Invite(callerID, teamID int, memberEmail string) (auth.Team, error) {
+// Invite creates an invitation to join the team for an email. The email does not need
+// to have an account yet: the invitation is then accepted when signing up. The token
+// of the invitation link is returned along with the invitation.
+func (s *TeamService) Invite(callerID, teamID int, memberEmail string) (auth.Invitation, error) {
 	team, err := s.repository.Get(teamID)
 	if err != nil {
-		return auth.Team{}, err
+		return auth.Invitation{}, err
 	}
 
 	// team.ID == 0 means that there was no team in the database
 	if team.ID == 0 {
-		return auth.Team{}, errTeamNotFound(teamID)
+		return auth.Invitation{}, errTeamNotFound(teamID)
 	}
 
 	// If the user is not a member of the team -> 404
 	if !userIsMemberOfTeam(callerID, team) {
-		return auth.Team{}, errTeamNotFound(teamID)
+		return auth.Invitation{}, errTeamNotFound(teamID)
 	}
 
 	// If the user is not an admin of the team -> 403
 	if !userIsAdminOfTeam(callerID, team) {
-		return auth.Team{}, errNotTeamAdmin(teamID)
+		return auth.Invitation{}, errNotTeamAdmin(teamID)
 	}
 
 	user, err := s.userRepository.GetByEmail(memberEmail)
+	if err != nil {
+		return auth.Invitation{}, err
+	} else if user.ID != 0 && userIsMemberOfTeam(user.ID, team) {
+		return auth.Invitation{}, errors.New(fmt.Sprintf("%s is already a member of team %d", memberEmail, teamID), errors.BadRequest())
+	}
+
+	invitations, err := pendingInvitations(s.invitationRepository, memberEmail)
+	if err != nil {
+		return auth.Invitation{}, err
+	}
+
+	// Reuse the pending invitation if there is one
+	invitation := auth.Invitation{}
+	for _, inv := range invitations {
+		if inv.TeamID == teamID {
+			invitation = inv
+			break
+		}
+	}
+
+	if invitation.ID == 0 {
+		now := time.Now()
+		invitation = auth.Invitation{
+			TeamID:    teamID,
+			Email:     memberEmail,
+			InviterID: callerID,
+			State:     auth.InvitationPending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(invitationTTL),
+		}
+		err = s.invitationRepository.Upsert(&invitation)
+		if err != nil {
+			return auth.Invitation{}, err
+		}
+	}
+
+	invitation.TeamName = team.Name
+	invitation.Token, err = s.invitationEncoder.EncodeInvitation(invitation.ID, invitation.ExpiresAt)
+	if err != nil {
+		return auth.Invitation{}, err
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation adds the caller to the team of the invitation. If token is empty,
+// the invitation must have been sent to the email of the caller, and the email must
+// be verified. Otherwise, the token of the invitation link is enough.
+func (s *TeamService) AcceptInvitation(callerID, invitationID int, token string) (auth.Team, error) {
+	user, invitation, err := s.pendingInvitation(callerID, invitationID, token)
 	if err != nil {
 		return auth.Team{}, err
+	}
+
+	return joinTeam(s.repository, s.invitationRepository, &invitation, user.ID)
+}
+
+// DeclineInvitation declines an invitation. The same rules as for AcceptInvitation
+// apply.
+func (s *TeamService) DeclineInvitation(callerID, invitationID int, token string) (auth.Invitation, error) {
+	_, invitation, err := s.pendingInvitation(callerID, invitationID, token)
+	if err != nil {
+		return auth.Invitation{}, err
+	}
+
+	invitation.State = auth.InvitationDeclined
+	err = s.invitationRepository.Upsert(&invitation)
+	if err != nil {
+		return auth.Invitation{}, err
+	}
+
+	return invitation, nil
+}
+
+// pendingInvitation retrieves the caller and an invitation the caller can answer.
+func (s *TeamService) pendingInvitation(callerID, invitationID int, token string) (auth.User, auth.Invitation, error) {
+	user, err := s.userRepository.Get(callerID)
+	if err != nil {
+		return auth.User{}, auth.Invitation{}, err
 	} else if user.ID == 0 {
-		return auth.Team{}, errors.New(fmt.Sprintf("no user found for email %s", memberEmail), errors.NotFound())
+		return auth.User{}, auth.Invitation{}, errUserNotFound(callerID)
 	}
 
-	if userIsMemberOfTeam(user.ID, team) {
-		return team, nil
+	invitation, err := s.invitationRepository.Get(invitationID)
+	if err != nil {
+		return auth.User{}, auth.Invitation{}, err
+	} else if invitation.ID == 0 {
+		return auth.User{}, auth.Invitation{}, errInvitationNotFound(invitationID)
 	}
 
-	team.Members = append(team.Members, auth.TeamMember{ID: user.ID, IsTeamAdmin: false})
-	err = s.repository.Upsert(&team)
+	if token != "" {
+		id, err := s.invitationEncoder.DecodeInvitation(token)
+		if err != nil || id != invitationID {
+			return auth.User{}, auth.Invitation{}, errors.New("invalid invitation token", errors.Forbidden(), errors.WithCause(err))
+		}
+	} else if invitation.Email != user.Email {
+		return auth.User{}, auth.Invitation{}, errInvitationNotFound(invitationID)
+	} else if !user.EmailVerified {
+		return auth.User{}, auth.Invitation{}, errors.New(
+			"verify your email or use the invitation link to answer the invitation",
+			errors.Forbidden(),
+		)
+	}
+
+	err = expireInvitation(s.invitationRepository, &invitation, time.Now())
 	if err != nil {
-		return auth.Team{}, err
+		return auth.User{}, auth.Invitation{}, err
 	}
 
-	return team, nil
+	if invitation.State != auth.InvitationPending {
+		return auth.User{}, auth.Invitation{}, errors.New(
+			fmt.Sprintf("invitation %d is %s", invitationID, invitation.State),
+			errors.BadRequest(),
+		)
+	}
+
+	return user, invitation, nil
 }
 
 func (s *TeamService) Kick(callerID, teamID, memberID int) (auth.Team, error) {
@@ -122,7 +244,7 @@ func (s *TeamService) Kick(callerID, teamID, memberID int) (auth.Team, error) {
 	index := -1
 	for i, member := range team.Members {
 		if member.ID == memberID {
-			if member.IsTeamAdmin {
+			if member.Role == auth.TeamAdmin {
 				return auth.Team{}, errors.New("cannot kick team admin", errors.BadRequest())
 			}
 			index = i
@@ -146,6 +268,8 @@ func (s *TeamService) Kick(callerID, teamID, memberID int) (auth.Team, error) {
 	return team, nil
 }
 
+// Delete deletes a team. Only team admins can delete a team, and the papers owned
+// by the team are transferred to them.
 func (s *TeamService) Delete(callerID, teamID int) error {
 	team, err := s.repository.Get(teamID)
 	if err != nil {
@@ -162,7 +286,7 @@ func (s *TeamService) Delete(callerID, teamID int) error {
 		return errNotTeamAdmin(teamID)
 	}
 
-	return s.repository.Delete(team.ID)
+	return s.delete(callerID, team)
 }
 
 func (s *TeamService) Share(callerID, teamID, paperID int, canEdit bool) (auth.Team, error) {
@@ -184,14 +308,7 @@ func (s *TeamService) Share(callerID, teamID, paperID int, canEdit bool) (auth.T
 		return auth.Team{}, errPaperNotFound(paperID)
 	}
 
-	found = false
-	for _, pID := range user.Owns {
-		if pID == paperID {
-			found = true
-			break
-		}
-	}
-	if !found {
+	if !userOwnsPaper(user, paperID) {
 		return auth.Team{}, errors.New(fmt.Sprintf("you cannot share paper %d because you are not the owner", paperID), errors.Forbidden())
 	}
 
@@ -207,6 +324,11 @@ func (s *TeamService) Share(callerID, teamID, paperID int, canEdit bool) (auth.T
 		return auth.Team{}, errTeamNotFound(teamID)
 	}
 
+	// Viewers cannot share papers with the team -> 403
+	if userRoleInTeam(callerID, team) == auth.TeamViewer {
+		return auth.Team{}, errors.New(fmt.Sprintf("viewers cannot share papers with team %d", teamID), errors.Forbidden())
+	}
+
 	found = false
 	for _, canSeeID := range team.CanSee {
 		if canSeeID == paperID {
@@ -239,6 +361,269 @@ func (s *TeamService) Share(callerID, teamID, paperID int, canEdit bool) (auth.T
 	return team, nil
 }
 
+// Unshare removes a paper from the papers a team can see and edit. If editOnly
+// is true, the team can still see the paper but can no longer edit it. Only
+// the owner of the paper and the admins of the team can unshare a paper.
+func (s *TeamService) Unshare(callerID, teamID, paperID int, editOnly bool) (auth.Team, error) {
+	user, err := s.userRepository.Get(callerID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if user.ID == 0 {
+		return auth.Team{}, errUserNotFound(callerID)
+	}
+
+	team, err := s.repository.Get(teamID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if team.ID == 0 {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	// If the user is not a member of the team -> 404
+	if !userIsMemberOfTeam(callerID, team) {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	canSeeIndex := indexOf(paperID, team.CanSee)
+	if canSeeIndex == -1 {
+		return auth.Team{}, errors.New(fmt.Sprintf("paper %d is not shared with team %d", paperID, teamID), errors.NotFound())
+	}
+
+	if !userOwnsPaper(user, paperID) && !userIsAdminOfTeam(callerID, team) {
+		return auth.Team{}, errors.New(
+			fmt.Sprintf("you cannot unshare paper %d because you are neither its owner nor a team admin", paperID),
+			errors.Forbidden(),
+		)
+	}
+
+	if canEditIndex := indexOf(paperID, team.CanEdit); canEditIndex != -1 {
+		team.CanEdit = append(team.CanEdit[:canEditIndex], team.CanEdit[canEditIndex+1:]...)
+	}
+
+	if !editOnly {
+		team.CanSee = append(team.CanSee[:canSeeIndex], team.CanSee[canSeeIndex+1:]...)
+	}
+
+	err = s.repository.Upsert(&team)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	return team, nil
+}
+
+// CreatePaper registers a new paper as owned by a team. The user creating the
+// paper must be an admin or an editor of the team.
+func (s *TeamService) CreatePaper(userID, teamID, paperID int) (auth.Team, error) {
+	team, err := s.repository.Get(teamID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if team.ID == 0 {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	// If the user is not a member of the team -> 404
+	if !userIsMemberOfTeam(userID, team) {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	// Viewers cannot add papers to the team -> 403
+	if userRoleInTeam(userID, team) == auth.TeamViewer {
+		return auth.Team{}, errors.New(fmt.Sprintf("viewers cannot create papers in team %d", teamID), errors.Forbidden())
+	}
+
+	teamOwnerID, err := s.repository.PaperOwner(paperID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if teamOwnerID == teamID {
+		return team, nil
+	}
+
+	userOwnerID, err := s.userRepository.PaperOwner(paperID)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	if teamOwnerID != 0 || userOwnerID != 0 {
+		return auth.Team{}, errors.New(fmt.Sprintf("paper %d is already owned", paperID), errors.Forbidden())
+	}
+
+	team.Owns = append(team.Owns, paperID)
+	err = s.repository.Upsert(&team)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	return team, nil
+}
+
+// Update renames a team and changes its description. Only team admins can
+// update a team.
+func (s *TeamService) Update(callerID, teamID int, name, description string) (auth.Team, error) {
+	team, err := s.adminTeam(callerID, teamID)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	if name == "" {
+		return auth.Team{}, errors.New("team name cannot be empty", errors.BadRequest())
+	}
+
+	team.Name = name
+	team.Description = description
+	err = s.repository.Upsert(&team)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	return team, nil
+}
+
+// SetRole promotes or demotes a member of a team. Only team admins can change
+// the roles, and a team always keeps at least one admin.
+func (s *TeamService) SetRole(callerID, teamID, memberID int, role auth.TeamRole) (auth.Team, error) {
+	if !role.IsValid() {
+		return auth.Team{}, errors.New(fmt.Sprintf("invalid role %q", role), errors.BadRequest())
+	}
+
+	team, err := s.adminTeam(callerID, teamID)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	index := -1
+	for i, member := range team.Members {
+		if member.ID == memberID {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		return auth.Team{}, errors.New(fmt.Sprintf("user %d is not a member of team %d", memberID, teamID), errors.NotFound())
+	}
+
+	if team.Members[index].Role == auth.TeamAdmin && role != auth.TeamAdmin && countAdmins(team) == 1 {
+		return auth.Team{}, errors.New("cannot demote the last admin of the team", errors.BadRequest())
+	}
+
+	team.Members[index].Role = role
+	err = s.repository.Upsert(&team)
+	if err != nil {
+		return auth.Team{}, err
+	}
+
+	return team, nil
+}
+
+// Leave removes the caller from a team. The last admin of a team cannot leave it
+// while there are other members, but if they are alone in the team, the team is
+// deleted.
+func (s *TeamService) Leave(callerID, teamID int) error {
+	team, err := s.repository.Get(teamID)
+	if err != nil {
+		return err
+	} else if team.ID == 0 {
+		return errTeamNotFound(teamID)
+	}
+
+	// If the user is not a member of the team -> 404
+	if !userIsMemberOfTeam(callerID, team) {
+		return errTeamNotFound(teamID)
+	}
+
+	if len(team.Members) == 1 {
+		return s.delete(callerID, team)
+	}
+
+	if userIsAdminOfTeam(callerID, team) && countAdmins(team) == 1 {
+		return errors.New("the last admin cannot leave the team, promote another member first", errors.BadRequest())
+	}
+
+	members := make([]auth.TeamMember, 0, len(team.Members)-1)
+	for _, member := range team.Members {
+		if member.ID != callerID {
+			members = append(members, member)
+		}
+	}
+	team.Members = members
+
+	return s.repository.Upsert(&team)
+}
+
+// delete deletes a team, transferring the papers it owns to the caller first:
+// they would not be owned by anyone, and not visible to anyone, otherwise.
+func (s *TeamService) delete(callerID int, team auth.Team) error {
+	if len(team.Owns) > 0 {
+		user, err := s.userRepository.Get(callerID)
+		if err != nil {
+			return err
+		} else if user.ID == 0 {
+			return errUserNotFound(callerID)
+		}
+
+		papers := team.Owns
+		team.Owns = []int{}
+		err = s.repository.Upsert(&team)
+		if err != nil {
+			return err
+		}
+
+		for _, paperID := range papers {
+			if indexOf(paperID, user.Owns) == -1 {
+				user.Owns = append(user.Owns, paperID)
+			}
+		}
+		err = s.userRepository.Upsert(&user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.repository.Delete(team.ID)
+}
+
+// adminTeam retrieves a team, checking that the caller is an admin of it.
+func (s *TeamService) adminTeam(callerID, teamID int) (auth.Team, error) {
+	team, err := s.repository.Get(teamID)
+	if err != nil {
+		return auth.Team{}, err
+	} else if team.ID == 0 {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	// If the user is not a member of the team -> 404
+	if !userIsMemberOfTeam(callerID, team) {
+		return auth.Team{}, errTeamNotFound(teamID)
+	}
+
+	// If the user is not an admin of the team -> 403
+	if !userIsAdminOfTeam(callerID, team) {
+		return auth.Team{}, errNotTeamAdmin(teamID)
+	}
+
+	return team, nil
+}
+
+func countAdmins(team auth.Team) int {
+	count := 0
+	for _, m := range team.Members {
+		if m.Role == auth.TeamAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+func userRoleInTeam(userID int, team auth.Team) auth.TeamRole {
+	for _, m := range team.Members {
+		if m.ID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
+
 func userIsMemberOfTeam(userID int, team auth.Team) bool {
 	for _, m := range team.Members {
 		if m.ID == userID {
@@ -251,7 +636,7 @@ func userIsMemberOfTeam(userID int, team auth.Team) bool {
 func userIsAdminOfTeam(userID int, team auth.Team) bool {
 	for _, m := range team.Members {
 		if m.ID == userID {
-			return m.IsTeamAdmin
+			return m.Role == auth.TeamAdmin
 		}
 	}
 	return false
@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -11,31 +12,33 @@ import (
 	"github.com/bobinette/papernet/auth/inmem"
 	"github.com/bobinette/papernet/auth/testutil"
 	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
 )
 
 func TestTeamService(t *testing.T) {
 	repo := inmem.NewInMemTeamRepository()
 	userRepo := inmem.NewInMemUserRepository(repo)
-	service := NewTeamService(repo, userRepo)
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	service := NewTeamService(repo, userRepo, invitationRepo, jwt.NewEncodeDecoder([]byte("key")))
 
 	// Insert users to be able to retrieve them by email when inviting
-	admin := auth.User{Email: "admin@paper.net", Owns: []int{1, 2}}
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true, Owns: []int{1, 2}}
 	require.NoError(t, userRepo.Upsert(&admin), "inserting admin must not fail")
 
-	member := auth.User{Email: "member@paper.net"}
+	member := auth.User{Email: "member@paper.net", EmailVerified: true}
 	require.NoError(t, userRepo.Upsert(&member), "inserting member must not fail")
 
-	otherMember := auth.User{Email: "otherMember@paper.net"}
+	otherMember := auth.User{Email: "otherMember@paper.net", EmailVerified: true}
 	require.NoError(t, userRepo.Upsert(&otherMember), "inserting otherMember must not fail")
 
-	nonMember := auth.User{Email: "nonMember@paper.net"}
+	nonMember := auth.User{Email: "nonMember@paper.net", EmailVerified: true}
 	require.NoError(t, userRepo.Upsert(&nonMember), "inserting nonMember must not fail")
 
 	// Pizza team
 	team := auth.Team{
 		Name: "Pizza team",
 		Members: []auth.TeamMember{
-			{ID: member.ID, IsTeamAdmin: true},
+			{ID: member.ID, Role: auth.TeamAdmin},
 		},
 		CanSee:  []int{1, 2},
 		CanEdit: []int{3},
@@ -52,7 +55,7 @@ func TestTeamService(t *testing.T) {
 		ID:   createdTeam.ID,
 		Name: "Pizza team",
 		Members: []auth.TeamMember{
-			{ID: admin.ID, IsTeamAdmin: true},
+			{ID: admin.ID, Role: auth.TeamAdmin},
 		},
 		CanSee:  []int{},
 		CanEdit: []int{},
@@ -66,46 +69,87 @@ func TestTeamService(t *testing.T) {
 
 	// Invite a new member. If the user is not a member of the team, it should
 	// get a 404, if not an admin -> 403, if the team does not exist -> 404
-	retrieved, err = service.Invite(admin.ID, team.ID+1, member.Email)
+	_, err = service.Invite(admin.ID, team.ID+1, member.Email)
 	if assert.Error(t, err, "inviting in a non existing should fail") {
 		errors.AssertCode(t, err, 404)
 	}
 
-	retrieved, err = service.Invite(nonMember.ID, team.ID, member.Email)
+	_, err = service.Invite(nonMember.ID, team.ID, member.Email)
 	if assert.Error(t, err, "inviting from a non member should fail") {
 		errors.AssertCode(t, err, 404)
 	}
 
-	team.Members = append(team.Members, auth.TeamMember{ID: member.ID, IsTeamAdmin: false})
-	retrieved, err = service.Invite(admin.ID, team.ID, member.Email)
+	invitation, err := service.Invite(admin.ID, team.ID, member.Email)
 	if assert.NoError(t, err, "inviting from an admin should not fail") {
-		testutil.AssertTeam(t, team, retrieved, "invited from admin")
+		assert.Equal(t, auth.InvitationPending, invitation.State, "invitation should be pending")
+		assert.NotEmpty(t, invitation.Token, "invitation should have a token")
 	}
 
-	retrieved, err = service.Invite(admin.ID, team.ID, admin.Email)
-	if assert.NoError(t, err, "admin inviting itself should not fail") {
-		testutil.AssertTeam(t, team, retrieved, "admin invited itself")
+	retrieved, err = service.Get(admin.ID, team.ID)
+	require.NoError(t, err, "getting team for admin should not fail")
+	testutil.AssertTeam(t, team, retrieved, "member should not be added before accepting")
+
+	_, err = service.Invite(admin.ID, team.ID, admin.Email)
+	if assert.Error(t, err, "admin inviting itself should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	again, err := service.Invite(admin.ID, team.ID, member.Email)
+	if assert.NoError(t, err, "inviting member again should not fail") {
+		assert.Equal(t, invitation.ID, again.ID, "pending invitation should be reused")
+	}
+
+	_, err = service.AcceptInvitation(otherMember.ID, invitation.ID, "")
+	if assert.Error(t, err, "accepting an invitation sent to another email should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.AcceptInvitation(otherMember.ID, invitation.ID, "invalid")
+	if assert.Error(t, err, "accepting with an invalid token should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	team.Members = append(team.Members, auth.TeamMember{ID: member.ID, Role: auth.TeamEditor})
+	retrieved, err = service.AcceptInvitation(member.ID, invitation.ID, "")
+	if assert.NoError(t, err, "accepting the invitation should not fail") {
+		testutil.AssertTeam(t, team, retrieved, "invitation accepted")
 	}
 
-	retrieved, err = service.Invite(admin.ID, team.ID, member.Email)
-	if assert.NoError(t, err, "inviting user already member should not fail") {
-		testutil.AssertTeam(t, team, retrieved, "member invited again")
+	_, err = service.AcceptInvitation(member.ID, invitation.ID, "")
+	if assert.Error(t, err, "accepting an invitation twice should fail") {
+		errors.AssertCode(t, err, 400)
 	}
 
-	retrieved, err = service.Invite(member.ID, team.ID, otherMember.Email)
+	_, err = service.Invite(admin.ID, team.ID, member.Email)
+	if assert.Error(t, err, "inviting user already member should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	_, err = service.Invite(member.ID, team.ID, otherMember.Email)
 	if assert.Error(t, err, "non admin member trying to invite should fail") {
 		errors.AssertCode(t, err, 403)
 	}
 
-	retrieved, err = service.Invite(nonMember.ID, team.ID, member.Email)
-	if assert.Error(t, err, "inviting in non existing should fail") {
-		errors.AssertCode(t, err, 404)
+	// Declined invitations cannot be accepted anymore
+	invitation, err = service.Invite(admin.ID, team.ID, otherMember.Email)
+	require.NoError(t, err, "inviting otherMember must not fail")
+	declined, err := service.DeclineInvitation(otherMember.ID, invitation.ID, "")
+	if assert.NoError(t, err, "declining should not fail") {
+		assert.Equal(t, auth.InvitationDeclined, declined.State, "invitation should be declined")
 	}
 
-	team.Members = append(team.Members, auth.TeamMember{ID: otherMember.ID, IsTeamAdmin: false})
-	retrieved, err = service.Invite(admin.ID, team.ID, otherMember.Email)
-	if assert.NoError(t, err, "inviting another member from an admin should not fail") {
-		testutil.AssertTeam(t, team, retrieved, "invited from admin again")
+	_, err = service.AcceptInvitation(otherMember.ID, invitation.ID, "")
+	if assert.Error(t, err, "accepting a declined invitation should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// The token of the link is enough to accept an invitation
+	team.Members = append(team.Members, auth.TeamMember{ID: otherMember.ID, Role: auth.TeamEditor})
+	invitation, err = service.Invite(admin.ID, team.ID, "other.member@paper.net")
+	require.NoError(t, err, "inviting another email must not fail")
+	retrieved, err = service.AcceptInvitation(otherMember.ID, invitation.ID, invitation.Token)
+	if assert.NoError(t, err, "accepting with the token should not fail") {
+		testutil.AssertTeam(t, team, retrieved, "invitation accepted with token")
 	}
 
 	// Get the team. If the user is a member of the team, it should be good,
@@ -163,6 +207,35 @@ func TestTeamService(t *testing.T) {
 		errors.AssertCode(t, err, 404)
 	}
 
+	// Unshare a paper. Only the owner of the paper or a team admin can
+	// unshare it. Non member -> 404, paper not shared -> 404.
+	retrieved, err = service.Unshare(nonMember.ID, team.ID, 2, false)
+	if assert.Error(t, err, "nonMember is not in team, unsharing should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	retrieved, err = service.Unshare(member.ID, team.ID, 2, false)
+	if assert.Error(t, err, "member is neither the owner of 2 nor a team admin, unsharing should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	retrieved, err = service.Unshare(admin.ID, team.ID, 3, false)
+	if assert.Error(t, err, "3 is not shared with the team, unsharing should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	retrieved, err = service.Unshare(admin.ID, team.ID, 2, true)
+	if assert.NoError(t, err, "admin owns 2, downgrading should not fail") {
+		assert.Contains(t, retrieved.CanSee, 2, "2 should still be in team seeable papers")
+		assert.NotContains(t, retrieved.CanEdit, 2, "2 should not be in team editable papers anymore")
+	}
+
+	retrieved, err = service.Unshare(admin.ID, team.ID, 1, false)
+	if assert.NoError(t, err, "admin owns 1, unsharing should not fail") {
+		assert.NotContains(t, retrieved.CanSee, 1, "1 should not be in team seeable papers anymore")
+		assert.NotContains(t, retrieved.CanEdit, 1, "1 should not be in team editable papers")
+	}
+
 	// Kick a member. If the user is not a member of the team, it should
 	// get a 404, if the team does not exist -> 404 as well. If the user
 	// is an admin, he/she can kick anyone that is not an admin. If the user
@@ -189,19 +262,17 @@ func TestTeamService(t *testing.T) {
 
 	retrieved, err = service.Kick(member.ID, team.ID, member.ID)
 	if assert.NoError(t, err, "member should be able to leave a team") {
-		assert.NotContains(t, retrieved.Members, auth.TeamMember{ID: member.ID, IsTeamAdmin: false}, "member should be in team anymore")
+		assert.NotContains(t, retrieved.Members, auth.TeamMember{ID: member.ID, Role: auth.TeamEditor}, "member should be in team anymore")
 	}
 
 	retrieved, err = service.Kick(admin.ID, team.ID, otherMember.ID)
 	if assert.NoError(t, err, "admin should be able to kick member") {
-		assert.NotContains(t, retrieved.Members, auth.TeamMember{ID: otherMember.ID, IsTeamAdmin: false}, "member should be in team anymore")
+		assert.NotContains(t, retrieved.Members, auth.TeamMember{ID: otherMember.ID, Role: auth.TeamEditor}, "member should be in team anymore")
 	}
 
 	// Invitations to test the get for user
-	_, err = service.Invite(admin.ID, team.ID, member.Email)
-	require.NoError(t, err, "inviting after delete in 1st team must not fail")
-	_, err = service.Invite(member.ID, otherTeam.ID, otherMember.Email)
-	require.NoError(t, err, "inviting after delete in 2nd team must not fail")
+	join(t, service, admin.ID, team.ID, member)
+	join(t, service, member.ID, otherTeam.ID, otherMember)
 
 	// Get the users' teams. admin should have 1 team of which it is admin. member
 	// should have 2 teams one of which it is admin. otherMember has 1 team of which
@@ -260,13 +331,260 @@ func TestTeamService(t *testing.T) {
 	assert.NoError(t, err, "deleting from an admin should be ok")
 }
 
+func TestTeamService_ExpiredInvitation(t *testing.T) {
+	repo := inmem.NewInMemTeamRepository()
+	userRepo := inmem.NewInMemUserRepository(repo)
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	service := NewTeamService(repo, userRepo, invitationRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true}
+	require.NoError(t, userRepo.Upsert(&admin), "inserting admin must not fail")
+	member := auth.User{Email: "member@paper.net", EmailVerified: true}
+	require.NoError(t, userRepo.Upsert(&member), "inserting member must not fail")
+
+	team, err := service.Create(admin.ID, auth.Team{Name: "Pizza team"})
+	require.NoError(t, err, "creating team must not fail")
+
+	invitation, err := service.Invite(admin.ID, team.ID, member.Email)
+	require.NoError(t, err, "inviting must not fail")
+
+	invitation.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, invitationRepo.Upsert(&invitation), "updating invitation must not fail")
+
+	_, err = service.AcceptInvitation(member.ID, invitation.ID, "")
+	if assert.Error(t, err, "accepting an expired invitation should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	expired, err := invitationRepo.Get(invitation.ID)
+	require.NoError(t, err, "getting invitation must not fail")
+	assert.Equal(t, auth.InvitationExpired, expired.State, "invitation should be marked as expired")
+
+	renewed, err := service.Invite(admin.ID, team.ID, member.Email)
+	if assert.NoError(t, err, "inviting again should not fail") {
+		assert.NotEqual(t, invitation.ID, renewed.ID, "a new invitation should be created")
+	}
+}
+
+func TestTeamService_Roles(t *testing.T) {
+	repo := inmem.NewInMemTeamRepository()
+	userRepo := inmem.NewInMemUserRepository(repo)
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	service := NewTeamService(repo, userRepo, invitationRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true, Owns: []int{1}}
+	require.NoError(t, userRepo.Upsert(&admin), "inserting admin must not fail")
+	viewer := auth.User{Email: "viewer@paper.net", EmailVerified: true, Owns: []int{2}}
+	require.NoError(t, userRepo.Upsert(&viewer), "inserting viewer must not fail")
+
+	team, err := service.Create(admin.ID, auth.Team{Name: "Pizza team", Description: "Pizza lovers"})
+	require.NoError(t, err, "creating team must not fail")
+	assert.Equal(t, "Pizza lovers", team.Description, "description should be kept on creation")
+	join(t, service, admin.ID, team.ID, viewer)
+
+	// Change the roles. Only admins can, and the role must be valid.
+	_, err = service.SetRole(viewer.ID, team.ID, viewer.ID, auth.TeamAdmin)
+	if assert.Error(t, err, "non admin changing roles should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.SetRole(admin.ID, team.ID, viewer.ID, auth.TeamRole("owner"))
+	if assert.Error(t, err, "setting an invalid role should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	_, err = service.SetRole(admin.ID, team.ID, admin.ID, auth.TeamEditor)
+	if assert.Error(t, err, "demoting the last admin should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	team, err = service.SetRole(admin.ID, team.ID, viewer.ID, auth.TeamViewer)
+	if assert.NoError(t, err, "demoting to viewer should not fail") {
+		assert.Equal(t, auth.TeamViewer, userRoleInTeam(viewer.ID, team), "member should be a viewer")
+	}
+
+	// Viewers cannot share, and cannot edit the papers of the team
+	_, err = service.Share(viewer.ID, team.ID, 2, false)
+	if assert.Error(t, err, "viewer sharing should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.Share(admin.ID, team.ID, 1, true)
+	require.NoError(t, err, "admin sharing must not fail")
+
+	user, err := userRepo.Get(viewer.ID)
+	require.NoError(t, err, "getting viewer must not fail")
+	assert.Contains(t, user.CanSee, 1, "viewer should see 1")
+	assert.NotContains(t, user.CanEdit, 1, "viewer should not edit 1")
+
+	// Rename the team
+	_, err = service.Update(viewer.ID, team.ID, "Yolo team", "")
+	if assert.Error(t, err, "non admin renaming should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.Update(admin.ID, team.ID, "", "")
+	if assert.Error(t, err, "empty name should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	team, err = service.Update(admin.ID, team.ID, "Yolo team", "Yolo lovers")
+	if assert.NoError(t, err, "admin renaming should not fail") {
+		assert.Equal(t, "Yolo team", team.Name)
+		assert.Equal(t, "Yolo lovers", team.Description)
+	}
+
+	// Leave the team. The last admin cannot leave while other members remain,
+	// and the team is deleted when its last member leaves.
+	err = service.Leave(admin.ID, team.ID)
+	if assert.Error(t, err, "last admin leaving should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	err = service.Leave(viewer.ID, team.ID)
+	assert.NoError(t, err, "viewer leaving should not fail")
+
+	err = service.Leave(viewer.ID, team.ID)
+	if assert.Error(t, err, "leaving twice should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	err = service.Leave(admin.ID, team.ID)
+	assert.NoError(t, err, "last member leaving should not fail")
+
+	team, err = repo.Get(team.ID)
+	require.NoError(t, err, "getting team must not fail")
+	assert.Equal(t, 0, team.ID, "team should be deleted")
+}
+
+func TestTeamService_Papers(t *testing.T) {
+	repo := inmem.NewInMemTeamRepository()
+	userRepo := inmem.NewInMemUserRepository(repo)
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	service := NewTeamService(repo, userRepo, invitationRepo, jwt.NewEncodeDecoder([]byte("key")))
+
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true, Owns: []int{1}}
+	require.NoError(t, userRepo.Upsert(&admin), "inserting admin must not fail")
+	editor := auth.User{Email: "editor@paper.net", EmailVerified: true}
+	require.NoError(t, userRepo.Upsert(&editor), "inserting editor must not fail")
+	viewer := auth.User{Email: "viewer@paper.net", EmailVerified: true}
+	require.NoError(t, userRepo.Upsert(&viewer), "inserting viewer must not fail")
+	nonMember := auth.User{Email: "nonMember@paper.net", EmailVerified: true}
+	require.NoError(t, userRepo.Upsert(&nonMember), "inserting nonMember must not fail")
+
+	team, err := service.Create(admin.ID, auth.Team{Name: "Pizza team"})
+	require.NoError(t, err, "creating team must not fail")
+	join(t, service, admin.ID, team.ID, editor)
+	join(t, service, admin.ID, team.ID, viewer)
+	_, err = service.SetRole(admin.ID, team.ID, viewer.ID, auth.TeamViewer)
+	require.NoError(t, err, "demoting viewer must not fail")
+
+	otherTeam, err := service.Create(nonMember.ID, auth.Team{Name: "Yolo team"})
+	require.NoError(t, err, "creating other team must not fail")
+
+	// Create papers in the team
+	_, err = service.CreatePaper(nonMember.ID, team.ID, 2)
+	if assert.Error(t, err, "non member creating a paper should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.CreatePaper(viewer.ID, team.ID, 2)
+	if assert.Error(t, err, "viewer creating a paper should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.CreatePaper(editor.ID, team.ID, 1)
+	if assert.Error(t, err, "creating a paper owned by a user should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	team, err = service.CreatePaper(editor.ID, team.ID, 2)
+	if assert.NoError(t, err, "editor creating a paper should not fail") {
+		assert.Equal(t, []int{2}, team.Owns, "team should own the paper")
+	}
+
+	_, err = service.CreatePaper(editor.ID, team.ID, 2)
+	assert.NoError(t, err, "creating a paper twice should not fail")
+
+	_, err = service.CreatePaper(nonMember.ID, otherTeam.ID, 2)
+	if assert.Error(t, err, "creating a paper owned by another team should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// The admins hold the owner rights, members see the paper and only non
+	// viewers can edit it.
+	testCases := []struct {
+		userID   int
+		teamOwns bool
+		canEdit  bool
+	}{
+		{admin.ID, true, true},
+		{editor.ID, false, true},
+		{viewer.ID, false, false},
+	}
+	for _, tc := range testCases {
+		user, err := userRepo.Get(tc.userID)
+		require.NoError(t, err, "getting user %d must not fail", tc.userID)
+		assert.Contains(t, user.CanSee, 2, "user %d should see the team paper", tc.userID)
+		assert.Equal(t, tc.canEdit, indexOf(2, user.CanEdit) != -1, "user %d: incorrect edit right", tc.userID)
+		assert.Equal(t, tc.teamOwns, indexOf(2, user.TeamOwns) != -1, "user %d: incorrect owner right", tc.userID)
+	}
+
+	// Only the admins of the owning team can share the paper
+	join(t, service, nonMember.ID, otherTeam.ID, editor)
+	_, err = service.Share(editor.ID, otherTeam.ID, 2, false)
+	if assert.Error(t, err, "editor sharing a team paper should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	join(t, service, nonMember.ID, otherTeam.ID, admin)
+	otherTeam, err = service.Share(admin.ID, otherTeam.ID, 2, false)
+	if assert.NoError(t, err, "team admin sharing a team paper should not fail") {
+		assert.Contains(t, otherTeam.CanSee, 2, "paper should be shared with the other team")
+	}
+
+	// Deleting the team transfers its papers to the admin deleting it
+	err = service.Delete(admin.ID, team.ID)
+	require.NoError(t, err, "deleting the team must not fail")
+
+	user, err := userRepo.Get(admin.ID)
+	require.NoError(t, err, "getting admin must not fail")
+	assert.Contains(t, user.Owns, 2, "admin should own the paper of the deleted team")
+
+	user, err = userRepo.Get(viewer.ID)
+	require.NoError(t, err, "getting viewer must not fail")
+	assert.NotContains(t, user.CanSee, 2, "viewer should not see the paper of the deleted team")
+
+	// Same when the last member leaves the team
+	lastTeam, err := service.Create(editor.ID, auth.Team{Name: "Last team"})
+	require.NoError(t, err, "creating team must not fail")
+	_, err = service.CreatePaper(editor.ID, lastTeam.ID, 4)
+	require.NoError(t, err, "creating a team paper must not fail")
+
+	err = service.Leave(editor.ID, lastTeam.ID)
+	require.NoError(t, err, "leaving the team must not fail")
+
+	user, err = userRepo.Get(editor.ID)
+	require.NoError(t, err, "getting editor must not fail")
+	assert.Contains(t, user.Owns, 4, "last member should own the paper of the team")
+}
+
+// join invites a user in a team and accepts the invitation on their behalf.
+func join(t *testing.T, service *TeamService, adminID, teamID int, user auth.User) {
+	invitation, err := service.Invite(adminID, teamID, user.Email)
+	require.NoError(t, err, "inviting %s must not fail", user.Email)
+
+	_, err = service.AcceptInvitation(user.ID, invitation.ID, "")
+	require.NoError(t, err, "%s accepting invitation must not fail", user.Email)
+}
+
 func TestUserIsMemberOfTeam(t *testing.T) {
 	team := auth.Team{
 		Members: []auth.TeamMember{
-			{ID: 1, IsTeamAdmin: false},
-			{ID: 2, IsTeamAdmin: false},
-			{ID: 3, IsTeamAdmin: true},
-			{ID: 4, IsTeamAdmin: false},
+			{ID: 1, Role: auth.TeamEditor},
+			{ID: 2, Role: auth.TeamEditor},
+			{ID: 3, Role: auth.TeamAdmin},
+			{ID: 4, Role: auth.TeamEditor},
 		},
 	}
 	tts := map[string]struct {
@@ -287,10 +605,10 @@ func TestUserIsMemberOfTeam(t *testing.T) {
 func TestUserIsAdminOfTeam(t *testing.T) {
 	team := auth.Team{
 		Members: []auth.TeamMember{
-			{ID: 1, IsTeamAdmin: false},
-			{ID: 2, IsTeamAdmin: false},
-			{ID: 3, IsTeamAdmin: true},
-			{ID: 4, IsTeamAdmin: false},
+			{ID: 1, Role: auth.TeamEditor},
+			{ID: 2, Role: auth.TeamEditor},
+			{ID: 3, Role: auth.TeamAdmin},
+			{ID: 4, Role: auth.TeamEditor},
 		},
 	}
 	tts := map[string]struct {
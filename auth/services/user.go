@@ -15,15 +15,25 @@ type Encoder interface {
 }
 
 type UserService struct {
-	repository auth.UserRepository
+	repository           auth.UserRepository
+	teamRepository       auth.TeamRepository
+	invitationRepository auth.InvitationRepository
 
-	encoder Encoder
+	sessions *SessionService
 }
 
-func NewUserService(repo auth.UserRepository, encoder Encoder) *UserService {
+func NewUserService(
+	repo auth.UserRepository,
+	teamRepo auth.TeamRepository,
+	invitationRepo auth.InvitationRepository,
+	sessions *SessionService,
+) *UserService {
 	return &UserService{
-		repository: repo,
-		encoder:    encoder,
+		repository:           repo,
+		teamRepository:       teamRepo,
+		invitationRepository: invitationRepo,
+
+		sessions: sessions,
 	}
 }
 
@@ -55,11 +65,27 @@ func (s *UserService) Upsert(u auth.User) (auth.User, error) {
 		if err != nil {
 			return auth.User{}, err
 		}
+
+		// The password of an account whose email was never verified may have
+		// been set by someone else than the owner of the email: handing the
+		// account to the identity provider user would let them in too.
+		if user.ID != 0 && u.EmailVerified && !user.EmailVerified && user.PasswordHash != "" {
+			return auth.User{}, errors.New(
+				"an account with a password uses this email: log in with the password, or reset it, to verify the email first",
+				errors.WithCode(http.StatusConflict),
+			)
+		}
 	}
 
-	// Update user details
+	// Update user details. A new email has to be verified again, unless the
+	// caller, an identity provider, already verified it.
+	wasVerified := user.EmailVerified && user.Email == u.Email
+	if user.Email != u.Email {
+		user.EmailVerified = false
+	}
 	user.Name = u.Name
 	user.Email = u.Email
+	user.EmailVerified = user.EmailVerified || u.EmailVerified
 
 	// Because admin is always false from web, and we do not want to remove the privilege
 	// every time an admin logs in
@@ -70,6 +96,73 @@ func (s *UserService) Upsert(u auth.User) (auth.User, error) {
 		return auth.User{}, err
 	}
 
+	// The invitations sent to the email can be accepted now that the identity
+	// provider verified it
+	if user.EmailVerified && !wasVerified {
+		err = acceptInvitations(s.teamRepository, s.invitationRepository, user)
+		if err != nil {
+			return auth.User{}, err
+		}
+		return s.Get(user.ID)
+	}
+
+	return user, nil
+}
+
+// LinkGoogle links a Google account to a user, who can then log in with it. A
+// Google account can only be linked to one user, and a user to one account.
+func (s *UserService) LinkGoogle(userID int, googleID string) (auth.User, error) {
+	if googleID == "" {
+		return auth.User{}, errors.New("missing google id", errors.BadRequest())
+	}
+
+	user, err := s.Get(userID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	linked, err := s.repository.GetByGoogleID(googleID)
+	if err != nil {
+		return auth.User{}, err
+	} else if linked.ID != 0 && linked.ID != userID {
+		return auth.User{}, errors.New("this Google account is linked to another user", errors.WithCode(http.StatusConflict))
+	}
+
+	if user.GoogleID == googleID {
+		return user, nil
+	} else if user.GoogleID != "" {
+		return auth.User{}, errors.New("another Google account is linked to this user, unlink it first", errors.WithCode(http.StatusConflict))
+	}
+
+	user.GoogleID = googleID
+	err = s.repository.Upsert(&user)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	return user, nil
+}
+
+// UnlinkGoogle removes the Google account linked to a user. The user needs a
+// password not to be locked out of their account.
+func (s *UserService) UnlinkGoogle(userID int) (auth.User, error) {
+	user, err := s.Get(userID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	if user.GoogleID == "" {
+		return user, nil
+	} else if user.PasswordHash == "" {
+		return auth.User{}, errors.New("set a password before unlinking your Google account", errors.BadRequest())
+	}
+
+	user.GoogleID = ""
+	err = s.repository.Upsert(&user)
+	if err != nil {
+		return auth.User{}, err
+	}
+
 	return user, nil
 }
 
@@ -89,7 +182,13 @@ func (s *UserService) CreatePaper(userID, paperID int) (auth.User, error) {
 	if ownerID == userID {
 		return user, nil
 	}
-	if ownerID != 0 {
+
+	teamOwnerID, err := s.teamRepository.PaperOwner(paperID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	if ownerID != 0 || teamOwnerID != 0 {
 		return auth.User{}, errors.New(
 			fmt.Sprintf("paper %d is already owned", paperID),
 			errors.WithCode(http.StatusForbidden),
@@ -158,12 +257,254 @@ func (s *UserService) Bookmark(callerID, paperID int, bookmark bool) (auth.User,
 	return user, nil
 }
 
-func (s *UserService) SignUp(email, password string) (string, error) {
+// SharePaper gives the user identified by email access to a paper, without going
+// through a team. Only the owner of the paper can share it. It returns the updated
+// list of users the paper is shared with.
+func (s *UserService) SharePaper(callerID, paperID int, email string, canEdit bool) ([]auth.Grantee, error) {
+	_, err := s.checkPaperOwner(callerID, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	} else if user.ID == 0 {
+		return nil, errors.New(fmt.Sprintf("no user found for email %s", email), errors.NotFound())
+	} else if user.ID == callerID {
+		return nil, errors.New("you cannot share a paper with yourself", errors.BadRequest())
+	}
+
+	err = s.repository.Grant(user.ID, paperID, canEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.PaperGrantees(paperID)
+}
+
+// UnsharePaper revokes the access given to a user on a paper. Only the owner of the
+// paper can unshare it. It returns the updated list of users the paper is shared with.
+func (s *UserService) UnsharePaper(callerID, paperID, userID int) ([]auth.Grantee, error) {
+	_, err := s.checkPaperOwner(callerID, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repository.Revoke(userID, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.PaperGrantees(paperID)
+}
+
+// PaperGrantees lists the users a paper has been shared with. Only the owner of the
+// paper can list them.
+func (s *UserService) PaperGrantees(callerID, paperID int) ([]auth.Grantee, error) {
+	_, err := s.checkPaperOwner(callerID, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.PaperGrantees(paperID)
+}
+
+// OfferPaper offers the ownership of a paper to the user identified by email. The
+// ownership is only transferred when the recipient accepts the offer. Only the owner
+// of the paper can offer it.
+func (s *UserService) OfferPaper(callerID, paperID int, email string) error {
+	caller, err := s.checkPaperOwner(callerID, paperID)
+	if err != nil {
+		return err
+	} else if indexOf(paperID, caller.Owns) == -1 {
+		return errors.New(fmt.Sprintf("paper %d is owned by a team and cannot be transferred", paperID), errors.BadRequest())
+	}
+
 	user, err := s.repository.GetByEmail(email)
 	if err != nil {
-		return "", err
+		return err
+	} else if user.ID == 0 {
+		return errors.New(fmt.Sprintf("no user found for email %s", email), errors.NotFound())
+	} else if user.ID == callerID {
+		return errors.New("you already own this paper", errors.BadRequest())
+	}
+
+	if indexOf(paperID, user.OwnershipOffers) != -1 {
+		return nil
+	}
+
+	user.OwnershipOffers = append(user.OwnershipOffers, paperID)
+	return s.repository.Upsert(&user)
+}
+
+// AcceptPaper accepts an ownership offer: the caller becomes the owner of the paper.
+func (s *UserService) AcceptPaper(callerID, paperID int) (auth.User, error) {
+	user, err := s.getOffer(callerID, paperID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	ownerID, err := s.repository.PaperOwner(paperID)
+	if err != nil {
+		return auth.User{}, err
+	} else if ownerID == 0 {
+		return auth.User{}, errPaperNotFound(paperID)
+	}
+
+	err = s.repository.TransferOwnership(paperID, ownerID, user.ID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	return s.Get(user.ID)
+}
+
+// DeclinePaper declines an ownership offer.
+func (s *UserService) DeclinePaper(callerID, paperID int) (auth.User, error) {
+	user, err := s.getOffer(callerID, paperID)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	index := indexOf(paperID, user.OwnershipOffers)
+	user.OwnershipOffers = append(user.OwnershipOffers[:index], user.OwnershipOffers[index+1:]...)
+	err = s.repository.Upsert(&user)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	return user, nil
+}
+
+// TransferPaper transfers the ownership of a paper to the user identified by email
+// without asking for their consent. It is meant for admins.
+func (s *UserService) TransferPaper(paperID int, email string) (auth.User, error) {
+	ownerID, err := s.repository.PaperOwner(paperID)
+	if err != nil {
+		return auth.User{}, err
+	} else if ownerID == 0 {
+		return auth.User{}, errPaperNotFound(paperID)
+	}
+
+	user, err := s.repository.GetByEmail(email)
+	if err != nil {
+		return auth.User{}, err
+	} else if user.ID == 0 {
+		return auth.User{}, errors.New(fmt.Sprintf("no user found for email %s", email), errors.NotFound())
+	}
+
+	if ownerID != user.ID {
+		err = s.repository.TransferOwnership(paperID, ownerID, user.ID)
+		if err != nil {
+			return auth.User{}, err
+		}
+	}
+
+	return s.Get(user.ID)
+}
+
+// TransferAllPapers transfers the ownership of all the papers of a user to another
+// one, without asking for their consent. It returns the ids of the transferred papers.
+func (s *UserService) TransferAllPapers(fromID, toID int) ([]int, error) {
+	from, err := s.Get(fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.Get(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromID == toID {
+		return []int{}, nil
+	}
+
+	transferred := make([]int, 0, len(from.Owns))
+	for _, paperID := range from.Owns {
+		err := s.repository.TransferOwnership(paperID, fromID, toID)
+		if err != nil {
+			return transferred, err
+		}
+		transferred = append(transferred, paperID)
+	}
+
+	return transferred, nil
+}
+
+// getOffer retrieves the caller and checks that the ownership of the paper was
+// offered to them.
+func (s *UserService) getOffer(callerID, paperID int) (auth.User, error) {
+	user, err := s.repository.Get(callerID)
+	if err != nil {
+		return auth.User{}, err
+	} else if user.ID == 0 {
+		return auth.User{}, errUserNotFound(callerID)
+	}
+
+	if indexOf(paperID, user.OwnershipOffers) == -1 {
+		return auth.User{}, errors.New(fmt.Sprintf("no ownership offer for paper %d", paperID), errors.NotFound())
+	}
+
+	return user, nil
+}
+
+// PendingInvitations lists the team invitations the user can still accept.
+// The invitations sent to an email are only listed once it is verified.
+func (s *UserService) PendingInvitations(userID int) ([]auth.Invitation, error) {
+	user, err := s.Get(userID)
+	if err != nil {
+		return nil, err
+	} else if !user.EmailVerified {
+		return []auth.Invitation{}, nil
+	}
+
+	invitations, err := pendingInvitations(s.invitationRepository, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, invitation := range invitations {
+		team, err := s.teamRepository.Get(invitation.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		invitations[i].TeamName = team.Name
+	}
+
+	return invitations, nil
+}
+
+// checkPaperOwner returns a 404 if the caller cannot see the paper, and a 403 if the
+// caller can see the paper but does not own it.
+func (s *UserService) checkPaperOwner(callerID, paperID int) (auth.User, error) {
+	user, err := s.repository.Get(callerID)
+	if err != nil {
+		return auth.User{}, err
+	} else if user.ID == 0 {
+		return auth.User{}, errUserNotFound(callerID)
+	}
+
+	if indexOf(paperID, user.CanSee) == -1 {
+		return auth.User{}, errPaperNotFound(paperID)
+	} else if !userOwnsPaper(user, paperID) {
+		return auth.User{}, errors.New(fmt.Sprintf("you are not the owner of paper %d", paperID), errors.Forbidden())
+	}
+
+	return user, nil
+}
+
+// SignUp creates a user with a password and logs them in. The invitations
+// sent to the email are not accepted on sign up, since anyone can sign up
+// with an email they do not own: they are accepted once the email is
+// verified, by AccountService.VerifyEmail.
+func (s *UserService) SignUp(email, password string) (Tokens, error) {
+	user, err := s.repository.GetByEmail(email)
+	if err != nil {
+		return Tokens{}, err
 	} else if user.ID != 0 {
-		return "", errors.New("email already exists", errors.BadRequest())
+		return Tokens{}, errors.New("email already exists", errors.BadRequest())
 	}
 
 	user = auth.User{
@@ -175,40 +516,49 @@ func (s *UserService) SignUp(email, password string) (string, error) {
 	// Generate "hash" to store from user password
 	hash, err := bcrypt.GenerateFromPassword([]byte(password+user.Salt), bcrypt.DefaultCost)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	}
 	user.PasswordHash = string(hash)
 
+	// The invitations sent to the email are accepted once the email is verified
 	err = s.repository.Upsert(&user)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	}
 
-	return s.encoder.Encode(user.ID, user.IsAdmin)
+	return s.sessions.Issue(user)
 }
 
-func (s *UserService) Login(email, password string) (string, error) {
+func (s *UserService) Login(email, password string) (Tokens, error) {
 	user, err := s.repository.GetByEmail(email)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	} else if user.ID == 0 {
-		return "", errors.New("email or password incorrect", errors.BadRequest())
+		return Tokens{}, errors.New("email or password incorrect", errors.BadRequest())
 	}
 
 	// Comparing the password with the hash
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password+user.Salt)); err != nil {
-		return "", errors.New("email or password incorrect", errors.BadRequest())
+		return Tokens{}, errors.New("email or password incorrect", errors.BadRequest())
 	}
 
-	return s.encoder.Encode(user.ID, user.IsAdmin)
+	return s.sessions.Issue(user)
 }
 
-func (s *UserService) Token(userID int) (string, error) {
+// Token issues tokens for a user without their password. It is meant for the
+// admins and the other services. A refresh token is only issued when session is
+// true, i.e. when the user is logging in through another service: the services
+// calling on behalf of a user only need an access token.
+func (s *UserService) Token(userID int, session bool) (Tokens, error) {
 	user, err := s.Get(userID)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
+	}
+
+	if !session {
+		return s.sessions.AccessToken(user)
 	}
-	return s.encoder.Encode(user.ID, user.IsAdmin)
+	return s.sessions.Issue(user)
 }
 
 func (s *UserService) All() ([]auth.User, error) {
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+	"github.com/bobinette/papernet/auth/inmem"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+)
+
+func TestUserService_SharePaper(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	service := NewUserService(repo, teamRepo, inmem.NewInMemInvitationRepository(), nil)
+
+	owner := auth.User{Email: "owner@paper.net", Owns: []int{1, 2}}
+	require.NoError(t, repo.Upsert(&owner), "inserting owner must not fail")
+
+	colleague := auth.User{Email: "colleague@paper.net"}
+	require.NoError(t, repo.Upsert(&colleague), "inserting colleague must not fail")
+
+	// Only the owner can share a paper. The paper is not found for users
+	// that cannot see it.
+	_, err := service.SharePaper(colleague.ID, 1, owner.Email, false)
+	if assert.Error(t, err, "colleague cannot see 1, sharing should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.SharePaper(owner.ID, 1, "unknown@paper.net", false)
+	if assert.Error(t, err, "sharing with an unknown email should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.SharePaper(owner.ID, 1, owner.Email, false)
+	if assert.Error(t, err, "sharing with oneself should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	grantees, err := service.SharePaper(owner.ID, 1, colleague.Email, false)
+	if assert.NoError(t, err, "owner sharing 1 should not fail") {
+		assert.Equal(t, []auth.Grantee{{ID: colleague.ID, Email: colleague.Email, CanEdit: false}}, grantees)
+	}
+
+	_, err = service.SharePaper(owner.ID, 2, colleague.Email, true)
+	require.NoError(t, err, "owner sharing 2 with edit rights should not fail")
+
+	// The grants appear in the user's permissions
+	user, err := service.Get(colleague.ID)
+	require.NoError(t, err, "getting colleague should not fail")
+	assert.Contains(t, user.CanSee, 1, "colleague should see 1")
+	assert.NotContains(t, user.CanEdit, 1, "colleague should not edit 1")
+	assert.Contains(t, user.CanEdit, 2, "colleague should edit 2")
+
+	// Seeing a paper is not enough to share it or list its grantees
+	_, err = service.SharePaper(colleague.ID, 1, owner.Email, false)
+	if assert.Error(t, err, "colleague is not the owner of 1, sharing should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.PaperGrantees(colleague.ID, 1)
+	if assert.Error(t, err, "colleague is not the owner of 1, listing grantees should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// Unshare
+	grantees, err = service.UnsharePaper(owner.ID, 2, colleague.ID)
+	if assert.NoError(t, err, "owner unsharing 2 should not fail") {
+		assert.Empty(t, grantees, "2 should not be shared anymore")
+	}
+
+	user, err = service.Get(colleague.ID)
+	require.NoError(t, err, "getting colleague should not fail")
+	assert.NotContains(t, user.CanSee, 2, "colleague should not see 2 anymore")
+	assert.NotContains(t, user.CanEdit, 2, "colleague should not edit 2 anymore")
+}
+
+func TestUserService_TransferPaper(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	service := NewUserService(repo, teamRepo, inmem.NewInMemInvitationRepository(), nil)
+
+	owner := auth.User{Email: "owner@paper.net", Owns: []int{1, 2, 3}}
+	require.NoError(t, repo.Upsert(&owner), "inserting owner must not fail")
+
+	recipient := auth.User{Email: "recipient@paper.net"}
+	require.NoError(t, repo.Upsert(&recipient), "inserting recipient must not fail")
+
+	other := auth.User{Email: "other@paper.net"}
+	require.NoError(t, repo.Upsert(&other), "inserting other must not fail")
+
+	// Only the owner can offer a paper
+	err := service.OfferPaper(recipient.ID, 1, other.Email)
+	if assert.Error(t, err, "recipient cannot see 1, offering should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	_, err = service.AcceptPaper(recipient.ID, 1)
+	if assert.Error(t, err, "accepting without an offer should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	// Offer 1 to both users. Once one of them accepts, the other offer is
+	// withdrawn.
+	require.NoError(t, service.OfferPaper(owner.ID, 1, recipient.Email), "offering 1 should not fail")
+	require.NoError(t, service.OfferPaper(owner.ID, 1, other.Email), "offering 1 again should not fail")
+
+	user, err := service.AcceptPaper(recipient.ID, 1)
+	if assert.NoError(t, err, "accepting 1 should not fail") {
+		assert.Contains(t, user.Owns, 1, "recipient should own 1")
+		assert.Empty(t, user.OwnershipOffers, "offer should be consumed")
+	}
+
+	user, err = service.Get(other.ID)
+	require.NoError(t, err, "getting other should not fail")
+	assert.Empty(t, user.OwnershipOffers, "offer to other should be withdrawn")
+
+	user, err = service.Get(owner.ID)
+	require.NoError(t, err, "getting owner should not fail")
+	assert.NotContains(t, user.Owns, 1, "owner should not own 1 anymore")
+
+	// Declining an offer keeps the ownership unchanged
+	require.NoError(t, service.OfferPaper(owner.ID, 2, recipient.Email), "offering 2 should not fail")
+	user, err = service.DeclinePaper(recipient.ID, 2)
+	if assert.NoError(t, err, "declining 2 should not fail") {
+		assert.NotContains(t, user.Owns, 2, "recipient should not own 2")
+		assert.Empty(t, user.OwnershipOffers, "offer should be removed")
+	}
+
+	// Forced transfers do not need consent
+	user, err = service.TransferPaper(2, other.Email)
+	if assert.NoError(t, err, "forcing the transfer of 2 should not fail") {
+		assert.Contains(t, user.Owns, 2, "other should own 2")
+	}
+
+	transferred, err := service.TransferAllPapers(owner.ID, recipient.ID)
+	if assert.NoError(t, err, "transferring all the papers should not fail") {
+		assert.Equal(t, []int{3}, transferred)
+	}
+
+	user, err = service.Get(recipient.ID)
+	require.NoError(t, err, "getting recipient should not fail")
+	assert.Contains(t, user.Owns, 3, "recipient should own 3")
+}
+
+func TestUserService_Invitations(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	invitationRepo := inmem.NewInMemInvitationRepository()
+	encoder := jwt.NewEncodeDecoder([]byte("key"))
+	sessions := NewSessionService(repo, inmem.NewInMemUserTokenRepository(), encoder)
+	service := NewUserService(repo, teamRepo, invitationRepo, sessions)
+	teamService := NewTeamService(teamRepo, repo, invitationRepo, encoder)
+
+	admin := auth.User{Email: "admin@paper.net", EmailVerified: true}
+	require.NoError(t, repo.Upsert(&admin), "inserting admin must not fail")
+	member := auth.User{Email: "member@paper.net", EmailVerified: true}
+	require.NoError(t, repo.Upsert(&member), "inserting member must not fail")
+
+	team, err := teamService.Create(admin.ID, auth.Team{Name: "Pizza team"})
+	require.NoError(t, err, "creating team must not fail")
+
+	// Existing users see their pending invitations
+	_, err = teamService.Invite(admin.ID, team.ID, member.Email)
+	require.NoError(t, err, "inviting member must not fail")
+
+	invitations, err := service.PendingInvitations(member.ID)
+	if assert.NoError(t, err, "listing pending invitations should not fail") && assert.Len(t, invitations, 1) {
+		assert.Equal(t, team.ID, invitations[0].TeamID)
+		assert.Equal(t, "Pizza team", invitations[0].TeamName)
+	}
+
+	// Signing up with an invited email is not enough to join the team: anyone
+	// could sign up with it.
+	invitation, err := teamService.Invite(admin.ID, team.ID, "newcomer@paper.net")
+	require.NoError(t, err, "inviting newcomer must not fail")
+
+	_, err = service.SignUp("newcomer@paper.net", "password")
+	require.NoError(t, err, "signing up must not fail")
+
+	newcomer, err := repo.GetByEmail("newcomer@paper.net")
+	require.NoError(t, err, "getting newcomer must not fail")
+
+	team, err = teamRepo.Get(team.ID)
+	require.NoError(t, err, "getting team must not fail")
+	assert.False(t, userIsMemberOfTeam(newcomer.ID, team), "unverified newcomer should not have joined the team")
+
+	invitations, err = service.PendingInvitations(newcomer.ID)
+	if assert.NoError(t, err, "listing pending invitations should not fail") {
+		assert.Empty(t, invitations, "invitations should not be listed before the email is verified")
+	}
+
+	_, err = teamService.AcceptInvitation(newcomer.ID, invitation.ID, "")
+	if assert.Error(t, err, "accepting an invitation with an unverified email should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// Identity providers verify the emails they return
+	_, err = teamService.Invite(admin.ID, team.ID, "provider@paper.net")
+	require.NoError(t, err, "inviting provider user must not fail")
+
+	user, err := service.Upsert(auth.User{Name: "Provider", Email: "provider@paper.net", EmailVerified: true})
+	require.NoError(t, err, "upserting provider user must not fail")
+
+	team, err = teamRepo.Get(team.ID)
+	require.NoError(t, err, "getting team must not fail")
+	assert.True(t, userIsMemberOfTeam(user.ID, team), "provider user should have joined the team")
+}
+
+func TestUserService_UpsertUnverifiedPassword(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	sessions := NewSessionService(repo, inmem.NewInMemUserTokenRepository(), jwt.NewEncodeDecoder([]byte("key")))
+	service := NewUserService(repo, teamRepo, inmem.NewInMemInvitationRepository(), sessions)
+
+	// Anyone can sign up with an email they do not own
+	_, err := service.SignUp("pizza@paper.net", "password")
+	require.NoError(t, err, "signing up must not fail")
+
+	// The account is not handed to the user of an identity provider
+	_, err = service.Upsert(auth.User{Name: "Pizza", Email: "pizza@paper.net", EmailVerified: true})
+	if assert.Error(t, err, "linking an unverified account with a password should fail") {
+		errors.AssertCode(t, err, 409)
+	}
+
+	user, err := repo.GetByEmail("pizza@paper.net")
+	require.NoError(t, err, "getting user must not fail")
+	assert.False(t, user.EmailVerified, "email should not be verified")
+
+	// Once the email is verified, the identity provider can log the user in
+	user.EmailVerified = true
+	require.NoError(t, repo.Upsert(&user), "verifying email must not fail")
+
+	linked, err := service.Upsert(auth.User{Name: "Pizza", Email: "pizza@paper.net", EmailVerified: true})
+	if assert.NoError(t, err, "linking a verified account should not fail") {
+		assert.Equal(t, user.ID, linked.ID, "the existing account should be used")
+	}
+}
+
+func TestUserService_TeamPapers(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	service := NewUserService(repo, teamRepo, inmem.NewInMemInvitationRepository(), nil)
+
+	admin := auth.User{Email: "admin@paper.net"}
+	require.NoError(t, repo.Upsert(&admin), "inserting admin must not fail")
+
+	colleague := auth.User{Email: "colleague@paper.net"}
+	require.NoError(t, repo.Upsert(&colleague), "inserting colleague must not fail")
+
+	team := auth.Team{
+		Name:    "Pizza team",
+		Members: []auth.TeamMember{{ID: admin.ID, Role: auth.TeamAdmin}},
+		Owns:    []int{1},
+	}
+	require.NoError(t, teamRepo.Upsert(&team), "inserting team must not fail")
+
+	// A paper owned by a team cannot be claimed by a user
+	_, err := service.CreatePaper(colleague.ID, 1)
+	if assert.Error(t, err, "creating a paper owned by a team should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// The admins of the team hold the owner rights on the paper
+	grantees, err := service.SharePaper(admin.ID, 1, colleague.Email, false)
+	if assert.NoError(t, err, "team admin sharing should not fail") {
+		assert.Equal(t, 1, len(grantees), "paper should be shared with colleague")
+	}
+
+	// ...but cannot give the paper away
+	err = service.OfferPaper(admin.ID, 1, colleague.Email)
+	if assert.Error(t, err, "offering a team paper should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+}
+
+func TestUserService_LinkGoogle(t *testing.T) {
+	teamRepo := inmem.NewInMemTeamRepository()
+	repo := inmem.NewInMemUserRepository(teamRepo)
+	service := NewUserService(repo, teamRepo, inmem.NewInMemInvitationRepository(), nil)
+
+	withPassword := auth.User{Email: "password@paper.net", PasswordHash: "hash"}
+	require.NoError(t, repo.Upsert(&withPassword), "inserting user with password must not fail")
+	googleOnly := auth.User{Email: "google@paper.net", GoogleID: "google-only"}
+	require.NoError(t, repo.Upsert(&googleOnly), "inserting google user must not fail")
+
+	user, err := service.LinkGoogle(withPassword.ID, "google-pizza")
+	if assert.NoError(t, err, "linking a google account should not fail") {
+		assert.Equal(t, "google-pizza", user.GoogleID)
+	}
+
+	// Linking again is a no-op
+	_, err = service.LinkGoogle(withPassword.ID, "google-pizza")
+	assert.NoError(t, err, "linking the same google account again should not fail")
+
+	// A Google account is linked to one user, and a user to one account
+	_, err = service.LinkGoogle(googleOnly.ID, "google-pizza")
+	if assert.Error(t, err, "linking a google account linked to another user should fail") {
+		errors.AssertCode(t, err, 409)
+	}
+
+	_, err = service.LinkGoogle(withPassword.ID, "google-yolo")
+	if assert.Error(t, err, "linking a second google account should fail") {
+		errors.AssertCode(t, err, 409)
+	}
+
+	// Users without a password would be locked out
+	_, err = service.UnlinkGoogle(googleOnly.ID)
+	if assert.Error(t, err, "unlinking the google account of a user without password should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	user, err = service.UnlinkGoogle(withPassword.ID)
+	if assert.NoError(t, err, "unlinking a google account should not fail") {
+		assert.Equal(t, "", user.GoogleID)
+	}
+
+	user, err = repo.GetByGoogleID("google-pizza")
+	require.NoError(t, err, "getting by google id must not fail")
+	assert.Equal(t, 0, user.ID, "the google account should not be linked anymore")
+}
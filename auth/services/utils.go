@@ -1,10 +1,11 @@
 package services
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
 
+	"github.com/bobinette/papernet/auth"
 	"github.com/bobinette/papernet/errors"
 )
 
@@ -23,13 +24,47 @@ func errTeamNotFound(id int) error {
 	return errors.New(fmt.Sprintf("No team for id %d", id), errors.NotFound())
 }
 
+// errInvitationNotFound returns a 404 for when an invitation could not be found.
+func errInvitationNotFound(id int) error {
+	return errors.New(fmt.Sprintf("No invitation for id %d", id), errors.NotFound())
+}
+
+// errAPITokenNotFound returns a 404 for when an API token could not be found.
+func errAPITokenNotFound(id int) error {
+	return errors.New(fmt.Sprintf("No API token for id %d", id), errors.NotFound())
+}
+
+// errReadingListNotFound returns a 404 for when a reading list could not be found.
+func errReadingListNotFound(id int) error {
+	return errors.New(fmt.Sprintf("No reading list for id %d", id), errors.NotFound())
+}
+
 // errNotTeamAdmin returns a 403 for when team admin privilege is needed
 func errNotTeamAdmin(id int) error {
 	return errors.New(fmt.Sprintf("You are not an admin of team %d", id), errors.Forbidden())
 }
 
+// indexOf returns the index of id in ids, or -1 if ids does not contain it.
+func indexOf(id int, ids []int) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// userOwnsPaper returns whether the user holds the owner rights on a paper, either
+// directly or as an admin of the team owning it.
+func userOwnsPaper(user auth.User, paperID int) bool {
+	return indexOf(paperID, user.Owns) != -1 || indexOf(paperID, user.TeamOwns) != -1
+}
+
+// randToken generates a random url safe token. The tokens are used as salts,
+// refresh tokens and in the password reset and email verification links, so
+// they have to be unpredictable.
 func randToken(size int) string {
 	b := make([]byte, size)
 	rand.Read(b)
-	return base64.StdEncoding.EncodeToString(b)
+	return base64.RawURLEncoding.EncodeToString(b)
 }
@@ -1,19 +1,89 @@
 package auth
 
+import (
+	"encoding/json"
+)
+
+// TeamRole is the role of a member in a team.
+type TeamRole string
+
+const (
+	// TeamAdmin can manage the team: invite, kick, change roles, rename...
+	TeamAdmin TeamRole = "admin"
+	// TeamEditor can share its own papers with the team, and edit the papers
+	// the team can edit.
+	TeamEditor TeamRole = "editor"
+	// TeamViewer can only see the papers shared with the team.
+	TeamViewer TeamRole = "viewer"
+)
+
+// IsValid returns whether the role is one of the known roles.
+func (r TeamRole) IsValid() bool {
+	return r == TeamAdmin || r == TeamEditor || r == TeamViewer
+}
+
 type TeamMember struct {
 	ID    int    `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
 
-	IsTeamAdmin bool `json:"admin"`
+	Role TeamRole `json:"role"`
+}
+
+// teamMemberJSON is the JSON representation of a team member. The admin flag
+// was replaced by the role: it is still derived from the role for the API
+// consumers relying on it, and will be removed in the next release.
+type teamMemberJSON struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+
+	Role        TeamRole `json:"role"`
+	IsTeamAdmin bool     `json:"admin"`
+}
+
+func (m TeamMember) MarshalJSON() ([]byte, error) {
+	return json.Marshal(teamMemberJSON{
+		ID:    m.ID,
+		Name:  m.Name,
+		Email: m.Email,
+
+		Role:        m.Role,
+		IsTeamAdmin: m.Role == TeamAdmin,
+	})
+}
+
+// UnmarshalJSON reads a team member, falling back on the deprecated admin flag
+// when the role is not set.
+func (m *TeamMember) UnmarshalJSON(data []byte) error {
+	var jm teamMemberJSON
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+
+	role := jm.Role
+	if role == "" && jm.IsTeamAdmin {
+		role = TeamAdmin
+	}
+
+	*m = TeamMember{
+		ID:    jm.ID,
+		Name:  jm.Name,
+		Email: jm.Email,
+
+		Role: role,
+	}
+	return nil
 }
 
 type Team struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
 
 	Members []TeamMember `json:"members"`
 
+	Owns    []int `json:"owns"`
 	CanSee  []int `json:"canSee"`
 	CanEdit []int `json:"canEdit"`
 }
@@ -22,6 +92,10 @@ type TeamRepository interface {
 	Get(int) (Team, error)
 	GetForUser(int) ([]Team, error)
 
+	// PaperOwner returns the id of the team owning a paper, 0 if the paper is
+	// not owned by a team.
+	PaperOwner(paperID int) (int, error)
+
 	Upsert(*Team) error
 	Delete(int) error
 }
@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+func TestAPITokenRepository(t *testing.T, repo auth.APITokenRepository) {
+	now := time.Now().Truncate(time.Second)
+	tokens := []*auth.APIToken{
+		{
+			UserID:    1,
+			Name:      "pizza script",
+			Scopes:    []string{"papers:read"},
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+			Token:     "token",
+		},
+		{
+			UserID:    1,
+			Name:      "imports",
+			Scopes:    []string{"papers:read", "imports"},
+			CreatedAt: now,
+			ExpiresAt: now.Add(48 * time.Hour),
+		},
+		{
+			UserID:    2,
+			Name:      "yolo",
+			Scopes:    []string{"crons"},
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+		},
+	}
+
+	// Insert tokens
+	for _, token := range tokens {
+		err := repo.Upsert(token)
+		require.NoError(t, err, "inserting token must not fail")
+		require.NotEqual(t, 0, token.ID, "id must be set by insert")
+	}
+	assert.NotEqual(t, tokens[0].ID, tokens[1].ID, "ids must be different")
+
+	// The signed token is not stored
+	tokens[0].Token = ""
+
+	// Get tokens by id
+	for _, token := range tokens {
+		testGetAPIToken(t, repo, token.ID, *token, "get token")
+	}
+
+	// Get non existing token
+	testGetAPIToken(t, repo, tokens[2].ID+1, auth.APIToken{}, "get non existing token")
+
+	// List tokens by user
+	testListAPITokens(t, repo, 1, tokens[:2], "list for 1")
+	testListAPITokens(t, repo, 3, nil, "list for unknown")
+
+	// Update
+	tokens[1].Name = "arxiv imports"
+	err := repo.Upsert(tokens[1])
+	require.NoError(t, err, "updating token must not fail")
+	testGetAPIToken(t, repo, tokens[1].ID, *tokens[1], "get token after update")
+
+	// Delete
+	err = repo.Delete(tokens[0].ID)
+	require.NoError(t, err, "deleting token must not fail")
+	testGetAPIToken(t, repo, tokens[0].ID, auth.APIToken{}, "get deleted token")
+	testListAPITokens(t, repo, 1, tokens[1:2], "list for 1 after delete")
+}
+
+func testGetAPIToken(t *testing.T, repo auth.APITokenRepository, id int, expected auth.APIToken, name string) {
+	token, err := repo.Get(id)
+	if assert.NoError(t, err, "%s - getting token should not fail", name) {
+		AssertAPIToken(t, expected, token, name)
+	}
+}
+
+func testListAPITokens(t *testing.T, repo auth.APITokenRepository, userID int, expected []*auth.APIToken, name string) {
+	tokens, err := repo.ListForUser(userID)
+	if !assert.NoError(t, err, "%s - listing tokens should not fail", name) {
+		return
+	}
+
+	if assert.Equal(t, len(expected), len(tokens), "%s - incorrect number of tokens", name) {
+		for i, token := range tokens {
+			AssertAPIToken(t, *expected[i], token, name)
+		}
+	}
+}
+
+func AssertAPIToken(t *testing.T, expected, actual auth.APIToken, name string) {
+	assert.Equal(t, expected.ID, actual.ID, "%s - ids should be equal", name)
+	assert.Equal(t, expected.UserID, actual.UserID, "%s - user ids should be equal", name)
+	assert.Equal(t, expected.Name, actual.Name, "%s - names should be equal", name)
+	assert.Equal(t, expected.Token, actual.Token, "%s - tokens should be equal", name)
+
+	if expected.ID != 0 {
+		assert.Equal(t, expected.Scopes, actual.Scopes, "%s - scopes should be equal", name)
+		assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "%s - creation dates should be equal", name)
+		assert.True(t, expected.ExpiresAt.Equal(actual.ExpiresAt), "%s - expiration dates should be equal", name)
+	}
+}
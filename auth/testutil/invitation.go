@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+func TestInvitationRepository(t *testing.T, repo auth.InvitationRepository) {
+	now := time.Now().Truncate(time.Second)
+	invitations := []*auth.Invitation{
+		{
+			TeamID:    1,
+			Email:     "pizza@paper.net",
+			InviterID: 1,
+			State:     auth.InvitationPending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+			Token:     "token",
+		},
+		{
+			TeamID:    2,
+			Email:     "pizza@paper.net",
+			InviterID: 2,
+			State:     auth.InvitationPending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+		},
+		{
+			TeamID:    2,
+			Email:     "yolo@paper.net",
+			InviterID: 2,
+			State:     auth.InvitationPending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+		},
+	}
+
+	// Insert invitations
+	for _, invitation := range invitations {
+		err := repo.Upsert(invitation)
+		require.NoError(t, err, "inserting invitation must not fail")
+		require.NotEqual(t, 0, invitation.ID, "id must be set by insert")
+	}
+	assert.NotEqual(t, invitations[0].ID, invitations[1].ID, "ids must be different")
+
+	// The token is not stored
+	invitations[0].Token = ""
+
+	// Get invitations by id
+	for _, invitation := range invitations {
+		testGetInvitation(t, repo, invitation.ID, *invitation, "get invitation")
+	}
+
+	// Get non existing invitation
+	testGetInvitation(t, repo, invitations[2].ID+1, auth.Invitation{}, "get non existing invitation")
+
+	// List invitations by email
+	testListInvitations(t, repo, "pizza@paper.net", invitations[:2], "list for pizza")
+	testListInvitations(t, repo, "unknown@paper.net", nil, "list for unknown")
+
+	// Update state
+	invitations[1].State = auth.InvitationAccepted
+	err := repo.Upsert(invitations[1])
+	require.NoError(t, err, "updating invitation must not fail")
+	testGetInvitation(t, repo, invitations[1].ID, *invitations[1], "get invitation after update")
+}
+
+func testGetInvitation(t *testing.T, repo auth.InvitationRepository, id int, expected auth.Invitation, name string) {
+	invitation, err := repo.Get(id)
+	if assert.NoError(t, err, "%s - getting invitation should not fail", name) {
+		AssertInvitation(t, expected, invitation, name)
+	}
+}
+
+func testListInvitations(t *testing.T, repo auth.InvitationRepository, email string, expected []*auth.Invitation, name string) {
+	invitations, err := repo.ListForEmail(email)
+	if !assert.NoError(t, err, "%s - listing invitations should not fail", name) {
+		return
+	}
+
+	if assert.Equal(t, len(expected), len(invitations), "%s - incorrect number of invitations", name) {
+		for i, invitation := range invitations {
+			AssertInvitation(t, *expected[i], invitation, name)
+		}
+	}
+}
+
+func AssertInvitation(t *testing.T, expected, actual auth.Invitation, name string) {
+	assert.Equal(t, expected.ID, actual.ID, "%s - ids should be equal", name)
+	assert.Equal(t, expected.TeamID, actual.TeamID, "%s - team ids should be equal", name)
+	assert.Equal(t, expected.Email, actual.Email, "%s - emails should be equal", name)
+	assert.Equal(t, expected.InviterID, actual.InviterID, "%s - inviter ids should be equal", name)
+	assert.Equal(t, expected.State, actual.State, "%s - states should be equal", name)
+	assert.Equal(t, expected.Token, actual.Token, "%s - tokens should be equal", name)
+
+	if expected.ID != 0 {
+		assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "%s - creation dates should be equal", name)
+		assert.True(t, expected.ExpiresAt.Equal(actual.ExpiresAt), "%s - expiration dates should be equal", name)
+	}
+}
@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+func TestReadingListRepository(t *testing.T, repo auth.ReadingListRepository) {
+	lists := []*auth.ReadingList{
+		{
+			OwnerID: 1,
+			Name:    "To read",
+			Papers:  []int{3, 1, 2},
+			Teams:   []int{},
+		},
+		{
+			OwnerID: 1,
+			Name:    "Deep learning",
+			Papers:  []int{},
+			Teams:   []int{4},
+		},
+		{
+			OwnerID: 2,
+			Name:    "Yolo",
+			Papers:  []int{10},
+			Teams:   []int{4, 5},
+		},
+	}
+
+	// Insert lists
+	for _, list := range lists {
+		err := repo.Upsert(list)
+		require.NoError(t, err, "inserting list must not fail")
+		require.NotEqual(t, 0, list.ID, "id must be set by insert")
+	}
+	assert.NotEqual(t, lists[0].ID, lists[1].ID, "ids must be different")
+
+	// Get lists by id
+	for _, list := range lists {
+		testGetReadingList(t, repo, list.ID, *list, "get list")
+	}
+
+	// Get non existing list
+	testGetReadingList(t, repo, lists[2].ID+1, auth.ReadingList{}, "get non existing list")
+
+	// List by user and team
+	testListReadingLists(t, repo.ListForUser, 1, lists[:2], "list for user 1")
+	testListReadingLists(t, repo.ListForUser, 3, nil, "list for unknown user")
+	testListReadingLists(t, repo.ListForTeam, 4, lists[1:], "list for team 4")
+	testListReadingLists(t, repo.ListForTeam, 5, lists[2:], "list for team 5")
+
+	// Reorder the papers, stop sharing with a team
+	lists[0].Papers = []int{2, 3, 1}
+	lists[0].Name = "Next"
+	err := repo.Upsert(lists[0])
+	require.NoError(t, err, "updating list must not fail")
+	testGetReadingList(t, repo, lists[0].ID, *lists[0], "get list after update")
+
+	lists[2].Teams = []int{5}
+	err = repo.Upsert(lists[2])
+	require.NoError(t, err, "updating list must not fail")
+	testListReadingLists(t, repo.ListForTeam, 4, lists[1:2], "list for team 4 after unshare")
+
+	// Delete
+	err = repo.Delete(lists[1].ID)
+	require.NoError(t, err, "deleting list must not fail")
+	testGetReadingList(t, repo, lists[1].ID, auth.ReadingList{}, "get deleted list")
+	testListReadingLists(t, repo.ListForUser, 1, lists[:1], "list for user 1 after delete")
+	testListReadingLists(t, repo.ListForTeam, 4, nil, "list for team 4 after delete")
+}
+
+func testGetReadingList(t *testing.T, repo auth.ReadingListRepository, id int, expected auth.ReadingList, name string) {
+	list, err := repo.Get(id)
+	if assert.NoError(t, err, "%s - getting list should not fail", name) {
+		AssertReadingList(t, expected, list, name)
+	}
+}
+
+func testListReadingLists(t *testing.T, f func(int) ([]auth.ReadingList, error), id int, expected []*auth.ReadingList, name string) {
+	lists, err := f(id)
+	if !assert.NoError(t, err, "%s - listing lists should not fail", name) {
+		return
+	}
+
+	if assert.Equal(t, len(expected), len(lists), "%s - incorrect number of lists", name) {
+		for i, list := range lists {
+			AssertReadingList(t, *expected[i], list, name)
+		}
+	}
+}
+
+func AssertReadingList(t *testing.T, expected, actual auth.ReadingList, name string) {
+	assert.Equal(t, expected.ID, actual.ID, "%s - ids should be equal", name)
+	assert.Equal(t, expected.OwnerID, actual.OwnerID, "%s - owner ids should be equal", name)
+	assert.Equal(t, expected.Name, actual.Name, "%s - names should be equal", name)
+
+	if expected.ID != 0 {
+		assert.Equal(t, expected.Papers, actual.Papers, "%s - papers should be equal, in the same order", name)
+		assert.Equal(t, expected.Teams, actual.Teams, "%s - teams should be equal", name)
+	}
+}
@@ -14,10 +14,12 @@ import (
 func TestTeamRepository(t *testing.T, repo auth.TeamRepository) {
 	teams := []*auth.Team{
 		{
-			Name: "Pizza",
+			Name:        "Pizza",
+			Description: "Pizza lovers",
 			Members: []auth.TeamMember{
-				{ID: 1, IsTeamAdmin: true},
-				{ID: 2, IsTeamAdmin: false},
+				{ID: 1, Role: auth.TeamAdmin},
+				{ID: 2, Role: auth.TeamEditor},
+				{ID: 5, Role: auth.TeamViewer},
 			},
 			CanSee:  []int{1, 2},
 			CanEdit: []int{1},
@@ -25,8 +27,8 @@ func TestTeamRepository(t *testing.T, repo auth.TeamRepository) {
 		{
 			Name: "Yolo",
 			Members: []auth.TeamMember{
-				{ID: 1, IsTeamAdmin: false},
-				{ID: 3, IsTeamAdmin: true},
+				{ID: 1, Role: auth.TeamEditor},
+				{ID: 3, Role: auth.TeamAdmin},
 			},
 			CanSee:  []int{2, 3, 4},
 			CanEdit: []int{3, 4},
@@ -53,11 +55,26 @@ func TestTeamRepository(t *testing.T, repo auth.TeamRepository) {
 	testUpdateTeam(t, repo, teams[0])
 	testGetTeam(t, repo, teams[0].ID, teams[0], "get team 0 after name update")
 
+	// Update a team's description
+	teams[0].Description = "Pizza and yolo lovers"
+	testUpdateTeam(t, repo, teams[0])
+	testGetTeam(t, repo, teams[0].ID, teams[0], "get team 0 after description update")
+
+	// Change members' roles
+	teams[0].Members = []auth.TeamMember{
+		{ID: 1, Role: auth.TeamAdmin},
+		{ID: 2, Role: auth.TeamViewer},
+		{ID: 5, Role: auth.TeamEditor},
+	}
+	testUpdateTeam(t, repo, teams[0])
+	testGetTeam(t, repo, teams[0].ID, teams[0], "get team 0 after roles update")
+	testGetTeamsForUser(t, repo, 2, []*auth.Team{teams[0]}, "get teams for viewer")
+
 	// Update a team's users (+2 -1)
 	teams[1].Members = []auth.TeamMember{
-		{ID: 2, IsTeamAdmin: false},
-		{ID: 3, IsTeamAdmin: false},
-		{ID: 4, IsTeamAdmin: true},
+		{ID: 2, Role: auth.TeamEditor},
+		{ID: 3, Role: auth.TeamEditor},
+		{ID: 4, Role: auth.TeamAdmin},
 	}
 	testUpdateTeam(t, repo, teams[1])
 	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after members update")
@@ -68,8 +85,32 @@ func TestTeamRepository(t *testing.T, repo auth.TeamRepository) {
 	testUpdateTeam(t, repo, teams[1])
 	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after permissions update")
 
+	// Downgrade papers (canSee: -0, canEdit: -2)
+	teams[1].CanEdit = []int{1, 3}
+	testUpdateTeam(t, repo, teams[1])
+	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after downgrade")
+
+	// Unshare papers (canSee: -2, canEdit: -1)
+	teams[1].CanSee = []int{3, 4}
+	teams[1].CanEdit = []int{3}
+	testUpdateTeam(t, repo, teams[1])
+	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after unshare")
+
+	// Give papers to a team
+	teams[1].Owns = []int{6, 7}
+	testUpdateTeam(t, repo, teams[1])
+	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after owns update")
+	testTeamPaperOwner(t, repo, 6, teams[1].ID, "team paper owner")
+	testTeamPaperOwner(t, repo, 3, 0, "paper not owned by a team")
+
+	teams[1].Owns = []int{7}
+	testUpdateTeam(t, repo, teams[1])
+	testGetTeam(t, repo, teams[1].ID, teams[1], "get team 1 after owns removal")
+	testTeamPaperOwner(t, repo, 6, 0, "team paper owner after removal")
+
 	// Delete a team
 	testDeleteTeam(t, repo, teams[1].ID, "delete team 1")
+	testTeamPaperOwner(t, repo, 7, 0, "team paper owner after delete")
 
 	// List teams for user again
 	testGetTeamsForUser(t, repo, 1, []*auth.Team{teams[0]}, "get teams for user after delete")
@@ -118,6 +159,13 @@ func testUpdateTeam(t *testing.T, repo auth.TeamRepository, team *auth.Team) {
 	assert.Equal(t, id, team.ID, "id should not change")
 }
 
+func testTeamPaperOwner(t *testing.T, repo auth.TeamRepository, paperID, teamID int, name string) {
+	ownerID, err := repo.PaperOwner(paperID)
+	if assert.NoError(t, err, "%s - paper owner should not fail", name) {
+		assert.Equal(t, teamID, ownerID, "%s - incorrect owner", name)
+	}
+}
+
 func testDeleteTeam(t *testing.T, repo auth.TeamRepository, teamID int, name string) {
 	err := repo.Delete(teamID)
 	assert.NoError(t, err, "delete should not fail")
@@ -131,6 +179,7 @@ func AssertTeam(t *testing.T, expected, actual auth.Team, name string) {
 	// General information
 	assert.Equal(t, expected.ID, actual.ID, "%s - teams' ids should be equal", name)
 	assert.Equal(t, expected.Name, actual.Name, "%s - teams' names should be equal", name)
+	assert.Equal(t, expected.Description, actual.Description, "%s - teams' descriptions should be equal", name)
 
 	// Members
 	if assert.Equal(t, len(expected.Members), len(actual.Members), "%s - number of members should be the same", name) {
@@ -141,6 +190,13 @@ func AssertTeam(t *testing.T, expected, actual auth.Team, name string) {
 	}
 
 	// Permissions
+	if assert.Equal(t, len(expected.Owns), len(actual.Owns), "%s - number of owned papers should be the same", name) {
+		// Order does not matter
+		for _, paperID := range expected.Owns {
+			assert.Contains(t, actual.Owns, paperID, "%s - paperID %d should be in team's owns", name, paperID)
+		}
+	}
+
 	if assert.Equal(t, len(expected.CanSee), len(actual.CanSee), "%s - number of seeable papers should be the same", name) {
 		// Order does not matter
 		for _, paperID := range expected.CanSee {
@@ -0,0 +1,129 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/auth"
+)
+
+func TestUserTokenRepository(t *testing.T, repo auth.UserTokenRepository) {
+	now := time.Now().Truncate(time.Second)
+	tokens := []*auth.UserToken{
+		{
+			Hash:      "pizza",
+			Kind:      auth.PasswordResetToken,
+			UserID:    1,
+			Email:     "pizza@paper.net",
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		},
+		{
+			Hash:      "yolo",
+			Kind:      auth.EmailVerificationToken,
+			UserID:    2,
+			Email:     "yolo@paper.net",
+			CreatedAt: now,
+			ExpiresAt: now.Add(24 * time.Hour),
+		},
+		{
+			Hash:      "margherita",
+			Kind:      auth.PasswordResetToken,
+			UserID:    1,
+			Email:     "pizza@paper.net",
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		},
+	}
+
+	// Insert tokens
+	for _, token := range tokens {
+		err := repo.Upsert(token)
+		require.NoError(t, err, "inserting token must not fail")
+	}
+
+	// Get tokens by hash
+	for _, token := range tokens {
+		testGetUserToken(t, repo, token.Hash, *token, "get token")
+	}
+
+	// Get non existing token
+	testGetUserToken(t, repo, "unknown", auth.UserToken{}, "get non existing token")
+
+	// List the tokens of a user by kind
+	testListUserTokens(t, repo, 1, auth.PasswordResetToken, []*auth.UserToken{tokens[0], tokens[2]}, "list reset tokens")
+	testListUserTokens(t, repo, 1, auth.EmailVerificationToken, []*auth.UserToken{}, "list verification tokens")
+	testListUserTokens(t, repo, 3, auth.PasswordResetToken, []*auth.UserToken{}, "list tokens of unknown user")
+
+	// Use a token
+	tokens[0].Used = true
+	err := repo.Upsert(tokens[0])
+	require.NoError(t, err, "updating token must not fail")
+	testGetUserToken(t, repo, tokens[0].Hash, *tokens[0], "get token after use")
+	testGetUserToken(t, repo, tokens[1].Hash, *tokens[1], "other token after use")
+
+	// Use a token atomically: the token is returned as it was before
+	previous, err := repo.Use(tokens[2].Hash, auth.EmailVerificationToken)
+	if assert.NoError(t, err, "using a token with the wrong kind should not fail") {
+		assert.False(t, previous.Used, "token should not have been used")
+	}
+	testGetUserToken(t, repo, tokens[2].Hash, *tokens[2], "token used with the wrong kind")
+
+	previous, err = repo.Use(tokens[2].Hash, auth.PasswordResetToken)
+	if assert.NoError(t, err, "using a token should not fail") {
+		assert.False(t, previous.Used, "token should not have been used before")
+	}
+	tokens[2].Used = true
+	testGetUserToken(t, repo, tokens[2].Hash, *tokens[2], "token after atomic use")
+
+	previous, err = repo.Use(tokens[2].Hash, auth.PasswordResetToken)
+	if assert.NoError(t, err, "using a token twice should not fail") {
+		assert.True(t, previous.Used, "token should have been used before")
+	}
+
+	// Delete the expired tokens
+	deleted, err := repo.DeleteExpired(now.Add(2 * time.Hour))
+	if assert.NoError(t, err, "deleting expired tokens should not fail") {
+		assert.Equal(t, 2, deleted, "the two reset tokens should be deleted")
+	}
+	testGetUserToken(t, repo, tokens[0].Hash, auth.UserToken{}, "expired token after delete")
+	testGetUserToken(t, repo, tokens[1].Hash, *tokens[1], "token after delete")
+	testListUserTokens(t, repo, 1, auth.PasswordResetToken, []*auth.UserToken{}, "list reset tokens after delete")
+}
+
+func testGetUserToken(t *testing.T, repo auth.UserTokenRepository, hash string, expected auth.UserToken, name string) {
+	token, err := repo.Get(hash)
+	if assert.NoError(t, err, "%s - getting token should not fail", name) {
+		AssertUserToken(t, expected, token, name)
+	}
+}
+
+func testListUserTokens(t *testing.T, repo auth.UserTokenRepository, userID int, kind auth.UserTokenKind, expected []*auth.UserToken, name string) {
+	tokens, err := repo.ListForUser(userID, kind)
+	if !assert.NoError(t, err, "%s - listing tokens should not fail", name) {
+		return
+	}
+
+	if assert.Equal(t, len(expected), len(tokens), "%s - number of tokens should be equal", name) {
+		byHash := make(map[string]auth.UserToken)
+		for _, token := range tokens {
+			byHash[token.Hash] = token
+		}
+		for _, token := range expected {
+			AssertUserToken(t, *token, byHash[token.Hash], name)
+		}
+	}
+}
+
+func AssertUserToken(t *testing.T, expected, actual auth.UserToken, name string) {
+	assert.Equal(t, expected.Hash, actual.Hash, "%s - hashes should be equal", name)
+	assert.Equal(t, expected.Kind, actual.Kind, "%s - kinds should be equal", name)
+	assert.Equal(t, expected.UserID, actual.UserID, "%s - user ids should be equal", name)
+	assert.Equal(t, expected.Used, actual.Used, "%s - used flags should be equal", name)
+	assert.Equal(t, expected.Email, actual.Email, "%s - emails should be equal", name)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "%s - creation dates should be equal", name)
+	assert.True(t, expected.ExpiresAt.Equal(actual.ExpiresAt), "%s - expiration dates should be equal", name)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -41,6 +42,16 @@ func TestUserRepository(t *testing.T, repo auth.UserRepository) {
 	users[0].Email = "pizza@yolo.space"
 	testUpdateUser(t, repo, users[0])
 
+	// Verify pizza user email
+	users[0].EmailVerified = true
+	testUpdateUser(t, repo, users[0])
+	testGetUser(t, repo, users[0].ID, *users[0], "get user 0 after email verification")
+
+	// Revoke pizza user sessions
+	users[0].SessionsRevokedAt = time.Now().Truncate(time.Second)
+	testUpdateUser(t, repo, users[0])
+	testGetUser(t, repo, users[0].ID, *users[0], "get user 0 after sessions revocation")
+
 	// Update yolo user owns and bookmarks
 	users[1].Owns = []int{1, 2, 3}
 	users[1].Bookmarks = []int{1, 2}
@@ -54,6 +65,52 @@ func TestUserRepository(t *testing.T, repo auth.UserRepository) {
 	// Get by email
 	testGetUserByEmail(t, repo, users[0].Email, *users[0], "get by email")
 
+	// Link a Google account to pizza user, then unlink it
+	testGetUserByGoogleID(t, repo, "", auth.User{}, "get by empty google id")
+	users[0].GoogleID = "google-pizza"
+	testUpdateUser(t, repo, users[0])
+	testGetUserByGoogleID(t, repo, "google-pizza", *users[0], "get by google id")
+
+	users[0].GoogleID = ""
+	testUpdateUser(t, repo, users[0])
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after unlinking google")
+	testGetUserByGoogleID(t, repo, "google-pizza", auth.User{}, "get by google id after unlinking")
+
+	// Share papers directly with pizza user
+	testGrant(t, repo, users[0].ID, 2, false)
+	testGrant(t, repo, users[0].ID, 3, true)
+	users[0].CanSee = []int{2, 3}
+	users[0].CanEdit = []int{3}
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after grants")
+	testPaperGrantees(t, repo, 3, []auth.Grantee{{ID: users[0].ID, Name: users[0].Name, Email: users[0].Email, CanEdit: true}})
+
+	// Downgrade and revoke
+	testGrant(t, repo, users[0].ID, 3, false)
+	users[0].CanEdit = []int{}
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after downgrade")
+	testPaperGrantees(t, repo, 3, []auth.Grantee{{ID: users[0].ID, Name: users[0].Name, Email: users[0].Email, CanEdit: false}})
+
+	err := repo.Revoke(users[0].ID, 2)
+	assert.NoError(t, err, "revoking should not fail")
+	users[0].CanSee = []int{3}
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after revoke")
+	testPaperGrantees(t, repo, 2, []auth.Grantee{})
+
+	// Offer paper 2 to pizza user, then transfer it
+	users[0].OwnershipOffers = []int{2}
+	testUpdateUser(t, repo, users[0])
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after offer")
+
+	err = repo.TransferOwnership(2, users[1].ID, users[0].ID)
+	assert.NoError(t, err, "transferring ownership should not fail")
+	testGetPaperOwner(t, repo, 2, users[0].ID)
+
+	users[0].Owns = []int{2}
+	users[0].CanSee = []int{2, 3}
+	users[0].CanEdit = []int{2}
+	users[0].OwnershipOffers = []int{}
+	testGetUser(t, repo, users[0].ID, *users[0], "get user after transfer")
+
 	// Delete user
 	testDeleteUser(t, repo, users[1].ID, "delete")
 
@@ -91,6 +148,13 @@ func testGetUserByEmail(t *testing.T, repo auth.UserRepository, email string, ex
 	}
 }
 
+func testGetUserByGoogleID(t *testing.T, repo auth.UserRepository, googleID string, expected auth.User, name string) {
+	user, err := repo.GetByGoogleID(googleID)
+	if assert.NoError(t, err, "%s - getting user by google id should not fail", name) {
+		AssertUser(t, expected, user, name)
+	}
+}
+
 func testUpdateUser(t *testing.T, repo auth.UserRepository, user *auth.User) {
 	id := user.ID
 	err := repo.Upsert(user)
@@ -113,6 +177,18 @@ func testGetPaperOwner(t *testing.T, repo auth.UserRepository, paperID, ownerID
 	assert.Equal(t, ownerID, userID, "incorrect owner id retrieved")
 }
 
+func testGrant(t *testing.T, repo auth.UserRepository, userID, paperID int, canEdit bool) {
+	err := repo.Grant(userID, paperID, canEdit)
+	assert.NoError(t, err, "granting paper %d to user %d should not fail", paperID, userID)
+}
+
+func testPaperGrantees(t *testing.T, repo auth.UserRepository, paperID int, expected []auth.Grantee) {
+	grantees, err := repo.PaperGrantees(paperID)
+	if assert.NoError(t, err, "getting grantees of paper %d should not fail", paperID) {
+		assert.Equal(t, expected, grantees, "incorrect grantees for paper %d", paperID)
+	}
+}
+
 func testAllUsers(t *testing.T, repo auth.UserRepository, users []*auth.User) {
 	retrieved, err := repo.List()
 	if !assert.NoError(t, err, "listing all users should not fail") {
@@ -142,6 +218,9 @@ func AssertUser(t *testing.T, expected, actual auth.User, name string) {
 	assert.Equal(t, expected.ID, actual.ID, "%s - ids should be equal", name)
 	assert.Equal(t, expected.Name, actual.Name, "%s - names should be equal", name)
 	assert.Equal(t, expected.Email, actual.Email, "%s - emails should be equal", name)
+	assert.Equal(t, expected.EmailVerified, actual.EmailVerified, "%s - email verification should be equal", name)
+	assert.Equal(t, expected.GoogleID, actual.GoogleID, "%s - google ids should be equal", name)
+	assert.True(t, expected.SessionsRevokedAt.Equal(actual.SessionsRevokedAt), "%s - sessions revocation dates should be equal", name)
 
 	// Papers
 	if assert.Equal(t, len(expected.Owns), len(actual.Owns), "%s - number of owned papers should be the same", name) {
@@ -167,4 +246,10 @@ func AssertUser(t *testing.T, expected, actual auth.User, name string) {
 			assert.Contains(t, actual.Bookmarks, paperID, "%s - paperID %d should be in bookmarked papers", name, paperID)
 		}
 	}
+
+	if assert.Equal(t, len(expected.OwnershipOffers), len(actual.OwnershipOffers), "%s - number of ownership offers should be the same", name) {
+		for _, paperID := range expected.OwnershipOffers {
+			assert.Contains(t, actual.OwnershipOffers, paperID, "%s - paperID %d should be in ownership offers", name, paperID)
+		}
+	}
 }
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"time"
+)
+
+type UserTokenKind string
+
+const (
+	PasswordResetToken     UserTokenKind = "passwordReset"
+	EmailVerificationToken UserTokenKind = "emailVerification"
+	RefreshToken           UserTokenKind = "refresh"
+
+	// RevokedAccessToken tokens record the ids of the access tokens revoked
+	// before their expiration. They are never handed to a user.
+	RevokedAccessToken UserTokenKind = "revokedAccess"
+)
+
+// UserToken is a single use token handed to a user: sent by email to reset
+// their password or to verify their email address, or returned on login to
+// refresh their access token. Only the hash of the token is stored, the token
+// itself is only known by the user.
+type UserToken struct {
+	Hash   string
+	Kind   UserTokenKind
+	UserID int
+	Used   bool
+
+	// Email is the address the token was sent to, for the tokens sent by
+	// email. The token cannot be used anymore if the user changes it.
+	Email string
+
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type UserTokenRepository interface {
+	Get(hash string) (UserToken, error)
+	// ListForUser returns the tokens of a given kind handed to a user.
+	ListForUser(userID int, kind UserTokenKind) ([]UserToken, error)
+	Upsert(*UserToken) error
+
+	// Use marks a token of the given kind as used, checking and updating the
+	// flag atomically. It returns the token as it was before, so that the
+	// caller can tell whether it had already been used.
+	Use(hash string, kind UserTokenKind) (UserToken, error)
+
+	// DeleteExpired deletes the tokens expired before a date, and returns how
+	// many were deleted.
+	DeleteExpired(before time.Time) (int, error)
+}
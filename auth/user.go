@@ -1,5 +1,9 @@
 package auth
 
+import (
+	"time"
+)
+
 type User struct {
 	ID    int    `json:"id"`
 	Name  string `json:"name"`
@@ -8,22 +12,60 @@ type User struct {
 	Salt         string `json:"-"`
 	PasswordHash string `json:"-"`
 
-	IsAdmin bool `json:"isAdmin"`
+	// SessionsRevokedAt is the last time the user logged out of all their
+	// devices. The tokens issued before are not valid anymore.
+	SessionsRevokedAt time.Time `json:"-"`
+
+	IsAdmin       bool `json:"isAdmin"`
+	EmailVerified bool `json:"emailVerified"`
+
+	// GoogleID is the Google account linked to the user, who can log in with
+	// it.
+	GoogleID string `json:"googleId"`
 
 	Owns      []int `json:"owns"`
 	CanSee    []int `json:"canSee"`
 	CanEdit   []int `json:"canEdit"`
 	Bookmarks []int `json:"bookmarks"`
+
+	// TeamOwns are the papers owned by the teams the user is an admin of. The
+	// user holds the owner rights on them.
+	TeamOwns []int `json:"teamOwns"`
+
+	// OwnershipOffers are the papers whose owner offered to transfer the
+	// ownership to the user.
+	OwnershipOffers []int `json:"ownershipOffers"`
+}
+
+// Grantee is a user a paper has been shared with directly, without going
+// through a team.
+type Grantee struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+
+	CanEdit bool `json:"canEdit"`
 }
 
 type UserRepository interface {
 	// User information
 	Get(int) (User, error)
 	GetByEmail(string) (User, error)
+	GetByGoogleID(string) (User, error)
 	List() ([]User, error)
 	Upsert(*User) error
 	Delete(int) error
 
-	// User -> Paper
+	// User -> Paper. PaperOwner only returns users, see TeamRepository.PaperOwner
+	// for the papers owned by teams.
 	PaperOwner(paperID int) (int, error)
+	// TransferOwnership moves the ownership of a paper from a user to another,
+	// and removes all the pending offers for that paper.
+	TransferOwnership(paperID, fromID, toID int) error
+
+	// User -> Paper grants. Granted papers are included in the CanSee and
+	// CanEdit lists of the user.
+	Grant(userID, paperID int, canEdit bool) error
+	Revoke(userID, paperID int) error
+	PaperGrantees(paperID int) ([]Grantee, error)
 }
@@ -15,12 +15,24 @@ type User struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
 
-	IsAdmin bool `json:"isAdmin"`
+	EmailVerified bool `json:"emailVerified"`
+	IsAdmin       bool `json:"isAdmin"`
+
+	GoogleID string `json:"googleId"`
 
 	Owns      []int `json:"owns"`
 	CanSee    []int `json:"canSee"`
 	CanEdit   []int `json:"canEdit"`
 	Bookmarks []int `json:"bookmarks"`
+
+	TeamOwns []int `json:"teamOwns"`
+}
+
+// Tokens are the tokens issued by the auth service when a user logs in.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 type HTTPClient interface {
@@ -60,27 +72,26 @@ func (c *Client) User(id int) (User, error) {
 	return user, nil
 }
 
-func (c *Client) Token(id int) (string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/token", c.baseURL, id), nil)
+func (c *Client) Token(id int) (Tokens, error) {
+	// The user is logging in: a refresh token is needed to stay logged in
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/token?session=true", c.baseURL, id), nil)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	}
 
 	res, err := c.client.Do(req)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	}
 	defer res.Body.Close()
 
-	var token struct {
-		AccessToken string `json:"access_token"`
-	}
-	err = json.NewDecoder(res.Body).Decode(&token)
+	var tokens Tokens
+	err = json.NewDecoder(res.Body).Decode(&tokens)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
 	}
 
-	return token.AccessToken, nil
+	return tokens, nil
 }
 
 func (c *Client) CreatePaper(userID, paperID int) error {
@@ -112,6 +123,35 @@ func (c *Client) CreatePaper(userID, paperID int) error {
 	return nil
 }
 
+func (c *Client) CreateTeamPaper(userID, teamID, paperID int) error {
+	body := bytes.Buffer{}
+	_ = json.NewEncoder(&body).Encode(map[string]int{"userID": userID, "paperID": paperID}) // Cannot fail
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/auth/v2/teams/%d/papers", c.baseURL, teamID), &body)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return err
+		}
+
+		return errors.New(fmt.Sprintf("error in call: %s", callErr.Message), errors.WithCode(res.StatusCode))
+	}
+
+	return nil
+}
+
 func (c *Client) Upsert(user User) (User, error) {
 	body := bytes.Buffer{}
 	err := json.NewEncoder(&body).Encode(user)
@@ -155,3 +195,209 @@ func (c *Client) Upsert(user User) (User, error) {
 
 	return retrievedUser, nil
 }
+
+// LinkGoogle links a Google account to a user.
+func (c *Client) LinkGoogle(userID int, googleID string) (User, error) {
+	body := bytes.Buffer{}
+	_ = json.NewEncoder(&body).Encode(map[string]string{"googleId": googleID}) // Cannot fail
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/auth/v2/users/%d/google", c.baseURL, userID), &body)
+	if err != nil {
+		return User{}, err
+	}
+
+	return c.doUser(req)
+}
+
+// UnlinkGoogle removes the Google account linked to a user.
+func (c *Client) UnlinkGoogle(userID int) (User, error) {
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/auth/v2/users/%d/google", c.baseURL, userID), nil)
+	if err != nil {
+		return User{}, err
+	}
+
+	return c.doUser(req)
+}
+
+// doUser sends a request returning a user. The errors of the auth service are
+// returned with their message and status code.
+func (c *Client) doUser(req *http.Request) (User, error) {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return User{}, err
+		}
+
+		return User{}, errors.New(callErr.Message, errors.WithCode(res.StatusCode))
+	}
+
+	var user User
+	err = json.NewDecoder(res.Body).Decode(&user)
+	if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// CheckAPIToken returns an error if the API token of a user has been revoked
+// or has expired.
+func (c *Client) CheckAPIToken(userID, tokenID int) error {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/tokens/%d", c.baseURL, userID, tokenID), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return err
+		}
+
+		return errors.New(fmt.Sprintf("error in call: %s", callErr.Message), errors.WithCode(res.StatusCode))
+	}
+
+	return nil
+}
+
+// ReadingList is a reading list, as seen by a user.
+type ReadingList struct {
+	ID      int    `json:"id"`
+	OwnerID int    `json:"ownerID"`
+	Name    string `json:"name"`
+	Papers  []int  `json:"papers"`
+	Teams   []int  `json:"teams"`
+}
+
+// ReadingList returns a reading list the user owns or that is shared with one
+// of their teams. Only the papers the user can see are returned.
+func (c *Client) ReadingList(userID, listID int) (ReadingList, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/lists/%d", c.baseURL, userID, listID), nil)
+	if err != nil {
+		return ReadingList{}, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return ReadingList{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return ReadingList{}, err
+		}
+
+		return ReadingList{}, errors.New(callErr.Message, errors.WithCode(res.StatusCode))
+	}
+
+	var list ReadingList
+	err = json.NewDecoder(res.Body).Decode(&list)
+	if err != nil {
+		return ReadingList{}, err
+	}
+
+	return list, nil
+}
+
+// TeamMember is a member of a team, with their role in the team.
+type TeamMember struct {
+	ID   int    `json:"id"`
+	Role string `json:"role"`
+}
+
+// Team is a team, as seen by its members.
+type Team struct {
+	ID      int          `json:"id"`
+	Name    string       `json:"name"`
+	Members []TeamMember `json:"members"`
+}
+
+// Team returns a team the user is a member of.
+func (c *Client) Team(userID, teamID int) (Team, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/teams/%d", c.baseURL, userID, teamID), nil)
+	if err != nil {
+		return Team{}, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return Team{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return Team{}, err
+		}
+
+		return Team{}, errors.New(callErr.Message, errors.WithCode(res.StatusCode))
+	}
+
+	var team Team
+	err = json.NewDecoder(res.Body).Decode(&team)
+	if err != nil {
+		return Team{}, err
+	}
+
+	return team, nil
+}
+
+// Teams returns the teams a user is a member of.
+func (c *Client) Teams(userID int) ([]Team, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/auth/v2/users/%d/teams", c.baseURL, userID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var callErr struct {
+			Message string `json:"error"`
+		}
+		err := json.NewDecoder(res.Body).Decode(&callErr)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, errors.New(callErr.Message, errors.WithCode(res.StatusCode))
+	}
+
+	var teams []Team
+	err = json.NewDecoder(res.Body).Decode(&teams)
+	if err != nil {
+		return nil, err
+	}
+
+	return teams, nil
+}
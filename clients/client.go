@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/bobinette/papernet/errors"
 )
@@ -22,10 +23,15 @@ type Client struct {
 	user     string
 	password string
 
-	mu    sync.Locker
-	token string
+	mu        sync.Locker
+	token     string
+	expiresAt time.Time
 }
 
+// expiryMargin is the time before the expiration of the token after which the
+// client authenticates again, so that the token does not expire in flight.
+const expiryMargin = 30 * time.Second
+
 func NewClient(user, password string, c HTTPClient, baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
@@ -49,17 +55,16 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	}
 
-	// @TODO: handle expired token
-
 	return c.client.Do(req)
 }
 
 func (c *Client) getToken() (string, error) {
 	c.mu.Lock()
 	token := c.token
+	expired := !time.Now().Before(c.expiresAt)
 	c.mu.Unlock()
 
-	if token == "" {
+	if token == "" || expired {
 		err := c.authenticate()
 		if err != nil {
 			return "", err
@@ -112,6 +117,7 @@ func (c *Client) authenticate() error {
 
 	var resBody struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
 	}
 	err = json.NewDecoder(res.Body).Decode(&resBody)
 	if err != nil {
@@ -120,6 +126,7 @@ func (c *Client) authenticate() error {
 
 	c.mu.Lock()
 	c.token = resBody.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(resBody.ExpiresIn)*time.Second - expiryMargin)
 	c.mu.Unlock()
 	return nil
 }
@@ -43,12 +43,14 @@ func init() {
 	AuthUserCommand.AddCommand(&AuthAllUsersCommand)
 	AuthUserCommand.AddCommand(&AuthUpsertUserCommand)
 	AuthUserCommand.AddCommand(&AuthDeleteCommand)
+	AuthUserCommand.AddCommand(&AuthTransferCommand)
 
 	inheritPersistentPreRun(&AuthCommand)
 	inheritPersistentPreRun(&AuthUserCommand)
 	inheritPersistentPreRun(&AuthTokenCommand)
 	inheritPersistentPreRun(&AuthUpsertUserCommand)
 	inheritPersistentPreRun(&AuthDeleteCommand)
+	inheritPersistentPreRun(&AuthTransferCommand)
 
 	inheritPersistentPreRun(&AuthAllUsersCommand)
 
@@ -74,20 +76,12 @@ var AuthCommand = cobra.Command{
 			logger.Fatal("error unmarshalling configuration:", err)
 		}
 
-		// Read key file
-		keyData, err := ioutil.ReadFile(authConfig.Auth.KeyPath)
-		if err != nil {
-			logger.Fatal("could not open key file:", err)
-		}
 		// Create token encoder
-		var key struct {
-			Key string `json:"k"`
-		}
-		err = json.Unmarshal(keyData, &key)
+		keys, err := jwt.LoadKeySet(authConfig.Auth.KeyPath)
 		if err != nil {
 			logger.Fatal("could not read key file:", err)
 		}
-		tokenEncoder := jwt.NewEncodeDecoder([]byte(key.Key))
+		tokenEncoder := jwt.NewKeySetEncodeDecoder(keys)
 
 		// Create user repository
 		store, err := cayley.NewStore(authConfig.Auth.Cayley.Store)
@@ -96,10 +90,12 @@ var AuthCommand = cobra.Command{
 		}
 		userRepository = cayley.NewUserRepository(store)
 		teamRepository := cayley.NewTeamRepository(store)
+		invitationRepository := cayley.NewInvitationRepository(store)
+		sessionService := services.NewSessionService(userRepository, cayley.NewUserTokenRepository(store), tokenEncoder)
 
 		// Create user service
-		userService = services.NewUserService(userRepository, tokenEncoder)
-		teamService = services.NewTeamService(teamRepository, userRepository)
+		userService = services.NewUserService(userRepository, teamRepository, invitationRepository, sessionService)
+		teamService = services.NewTeamService(teamRepository, userRepository, invitationRepository, tokenEncoder)
 	},
 }
 
@@ -154,12 +150,12 @@ var AuthTokenCommand = cobra.Command{
 			logger.Fatal(err)
 		}
 
-		token, err := userService.Token(userID)
+		tokens, err := userService.Token(userID, false)
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		logger.Print(token)
+		logger.Print(tokens.AccessToken)
 	},
 }
 
@@ -187,6 +183,39 @@ var AuthDeleteCommand = cobra.Command{
 	},
 }
 
+var AuthTransferCommand = cobra.Command{
+	Use:   "transfer",
+	Short: "Transfer all the papers of a user to another user",
+	Long:  "Transfer the ownership of all the papers of a user to another user, without asking for their consent",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 && args[0] == "help" {
+			cmd.Help()
+			return
+		}
+
+		if len(args) != 2 {
+			logger.Fatal("user transfer wants 2 arguments: the id of the current owner and the id of the new owner")
+		}
+
+		fromID, err := strconv.Atoi(args[0])
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		toID, err := strconv.Atoi(args[1])
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		transferred, err := userService.TransferAllPapers(fromID, toID)
+		if err != nil {
+			logger.Fatalf("error transferring papers (transferred so far: %v): %v", transferred, err)
+		}
+
+		cmd.Printf("%d papers transferred from user %d to user %d: %v\n", len(transferred), fromID, toID, transferred)
+	},
+}
+
 var AuthAllUsersCommand = cobra.Command{
 	Use:   "all",
 	Short: "Retrieve all the users",
@@ -12,6 +12,7 @@ import (
 
 	ppnBolt "github.com/bobinette/papernet/bolt"
 	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/pdf"
 
 	"github.com/bobinette/papernet/auth/cayley"
 	authServices "github.com/bobinette/papernet/auth/services"
@@ -21,6 +22,7 @@ import (
 	"github.com/bobinette/papernet/papernet"
 	"github.com/bobinette/papernet/papernet/bleve"
 	"github.com/bobinette/papernet/papernet/bolt"
+	"github.com/bobinette/papernet/papernet/fs"
 	"github.com/bobinette/papernet/papernet/services"
 )
 
@@ -32,6 +34,9 @@ type PaperConfig struct {
 		Bleve struct {
 			Store string `toml:"store"`
 		} `toml:"bleve"`
+		Attachments struct {
+			Dir string `toml:"dir"`
+		} `toml:"attachments"`
 	} `toml:"paper"`
 	// Legacy
 	Bolt struct {
@@ -47,8 +52,9 @@ var (
 	paperRepository papernet.PaperRepository
 	paperIndex      papernet.PaperIndex
 
-	tagService   *services.TagService
-	paperService *services.PaperService
+	tagService        *services.TagService
+	paperService      *services.PaperService
+	attachmentService *services.AttachmentService
 )
 
 func init() {
@@ -60,6 +66,7 @@ func init() {
 	PaperCommand.AddCommand(&PaperFixSequenceCommand)
 	PaperCommand.AddCommand(&PaperIndexCommand)
 	PaperIndexCommand.AddCommand(&PaperIndexAllCommand)
+	PaperCommand.AddCommand(&PaperExtractTextCommand)
 
 	inheritPersistentPreRun(&SavePaperCommand)
 	inheritPersistentPreRun(&DeletePaperCommand)
@@ -68,6 +75,7 @@ func init() {
 	inheritPersistentPreRun(&PaperFixSequenceCommand)
 	inheritPersistentPreRun(&PaperIndexCommand)
 	inheritPersistentPreRun(&PaperIndexAllCommand)
+	inheritPersistentPreRun(&PaperExtractTextCommand)
 	inheritPersistentPreRun(&PaperCommand)
 
 	RootCmd.AddCommand(&PaperCommand)
@@ -93,20 +101,12 @@ var PaperCommand = cobra.Command{
 			logger.Fatal("error unmarshalling configuration:", err)
 		}
 
-		// Read key file
-		keyData, err := ioutil.ReadFile(authConfig.Auth.KeyPath)
-		if err != nil {
-			logger.Fatal("could not open key file:", err)
-		}
 		// Create token encoder
-		var key struct {
-			Key string `json:"k"`
-		}
-		err = json.Unmarshal(keyData, &key)
+		keys, err := jwt.LoadKeySet(authConfig.Auth.KeyPath)
 		if err != nil {
 			logger.Fatal("could not read key file:", err)
 		}
-		tokenEncoder := jwt.NewEncodeDecoder([]byte(key.Key))
+		tokenEncoder := jwt.NewKeySetEncodeDecoder(keys)
 
 		// Create user repository
 		store, err := cayley.NewStore(authConfig.Auth.Cayley.Store)
@@ -114,7 +114,10 @@ var PaperCommand = cobra.Command{
 			logger.Fatal("could not open user graph:", err)
 		}
 		userRepository := cayley.NewUserRepository(store)
-		userService = authServices.NewUserService(userRepository, tokenEncoder)
+		teamRepository := cayley.NewTeamRepository(store)
+		invitationRepository := cayley.NewInvitationRepository(store)
+		sessionService := authServices.NewSessionService(userRepository, cayley.NewUserTokenRepository(store), tokenEncoder)
+		userService = authServices.NewUserService(userRepository, teamRepository, invitationRepository, sessionService)
 
 		// Load paper service
 		err = toml.Unmarshal(data, &paperConfig)
@@ -130,9 +133,17 @@ var PaperCommand = cobra.Command{
 		paperRepo := bolt.PaperRepository{Driver: boltDriver}
 		paperRepository = &paperRepo
 		tagIndex := bolt.TagIndex{Driver: boltDriver}
+		attachmentRepository := bolt.AttachmentRepository{Driver: boltDriver}
+		textRepository := bolt.AttachmentTextRepository{Driver: boltDriver}
+		annotationRepository := bolt.AnnotationRepository{Driver: boltDriver}
+		noteRepository := bolt.NoteRepository{Driver: boltDriver}
 
 		// Create paper index
-		index := &bleve.PaperIndex{}
+		index := &bleve.PaperIndex{
+			Texts:       &textRepository,
+			Annotations: &annotationRepository,
+			Notes:       &noteRepository,
+		}
 		if err := index.Open(paperConfig.Paper.Bleve.Store); err != nil {
 			logger.Fatal("could not open paper index:", err)
 		}
@@ -142,7 +153,17 @@ var PaperCommand = cobra.Command{
 
 		// Create services
 		tagService = services.NewTagService(&tagIndex)
-		paperService = services.NewPaperService(paperRepository, paperIndex, authClient, tagService)
+
+		// Only the attachments stored locally can be read from the cli
+		localStorage := &fs.AttachmentStorage{Dir: paperConfig.Paper.Attachments.Dir}
+		attachmentService = services.NewAttachmentService(&attachmentRepository, &textRepository, pdf.TextExtractor{}, paperRepository, paperIndex, localStorage)
+		annotationService := services.NewAnnotationService(&annotationRepository, paperRepository, paperIndex, authClient)
+		noteService := services.NewNoteService(&noteRepository, paperRepository, paperIndex, authClient)
+
+		// The attachments, annotations and notes are deleted with their paper,
+		// as from the http api. The papers with attachments in a drive cannot
+		// be deleted from the cli.
+		paperService = services.NewPaperService(paperRepository, paperIndex, authClient, tagService, attachmentService, annotationService, noteService)
 	},
 }
 
@@ -359,6 +380,46 @@ var PaperIndexAllCommand = cobra.Command{
 	},
 }
 
+var PaperExtractTextCommand = cobra.Command{
+	Use:   "extract-text",
+	Short: "Extract the text of the attachments",
+	Long:  "Extract again the text of the attachments of papers, all of them if no id is given, and reindex the papers",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 && args[0] == "help" {
+			cmd.Help()
+			return
+		}
+
+		ids, err := ints(args)
+		if err != nil {
+			logger.Fatal("error reading ids:", err)
+		}
+
+		if len(ids) == 0 {
+			papers, err := paperRepository.List()
+			if err != nil {
+				logger.Fatal("error retrieving papers:", err)
+			}
+
+			for _, paper := range papers {
+				ids = append(ids, paper.ID)
+			}
+		}
+
+		for _, id := range ids {
+			n, err := attachmentService.ExtractText(id)
+			if err != nil {
+				logger.Errorf("error extracting text of paper %d: %v", id, err)
+				continue
+			}
+
+			if n > 0 {
+				logger.Printf("extracted text of %d attachments of paper %d", n, id)
+			}
+		}
+	},
+}
+
 func ints(strs []string) ([]int, error) {
 	ints := make([]int, len(strs))
 
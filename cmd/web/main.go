@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -14,6 +13,7 @@ import (
 	"github.com/bobinette/papernet/bleve"
 	"github.com/bobinette/papernet/bolt"
 	"github.com/bobinette/papernet/gin"
+	"github.com/bobinette/papernet/google"
 	"github.com/bobinette/papernet/log"
 	"github.com/bobinette/papernet/web"
 
@@ -30,6 +30,7 @@ import (
 	kitgoogle "github.com/bobinette/papernet/google/cmd"
 	kitimports "github.com/bobinette/papernet/imports/cmd"
 	kitoauth "github.com/bobinette/papernet/oauth/cmd"
+	kitoidc "github.com/bobinette/papernet/oidc/cmd"
 	kitpaper "github.com/bobinette/papernet/papernet/cmd"
 )
 
@@ -47,6 +48,7 @@ type Configuration struct {
 	Imports kitimports.Configuration `toml:"imports"`
 	Paper   kitpaper.Configuration   `toml:"paper"`
 	Google  kitgoogle.Configuration  `toml:"google"`
+	OIDC    kitoidc.Configuration    `toml:"oidc"`
 	Cron    kitcron.Configuration    `toml:"cron"`
 
 	Bolt struct {
@@ -98,17 +100,12 @@ func main() {
 	importer.Register("medium.com", &papernet.MediumImporter{})
 
 	// Auth
-	keyData, err := ioutil.ReadFile(cfg.Auth.KeyPath)
-	if err != nil {
-		logger.Fatal("could not open key file:", err)
-	}
-	var key papernet.SigningKey
-	err = json.Unmarshal(keyData, &key)
+	keys, err := jwt.LoadKeySet(cfg.Auth.KeyPath)
 	if err != nil {
 		logger.Fatal("could not read key file:", err)
 	}
 
-	decoder := jwt.NewEncodeDecoder([]byte(key.Key))
+	decoder := jwt.NewKeySetEncodeDecoder(keys)
 	authenticator := auth.Authenticator{
 		Decoder: decoder,
 	}
@@ -135,21 +132,28 @@ func main() {
 	pc := paperClient.NewClient(client, cfg.Clients.Auth.BaseURL)
 	ic := importsClient.NewClient(client, cfg.Clients.Auth.BaseURL)
 
-	// Auth service
-	userService := kitauth.Start(server, cfg.Auth, logger)
+	// Auth service, sending its emails with the settings of the cron service
+	mailTransport, err := kitcron.NewMailTransport(cfg.Cron.Mail)
+	if err != nil {
+		logger.Fatal("could not create mail transport:", err)
+	}
+	userService := kitauth.Start(server, cfg.Auth, logger, mailTransport, cfg.Cron.Mail.Email)
+
+	// OpenID Connect providers
+	oidcProviders := kitoidc.Start(server, cfg.OIDC, logger, ac)
 
 	// OAuth service
-	kitoauth.Start(server, cfg.Oauth, logger)
+	kitoauth.Start(server, cfg.Oauth, logger, oidcProviders...)
+
+	// Google service, creating papers from the files added to the drives
+	googleService := kitgoogle.Start(server, cfg.Google, logger, ac, pc)
 
-	// Paper service
-	kitpaper.Start(server, cfg.Paper, logger, ac)
+	// Paper service, storing the attachments locally or in the drive
+	kitpaper.Start(server, cfg.Paper, logger, ac, ic, google.NewDriveStorage(googleService))
 
 	// Imports service
 	kitimports.Start(server, cfg.Imports, logger, pc, ac)
 
-	// Google service
-	kitgoogle.Start(server, cfg.Google, logger, ac)
-
 	// Cron service
 	kitcron.Start(server, cfg.Cron, logger, ac, ic)
 
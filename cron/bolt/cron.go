@@ -0,0 +1,105 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/cron"
+	"github.com/bobinette/papernet/errors"
+)
+
+var cronsBucket = []byte("crons")
+
+type Repository struct {
+	driver *Driver
+}
+
+func NewRepository(driver *Driver) *Repository {
+	return &Repository{
+		driver: driver,
+	}
+}
+
+func (r *Repository) List(ctx context.Context) ([]cron.Cron, error) {
+	return r.filter(func(cron.Cron) bool { return true })
+}
+
+func (r *Repository) GetForUser(ctx context.Context, userID int) ([]cron.Cron, error) {
+	return r.filter(func(c cron.Cron) bool { return c.UserID == userID })
+}
+
+func (r *Repository) filter(keep func(cron.Cron) bool) ([]cron.Cron, error) {
+	crons := make([]cron.Cron, 0)
+	err := r.driver.store.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(cronsBucket).ForEach(func(k, v []byte) error {
+			var c cron.Cron
+			if err := json.Unmarshal(v, &c); err != nil {
+				return err
+			}
+
+			if keep(c) {
+				crons = append(crons, c)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return crons, nil
+}
+
+func (r *Repository) Insert(ctx context.Context, c *cron.Cron) error {
+	if c.ID != 0 {
+		return errors.New("cannot update cron", errors.BadRequest())
+	}
+
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(cronsBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		inserted := *c
+		inserted.ID = uint(id)
+
+		data, err := json.Marshal(inserted)
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put(itob(inserted.ID), data); err != nil {
+			return err
+		}
+
+		c.ID = inserted.ID
+		return nil
+	})
+}
+
+// Delete removes the cron and all its results.
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(cronsBucket).Delete(itob(id)); err != nil {
+			return err
+		}
+
+		results := tx.Bucket(resultsBucket)
+		if results.Bucket(itob(id)) == nil {
+			return nil
+		}
+		return results.DeleteBucket(itob(id))
+	})
+}
+
+func itob(v uint) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
@@ -0,0 +1,40 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/cron/testutil"
+)
+
+func setUp(t *testing.T) (*Driver, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err, "tmp file")
+
+	filename := tmpFile.Name()
+	driver := &Driver{}
+	err = driver.Open(filename)
+	require.NoError(t, err, "open driver")
+
+	return driver, func() {
+		driver.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	driver, tearDown := setUp(t)
+	defer tearDown()
+
+	testutil.TestRepository(t, NewRepository(driver))
+}
+
+func TestResultsRepository(t *testing.T) {
+	driver, tearDown := setUp(t)
+	defer tearDown()
+
+	testutil.TestResultRepository(t, NewResultsRepository(driver), NewRepository(driver))
+}
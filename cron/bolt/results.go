@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/cron"
+)
+
+// The results are stored in a sub bucket per cron, keyed by source and
+// reference.
+var resultsBucket = []byte("results")
+
+type ResultsRepository struct {
+	driver *Driver
+}
+
+func NewResultsRepository(driver *Driver) *ResultsRepository {
+	return &ResultsRepository{
+		driver: driver,
+	}
+}
+
+func (r *ResultsRepository) Insert(ctx context.Context, cronID uint, paper cron.Paper) error {
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(resultsBucket).CreateBucketIfNotExists(itob(cronID))
+		if err != nil {
+			return err
+		}
+
+		key := resultKey(paper.Source, paper.Reference)
+		if bucket.Get(key) != nil {
+			return nil
+		}
+
+		data, err := json.Marshal(paper)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, data)
+	})
+}
+
+func (r *ResultsRepository) Seen(ctx context.Context, cronID uint, source string, references []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	err := r.driver.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(resultsBucket).Bucket(itob(cronID))
+		if bucket == nil {
+			return nil
+		}
+
+		for _, ref := range references {
+			if bucket.Get(resultKey(source, ref)) != nil {
+				seen[ref] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return seen, nil
+}
+
+func resultKey(source, reference string) []byte {
+	return []byte(source + "\x00" + reference)
+}
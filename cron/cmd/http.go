@@ -2,20 +2,27 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
 
 	"github.com/bobinette/papernet/clients/auth"
 	"github.com/bobinette/papernet/clients/imports"
+	"github.com/bobinette/papernet/jwt"
 	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/mail"
 
 	"github.com/bobinette/papernet/cron"
-	"github.com/bobinette/papernet/cron/mail"
+	"github.com/bobinette/papernet/cron/bolt"
+	"github.com/bobinette/papernet/cron/flock"
+	cronmail "github.com/bobinette/papernet/cron/mail"
 	"github.com/bobinette/papernet/cron/mysql"
+	"github.com/bobinette/papernet/cron/sqlite"
 )
 
 type Configuration struct {
 	KeyPath string `toml:"key"`
+
+	// Backend is one of "mysql" (default), "sqlite" or "bolt"
+	Backend string `toml:"backend"`
 	MySQL   struct {
 		Host     string `toml:"host"`
 		Port     string `toml:"port"`
@@ -23,12 +30,48 @@ type Configuration struct {
 		Password string `toml:"password"`
 		Database string `toml:"database"`
 	} `toml:"mysql"`
-	Mail struct {
-		Email    string `toml:"email"`
-		Password string `toml:"password"`
-		Server   string `toml:"server"`
-		Port     int    `toml:"port"`
+	SQLite struct {
+		Store string `toml:"store"`
+	} `toml:"sqlite"`
+	Bolt struct {
+		Store string `toml:"store"`
+	} `toml:"bolt"`
+
+	Lock struct {
+		// Backend is one of "none" (default), "mysql" or "file"
+		Backend string `toml:"backend"`
+		Dir     string `toml:"dir"`
+	} `toml:"lock"`
+	Mail MailConfiguration `toml:"mail"`
+}
+
+type MailConfiguration struct {
+	// Transport is one of "smtp" (default), "maildir" or "inmem"
+	Transport string `toml:"transport"`
+	Digest    bool   `toml:"digest"`
+
+	// SMTP transport
+	Email    string `toml:"email"`
+	Password string `toml:"password"`
+	Server   string `toml:"server"`
+	Port     int    `toml:"port"`
+	TLS      string `toml:"tls"`
+
+	// Maildir transport
+	Maildir string `toml:"maildir"`
+}
+
+// NewMailTransport creates the mail transport described by the configuration.
+func NewMailTransport(conf MailConfiguration) (mail.Transport, error) {
+	switch conf.Transport {
+	case "", "smtp":
+		return mail.NewSMTPTransport(conf.Server, conf.Port, conf.Email, conf.Password, mail.TLSMode(conf.TLS))
+	case "maildir":
+		return mail.NewMaildirTransport(conf.Maildir)
+	case "inmem":
+		return mail.NewInMemTransport(), nil
 	}
+	return nil, fmt.Errorf("unknown mail transport %q", conf.Transport)
 }
 
 func Start(
@@ -38,39 +81,90 @@ func Start(
 	authClient *auth.Client,
 	imporstClient *imports.Client,
 ) {
-	// Load key from file
-	keyData, err := ioutil.ReadFile(conf.KeyPath)
+	// Load keys from file. The services verifying the tokens only need the public keys.
+	keys, err := jwt.LoadKeySet(conf.KeyPath)
 	if err != nil {
-		logger.Fatal("could not open key file:", err)
+		logger.Fatal("could not load key file:", err)
 	}
 
-	// Extract key from data
-	var key struct {
-		Key string `json:"k"`
+	// The MySQL driver is shared by the repositories and the locker
+	var mysqlDriver *mysql.Driver
+	getMySQLDriver := func() *mysql.Driver {
+		if mysqlDriver != nil {
+			return mysqlDriver
+		}
+
+		driver, err := mysql.NewDriver(
+			conf.MySQL.Host,
+			conf.MySQL.Port,
+			conf.MySQL.User,
+			conf.MySQL.Password,
+			conf.MySQL.Database,
+		)
+		if err != nil {
+			logger.Fatal("error connecting to MySQL:", err)
+		}
+
+		mysqlDriver = driver
+		return driver
 	}
-	err = json.Unmarshal(keyData, &key)
-	if err != nil {
-		logger.Fatal("could not read key file:", err)
+
+	var repo cron.Repository
+	var resultsRepo cron.ResultRepository
+	switch conf.Backend {
+	case "", "mysql":
+		driver := getMySQLDriver()
+		repo = mysql.NewRepository(driver)
+		resultsRepo = mysql.NewResultsRepository(driver)
+	case "sqlite":
+		driver, err := sqlite.NewDriver(conf.SQLite.Store)
+		if err != nil {
+			logger.Fatal("error opening SQLite database:", err)
+		}
+
+		repo = sqlite.NewRepository(driver)
+		resultsRepo = sqlite.NewResultsRepository(driver)
+	case "bolt":
+		driver := &bolt.Driver{}
+		if err := driver.Open(conf.Bolt.Store); err != nil {
+			logger.Fatal("could not open db:", err)
+		}
+
+		repo = bolt.NewRepository(driver)
+		resultsRepo = bolt.NewResultsRepository(driver)
+	default:
+		logger.Fatalf("unknown cron backend %q", conf.Backend)
+	}
+
+	var locker cron.Locker
+	switch conf.Lock.Backend {
+	case "", "none":
+	case "mysql":
+		locker = mysql.NewLocker(getMySQLDriver())
+	case "file":
+		fileLocker, err := flock.NewLocker(conf.Lock.Dir)
+		if err != nil {
+			logger.Fatal("could not create file locker:", err)
+		}
+		locker = fileLocker
+	default:
+		logger.Fatalf("unknown cron lock backend %q", conf.Lock.Backend)
 	}
 
-	driver, err := mysql.NewDriver(
-		conf.MySQL.Host,
-		conf.MySQL.Port,
-		conf.MySQL.User,
-		conf.MySQL.Password,
-		conf.MySQL.Database,
-	)
+	transport, err := NewMailTransport(conf.Mail)
 	if err != nil {
-		logger.Fatal("error connecting to MySQL:", err)
+		logger.Fatal("could not create mail transport:", err)
 	}
 
-	repo := mysql.NewRepository(driver)
-	resultsRepo := mysql.NewResultsRepository(driver)
+	notifierFactory := cronmail.NewNotifierFactory(authClient, transport, conf.Mail.Email)
 
-	notifierFactory := mail.NewNotifierFactory(authClient, conf.Mail.Email, conf.Mail.Password, conf.Mail.Server, conf.Mail.Port)
+	var digestNotifier cron.DigestNotifier
+	if conf.Mail.Digest {
+		digestNotifier = cronmail.NewDigestNotifier(authClient, transport, conf.Mail.Email)
+	}
 
-	service := cron.NewService(repo, resultsRepo, notifierFactory, imporstClient, logger)
-	service.RegisterHTTP(srv, []byte(key.Key), authClient)
+	service := cron.NewService(repo, resultsRepo, notifierFactory, digestNotifier, locker, imporstClient, logger)
+	service.RegisterHTTP(srv, keys, authClient)
 
 	service.StartCron(context.Background())
 }
@@ -0,0 +1,17 @@
+package flock
+
+import (
+	"os"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+var errNotSupported = errors.New("file locks are not supported on windows")
+
+func tryLock(f *os.File) (bool, error) {
+	return false, errNotSupported
+}
+
+func unlock(f *os.File) error {
+	return errNotSupported
+}
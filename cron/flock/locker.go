@@ -0,0 +1,52 @@
+// Package flock implements cron.Locker with file locks, for instances
+// running on the same host without MySQL.
+package flock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/bobinette/papernet/cron"
+)
+
+// Locker takes locks on files in a directory. The file for a lock is kept
+// after the lock is released.
+type Locker struct {
+	dir string
+}
+
+// NewLocker creates dir if it does not exist yet.
+func NewLocker(dir string) (*Locker, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &Locker{
+		dir: dir,
+	}, nil
+}
+
+func (l *Locker) TryLock(ctx context.Context, name string) (cron.Lock, bool, error) {
+	f, err := os.OpenFile(filepath.Join(l.dir, name+".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ok, err := tryLock(f)
+	if err != nil || !ok {
+		f.Close()
+		return nil, false, err
+	}
+
+	return &lock{f: f}, true, nil
+}
+
+type lock struct {
+	f *os.File
+}
+
+func (l *lock) Unlock() error {
+	defer l.f.Close()
+	return unlock(l.f)
+}
@@ -0,0 +1,44 @@
+package flock
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err, "tmp dir")
+	defer os.RemoveAll(dir)
+
+	// Two lockers on the same directory act as two instances
+	l1, err := NewLocker(dir)
+	require.NoError(t, err, "create locker 1")
+	l2, err := NewLocker(dir)
+	require.NoError(t, err, "create locker 2")
+
+	ctx := context.Background()
+	lock, ok, err := l1.TryLock(ctx, "job")
+	require.NoError(t, err, "lock 1")
+	assert.True(t, ok, "lock 1 should be acquired")
+
+	_, ok, err = l2.TryLock(ctx, "job")
+	require.NoError(t, err, "lock 2 while held")
+	assert.False(t, ok, "lock 2 should not be acquired while held")
+
+	other, ok, err := l2.TryLock(ctx, "other job")
+	require.NoError(t, err, "lock other job")
+	assert.True(t, ok, "locks are per name")
+	require.NoError(t, other.Unlock(), "unlock other job")
+
+	require.NoError(t, lock.Unlock(), "unlock 1")
+
+	lock, ok, err = l2.TryLock(ctx, "job")
+	require.NoError(t, err, "lock 2 after release")
+	assert.True(t, ok, "lock 2 should be acquired after release")
+	require.NoError(t, lock.Unlock(), "unlock 2")
+}
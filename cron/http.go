@@ -56,14 +56,15 @@ func extractUserID(ctx context.Context) (int, error) {
 	return ppnClaims.UserID, nil
 }
 
-func (s *Service) RegisterHTTP(srv HTTPServer, jwtKey []byte, authClient *auth.Client) {
+func (s *Service) RegisterHTTP(srv HTTPServer, keys *jwt.KeySet, authClient *auth.Client) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
 
 	authenticator := users.NewAuthenticator(authClient)
-	authenticationMiddleware := jwt.Middleware(jwtKey)
+	authenticationMiddleware := jwt.Middleware(keys, jwt.ScopeCrons)
+	adminMiddleware := jwt.Middleware(keys)
 
 	cronListHandler := kithttp.NewServer(
 		authenticationMiddleware(authenticator.Valid(makeCronListEndpoint(s))),
@@ -80,7 +81,7 @@ func (s *Service) RegisterHTTP(srv HTTPServer, jwtKey []byte, authClient *auth.C
 	)
 
 	cronRunHandler := kithttp.NewServer(
-		authenticationMiddleware(authenticator.Admin(makeCronRunEndpoint(s))),
+		adminMiddleware(authenticator.Admin(makeCronRunEndpoint(s))),
 		decodeCronRunRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -5,34 +5,33 @@ import (
 	"context"
 	"fmt"
 	"html/template"
-	"net/smtp"
 	"strings"
+	texttemplate "text/template"
 
 	"github.com/bobinette/papernet/clients/auth"
 	"github.com/bobinette/papernet/cron"
 	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/mail"
 )
 
+const searchLink = "https://papernet.bobi.space/search"
+
 type MailNotifier struct {
 	authClient *auth.Client
 	cron       cron.Cron
 
-	email    string
-	password string
-	server   string
-	port     int
+	transport mail.Transport
+	from      string
 }
 
-func NewNotifierFactory(authClient *auth.Client, email, password, server string, port int) cron.NotifierFactory {
+func NewNotifierFactory(authClient *auth.Client, transport mail.Transport, from string) cron.NotifierFactory {
 	return func(cron cron.Cron) (cron.Notifier, error) {
 		return &MailNotifier{
 			authClient: authClient,
 			cron:       cron,
 
-			email:    email,
-			password: password,
-			server:   server,
-			port:     port,
+			transport: transport,
+			from:      from,
 		}, nil
 	}
 }
@@ -45,14 +44,6 @@ func (n *MailNotifier) Notify(ctx context.Context, papers []cron.Paper) error {
 		return errors.New(fmt.Sprintf("no email for user %d", n.cron.UserID))
 	}
 
-	// Set up authentication information.
-	auth := smtp.PlainAuth(
-		n.email,
-		n.email,
-		n.password,
-		n.server,
-	)
-
 	body := struct {
 		Papers  []cron.Paper
 		Link    string
@@ -60,72 +51,128 @@ func (n *MailNotifier) Notify(ctx context.Context, papers []cron.Paper) error {
 		Sources string
 	}{
 		Papers:  papers,
-		Link:    "https://papernet.bobi.space/search",
+		Link:    searchLink,
 		Q:       n.cron.Q,
 		Sources: strings.Join(n.cron.Sources, ","),
 	}
-	return NewRequest([]string{user.Email}, n.email, "Your search got new results", n.server, n.port).
-		ParseTemplate("cron/mail/template.html", body).
-		SendEmail(auth)
-}
 
-// Taken from https://medium.com/@dhanushgopinath/sending-html-emails-using-templates-in-golang-9e953ca32f3d
+	msg, err := newMessage(n.from, user.Email, "Your search got new results!", mailTemplate, mailTextTemplate, body)
+	if err != nil {
+		return err
+	}
+	return n.transport.Send(ctx, msg)
+}
 
-type Request struct {
-	from    string
-	to      []string
-	subject string
+// DigestNotifier sends a single email per user, grouping the new papers of
+// all their crons.
+type DigestNotifier struct {
+	authClient *auth.Client
 
-	body string
+	transport mail.Transport
+	from      string
+}
 
-	server string
-	port   int
+func NewDigestNotifier(authClient *auth.Client, transport mail.Transport, from string) *DigestNotifier {
+	return &DigestNotifier{
+		authClient: authClient,
 
-	err error
+		transport: transport,
+		from:      from,
+	}
 }
 
-func NewRequest(to []string, from, subject, server string, port int) *Request {
-	return &Request{
-		from:    from,
-		to:      to,
-		subject: subject,
+type digestPaper struct {
+	cron.Paper
+	AlsoMatched []string
+}
 
-		server: server,
-		port:   port,
-	}
+type digestGroup struct {
+	Q       string
+	Sources string
+	Papers  []digestPaper
 }
 
-func (r *Request) SendEmail(auth smtp.Auth) error {
-	if r.err != nil {
-		return r.err
+func (n *DigestNotifier) NotifyDigest(ctx context.Context, digest cron.Digest) error {
+	user, err := n.authClient.User(digest.UserID)
+	if err != nil {
+		return err
+	} else if user.Email == "" {
+		return errors.New(fmt.Sprintf("no email for user %d", digest.UserID))
 	}
 
-	from := fmt.Sprintf("From: \"Papernet\" <%s>\n", r.from)
-	to := fmt.Sprintf("To: %s\n", strings.Join(r.to, " "))
-	subject := "Subject: " + r.subject + "!\n"
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	msg := []byte(from + to + subject + mime + "\n" + r.body)
-	addr := fmt.Sprintf("%s:%d", r.server, r.port)
+	body := struct {
+		Groups []digestGroup
+		Count  int
+		Link   string
+	}{
+		Groups: groupDigest(digest),
+		Count:  len(digest.Items),
+		Link:   searchLink,
+	}
 
-	if err := smtp.SendMail(addr, auth, r.from, r.to, msg); err != nil {
+	msg, err := newMessage(n.from, user.Email, "Your searches got new results!", digestTemplate, digestTextTemplate, body)
+	if err != nil {
 		return err
 	}
-	return nil
+	return n.transport.Send(ctx, msg)
+}
+
+// groupDigest groups the papers of a digest under the first cron that
+// matched them. The queries of the other crons are kept in AlsoMatched.
+func groupDigest(digest cron.Digest) []digestGroup {
+	groups := make([]digestGroup, 0)
+	indexes := make(map[uint]int)
+	for _, item := range digest.Items {
+		first := item.Crons[0]
+
+		i, ok := indexes[first.ID]
+		if !ok {
+			i = len(groups)
+			indexes[first.ID] = i
+			groups = append(groups, digestGroup{
+				Q:       first.Q,
+				Sources: strings.Join(first.Sources, ","),
+			})
+		}
+
+		alsoMatched := make([]string, 0, len(item.Crons)-1)
+		for _, c := range item.Crons[1:] {
+			alsoMatched = append(alsoMatched, c.Q)
+		}
+
+		groups[i].Papers = append(groups[i].Papers, digestPaper{Paper: item.Paper, AlsoMatched: alsoMatched})
+	}
+	return groups
 }
 
-func (r *Request) ParseTemplate(templateFileName string, data interface{}) *Request {
-	t, err := template.New("mail").Parse(mailTemplate)
+// newMessage renders the HTML and the plain text bodies of an email.
+func newMessage(from, to, subject, htmlTemplate, textTemplate string, data interface{}) (mail.Message, error) {
+	t, err := template.New("html").Parse(htmlTemplate)
 	if err != nil {
-		r.err = err
-		return r
+		return mail.Message{}, err
+	}
+
+	html := new(bytes.Buffer)
+	if err := t.Execute(html, data); err != nil {
+		return mail.Message{}, err
 	}
 
-	buf := new(bytes.Buffer)
-	if err = t.Execute(buf, data); err != nil {
-		r.err = err
-		return r
+	tt, err := texttemplate.New("text").Parse(textTemplate)
+	if err != nil {
+		return mail.Message{}, err
 	}
 
-	r.body = buf.String()
-	return r
+	text := new(bytes.Buffer)
+	if err := tt.Execute(text, data); err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		From:    from,
+		To:      []string{to},
+		Subject: subject,
+
+		HTML: html.String(),
+		Text: text.String(),
+	}, nil
 }
@@ -0,0 +1,126 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	netmail "net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/cron"
+	"github.com/bobinette/papernet/mail"
+)
+
+// mockAuthClient returns a client for a fake auth service. The server has to
+// be closed by the caller.
+func mockAuthClient() (*auth.Client, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(auth.User{ID: 1, Email: "user@papernet.io"})
+	}))
+	return auth.NewClient(&http.Client{}, srv.URL), srv
+}
+
+// parts parses a message and returns its parts indexed by content type.
+func parts(t *testing.T, msg mail.Message) map[string]string {
+	data, err := msg.Bytes()
+	require.NoError(t, err)
+
+	m, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	res := make(map[string]string)
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+
+		content, err := ioutil.ReadAll(p)
+		require.NoError(t, err)
+
+		contentType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		res[contentType] = string(content)
+	}
+	return res
+}
+
+func TestMailNotifier_Notify(t *testing.T) {
+	transport := mail.NewInMemTransport()
+	authClient, srv := mockAuthClient()
+	defer srv.Close()
+
+	factory := NewNotifierFactory(authClient, transport, "papernet@papernet.io")
+
+	notifier, err := factory(cron.Cron{ID: 1, UserID: 1, Q: "nlp", Sources: []string{"arxiv"}})
+	require.NoError(t, err)
+
+	err = notifier.Notify(context.Background(), []cron.Paper{
+		{Source: "arxiv", Reference: "1", Title: "Paper 1", References: []string{"https://arxiv.org/abs/1"}},
+	})
+	require.NoError(t, err)
+
+	messages := transport.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"user@papernet.io"}, messages[0].To)
+
+	bodies := parts(t, messages[0])
+	assert.Contains(t, bodies["text/plain"], "Paper 1")
+	assert.Contains(t, bodies["text/html"], `<a href="https://arxiv.org/abs/1">`)
+}
+
+func TestDigestNotifier_NotifyDigest(t *testing.T) {
+	transport := mail.NewInMemTransport()
+	authClient, srv := mockAuthClient()
+	defer srv.Close()
+
+	notifier := NewDigestNotifier(authClient, transport, "papernet@papernet.io")
+
+	nlp := cron.Cron{ID: 1, UserID: 1, Q: "nlp", Sources: []string{"arxiv"}}
+	gan := cron.Cron{ID: 2, UserID: 1, Q: "gan", Sources: []string{"arxiv"}}
+	digest := cron.Digest{
+		UserID: 1,
+		Items: []cron.DigestItem{
+			{
+				Paper: cron.Paper{Source: "arxiv", Reference: "1", Title: "Paper 1", References: []string{"https://arxiv.org/abs/1"}},
+				Crons: []cron.Cron{nlp, gan},
+			},
+			{
+				Paper: cron.Paper{Source: "arxiv", Reference: "2", Title: "Paper 2", References: []string{"https://arxiv.org/abs/2"}},
+				Crons: []cron.Cron{gan},
+			},
+			{
+				// A paper without references does not break the digest
+				Paper: cron.Paper{Source: "arxiv", Reference: "3", Title: "Paper 3"},
+				Crons: []cron.Cron{nlp},
+			},
+		},
+	}
+
+	err := notifier.NotifyDigest(context.Background(), digest)
+	require.NoError(t, err)
+
+	messages := transport.Messages()
+	require.Len(t, messages, 1, "a single email should be sent for all the crons")
+
+	text := parts(t, messages[0])["text/plain"]
+	assert.Equal(t, 1, strings.Count(text, "Paper 1"), "papers should be deduplicated")
+	assert.Contains(t, text, `Also matched: "gan"`)
+	assert.Contains(t, text, "Paper 2")
+	assert.Contains(t, text, "Paper 3")
+	assert.Contains(t, parts(t, messages[0])["text/html"], "Paper 3")
+}
@@ -17,3 +17,46 @@ const mailTemplate = `
     </body>
 </html>
 `
+
+const mailTextTemplate = `Your search "{{.Q}}" (on {{.Sources}}) has new results:
+{{ range .Papers }}
+  - {{ .Title }}
+    {{ index .References 0 }}
+{{ end }}
+Check them out at {{.Link}}.
+`
+
+const digestTemplate = `
+{{ define "link" }}<a href="{{.}}">{{.}}</a>{{end}}
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN"
+        "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html>
+    </head>
+    <body>
+        <p>Your searches have {{.Count}} new results:</p>
+        {{ range .Groups }}
+            <h3>"{{.Q}}" (on {{.Sources}})</h3>
+            <ul>
+                {{ range .Papers }}
+                    <li>
+                        {{ .Title }}{{ if .References }} - {{ template "link" index .References 0 }}{{ end }}
+                        {{ if .AlsoMatched }}<br/><small>Also matched: {{ range $i, $q := .AlsoMatched }}{{ if $i }}, {{ end }}"{{ $q }}"{{ end }}</small>{{ end }}
+                    </li>
+                {{ end }}
+            </ul>
+        {{ end }}
+        <p>Check them out at {{template "link" .Link}}.</p>
+    </body>
+</html>
+`
+
+const digestTextTemplate = `Your searches have {{.Count}} new results:
+{{ range .Groups }}
+"{{.Q}}" (on {{.Sources}})
+{{ range .Papers }}
+  - {{ .Title }}{{ if .References }}
+    {{ index .References 0 }}{{ end }}{{ if .AlsoMatched }}
+    Also matched: {{ range $i, $q := .AlsoMatched }}{{ if $i }}, {{ end }}"{{ $q }}"{{ end }}{{ end }}
+{{ end }}{{ end }}
+Check them out at {{.Link}}.
+`
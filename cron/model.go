@@ -44,9 +44,13 @@ type SearchResults struct {
 	} `json:"pagination"`
 }
 
+// ResultRepository keeps track of the papers surfaced by the crons. A paper is
+// identified by its source and reference, and recording it twice is a no-op.
 type ResultRepository interface {
 	Insert(ctx context.Context, cronID uint, paper Paper) error
-	GetLastResult(ctx context.Context, cronID uint, source string) (Paper, error)
+	// Seen returns the references, among the given ones, that were already
+	// recorded for the cron and source.
+	Seen(ctx context.Context, cronID uint, source string, references []string) (map[string]bool, error)
 }
 
 type Notifier interface {
@@ -54,3 +58,35 @@ type Notifier interface {
 }
 
 type NotifierFactory func(cron Cron) (Notifier, error)
+
+// DigestItem is a paper found during a run, along with all the crons of the
+// user whose query matched it.
+type DigestItem struct {
+	Paper Paper
+	Crons []Cron
+}
+
+// Digest gathers the new papers found by all the crons of a user during a
+// single run. A paper matched by several crons appears only once.
+type Digest struct {
+	UserID int
+	Items  []DigestItem
+}
+
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, digest Digest) error
+}
+
+// Locker prevents several instances of the cron service from running the
+// same job at the same time.
+type Locker interface {
+	// TryLock acquires the lock called name without waiting. The boolean is
+	// false if the lock is already held by another instance.
+	TryLock(ctx context.Context, name string) (Lock, bool, error)
+}
+
+// Lock is a lock acquired with a Locker. It is released when the instance
+// holding it stops, even without calling Unlock.
+type Lock interface {
+	Unlock() error
+}
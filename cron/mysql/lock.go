@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bobinette/papernet/cron"
+)
+
+// Locker uses MySQL named locks (GET_LOCK) so that instances sharing the
+// same database do not run the same job concurrently. A named lock belongs
+// to a connection, and is released by MySQL when the connection is lost.
+type Locker struct {
+	driver *Driver
+}
+
+func NewLocker(driver *Driver) *Locker {
+	return &Locker{
+		driver: driver,
+	}
+}
+
+func (l *Locker) TryLock(ctx context.Context, name string) (cron.Lock, bool, error) {
+	conn, err := l.driver.db.DB().Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// GET_LOCK returns 1 if the lock was obtained, 0 if it is held by
+	// another connection and NULL on error
+	var res sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&res)
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	} else if !res.Valid || res.Int64 != 1 {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return &lock{conn: conn, name: name}, true, nil
+}
+
+type lock struct {
+	conn *sql.Conn
+	name string
+}
+
+func (l *lock) Unlock() error {
+	defer l.conn.Close()
+
+	_, err := l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.name)
+	return err
+}
@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	driver := setUp(t)
+	locker := NewLocker(driver)
+
+	ctx := context.Background()
+	lock, ok, err := locker.TryLock(ctx, "papernet_test_lock")
+	require.NoError(t, err, "lock 1")
+	assert.True(t, ok, "lock 1 should be acquired")
+
+	// The locker uses a new connection every time, so it behaves as
+	// another instance
+	_, ok, err = locker.TryLock(ctx, "papernet_test_lock")
+	require.NoError(t, err, "lock 2 while held")
+	assert.False(t, ok, "lock 2 should not be acquired while held")
+
+	require.NoError(t, lock.Unlock(), "unlock 1")
+
+	lock, ok, err = locker.TryLock(ctx, "papernet_test_lock")
+	require.NoError(t, err, "lock 2 after release")
+	assert.True(t, ok, "lock 2 should be acquired after release")
+	require.NoError(t, lock.Unlock(), "unlock 2")
+}
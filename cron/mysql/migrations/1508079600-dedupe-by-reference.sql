@@ -0,0 +1,31 @@
+-- Migration: dedupe-by-reference
+-- Created at: 2017-10-15 15:00:00
+-- ====  UP  ====
+
+BEGIN;
+
+ALTER TABLE `search_results`
+    ADD COLUMN `reference` VARCHAR(256) NOT NULL DEFAULT '' AFTER `source`;
+
+UPDATE `search_results`
+    SET `reference` = JSON_UNQUOTE(JSON_EXTRACT(`result`, '$.reference'));
+
+-- Keep only the first result for each reference
+DELETE r1 FROM `search_results` r1
+    INNER JOIN `search_results` r2
+    ON r1.`cron_id` = r2.`cron_id` AND r1.`source` = r2.`source` AND r1.`reference` = r2.`reference` AND r1.`id` > r2.`id`;
+
+ALTER TABLE `search_results`
+    ADD UNIQUE INDEX `search_results_cron_id_source_reference_UNIQUE` (`cron_id`, `source`, `reference`);
+
+COMMIT;
+
+-- ==== DOWN ====
+
+BEGIN;
+
+ALTER TABLE `search_results`
+    DROP INDEX `search_results_cron_id_source_reference_UNIQUE`,
+    DROP COLUMN `reference`;
+
+COMMIT;
@@ -0,0 +1,16 @@
+package mysql
+
+import (
+	"github.com/bobinette/papernet/cron/orm"
+)
+
+// NewRepository returns a cron repository stored in the MySQL database.
+func NewRepository(driver *Driver) *orm.Repository {
+	return orm.NewRepository(driver.db)
+}
+
+// NewResultsRepository returns a results repository stored in the MySQL
+// database.
+func NewResultsRepository(driver *Driver) *orm.ResultsRepository {
+	return orm.NewResultsRepository(driver.db)
+}
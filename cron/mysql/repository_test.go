@@ -0,0 +1,41 @@
+package mysql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/cron/testutil"
+)
+
+// setUp connects to the MySQL database defined by the PAPERNET_MYSQL_*
+// environment variables. The tests are skipped when PAPERNET_MYSQL_HOST is
+// not set. The database should be migrated and empty.
+func setUp(t *testing.T) *Driver {
+	host := os.Getenv("PAPERNET_MYSQL_HOST")
+	if host == "" {
+		t.Skip("PAPERNET_MYSQL_HOST not set")
+	}
+
+	driver, err := NewDriver(
+		host,
+		os.Getenv("PAPERNET_MYSQL_PORT"),
+		os.Getenv("PAPERNET_MYSQL_USER"),
+		os.Getenv("PAPERNET_MYSQL_PASSWORD"),
+		os.Getenv("PAPERNET_MYSQL_DATABASE"),
+	)
+	require.NoError(t, err, "open driver")
+
+	return driver
+}
+
+func TestRepository(t *testing.T) {
+	driver := setUp(t)
+	testutil.TestRepository(t, NewRepository(driver))
+}
+
+func TestResultsRepository(t *testing.T) {
+	driver := setUp(t)
+	testutil.TestResultRepository(t, NewResultsRepository(driver), NewRepository(driver))
+}
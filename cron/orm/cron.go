@@ -1,26 +1,31 @@
-package mysql
+// Package orm implements the cron repositories on top of gorm. It is shared by
+// the SQL backends, that only provide the connection to the database with the
+// right dialect, and the schema.
+package orm
 
 import (
 	"context"
 
+	"github.com/jinzhu/gorm"
+
 	"github.com/bobinette/papernet/cron"
 	"github.com/bobinette/papernet/errors"
 )
 
 type Repository struct {
-	driver *Driver
+	db *gorm.DB
 }
 
-func NewRepository(driver *Driver) *Repository {
+func NewRepository(db *gorm.DB) *Repository {
 	repo := &Repository{
-		driver: driver,
+		db: db,
 	}
 	return repo
 }
 
 func (r *Repository) List(ctx context.Context) ([]cron.Cron, error) {
 	var dbCrons []Cron
-	err := r.driver.db.
+	err := r.db.
 		Find(&dbCrons).
 		Error
 	if err != nil {
@@ -36,7 +41,7 @@ func (r *Repository) List(ctx context.Context) ([]cron.Cron, error) {
 
 func (r *Repository) GetForUser(ctx context.Context, userID int) ([]cron.Cron, error) {
 	var dbCrons []Cron
-	err := r.driver.db.
+	err := r.db.
 		Where("user_id = ?", userID).
 		Find(&dbCrons).
 		Error
@@ -57,7 +62,7 @@ func (r *Repository) Insert(ctx context.Context, c *cron.Cron) error {
 	}
 
 	dbCron := newCron(*c)
-	err := r.driver.db.Save(&dbCron).Error
+	err := r.db.Save(&dbCron).Error
 	if err != nil {
 		return err
 	}
@@ -67,5 +72,5 @@ func (r *Repository) Insert(ctx context.Context, c *cron.Cron) error {
 }
 
 func (r *Repository) Delete(ctx context.Context, id uint) error {
-	return r.driver.db.Delete(Cron{ID: id}).Error
+	return r.db.Delete(Cron{ID: id}).Error
 }
@@ -1,4 +1,4 @@
-package mysql
+package orm
 
 import (
 	"database/sql/driver"
@@ -65,8 +65,9 @@ func (d *dbPaper) Scan(input interface{}) error {
 type SearchResult struct {
 	ID uint
 
-	CronID uint
-	Source string
+	CronID    uint
+	Source    string
+	Reference string
 
 	Result *dbPaper
 
@@ -76,8 +77,9 @@ type SearchResult struct {
 func newSearchResult(cronID uint, paper cron.Paper) SearchResult {
 	dbp := dbPaper(paper)
 	return SearchResult{
-		CronID: cronID,
-		Source: paper.Source,
+		CronID:    cronID,
+		Source:    paper.Source,
+		Reference: paper.Reference,
 
 		Result: &dbp,
 
@@ -0,0 +1,56 @@
+package orm
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/bobinette/papernet/cron"
+)
+
+type ResultsRepository struct {
+	db *gorm.DB
+}
+
+func NewResultsRepository(db *gorm.DB) *ResultsRepository {
+	repo := &ResultsRepository{
+		db: db,
+	}
+	return repo
+}
+
+func (r *ResultsRepository) Insert(ctx context.Context, cronID uint, paper cron.Paper) error {
+	seen, err := r.Seen(ctx, cronID, paper.Source, []string{paper.Reference})
+	if err != nil {
+		return err
+	} else if seen[paper.Reference] {
+		return nil
+	}
+
+	dbResult := newSearchResult(cronID, paper)
+	return r.db.Save(&dbResult).Error
+}
+
+func (r *ResultsRepository) Seen(ctx context.Context, cronID uint, source string, references []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	if len(references) == 0 {
+		return seen, nil
+	}
+
+	var dbResults []SearchResult
+	err := r.db.
+		Select("reference").
+		Where("cron_id = ?", cronID).
+		Where("source = ?", source).
+		Where("reference IN (?)", references).
+		Find(&dbResults).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dbResult := range dbResults {
+		seen[dbResult.Reference] = true
+	}
+	return seen, nil
+}
@@ -2,10 +2,12 @@ package cron
 
 import (
 	"context"
+	"net/http"
 
 	"gopkg.in/robfig/cron.v2"
 
 	"github.com/bobinette/papernet/clients/imports"
+	"github.com/bobinette/papernet/errors"
 	"github.com/bobinette/papernet/log"
 	"github.com/bobinette/papernet/users"
 )
@@ -13,22 +15,41 @@ import (
 const (
 	spec = "0 0 0 * * *" // Daily at midnight
 	// spec = "2 * * * * *" // Every 2 minutes. For dev
+
+	// searchLimit is the number of results fetched per source when running
+	// a cron.
+	searchLimit = 10
+
+	// runLock is the name of the lock taken while running the crons.
+	runLock = "papernet_cron_run"
 )
 
+var errAlreadyRunning = errors.New("crons are already running", errors.WithCode(http.StatusConflict))
+
 type Service struct {
 	repo          Repository
 	resultRepo    ResultRepository
 	importsClient *imports.Client
 
 	notifierFactory NotifierFactory
+	digestNotifier  DigestNotifier
+
+	locker Locker
 
 	logger log.Logger
 }
 
+// NewService creates a cron service. When digestNotifier is not nil, the new
+// papers of all the crons of a user are sent in a single digest instead of
+// one notification per cron. locker should be set when several instances of
+// the service share the same repositories, so only one of them runs the
+// crons. It can be nil otherwise.
 func NewService(
 	repo Repository,
 	resultRepo ResultRepository,
 	notifierFactory NotifierFactory,
+	digestNotifier DigestNotifier,
+	locker Locker,
 	importsClient *imports.Client,
 	logger log.Logger,
 ) *Service {
@@ -38,6 +59,9 @@ func NewService(
 		importsClient: importsClient,
 
 		notifierFactory: notifierFactory,
+		digestNotifier:  digestNotifier,
+
+		locker: locker,
 
 		logger: logger,
 	}
@@ -53,20 +77,17 @@ func (s *Service) Insert(ctx context.Context, cron *Cron) error {
 		return err
 	}
 
-	// Run the cron, and store the most recent result as a baseline for
-	// future runs
-	var res map[string]SearchResults
-	err = s.importsClient.Search(ctx, cron.Q, 1, 0, cron.Sources).Decode(&res)
+	// Run the cron, and record the current results as seen so that only the
+	// papers published from now on are notified
+	res, err := s.newPapers(ctx, *cron)
 	if err != nil {
 		return err
 	}
 
-	for _, sr := range res {
-		for _, paper := range sr.Papers {
-			err := s.resultRepo.Insert(ctx, cron.ID, paper)
-			if err != nil {
-				return err
-			}
+	for _, r := range res {
+		err := s.insertResults(ctx, cron.ID, r.Surfaced)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -75,7 +96,9 @@ func (s *Service) Insert(ctx context.Context, cron *Cron) error {
 func (s *Service) StartCron(ctx context.Context) {
 	c := cron.New()
 	c.AddFunc(spec, func() {
-		if err := s.RunCrons(ctx); err != nil {
+		if err := s.RunCrons(ctx); err == errAlreadyRunning {
+			s.logger.Print("crons already running on another instance")
+		} else if err != nil {
 			s.logger.Errorf("could not execute crons: %v", err)
 		} else {
 			s.logger.Print("successfully ran crons")
@@ -84,17 +107,34 @@ func (s *Service) StartCron(ctx context.Context) {
 	c.Start()
 }
 
+// RunCrons runs all the crons and notifies their users. It returns an error
+// with the code 409 if another instance is already running them.
 func (s *Service) RunCrons(ctx context.Context) error {
+	if s.locker != nil {
+		lock, ok, err := s.locker.TryLock(ctx, runLock)
+		if err != nil {
+			return err
+		} else if !ok {
+			return errAlreadyRunning
+		}
+		defer func() {
+			if err := lock.Unlock(); err != nil {
+				s.logger.Errorf("could not release cron lock: %v", err)
+			}
+		}()
+	}
+
 	crons, err := s.repo.List(ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, cron := range crons {
-		var res map[string]SearchResults
+	if s.digestNotifier != nil {
+		return s.runDigests(ctx, crons)
+	}
 
-		userCtx := users.AddToContext(ctx, users.User{ID: cron.UserID})
-		err := s.importsClient.Search(userCtx, cron.Q, 10, 0, cron.Sources).Decode(&res)
+	for _, cron := range crons {
+		res, err := s.newPapers(ctx, cron)
 		if err != nil {
 			return err
 		}
@@ -104,43 +144,157 @@ func (s *Service) RunCrons(ctx context.Context) error {
 			return err
 		}
 
-		for source, sr := range res {
-			last, err := s.resultRepo.GetLastResult(ctx, cron.ID, source)
+		for _, r := range res {
+			if len(r.Papers) > 0 {
+				err = notifier.Notify(ctx, r.Papers)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = s.insertResults(ctx, cron.ID, r.Surfaced)
 			if err != nil {
 				return err
 			}
+		}
+	}
 
-			papers := make([]Paper, 0, len(sr.Papers))
+	return nil
+}
 
-			for _, paper := range sr.Papers {
-				// check the source to make sure last is not empty (can't compare to nil)
-				if last.Source != "" && (last.CreatedAt.After(paper.CreatedAt) || last.CreatedAt.Equal(paper.CreatedAt)) {
-					continue
-				}
+// runDigests runs all the crons and sends a single notification per user,
+// containing the new papers of all their crons.
+func (s *Service) runDigests(ctx context.Context, crons []Cron) error {
+	type pendingResults struct {
+		cronID uint
+		papers []Paper
+	}
+
+	digests := make(map[int]*Digest)
+	indexes := make(map[int]map[string]int)
+	pending := make(map[int][]pendingResults)
+	userIDs := make([]int, 0)
+
+	for _, cron := range crons {
+		res, err := s.newPapers(ctx, cron)
+		if err != nil {
+			return err
+		}
 
-				if paper.ID != 0 {
-					// Paper already imported, nothing to do with it
+		digest, ok := digests[cron.UserID]
+		if !ok {
+			digest = &Digest{UserID: cron.UserID}
+			digests[cron.UserID] = digest
+			indexes[cron.UserID] = make(map[string]int)
+			userIDs = append(userIDs, cron.UserID)
+		}
+
+		for _, r := range res {
+			for _, paper := range r.Papers {
+				key := paper.Source + "|" + paper.Reference
+				if i, ok := indexes[cron.UserID][key]; ok {
+					digest.Items[i].Crons = append(digest.Items[i].Crons, cron)
 					continue
 				}
 
-				papers = append(papers, paper)
+				indexes[cron.UserID][key] = len(digest.Items)
+				digest.Items = append(digest.Items, DigestItem{Paper: paper, Crons: []Cron{cron}})
+			}
+			pending[cron.UserID] = append(pending[cron.UserID], pendingResults{cronID: cron.ID, papers: r.Surfaced})
+		}
+	}
+
+	for _, userID := range userIDs {
+		digest := digests[userID]
+		if len(digest.Items) > 0 {
+			err := s.digestNotifier.NotifyDigest(ctx, *digest)
+			if err != nil {
+				return err
 			}
+		}
 
-			if len(papers) > 0 {
-				err = notifier.Notify(ctx, papers)
-				if err != nil {
-					return err
-				}
+		// Only record the results once the user has been notified, so they
+		// are sent again on the next run if the notification failed.
+		for _, p := range pending[userID] {
+			err := s.insertResults(ctx, p.cronID, p.papers)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-				for _, paper := range papers {
-					err = s.resultRepo.Insert(ctx, cron.ID, paper)
-					if err != nil {
-						return err
-					}
-				}
+	return nil
+}
+
+// sourceResults contains the results of a cron for a source that were never
+// surfaced before.
+type sourceResults struct {
+	// Surfaced contains all the papers returned for the first time by the
+	// search. They should be recorded once the user has been notified.
+	Surfaced []Paper
+	// Papers contains the surfaced papers that are not in Papernet yet, and
+	// should be notified.
+	Papers []Paper
+}
+
+// newPapers runs the search of a cron and returns, by source, the papers
+// that were not surfaced by a previous run. Papers are identified by their
+// reference, so that results sharing a timestamp or backdated by the source
+// are neither missed nor notified twice.
+func (s *Service) newPapers(ctx context.Context, cron Cron) (map[string]sourceResults, error) {
+	var res map[string]SearchResults
+
+	userCtx := users.AddToContext(ctx, users.User{ID: cron.UserID})
+	err := s.importsClient.Search(userCtx, cron.Q, searchLimit, 0, cron.Sources).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	newPapers := make(map[string]sourceResults)
+	for source, sr := range res {
+		references := make([]string, len(sr.Papers))
+		for i, paper := range sr.Papers {
+			references[i] = paper.Reference
+		}
+
+		seen, err := s.resultRepo.Seen(ctx, cron.ID, source, references)
+		if err != nil {
+			return nil, err
+		} else if seen == nil {
+			seen = make(map[string]bool)
+		}
+
+		var r sourceResults
+		for _, paper := range sr.Papers {
+			if seen[paper.Reference] {
+				continue
 			}
+			// The search can return the same paper twice
+			seen[paper.Reference] = true
+
+			r.Surfaced = append(r.Surfaced, paper)
+			if paper.ID != 0 {
+				// Paper already imported, nothing to do with it
+				continue
+			}
+
+			r.Papers = append(r.Papers, paper)
+		}
+
+		if len(r.Surfaced) > 0 {
+			newPapers[source] = r
 		}
 	}
 
+	return newPapers, nil
+}
+
+func (s *Service) insertResults(ctx context.Context, cronID uint, papers []Paper) error {
+	for _, paper := range papers {
+		err := s.resultRepo.Insert(ctx, cronID, paper)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
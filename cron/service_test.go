@@ -0,0 +1,185 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/clients/imports"
+	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/users"
+)
+
+type mockRepository struct {
+	crons []Cron
+}
+
+func (r *mockRepository) GetForUser(ctx context.Context, userID int) ([]Cron, error) { return nil, nil }
+func (r *mockRepository) List(ctx context.Context) ([]Cron, error)                   { return r.crons, nil }
+func (r *mockRepository) Insert(ctx context.Context, cron *Cron) error {
+	cron.ID = uint(len(r.crons) + 1)
+	r.crons = append(r.crons, *cron)
+	return nil
+}
+func (r *mockRepository) Delete(ctx context.Context, id uint) error { return nil }
+
+type mockResultRepository struct {
+	seen map[uint]map[string]bool
+}
+
+func (r *mockResultRepository) Insert(ctx context.Context, cronID uint, paper Paper) error {
+	if r.seen[cronID] == nil {
+		r.seen[cronID] = make(map[string]bool)
+	}
+	r.seen[cronID][paper.Source+"|"+paper.Reference] = true
+	return nil
+}
+
+func (r *mockResultRepository) Seen(ctx context.Context, cronID uint, source string, references []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	for _, ref := range references {
+		if r.seen[cronID][source+"|"+ref] {
+			seen[ref] = true
+		}
+	}
+	return seen, nil
+}
+
+type mockNotifier struct {
+	notified map[uint][]Paper
+	digests  []Digest
+}
+
+func (n *mockNotifier) factory(cron Cron) (Notifier, error) {
+	return notifierFunc(func(ctx context.Context, papers []Paper) error {
+		n.notified[cron.ID] = append(n.notified[cron.ID], papers...)
+		return nil
+	}), nil
+}
+
+func (n *mockNotifier) NotifyDigest(ctx context.Context, digest Digest) error {
+	n.digests = append(n.digests, digest)
+	return nil
+}
+
+type notifierFunc func(ctx context.Context, papers []Paper) error
+
+func (f notifierFunc) Notify(ctx context.Context, papers []Paper) error { return f(ctx, papers) }
+
+// mockImportsService serves the results of the searches, by query. The
+// server has to be closed by the caller.
+func mockImportsService(results map[string]map[string]SearchResults) (*imports.Client, *httptest.Server) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/imports/v2/search" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token"})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(results[req.URL.Query().Get("q")])
+	}))
+	return imports.NewClient(&http.Client{}, srv.URL), srv
+}
+
+func searchResults(papers ...Paper) map[string]SearchResults {
+	return map[string]SearchResults{"arxiv": {Papers: papers}}
+}
+
+func TestService_RunCrons(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	results := map[string]map[string]SearchResults{
+		"nlp": searchResults(
+			Paper{Source: "arxiv", Reference: "1", Title: "Paper 1", CreatedAt: now},
+		),
+	}
+
+	importsClient, srv := mockImportsService(results)
+	defer srv.Close()
+
+	repo := &mockRepository{}
+	resultRepo := &mockResultRepository{seen: make(map[uint]map[string]bool)}
+	notifier := &mockNotifier{notified: make(map[uint][]Paper)}
+	service := NewService(repo, resultRepo, notifier.factory, nil, nil, importsClient, log.New("test"))
+
+	// Creating the cron records the current results as seen
+	c := Cron{UserID: 1, Q: "nlp", Sources: []string{"arxiv"}}
+	ctx := usersContext(1)
+	err := service.Insert(ctx, &c)
+	require.NoError(t, err)
+
+	// New papers with the same timestamp, a backdated one, and a paper
+	// returned twice by the search
+	results["nlp"] = searchResults(
+		Paper{Source: "arxiv", Reference: "2", Title: "Paper 2", CreatedAt: now},
+		Paper{Source: "arxiv", Reference: "1", Title: "Paper 1", CreatedAt: now},
+		Paper{Source: "arxiv", Reference: "3", Title: "Paper 3", CreatedAt: now.Add(-24 * time.Hour)},
+		Paper{Source: "arxiv", Reference: "2", Title: "Paper 2", CreatedAt: now},
+		Paper{Source: "arxiv", Reference: "4", Title: "Paper 4", CreatedAt: now, ID: 12},
+	)
+	err = service.RunCrons(context.Background())
+	require.NoError(t, err)
+
+	notified := notifier.notified[c.ID]
+	require.Len(t, notified, 2)
+	assert.Equal(t, "2", notified[0].Reference)
+	assert.Equal(t, "3", notified[1].Reference)
+	assert.True(t, resultRepo.seen[c.ID]["arxiv|4"], "imported papers should be recorded")
+
+	// Running again does not notify anything
+	notifier.notified = make(map[uint][]Paper)
+	err = service.RunCrons(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, notifier.notified)
+}
+
+func TestService_RunCrons_Digest(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	results := map[string]map[string]SearchResults{}
+
+	importsClient, srv := mockImportsService(results)
+	defer srv.Close()
+
+	repo := &mockRepository{}
+	resultRepo := &mockResultRepository{seen: make(map[uint]map[string]bool)}
+	notifier := &mockNotifier{notified: make(map[uint][]Paper)}
+	service := NewService(repo, resultRepo, notifier.factory, notifier, nil, importsClient, log.New("test"))
+
+	crons := []Cron{
+		{UserID: 1, Q: "nlp", Sources: []string{"arxiv"}},
+		{UserID: 1, Q: "gan", Sources: []string{"arxiv"}},
+		{UserID: 2, Q: "rnn", Sources: []string{"arxiv"}},
+	}
+	for i := range crons {
+		err := service.Insert(usersContext(crons[i].UserID), &crons[i])
+		require.NoError(t, err)
+	}
+
+	results["nlp"] = searchResults(
+		Paper{Source: "arxiv", Reference: "1", Title: "Paper 1", CreatedAt: now},
+		Paper{Source: "arxiv", Reference: "2", Title: "Paper 2", CreatedAt: now},
+	)
+	results["gan"] = searchResults(
+		Paper{Source: "arxiv", Reference: "2", Title: "Paper 2", CreatedAt: now},
+	)
+	err := service.RunCrons(context.Background())
+	require.NoError(t, err)
+
+	assert.Empty(t, notifier.notified, "crons should not be notified separately")
+	require.Len(t, notifier.digests, 1, "user 2 has nothing new")
+
+	digest := notifier.digests[0]
+	assert.Equal(t, 1, digest.UserID)
+	require.Len(t, digest.Items, 2)
+	assert.Equal(t, "1", digest.Items[0].Paper.Reference)
+	assert.Equal(t, "2", digest.Items[1].Paper.Reference)
+	assert.Len(t, digest.Items[1].Crons, 2, "paper 2 was matched by both crons")
+}
+
+func usersContext(userID int) context.Context {
+	return users.AddToContext(context.Background(), users.User{ID: userID})
+}
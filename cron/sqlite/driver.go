@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // run init method
+)
+
+// schema mirrors the MySQL migrations. It is applied when opening the
+// database, as there is no migration tool for the embedded database.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS crons (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		user_id INTEGER NOT NULL,
+		query TEXT NOT NULL,
+		sources TEXT NOT NULL,
+
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS cron_user_id_idx ON crons (user_id)`,
+	`CREATE TABLE IF NOT EXISTS search_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		cron_id INTEGER NOT NULL REFERENCES crons (id) ON DELETE CASCADE,
+		source VARCHAR(256) NOT NULL,
+		reference VARCHAR(256) NOT NULL,
+
+		result TEXT NOT NULL,
+
+		created_at DATETIME NOT NULL
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS search_results_cron_id_source_reference_idx ON search_results (cron_id, source, reference)`,
+}
+
+type Driver struct {
+	db *gorm.DB
+}
+
+// NewDriver opens the SQLite database stored in path, creating it and its
+// tables if needed.
+func NewDriver(path string) (*Driver, error) {
+	db, err := gorm.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite does not support concurrent writes, and the foreign keys pragma
+	// is set per connection.
+	db.DB().SetMaxOpenConns(1)
+	if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	for _, stmt := range schema {
+		if err := db.Exec(stmt).Error; err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	driver := &Driver{
+		db: db,
+	}
+	return driver, nil
+}
+
+// Close closes the underlying database.
+func (d *Driver) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/cron"
+	"github.com/bobinette/papernet/errors"
+)
+
+// TestRepository checks that repo behaves as expected from a cron.Repository.
+// It expects repo to be empty.
+func TestRepository(t *testing.T, repo cron.Repository) {
+	ctx := context.Background()
+
+	crons := []*cron.Cron{
+		{UserID: 1, Q: "nlp", Sources: []string{"arxiv"}},
+		{UserID: 1, Q: "gan", Sources: []string{"arxiv", "medium"}},
+		{UserID: 2, Q: "rnn", Sources: []string{"arxiv"}},
+	}
+
+	for i, c := range crons {
+		err := repo.Insert(ctx, c)
+		require.NoError(t, err, "insert cron %d", i)
+		assert.NotEqual(t, uint(0), c.ID, "insert cron %d should set the id", i)
+	}
+
+	// Updating is not supported
+	err := repo.Insert(ctx, &cron.Cron{ID: crons[0].ID, UserID: 1, Q: "update"})
+	errors.AssertCode(t, err, http.StatusBadRequest)
+
+	testListCrons(t, repo, crons, "list")
+	testGetForUser(t, repo, 1, crons[:2], "get for user 1")
+	testGetForUser(t, repo, 2, crons[2:], "get for user 2")
+	testGetForUser(t, repo, 100, nil, "get for user that does not exist")
+
+	err = repo.Delete(ctx, crons[1].ID)
+	require.NoError(t, err, "delete cron")
+
+	testListCrons(t, repo, []*cron.Cron{crons[0], crons[2]}, "list after delete")
+	testGetForUser(t, repo, 1, crons[:1], "get for user 1 after delete")
+}
+
+func testListCrons(t *testing.T, repo cron.Repository, expected []*cron.Cron, name string) {
+	crons, err := repo.List(context.Background())
+	require.NoError(t, err, name)
+	assertCrons(t, expected, crons, name)
+}
+
+func testGetForUser(t *testing.T, repo cron.Repository, userID int, expected []*cron.Cron, name string) {
+	crons, err := repo.GetForUser(context.Background(), userID)
+	require.NoError(t, err, name)
+	assertCrons(t, expected, crons, name)
+}
+
+type byID []cron.Cron
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func assertCrons(t *testing.T, expected []*cron.Cron, crons []cron.Cron, name string) {
+	require.Equal(t, len(expected), len(crons), "%s - number of crons", name)
+
+	sort.Sort(byID(crons))
+	for i, c := range crons {
+		assert.Equal(t, *expected[i], c, fmt.Sprintf("%s - cron %d", name, i))
+	}
+}
+
+// TestResultRepository checks that repo behaves as expected from a
+// cron.ResultRepository. cronRepo is used to create the crons the results
+// belong to, and should use the same storage as repo. Both are expected to be
+// empty.
+func TestResultRepository(t *testing.T, repo cron.ResultRepository, cronRepo cron.Repository) {
+	ctx := context.Background()
+
+	c := cron.Cron{UserID: 1, Q: "nlp", Sources: []string{"arxiv", "medium"}}
+	err := cronRepo.Insert(ctx, &c)
+	require.NoError(t, err, "insert cron")
+
+	// Nothing seen yet
+	testSeen(t, repo, c.ID, "arxiv", []string{"1", "2"}, nil, "no result")
+	testSeen(t, repo, c.ID, "arxiv", nil, nil, "no reference")
+
+	// Papers sharing the same date are all recorded
+	now := time.Now().UTC().Truncate(time.Second)
+	papers := []cron.Paper{
+		{Source: "arxiv", Reference: "1", Title: "Paper 1", CreatedAt: now},
+		{Source: "arxiv", Reference: "2", Title: "Paper 2", CreatedAt: now},
+		{Source: "medium", Reference: "1", Title: "Paper 3", CreatedAt: now.Add(-1 * time.Hour)},
+	}
+	for i, paper := range papers {
+		err := repo.Insert(ctx, c.ID, paper)
+		require.NoError(t, err, "insert paper %d", i)
+	}
+
+	// Inserting a paper twice is a no-op
+	err = repo.Insert(ctx, c.ID, papers[0])
+	require.NoError(t, err, "insert paper twice")
+
+	testSeen(t, repo, c.ID, "arxiv", []string{"1", "2", "3"}, []string{"1", "2"}, "arxiv results")
+	testSeen(t, repo, c.ID, "medium", []string{"1", "2"}, []string{"1"}, "medium results")
+	testSeen(t, repo, c.ID, "unknown", []string{"1"}, nil, "unknown source")
+	testSeen(t, repo, c.ID+100, "arxiv", []string{"1"}, nil, "unknown cron")
+
+	// Results are deleted with their cron
+	err = cronRepo.Delete(ctx, c.ID)
+	require.NoError(t, err, "delete cron")
+	testSeen(t, repo, c.ID, "arxiv", []string{"1", "2"}, nil, "deleted cron")
+}
+
+func testSeen(t *testing.T, repo cron.ResultRepository, cronID uint, source string, references, expected []string, name string) {
+	seen, err := repo.Seen(context.Background(), cronID, source, references)
+	require.NoError(t, err, name)
+
+	expectedSeen := make(map[string]bool)
+	for _, ref := range expected {
+		expectedSeen[ref] = true
+	}
+
+	got := make(map[string]bool)
+	for ref, ok := range seen {
+		if ok {
+			got[ref] = true
+		}
+	}
+	assert.Equal(t, expectedSeen, got, name)
+}
@@ -7,8 +7,9 @@ import (
 type AuthUser struct {
 	User
 
-	Name  string `json:"-"`
-	Email string `json:"-"`
+	Name          string `json:"-"`
+	Email         string `json:"-"`
+	EmailVerified bool   `json:"-"`
 }
 
 type UserClient struct {
@@ -24,9 +25,10 @@ func NewUserClient(client *auth.Client) *UserClient {
 
 func (c *UserClient) Upsert(user AuthUser) (AuthUser, error) {
 	authUser := auth.User{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
 	}
 
 	authUser, err := c.client.Upsert(authUser)
@@ -38,11 +40,24 @@ func (c *UserClient) Upsert(user AuthUser) (AuthUser, error) {
 			ID:       authUser.ID,
 			GoogleID: user.GoogleID,
 		},
-		Name:  authUser.Name,
-		Email: authUser.Email,
+		Name:          authUser.Name,
+		Email:         authUser.Email,
+		EmailVerified: authUser.EmailVerified,
 	}, nil
 }
 
-func (c *UserClient) Token(user AuthUser) (string, error) {
+// LinkGoogle records in the auth service the Google account of a user.
+func (c *UserClient) LinkGoogle(userID int, googleID string) error {
+	_, err := c.client.LinkGoogle(userID, googleID)
+	return err
+}
+
+// UnlinkGoogle removes from the auth service the Google account of a user.
+func (c *UserClient) UnlinkGoogle(userID int) error {
+	_, err := c.client.UnlinkGoogle(userID)
+	return err
+}
+
+func (c *UserClient) Token(user AuthUser) (auth.Tokens, error) {
 	return c.client.Token(user.ID)
 }
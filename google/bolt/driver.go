@@ -25,6 +25,8 @@ func (d *Driver) Open(path string) error {
 	err = store.Update(func(tx *bolt.Tx) error {
 		buckets := [][]byte{
 			googleBucket,
+			driveFilesBucket,
+			statesBucket,
 		}
 		for _, bucket := range buckets {
 			_, err := tx.CreateBucketIfNotExists(bucket)
@@ -0,0 +1,59 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/google"
+)
+
+var driveFilesBucket = []byte("drive_files")
+
+type DriveFileRepository struct {
+	driver *Driver
+}
+
+func NewDriveFileRepository(driver *Driver) *DriveFileRepository {
+	return &DriveFileRepository{
+		driver: driver,
+	}
+}
+
+func (r *DriveFileRepository) Seen(userID int, fileIDs []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	err := r.driver.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(driveFilesBucket)
+
+		for _, id := range fileIDs {
+			if bucket.Get(fileKey(userID, id)) != nil {
+				seen[id] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return seen, nil
+}
+
+func (r *DriveFileRepository) Save(record google.DriveFileRecord) error {
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(driveFilesBucket)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(fileKey(record.UserID, record.FileID), data)
+	})
+}
+
+// fileKey returns the key of a file of a user. The ids of the Drive files do
+// not contain slashes.
+func fileKey(userID int, fileID string) []byte {
+	return []byte(fmt.Sprintf("%d/%s", userID, fileID))
+}
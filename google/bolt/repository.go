@@ -1,6 +1,7 @@
 package bolt
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 
@@ -67,6 +68,11 @@ func (r *UserRepository) Upsert(user google.User) error {
 			return err
 		}
 
+		// The user may have linked another Google account
+		if err := deleteGoogleKey(bucket, user.ID); err != nil {
+			return err
+		}
+
 		if err := bucket.Put([]byte(user.GoogleID), data); err != nil {
 			return err
 		}
@@ -82,6 +88,59 @@ func (r *UserRepository) Upsert(user google.User) error {
 	})
 }
 
+// Delete removes a user, under both its keys.
+func (r *UserRepository) Delete(id int) error {
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(googleBucket)
+
+		if err := deleteGoogleKey(bucket, id); err != nil {
+			return err
+		}
+		return bucket.Delete(itob(id))
+	})
+}
+
+// deleteGoogleKey removes the entry of a user keyed by their Google id.
+func deleteGoogleKey(bucket *bolt.Bucket, id int) error {
+	data := bucket.Get(itob(id))
+	if data == nil {
+		return nil
+	}
+
+	var user google.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return err
+	}
+	return bucket.Delete([]byte(user.GoogleID))
+}
+
+// List returns the users, ordered by id. The users are stored twice, by id and
+// by Google id: only the entries keyed by id are read.
+func (r *UserRepository) List() ([]google.User, error) {
+	var users []google.User
+	err := r.driver.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(googleBucket)
+
+		return bucket.ForEach(func(k, v []byte) error {
+			var user google.User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return err
+			}
+
+			if user.ID == 0 || !bytes.Equal(k, itob(user.ID)) {
+				return nil
+			}
+			users = append(users, user)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func itob(v int) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, uint64(v))
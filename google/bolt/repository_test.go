@@ -25,3 +25,35 @@ func TestRepository(t *testing.T) {
 	repo := NewUserRepository(&driver)
 	google.TestRepository(t, repo)
 }
+
+func TestDriveFileRepository(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+
+	filename := tmpFile.Name()
+	defer os.Remove(filename)
+
+	driver := Driver{}
+	err = driver.Open(filename)
+	require.NoError(t, err)
+	defer driver.Close()
+
+	repo := NewDriveFileRepository(&driver)
+	google.TestDriveFileRepository(t, repo)
+}
+
+func TestStateStore(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+
+	filename := tmpFile.Name()
+	defer os.Remove(filename)
+
+	driver := Driver{}
+	err = driver.Open(filename)
+	require.NoError(t, err)
+	defer driver.Close()
+
+	store := NewStateStore(&driver)
+	google.TestStateStore(t, store)
+}
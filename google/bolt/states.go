@@ -0,0 +1,92 @@
+package bolt
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/google"
+)
+
+var statesBucket = []byte("oauth_states")
+
+type storedState struct {
+	State     google.OAuthState `json:"state"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+type StateStore struct {
+	driver *Driver
+}
+
+func NewStateStore(driver *Driver) *StateStore {
+	return &StateStore{
+		driver: driver,
+	}
+}
+
+func (s *StateStore) Save(key string, state google.OAuthState, ttl time.Duration) error {
+	now := time.Now()
+
+	return s.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(statesBucket)
+
+		// Forget the states of the logins that were never completed
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var st storedState
+			if err := json.Unmarshal(v, &st); err != nil || !now.Before(st.ExpiresAt) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		if bucket.Get([]byte(key)) != nil {
+			return google.ErrStateExists
+		}
+
+		data, err := json.Marshal(storedState{State: state, ExpiresAt: now.Add(ttl)})
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(key), data)
+	})
+}
+
+func (s *StateStore) Pop(key string) (google.OAuthState, bool, error) {
+	var st storedState
+	found := false
+	err := s.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(statesBucket)
+
+		data := bucket.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		if err := json.Unmarshal(data, &st); err != nil {
+			return err
+		}
+		found = true
+
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return google.OAuthState{}, false, err
+	}
+
+	if !found || !time.Now().Before(st.ExpiresAt) {
+		return google.OAuthState{}, false, nil
+	}
+	return st.State, true, nil
+}
@@ -1,12 +1,14 @@
 package oauth
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"context"
 
+	"github.com/bobinette/papernet/jwt"
 	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/pdf"
 
 	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/clients/paper"
 
 	"github.com/bobinette/papernet/google"
 	"github.com/bobinette/papernet/google/bolt"
@@ -18,20 +20,13 @@ type Configuration struct {
 	File    string `toml:"file"`
 }
 
-func Start(srv google.Server, cfg Configuration, logger log.Logger, authClient *auth.Client) {
-	// Load key from file
-	keyData, err := ioutil.ReadFile(cfg.KeyPath)
+// Start registers the Google endpoints and starts the sync of the drives.
+// The papers of the synced files are created with paperClient.
+func Start(srv google.Server, cfg Configuration, logger log.Logger, authClient *auth.Client, paperClient *paper.Client) *google.Service {
+	// Load keys from file. The services verifying the tokens only need the public keys.
+	keys, err := jwt.LoadKeySet(cfg.KeyPath)
 	if err != nil {
-		logger.Fatal("could not open key file:", err)
-	}
-
-	// Extract key from data
-	var key struct {
-		Key string `json:"k"`
-	}
-	err = json.Unmarshal(keyData, &key)
-	if err != nil {
-		logger.Fatal("could not read key file:", err)
+		logger.Fatal("could not load key file:", err)
 	}
 
 	// Create service
@@ -43,10 +38,25 @@ func Start(srv google.Server, cfg Configuration, logger log.Logger, authClient *
 	driverServiceFactory := google.NewGDriveService
 
 	repository := bolt.NewUserRepository(boltDriver)
+	fileRepository := bolt.NewDriveFileRepository(boltDriver)
+	stateStore := bolt.NewStateStore(boltDriver)
 	userClient := google.NewUserClient(authClient)
-	service, err := google.NewService(repository, cfg.File, driverServiceFactory, userClient)
+	service, err := google.NewService(
+		repository,
+		cfg.File,
+		driverServiceFactory,
+		userClient,
+		stateStore,
+		fileRepository,
+		paperClient,
+		pdf.DraftExtractor{},
+	)
 	if err != nil {
 		logger.Fatal("could not instantiate google service", err)
 	}
-	google.RegisterGoogleHTTPRoutes(srv, service, []byte(key.Key), authClient)
+	google.RegisterGoogleHTTPRoutes(srv, service, keys, authClient)
+
+	service.StartSync(context.Background(), logger)
+
+	return service
 }
@@ -3,6 +3,7 @@ package google
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -61,19 +62,23 @@ func (ds *GDriveService) UserHasAllowedDrive() (bool, error) {
 	return false, nil
 }
 
-func (ds *GDriveService) ListFiles(folderID, name string) ([]DriveFile, string, error) {
+func (ds *GDriveService) ListFiles(folderID, name, pageToken string) ([]DriveFile, string, error) {
 	q := fmt.Sprintf("trashed = false and '%s' in parents", folderID)
 	if name != "" {
 		q = fmt.Sprintf("%s and name contains '%s'", q, name)
 	}
 
-	r, err := ds.service.Files.
+	call := ds.service.Files.
 		List().
 		Q(q).
 		PageSize(10).
 		Fields("nextPageToken, files(id, name, mimeType, webViewLink)").
-		OrderBy("name").
-		Do()
+		OrderBy("name")
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+
+	r, err := call.Do()
 	if err != nil {
 		return nil, "", errors.New("unable to retrieve files", errors.WithCause(err))
 	}
@@ -117,6 +122,28 @@ func (ds *GDriveService) CreateFile(name, typ, folderID string, data []byte) (Dr
 	return dFile, nil
 }
 
+func (ds *GDriveService) DownloadFile(id string) (io.ReadCloser, error) {
+	res, err := ds.service.Files.Get(id).Download()
+	if err != nil {
+		return nil, errors.New("error downloading file from Google Drive", errors.WithCause(err))
+	}
+
+	return res.Body, nil
+}
+
+func (ds *GDriveService) DeleteFile(id string) error {
+	err := ds.service.Files.Delete(id).Do()
+	if err != nil {
+		// The file has already been removed from the drive
+		if err, ok := err.(*googleapi.Error); ok && err.Code == http.StatusNotFound {
+			return nil
+		}
+		return errors.New("error deleting file from Google Drive", errors.WithCause(err))
+	}
+
+	return nil
+}
+
 func (ds *GDriveService) GetFolderID(name string) (string, error) {
 	// Adapted from https://gist.github.com/TheGU/e6d0ae13f2fa83f3bd8d
 	q := fmt.Sprintf("name='%s' and mimeType='application/vnd.google-apps.folder'", name)
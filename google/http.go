@@ -15,14 +15,16 @@ import (
 	"github.com/bobinette/papernet/users"
 )
 
-func RegisterGoogleHTTPRoutes(srv Server, service *Service, jwtKey []byte, authClient *auth.Client) {
+func RegisterGoogleHTTPRoutes(srv Server, service *Service, keys *jwt.KeySet, authClient *auth.Client) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
 
 	authenticator := users.NewAuthenticator(authClient)
-	jwtMiddleware := jwt.Middleware(jwtKey)
+
+	// The drive is not exposed to the API tokens
+	jwtMiddleware := jwt.Middleware(keys)
 
 	googleLoginURLHandler := kithttp.NewServer(
 		makeGoogleLoginURLEndpoint(service),
@@ -66,13 +68,48 @@ func RegisterGoogleHTTPRoutes(srv Server, service *Service, jwtKey []byte, authC
 		opts...,
 	)
 
+	googleDriveSyncHandler := kithttp.NewServer(
+		jwtMiddleware(authenticator.Authenticated(makeGoogleDriveSyncHandler(service))),
+		decodeGoogleDriveRequest, // Nothing to decode
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	googleDriveDisconnectHandler := kithttp.NewServer(
+		jwtMiddleware(authenticator.Authenticated(makeGoogleDriveDisconnectHandler(service))),
+		decodeGoogleDriveRequest, // Nothing to decode
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	googleLinkHandler := kithttp.NewServer(
+		jwtMiddleware(authenticator.Authenticated(makeGoogleLinkHandler(service))),
+		decodeGoogleDriveRequireRequest, // Same fromURL query parameter
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	googleUnlinkHandler := kithttp.NewServer(
+		jwtMiddleware(authenticator.Authenticated(makeGoogleUnlinkHandler(service))),
+		decodeGoogleDriveRequest, // Nothing to decode
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
 	srv.RegisterHandler("/google/login", "GET", googleLoginURLHandler)
 	srv.RegisterHandler("/google/login", "POST", googleLoginHandler)
 
+	// The Google account is linked from the account of the user, but the link
+	// goes through the Google login.
+	srv.RegisterHandler("/auth/v2/me/google", "GET", googleLinkHandler)
+	srv.RegisterHandler("/auth/v2/me/google", "DELETE", googleUnlinkHandler)
+
 	srv.RegisterHandler("/google/drive", "GET", googleDriveHandler)
+	srv.RegisterHandler("/google/drive", "DELETE", googleDriveDisconnectHandler)
 	srv.RegisterHandler("/google/drive/require", "GET", googleDriveRequireHandler)
 	srv.RegisterHandler("/google/drive/files", "GET", googleDriveFilesHandler)
 	srv.RegisterHandler("/google/drive/files", "POST", googleDriveUploadFileHandler)
+	srv.RegisterHandler("/google/drive/sync", "POST", googleDriveSyncHandler)
 
 	// @TODO: remove because legacy
 	srv.RegisterHandler("/login/google", "GET", googleLoginURLHandler)
@@ -81,7 +118,11 @@ func RegisterGoogleHTTPRoutes(srv Server, service *Service, jwtKey []byte, authC
 
 func makeGoogleLoginURLEndpoint(s *Service) endpoint.Endpoint {
 	return func(_ context.Context, _ interface{}) (interface{}, error) {
-		return map[string]string{"url": s.LoginURL()}, nil
+		url, err := s.LoginURL()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"url": url}, nil
 	}
 }
 
@@ -101,11 +142,16 @@ func makeGoogleLoginEndpoint(s *Service) endpoint.Endpoint {
 			return nil, errInvalidRequest
 		}
 
-		token, fromURL, err := s.Login(req.State, req.Code)
+		tokens, fromURL, err := s.Login(req.State, req.Code)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]interface{}{"access_token": token, "fromURL": fromURL}, nil
+		return map[string]interface{}{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"fromURL":       fromURL,
+		}, nil
 	}
 }
 
@@ -120,6 +166,41 @@ func decodeGoogleLoginRequest(_ context.Context, r *http.Request) (interface{},
 	return req, nil
 }
 
+func makeGoogleLinkHandler(s *Service) endpoint.Endpoint {
+	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		user, err := users.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		fromURL, ok := r.(string)
+		if !ok {
+			return nil, errInvalidRequest
+		}
+
+		url, err := s.LinkURL(user.ID, fromURL)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"url": url}, nil
+	}
+}
+
+func makeGoogleUnlinkHandler(s *Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		user, err := users.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.unlink(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"linked": false}, nil
+	}
+}
+
 func makeGoogleDriveHandler(s *Service) endpoint.Endpoint {
 	return func(ctx context.Context, _ interface{}) (interface{}, error) {
 		user, err := users.FromContext(ctx)
@@ -139,14 +220,37 @@ func decodeGoogleDriveRequest(_ context.Context, r *http.Request) (interface{},
 	return nil, nil
 }
 
+func makeGoogleDriveDisconnectHandler(s *Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		user, err := users.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.disconnectDrive(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"hasAccess": false}, nil
+	}
+}
+
 func makeGoogleDriveRequireHandler(s *Service) endpoint.Endpoint {
 	return func(ctx context.Context, r interface{}) (interface{}, error) {
+		user, err := users.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
 		fromURL, ok := r.(string)
 		if !ok {
 			return nil, errInvalidRequest
 		}
 
-		url := s.requireDrive(fromURL)
+		url, err := s.requireDrive(user.ID, fromURL)
+		if err != nil {
+			return nil, err
+		}
 		return map[string]interface{}{"url": url}, nil
 	}
 }
@@ -157,7 +261,8 @@ func decodeGoogleDriveRequireRequest(_ context.Context, r *http.Request) (interf
 }
 
 type googleDriveFilesRequest struct {
-	name string
+	name      string
+	pageToken string
 }
 
 func makeGoogleDriveFilesHandler(s *Service) endpoint.Endpoint {
@@ -172,7 +277,7 @@ func makeGoogleDriveFilesHandler(s *Service) endpoint.Endpoint {
 			return nil, errInvalidRequest
 		}
 
-		files, nextPageToken, err := s.inspectDrive(user.ID, req.name)
+		files, nextPageToken, err := s.inspectDrive(user.ID, req.name, req.pageToken)
 		if err != nil {
 			return nil, err
 		}
@@ -182,9 +287,11 @@ func makeGoogleDriveFilesHandler(s *Service) endpoint.Endpoint {
 
 func decodeGoogleDriveFilesRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	name := r.URL.Query().Get("name")
+	pageToken := r.URL.Query().Get("pageToken")
 
 	return googleDriveFilesRequest{
-		name: name,
+		name:      name,
+		pageToken: pageToken,
 	}, nil
 }
 
@@ -206,7 +313,7 @@ func makeGoogleDriveUploadFileHandler(s *Service) endpoint.Endpoint {
 			return nil, errInvalidRequest
 		}
 
-		file, err := s.addFile(user.ID, req.Filename, req.Filetype, req.Data)
+		file, err := s.addFile(user.ID, 0, req.Filename, req.Filetype, req.Data)
 		if err != nil {
 			return nil, err
 		}
@@ -229,3 +336,18 @@ func decodeGoogleDriveUploadFileRequest(_ context.Context, r *http.Request) (int
 	}
 	return body, nil
 }
+
+func makeGoogleDriveSyncHandler(s *Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		user, err := users.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		papers, err := s.SyncDrive(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"data": papers}, nil
+	}
+}
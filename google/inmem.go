@@ -1,5 +1,10 @@
 package google
 
+import (
+	"sync"
+	"time"
+)
+
 type InmemRepository struct {
 	users []User
 }
@@ -28,6 +33,10 @@ func (r InmemRepository) GetByGoogleID(googleID string) (User, error) {
 	return User{}, nil
 }
 
+func (r InmemRepository) List() ([]User, error) {
+	return r.users, nil
+}
+
 func (r *InmemRepository) Upsert(user User) error {
 	for i, u := range r.users {
 		if u.ID == user.ID {
@@ -39,3 +48,100 @@ func (r *InmemRepository) Upsert(user User) error {
 	r.users = append(r.users, user)
 	return nil
 }
+
+func (r *InmemRepository) Delete(id int) error {
+	for i, u := range r.users {
+		if u.ID == id {
+			r.users = append(r.users[:i], r.users[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+type InmemDriveFileRepository struct {
+	records []DriveFileRecord
+}
+
+func NewInmemDriveFileRepository() *InmemDriveFileRepository {
+	return &InmemDriveFileRepository{
+		records: make([]DriveFileRecord, 0),
+	}
+}
+
+func (r InmemDriveFileRepository) Seen(userID int, fileIDs []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	for _, record := range r.records {
+		if record.UserID != userID {
+			continue
+		}
+
+		for _, id := range fileIDs {
+			if record.FileID == id {
+				seen[id] = true
+			}
+		}
+	}
+	return seen, nil
+}
+
+func (r *InmemDriveFileRepository) Save(record DriveFileRecord) error {
+	for i, rec := range r.records {
+		if rec.UserID == record.UserID && rec.FileID == record.FileID {
+			r.records[i] = record
+			return nil
+		}
+	}
+
+	r.records = append(r.records, record)
+	return nil
+}
+
+type inmemState struct {
+	state     OAuthState
+	expiresAt time.Time
+}
+
+type InmemStateStore struct {
+	mu     sync.Mutex
+	states map[string]inmemState
+}
+
+func NewInmemStateStore() *InmemStateStore {
+	return &InmemStateStore{
+		states: make(map[string]inmemState),
+	}
+}
+
+func (s *InmemStateStore) Save(key string, state OAuthState, ttl time.Duration) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget the states of the logins that were never completed
+	for k, st := range s.states {
+		if !now.Before(st.expiresAt) {
+			delete(s.states, k)
+		}
+	}
+
+	if _, ok := s.states[key]; ok {
+		return ErrStateExists
+	}
+
+	s.states[key] = inmemState{state: state, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *InmemStateStore) Pop(key string) (OAuthState, bool, error) {
+	s.mu.Lock()
+	st, ok := s.states[key]
+	delete(s.states, key)
+	s.mu.Unlock()
+
+	if !ok || !time.Now().Before(st.expiresAt) {
+		return OAuthState{}, false, nil
+	}
+	return st.state, true, nil
+}
@@ -8,3 +8,13 @@ func TestInmemRepository(t *testing.T) {
 	repo := NewInmemRepository()
 	TestRepository(t, repo)
 }
+
+func TestInmemDriveFileRepository(t *testing.T) {
+	repo := NewInmemDriveFileRepository()
+	TestDriveFileRepository(t, repo)
+}
+
+func TestInmemStateStore(t *testing.T) {
+	store := NewInmemStateStore()
+	TestStateStore(t, store)
+}
@@ -0,0 +1,265 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/errors"
+)
+
+// fakeGoogleServer exchanges the codes for tokens named after them, and
+// returns the profile of the tokens.
+func fakeGoogleServer(t *testing.T, profiles map[string]googleUser) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": r.PostForm.Get("code"),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		case "/userinfo":
+			code := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			json.NewEncoder(w).Encode(profiles[code])
+		case "/revoke":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// fakeAuth is the part of the auth service used by the Google service.
+type fakeAuth struct {
+	users      map[string]int // email -> id
+	passwords  map[int]bool
+	unverified map[int]bool
+	googleIDs  map[int]string
+}
+
+func (a *fakeAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fail := func(code int, msg string) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+
+	if r.URL.Path == "/auth/v2/users" {
+		var user auth.User
+		json.NewDecoder(r.Body).Decode(&user)
+		if id, ok := a.users[user.Email]; ok && a.passwords[id] && a.unverified[id] {
+			fail(http.StatusConflict, "unverified account with a password")
+			return
+		}
+		if _, ok := a.users[user.Email]; !ok {
+			a.users[user.Email] = len(a.users) + 1
+		}
+		user.ID = a.users[user.Email]
+		json.NewEncoder(w).Encode(user)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/auth/v2/users/"), "/")
+	id, _ := strconv.Atoi(parts[0])
+	switch {
+	case parts[1] == "token":
+		json.NewEncoder(w).Encode(auth.Tokens{AccessToken: fmt.Sprintf("token-%d", id)})
+	case parts[1] == "google" && r.Method == "POST":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		for userID, googleID := range a.googleIDs {
+			if googleID == body["googleId"] && userID != id {
+				fail(http.StatusConflict, "linked to another user")
+				return
+			}
+		}
+		if a.googleIDs[id] != "" && a.googleIDs[id] != body["googleId"] {
+			fail(http.StatusConflict, "another account is linked")
+			return
+		}
+		a.googleIDs[id] = body["googleId"]
+		json.NewEncoder(w).Encode(auth.User{ID: id, GoogleID: body["googleId"]})
+	case parts[1] == "google" && r.Method == "DELETE":
+		if !a.passwords[id] {
+			fail(http.StatusBadRequest, "no password")
+			return
+		}
+		delete(a.googleIDs, id)
+		json.NewEncoder(w).Encode(auth.User{ID: id})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func createLinkService(t *testing.T, gs *httptest.Server, as *httptest.Server) (*Service, *InmemRepository) {
+	repo := NewInmemRepository()
+	service := &Service{
+		repository: repo,
+		userClient: NewUserClient(auth.NewClient(http.DefaultClient, as.URL)),
+		states:     NewInmemStateStore(),
+
+		endpoint: oauth2.Endpoint{TokenURL: gs.URL + "/token"},
+	}
+	return service, repo
+}
+
+func login(t *testing.T, s *Service, userID int, code string) (auth.Tokens, error) {
+	state, err := s.generateState(OAuthState{Scopes: []string{userInfoScope}, UserID: userID})
+	require.NoError(t, err)
+
+	tokens, _, err := s.Login(state, code)
+	return tokens, err
+}
+
+func TestGenerateState(t *testing.T) {
+	service := &Service{states: NewInmemStateStore()}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		state, err := service.generateState(OAuthState{UserID: 1})
+		require.NoError(t, err)
+
+		assert.Equal(t, state, url.QueryEscape(state), "state should be url safe")
+		assert.False(t, seen[state], "states should not repeat")
+		seen[state] = true
+	}
+}
+
+func TestLogin_LinkByVerifiedEmail(t *testing.T) {
+	gs := fakeGoogleServer(t, map[string]googleUser{
+		"pizza":      {GoogleID: "g-pizza", Email: "pizza@paper.net", EmailVerified: true},
+		"unverified": {GoogleID: "g-yolo", Email: "pizza@paper.net"},
+	})
+	defer gs.Close()
+	defer func(u string) { userInfoURL = u }(userInfoURL)
+	userInfoURL = gs.URL + "/userinfo"
+
+	fa := &fakeAuth{
+		users:     map[string]int{"pizza@paper.net": 1},
+		passwords: map[int]bool{1: true},
+		googleIDs: map[int]string{},
+	}
+	as := httptest.NewServer(fa)
+	defer as.Close()
+
+	service, repo := createLinkService(t, gs, as)
+
+	// The unverified emails are not trusted
+	_, err := login(t, service, 0, "unverified")
+	if assert.Error(t, err) {
+		errors.AssertCode(t, err, http.StatusForbidden)
+	}
+
+	// The password account is reused
+	tokens, err := login(t, service, 0, "pizza")
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", tokens.AccessToken)
+	assert.Equal(t, map[int]string{1: "g-pizza"}, fa.googleIDs)
+
+	user, err := repo.GetByGoogleID("g-pizza")
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.Contains(t, user.Tokens, userInfoScope)
+
+	// Logging in again finds the same user
+	tokens, err = login(t, service, 0, "pizza")
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", tokens.AccessToken)
+	assert.Len(t, fa.users, 1, "no user should be created")
+}
+
+func TestLogin_UnverifiedPasswordAccount(t *testing.T) {
+	gs := fakeGoogleServer(t, map[string]googleUser{
+		"pizza": {GoogleID: "g-pizza", Email: "pizza@paper.net", EmailVerified: true},
+	})
+	defer gs.Close()
+	defer func(u string) { userInfoURL = u }(userInfoURL)
+	userInfoURL = gs.URL + "/userinfo"
+
+	// Someone signed up with the email, without verifying it
+	fa := &fakeAuth{
+		users:      map[string]int{"pizza@paper.net": 1},
+		passwords:  map[int]bool{1: true},
+		unverified: map[int]bool{1: true},
+		googleIDs:  map[int]string{},
+	}
+	as := httptest.NewServer(fa)
+	defer as.Close()
+
+	service, repo := createLinkService(t, gs, as)
+
+	// The account is not handed over, and the Google account is not linked
+	_, err := login(t, service, 0, "pizza")
+	if assert.Error(t, err) {
+		errors.AssertCode(t, err, http.StatusConflict)
+	}
+	assert.Empty(t, fa.googleIDs, "the Google account should not be linked")
+
+	user, err := repo.GetByGoogleID("g-pizza")
+	require.NoError(t, err)
+	assert.Equal(t, 0, user.ID, "no user should be stored")
+}
+
+func TestLogin_LinkAndUnlink(t *testing.T) {
+	gs := fakeGoogleServer(t, map[string]googleUser{
+		"pizza": {GoogleID: "g-pizza", Email: "other@gmail.com", EmailVerified: true},
+	})
+	defer gs.Close()
+	defer func(u string) { userInfoURL = u }(userInfoURL)
+	userInfoURL = gs.URL + "/userinfo"
+	defer func(u string) { revokeURL = u }(revokeURL)
+	revokeURL = gs.URL + "/revoke"
+
+	fa := &fakeAuth{
+		users:     map[string]int{"pizza@paper.net": 1, "yolo@paper.net": 2},
+		passwords: map[int]bool{1: true},
+		googleIDs: map[int]string{},
+	}
+	as := httptest.NewServer(fa)
+	defer as.Close()
+
+	service, repo := createLinkService(t, gs, as)
+
+	// A logged in user links an account with another email
+	_, err := login(t, service, 1, "pizza")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "g-pizza"}, fa.googleIDs)
+	assert.Len(t, fa.users, 2, "no user should be created")
+
+	// The account cannot be linked to a second user
+	_, err = login(t, service, 2, "pizza")
+	if assert.Error(t, err) {
+		errors.AssertCode(t, err, http.StatusConflict)
+	}
+
+	user, err := repo.GetByGoogleID("g-pizza")
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	// Unlinking forgets the account on both sides
+	err = service.unlink(1)
+	require.NoError(t, err)
+	assert.Empty(t, fa.googleIDs)
+
+	user, err = repo.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, User{}, user)
+
+	// Once unlinked, the account can be linked to another user
+	_, err = login(t, service, 2, "pizza")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]string{2: "g-pizza"}, fa.googleIDs)
+}
@@ -1,9 +1,15 @@
 package google
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"golang.org/x/oauth2"
+
+	"github.com/bobinette/papernet/clients/paper"
+	"github.com/bobinette/papernet/errors"
 )
 
 type User struct {
@@ -18,6 +24,34 @@ type UserRepository interface {
 	GetByGoogleID(googleID string) (User, error)
 
 	Upsert(User) error
+	List() ([]User, error)
+	Delete(id int) error
+}
+
+// OAuthState is the information kept during a login, until the user comes
+// back from Google with the state of the login.
+type OAuthState struct {
+	FromURL string   `json:"fromURL"`
+	Scopes  []string `json:"scopes"`
+
+	// UserID is the user linking their Google account, 0 for a login.
+	UserID int `json:"userID"`
+}
+
+// ErrStateExists is returned when saving a state under the key of a login in
+// progress.
+var ErrStateExists = errors.New("state already exists")
+
+// StateStore keeps the states of the logins in progress. It can be shared by
+// several instances of the service, so that a login can start on one and end
+// on another.
+type StateStore interface {
+	// Save stores a state, valid for ttl. It returns ErrStateExists if a
+	// state that has not expired is already stored under key.
+	Save(key string, state OAuthState, ttl time.Duration) error
+	// Pop returns and forgets the state stored under key. ok is false if
+	// there is no such state or if it expired.
+	Pop(key string) (state OAuthState, ok bool, err error)
 }
 
 type DriveFile struct {
@@ -33,7 +67,38 @@ type DriveService interface {
 	UserHasAllowedDrive() (bool, error)
 	GetFolderID(name string) (string, error)
 
-	ListFiles(folderID string, pageToken string) ([]DriveFile, string, error)
+	// ListFiles returns a page of the files of a folder whose name contains
+	// name, along with the token of the next page. The token is empty on the
+	// last page.
+	ListFiles(folderID, name, pageToken string) ([]DriveFile, string, error)
 	CreateFile(name, typ, folderID string, data []byte) (DriveFile, error)
+	DownloadFile(id string) (io.ReadCloser, error)
+	DeleteFile(id string) error
 	CreateFolder(name string) (string, error)
 }
+
+// DriveFileRecord records that a file of the Papernet folder of a user has
+// been processed, either because Papernet created it or because it was
+// synced. PaperID is 0 for the files not linked to a paper.
+type DriveFileRecord struct {
+	UserID  int    `json:"userId"`
+	FileID  string `json:"fileId"`
+	PaperID int    `json:"paperId"`
+
+	SyncedAt time.Time `json:"syncedAt"`
+}
+
+// DriveFileRepository remembers the processed files, so they are synced only
+// once.
+type DriveFileRepository interface {
+	// Seen returns the ids, among fileIDs, of the files already processed for
+	// the user.
+	Seen(userID int, fileIDs []string) (map[string]bool, error)
+	Save(DriveFileRecord) error
+}
+
+// PaperCreator creates the papers of the synced files. The context contains
+// the user.
+type PaperCreator interface {
+	Insert(ctx context.Context, p paper.Paper) (paper.Paper, error)
+}
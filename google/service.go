@@ -4,36 +4,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"sync"
+	"net/http"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
 
+	"github.com/bobinette/papernet/clients/auth"
 	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
 )
 
 const (
-	userInfoURL   = "https://www.googleapis.com/oauth2/v3/userinfo"
 	userInfoScope = "https://www.googleapis.com/auth/userinfo.email"
 
 	errInsufficientPermissions = "insufficientPermissions"
 
+	// stateTTL is the time a user has to log in with Google once the login
+	// url has been generated.
+	stateTTL = 15 * time.Minute
+
 	papernetFolderName = "Papernet"
 )
 
-type googleUser struct {
-	GoogleID string `json:"sub"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-}
+// driveScopes are the scopes requested to access the drive of a user.
+var driveScopes = []string{drive.DriveFileScope, drive.DriveReadonlyScope, userInfoScope}
 
-type originInfo struct {
-	fromURL string
-	scopes  []string
+// userInfoURL is the endpoint returning the profile of the Google users.
+var userInfoURL = "https://www.googleapis.com/oauth2/v3/userinfo"
 
-	// @TODO: handle state expiration
+type googleUser struct {
+	GoogleID      string `json:"sub"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type Service struct {
@@ -42,16 +47,32 @@ type Service struct {
 
 	driveServiceFactory DriveServiceFactory
 
+	// Drive sync
+	files     DriveFileRepository
+	papers    PaperCreator
+	extractor papernet.DraftExtractor
+
 	// Config
 	clientID     string
 	clientSecret string
 	redirectURL  string
+	endpoint     oauth2.Endpoint
 
-	stateMutex sync.Locker
-	state      map[string]originInfo
+	states StateStore
 }
 
-func NewService(repo UserRepository, configPath string, dsf DriveServiceFactory, userClient *UserClient) (*Service, error) {
+// NewService creates the Google service. The files of the Papernet folder of
+// the users are synced to papers created with papers, pre-filled by extractor.
+func NewService(
+	repo UserRepository,
+	configPath string,
+	dsf DriveServiceFactory,
+	userClient *UserClient,
+	states StateStore,
+	files DriveFileRepository,
+	papers PaperCreator,
+	extractor papernet.DraftExtractor,
+) (*Service, error) {
 	c, err := ioutil.ReadFile(configPath)
 	if err != nil {
 		return nil, err
@@ -73,150 +94,337 @@ func NewService(repo UserRepository, configPath string, dsf DriveServiceFactory,
 
 		driveServiceFactory: dsf,
 
+		files:     files,
+		papers:    papers,
+		extractor: extractor,
+
 		// Config
 		clientID:     creds.ClientID,
 		clientSecret: creds.ClientSecret,
 		redirectURL:  creds.RedirectURL,
+		endpoint:     google.Endpoint,
 
-		stateMutex: &sync.RWMutex{},
-		state:      make(map[string]originInfo),
+		states: states,
 	}, nil
 }
 
-func (s *Service) LoginURL() string {
-	state := s.generateState("", userInfoScope)
-	return s.config(userInfoScope).AuthCodeURL(state, oauth2.AccessTypeOffline)
+func (s *Service) LoginURL() (string, error) {
+	state, err := s.generateState(OAuthState{Scopes: []string{userInfoScope}})
+	if err != nil {
+		return "", err
+	}
+	return s.config(userInfoScope).AuthCodeURL(state, oauth2.AccessTypeOffline), nil
 }
 
-func (s *Service) Login(state, code string) (string, string, error) {
-	info, ok := s.checkState(state)
-	if !ok {
-		return "", "", errors.New("invalid state", errors.BadRequest())
+// LinkURL returns the url a logged in user is sent to in order to link their
+// Google account. The link is completed by Login, when they come back.
+func (s *Service) LinkURL(userID int, fromURL string) (string, error) {
+	state, err := s.generateState(OAuthState{
+		FromURL: fromURL,
+		Scopes:  []string{userInfoScope},
+		UserID:  userID,
+	})
+	if err != nil {
+		return "", err
 	}
+	return s.config(userInfoScope).AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+}
 
-	s.stateMutex.Lock()
-	delete(s.state, state)
-	s.stateMutex.Unlock()
+// Login completes the login of a user coming back from Google, or the link of
+// their Google account if they started from LinkURL, and returns their tokens
+// and the url they started from.
+func (s *Service) Login(state, code string) (auth.Tokens, string, error) {
+	info, ok, err := s.checkState(state)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	} else if !ok {
+		return auth.Tokens{}, "", errors.New("invalid state", errors.BadRequest())
+	}
 
 	tok, err := s.config().Exchange(oauth2.NoContext, code)
 	if err != nil {
-		return "", "", err
+		return auth.Tokens{}, "", err
 	}
 
 	gUser, err := s.retrieveGoogleUser(tok)
 	if err != nil {
-		return "", "", err
+		return auth.Tokens{}, "", err
 	}
 
-	user, err := s.repository.GetByGoogleID(gUser.GoogleID)
+	var user User
+	if info.UserID != 0 {
+		user, err = s.link(info.UserID, gUser)
+	} else {
+		user, err = s.loginUser(gUser)
+	}
 	if err != nil {
-		return "", "", err
-	} else if user.ID == 0 {
-		user.GoogleID = gUser.GoogleID
+		return auth.Tokens{}, "", err
 	}
 
 	if user.Tokens == nil {
 		user.Tokens = make(map[string]*oauth2.Token)
 	}
 
-	for _, scope := range info.scopes {
+	for _, scope := range info.Scopes {
 		user.Tokens[scope] = tok
 	}
 
-	authUser := AuthUser{
-		User:  user,
-		Name:  gUser.Name,
-		Email: gUser.Email,
+	err = s.repository.Upsert(user)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	tokens, err := s.userClient.Token(AuthUser{User: user})
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	return tokens, info.FromURL, nil
+}
+
+// loginUser returns the user a Google account is linked to. An account seen
+// for the first time is linked to the user with the same email, created if
+// needed. Only the emails verified by Google are trusted, otherwise anyone
+// could take over an account by registering its email with Google. The auth
+// service refuses to hand over an account with a password whose email was
+// never verified, and the Google account is then not linked.
+func (s *Service) loginUser(gUser googleUser) (User, error) {
+	user, err := s.repository.GetByGoogleID(gUser.GoogleID)
+	if err != nil {
+		return User{}, err
+	} else if user.ID != 0 {
+		// Linking again does nothing once linked, but records the accounts
+		// linked before the auth service knew about Google.
+		return user, s.userClient.LinkGoogle(user.ID, gUser.GoogleID)
+	}
+
+	if gUser.Email == "" {
+		return User{}, errors.New("Google did not return an email", errors.BadRequest())
+	} else if !gUser.EmailVerified {
+		return User{}, errors.New("the email of the Google account has not been verified", errors.Forbidden())
 	}
 
-	// Update infos in auth
-	authUser, err = s.userClient.Upsert(authUser)
+	authUser, err := s.userClient.Upsert(AuthUser{
+		Name:          gUser.Name,
+		Email:         gUser.Email,
+		EmailVerified: true,
+	})
 	if err != nil {
-		return "", "", err
+		return User{}, err
 	} else if authUser.ID == 0 {
-		return "", "", errors.New("user got no id")
+		return User{}, errors.New("user got no id")
 	}
 
-	user.ID = authUser.ID
-	err = s.repository.Upsert(user)
+	err = s.userClient.LinkGoogle(authUser.ID, gUser.GoogleID)
+	if err != nil {
+		return User{}, err
+	}
+
+	return User{ID: authUser.ID, GoogleID: gUser.GoogleID}, nil
+}
+
+// link links a Google account to a logged in user, whatever its email.
+func (s *Service) link(userID int, gUser googleUser) (User, error) {
+	linked, err := s.repository.GetByGoogleID(gUser.GoogleID)
+	if err != nil {
+		return User{}, err
+	} else if linked.ID != 0 && linked.ID != userID {
+		return User{}, errors.New("this Google account is linked to another user", errors.WithCode(http.StatusConflict))
+	}
+
+	// The auth service checks that the user has no other Google account
+	err = s.userClient.LinkGoogle(userID, gUser.GoogleID)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := s.repository.GetByID(userID)
+	if err != nil {
+		return User{}, err
+	} else if user.GoogleID != gUser.GoogleID {
+		user = User{ID: userID, GoogleID: gUser.GoogleID}
+	}
+
+	return user, nil
+}
+
+// unlink removes the Google account linked to a user and revokes the access
+// they granted. The auth service refuses to unlink the users without password.
+func (s *Service) unlink(userID int) error {
+	user, err := s.repository.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.userClient.UnlinkGoogle(userID)
 	if err != nil {
-		return "", "", err
+		return err
 	}
 
-	token, err := s.userClient.Token(authUser)
+	err = s.repository.Delete(userID)
 	if err != nil {
-		return "", "", err
+		return err
 	}
 
-	return token, info.fromURL, nil
+	// The tokens are not stored anymore: failing to revoke them only leaves
+	// them to expire.
+	revoked := make(map[string]bool)
+	for _, token := range user.Tokens {
+		if token == nil || revoked[token.AccessToken] {
+			continue
+		}
+		revoked[token.AccessToken] = true
+		revoke(http.DefaultClient, token)
+	}
+
+	return nil
 }
 
+// hasDrive returns whether the user has given access to their drive. The
+// access is considered lost when the token cannot be refreshed anymore.
 func (s *Service) hasDrive(userID int) (bool, error) {
 	user, err := s.repository.GetByID(userID)
 	if err != nil {
 		return false, err
 	}
 
-	token, ok := user.Tokens[drive.DriveFileScope]
-	if !ok {
+	// The users who only granted access to the files created by Papernet
+	// have to grant access again for the sync to see their files
+	if _, ok := user.Tokens[drive.DriveReadonlyScope]; !ok {
+		return false, nil
+	} else if _, ok := user.Tokens[drive.DriveFileScope]; !ok {
 		return false, nil
 	}
 
-	client := s.config(drive.DriveFileScope).Client(oauth2.NoContext, token)
-	driveService, err := s.driveServiceFactory(client)
-	if err != nil {
-		return false, fmt.Errorf("unable to retrieve drive Client %v\n", err)
+	driveService, err := s.driveService(userID)
+	if _, ok := err.(reauthorizationError); ok {
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
 
 	return driveService.UserHasAllowedDrive()
 }
 
-func (s *Service) requireDrive(fromURL string) string {
-	state := s.generateState(fromURL, drive.DriveFileScope, userInfoScope)
-	config := s.config(drive.DriveFileScope, userInfoScope)
-	return config.AuthCodeURL(state, oauth2.AccessTypeOffline)
+// requireDrive returns the url a user is sent to in order to give access to
+// their drive. The Google account is linked to the user if it was not.
+//
+// Papernet asks to write the files it creates, the attachments, and to read
+// the whole drive: the files the user adds to the Papernet folder, from their
+// phone for instance, are not visible with the file scope alone. The consent
+// screen shows the user both accesses.
+func (s *Service) requireDrive(userID int, fromURL string) (string, error) {
+	state, err := s.generateState(OAuthState{
+		FromURL: fromURL,
+		Scopes:  driveScopes,
+		UserID:  userID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	config := s.config(driveScopes...)
+	return config.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
 }
 
-func (s *Service) inspectDrive(userID int, q string) ([]DriveFile, string, error) {
-	user, err := s.repository.GetByID(userID)
+func (s *Service) inspectDrive(userID int, q, pageToken string) ([]DriveFile, string, error) {
+	ds, err := s.driveService(userID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	token := user.Tokens[drive.DriveFileScope]
-	client := s.config(drive.DriveFileScope).Client(oauth2.NoContext, token)
-	ds, err := s.driveServiceFactory(client)
+	folderID, err := s.getOrCreateFolder(ds)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ds.ListFiles(folderID, q, pageToken)
+}
+
+// addFile uploads a file to the Papernet folder of a user. The file is
+// recorded as processed, so that the sync does not create a paper for it.
+func (s *Service) addFile(userID, paperID int, filename, filetype string, data []byte) (DriveFile, error) {
+	ds, err := s.driveService(userID)
 	if err != nil {
-		return nil, "", fmt.Errorf("unable to create drive Client %v\n", err)
+		return DriveFile{}, err
 	}
 
 	folderID, err := s.getOrCreateFolder(ds)
 	if err != nil {
-		return nil, "", err
+		return DriveFile{}, err
+	}
+
+	file, err := ds.CreateFile(filename, filetype, folderID, data)
+	if err != nil {
+		return DriveFile{}, err
+	}
+
+	err = s.files.Save(DriveFileRecord{
+		UserID:   userID,
+		FileID:   file.ID,
+		PaperID:  paperID,
+		SyncedAt: time.Now(),
+	})
+	if err != nil {
+		return DriveFile{}, err
 	}
 
-	return ds.ListFiles(folderID, q)
+	return file, nil
 }
 
-func (s *Service) addFile(userID int, filename, filetype string, data []byte) (DriveFile, error) {
+// driveService returns the drive of a user, authenticated with the token they
+// granted when giving access to their drive. The token is refreshed if needed
+// before returning, so that an error requiring the user to grant access again
+// is returned early.
+func (s *Service) driveService(userID int) (DriveService, error) {
 	user, err := s.repository.GetByID(userID)
 	if err != nil {
-		return DriveFile{}, err
+		return nil, err
+	}
+
+	token, ok := user.Tokens[drive.DriveFileScope]
+	if !ok {
+		return nil, errors.New("access to the drive has not been granted", errors.Forbidden())
 	}
 
-	token := user.Tokens[drive.DriveFileScope]
-	client := s.config(drive.DriveFileScope).Client(oauth2.NoContext, token)
+	ts := oauth2.ReuseTokenSource(nil, s.tokenSource(userID, token, drive.DriveFileScope))
+	if _, err := ts.Token(); err != nil {
+		return nil, err
+	}
+
+	client := oauth2.NewClient(oauth2.NoContext, ts)
 	ds, err := s.driveServiceFactory(client)
 	if err != nil {
-		return DriveFile{}, fmt.Errorf("unable to create drive Client %v\n", err)
+		return nil, fmt.Errorf("unable to create drive Client %v\n", err)
 	}
+	return ds, nil
+}
 
-	folderID, err := s.getOrCreateFolder(ds)
+// disconnectDrive revokes the access to the drive of a user, and forgets the
+// token. The token is also forgotten for the other scopes it was granted with.
+func (s *Service) disconnectDrive(userID int) error {
+	user, err := s.repository.GetByID(userID)
 	if err != nil {
-		return DriveFile{}, err
+		return err
 	}
 
-	return ds.CreateFile(filename, filetype, folderID, data)
+	token, ok := user.Tokens[drive.DriveFileScope]
+	if !ok {
+		return nil
+	}
+
+	err = revoke(http.DefaultClient, token)
+	if err != nil {
+		return err
+	}
+
+	for scope, t := range user.Tokens {
+		if t == nil || t.AccessToken == token.AccessToken || (token.RefreshToken != "" && t.RefreshToken == token.RefreshToken) {
+			delete(user.Tokens, scope)
+		}
+	}
+
+	return s.repository.Upsert(user)
 }
 
 func (s *Service) getOrCreateFolder(ds DriveService) (string, error) {
@@ -240,7 +448,7 @@ func (s *Service) config(scopes ...string) *oauth2.Config {
 		ClientSecret: s.clientSecret,
 		RedirectURL:  s.redirectURL,
 		Scopes:       scopes,
-		Endpoint:     google.Endpoint,
+		Endpoint:     s.endpoint,
 	}
 }
 
@@ -261,29 +469,20 @@ func (s *Service) retrieveGoogleUser(tok *oauth2.Token) (googleUser, error) {
 	return user, nil
 }
 
-func (s *Service) generateState(fromURL string, scopes ...string) string {
+// generateState stores the information of a login until the user comes back
+// from Google, and returns the state identifying it.
+func (s *Service) generateState(info OAuthState) (string, error) {
 	state := randToken(32)
-	s.stateMutex.Lock()
-	s.state[state] = originInfo{
-		fromURL: fromURL,
-		scopes:  scopes,
-	}
-	s.stateMutex.Unlock()
-
-	go func() {
-		time.Sleep(15 * time.Minute)
-		s.stateMutex.Lock()
-		delete(s.state, state)
-		s.stateMutex.Unlock()
-	}()
 
-	return state
+	err := s.states.Save(state, info, stateTTL)
+	if err != nil {
+		return "", err
+	}
+	return state, nil
 }
 
-func (s *Service) checkState(state string) (originInfo, bool) {
-	s.stateMutex.Lock()
-	info, ok := s.state[state]
-	delete(s.state, state)
-	s.stateMutex.Unlock()
-	return info, ok
+// checkState returns the information stored with a state. A state can only be
+// used once, and not after it expired.
+func (s *Service) checkState(state string) (OAuthState, bool, error) {
+	return s.states.Pop(state)
 }
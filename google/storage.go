@@ -0,0 +1,77 @@
+package google
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+)
+
+// DriveStorage stores the attachments of the papers in the Papernet folder
+// of the drive of the user uploading them. The files are read and deleted
+// with the uploader's token, so every user allowed to see the paper can read
+// them, and every user allowed to edit it can delete them.
+//
+// Once the uploader disconnects their drive, or unlinks their Google account,
+// the files they uploaded cannot be read from Papernet anymore.
+type DriveStorage struct {
+	service *Service
+}
+
+func NewDriveStorage(service *Service) *DriveStorage {
+	return &DriveStorage{
+		service: service,
+	}
+}
+
+func (s *DriveStorage) Name() string {
+	return "drive"
+}
+
+func (s *DriveStorage) Save(a *papernet.Attachment, data []byte) error {
+	file, err := s.service.addFile(a.UserID, a.PaperID, a.Name, a.MimeType, data)
+	if err != nil {
+		return err
+	}
+
+	a.Key = file.ID
+	a.URL = file.URL
+	return nil
+}
+
+// Open downloads the file with the token of the uploader. It fails when the
+// uploader does not give access to their drive anymore.
+func (s *DriveStorage) Open(a papernet.Attachment) (io.ReadCloser, error) {
+	ds, err := s.service.driveService(a.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.DownloadFile(a.Key)
+}
+
+// Delete removes the file with the token of the uploader. When the uploader
+// does not give access to their drive anymore, the file is left in their
+// drive, which they own, so that the attachment can still be removed.
+func (s *DriveStorage) Delete(a papernet.Attachment) error {
+	ds, err := s.service.driveService(a.UserID)
+	if isDriveAccessLost(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return ds.DeleteFile(a.Key)
+}
+
+// isDriveAccessLost returns whether err means that the user never gave, or
+// does not give anymore, access to their drive.
+func isDriveAccessLost(err error) bool {
+	if _, ok := err.(reauthorizationError); ok {
+		return true
+	}
+
+	errWithCode, ok := err.(errors.Error)
+	return ok && errWithCode.Code() == http.StatusForbidden
+}
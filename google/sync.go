@@ -0,0 +1,215 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"gopkg.in/robfig/cron.v2"
+
+	"github.com/bobinette/papernet/clients/paper"
+	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/users"
+)
+
+const (
+	syncSpec = "0 */30 * * * *" // Every 30 minutes
+
+	// maxSyncFileSize is the size above which the synced files are not read,
+	// their paper is only named after them.
+	maxSyncFileSize = 32 << 20
+
+	folderMimeType = "application/vnd.google-apps.folder"
+	pdfMimeType    = "application/pdf"
+)
+
+// StartSync periodically syncs the drive of all the users.
+func (s *Service) StartSync(ctx context.Context, logger log.Logger) {
+	c := cron.New()
+	c.AddFunc(syncSpec, func() {
+		// cron does not recover from the panics, they would stop the service
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("sync of the drives panicked: %v", r)
+			}
+		}()
+
+		if err := s.SyncDrives(ctx, logger); err != nil {
+			logger.Errorf("could not sync drives: %v", err)
+		}
+	})
+	c.Start()
+}
+
+// SyncDrives syncs the drive of all the users who granted access to it. The
+// errors on the drive of a user are logged, not to block the other users.
+func (s *Service) SyncDrives(ctx context.Context, logger log.Logger) error {
+	googleUsers, err := s.repository.List()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range googleUsers {
+		if _, ok := user.Tokens[drive.DriveFileScope]; !ok {
+			continue
+		}
+
+		papers, err := s.SyncDrive(ctx, user.ID)
+		if err != nil {
+			logger.Errorf("could not sync drive of user %d: %v", user.ID, err)
+		}
+
+		if len(papers) > 0 {
+			logger.Printf("synced %d files of the drive of user %d", len(papers), user.ID)
+		}
+	}
+	return nil
+}
+
+// SyncDrive creates a paper for each file of the Papernet folder of a user
+// that was not processed yet, and returns the papers created. The papers are
+// pre-filled with the metadata of the PDF files, and refer to their file.
+//
+// A file whose sync panics is marked as synced, without paper, so that it
+// does not break every sync. The other files are still synced, and the error
+// is returned with the papers created.
+func (s *Service) SyncDrive(ctx context.Context, userID int) ([]paper.Paper, error) {
+	ds, err := s.driveService(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folderID, err := s.getOrCreateFolder(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	papers := make([]paper.Paper, 0)
+	var failed error
+	pageToken := ""
+	for {
+		files, next, err := ds.ListFiles(folderID, "", pageToken)
+		if err != nil {
+			return papers, err
+		}
+
+		ids := make([]string, len(files))
+		for i, file := range files {
+			ids[i] = file.ID
+		}
+
+		seen, err := s.files.Seen(userID, ids)
+		if err != nil {
+			return papers, err
+		}
+
+		for _, file := range files {
+			if seen[file.ID] || file.MimeType == folderMimeType {
+				continue
+			}
+
+			p, err := s.syncFile(ctx, userID, ds, file)
+			if _, ok := err.(syncPanicError); ok {
+				failed = err
+			} else if err != nil {
+				return papers, err
+			}
+
+			err = s.files.Save(DriveFileRecord{
+				UserID:   userID,
+				FileID:   file.ID,
+				PaperID:  p.ID,
+				SyncedAt: time.Now(),
+			})
+			if err != nil {
+				return papers, err
+			}
+
+			if p.ID != 0 {
+				papers = append(papers, p)
+			}
+		}
+
+		if next == "" {
+			return papers, failed
+		}
+		pageToken = next
+	}
+}
+
+// syncPanicError is returned when the sync of a file panicked.
+type syncPanicError struct {
+	fileID string
+	cause  interface{}
+}
+
+func (e syncPanicError) Error() string {
+	return fmt.Sprintf("sync of file %s panicked: %v", e.fileID, e.cause)
+}
+
+// syncFile creates the paper of a synced file, turning a panic into a
+// syncPanicError.
+func (s *Service) syncFile(ctx context.Context, userID int, ds DriveService, file DriveFile) (p paper.Paper, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = syncPanicError{fileID: file.ID, cause: r}
+		}
+	}()
+
+	return s.createPaper(ctx, userID, ds, file)
+}
+
+// createPaper creates the paper of a synced file, owned by the user.
+func (s *Service) createPaper(ctx context.Context, userID int, ds DriveService, file DriveFile) (paper.Paper, error) {
+	p := paper.Paper{
+		Title:      strings.TrimSuffix(file.Name, path.Ext(file.Name)),
+		References: make([]string, 0),
+	}
+	if file.URL != "" {
+		p.References = append(p.References, file.URL)
+	}
+
+	if file.MimeType == pdfMimeType && s.extractor != nil {
+		data, err := download(ds, file.ID)
+		if err != nil {
+			return paper.Paper{}, err
+		}
+
+		// The file is synced even if it cannot be read, the user completes
+		// the paper
+		if draft, err := s.extractor.ExtractDraft(data); err == nil {
+			if draft.Paper.Title != "" {
+				p.Title = draft.Paper.Title
+			}
+			p.Summary = draft.Paper.Summary
+			p.Authors = draft.Paper.Authors
+			p.Tags = draft.Paper.Tags
+			p.References = append(p.References, draft.Paper.References...)
+		}
+	}
+
+	userCtx := users.AddToContext(ctx, users.User{ID: userID})
+	return s.papers.Insert(userCtx, p)
+}
+
+// download reads a file of the drive. The files too large are not read.
+func download(ds DriveService, id string) ([]byte, error) {
+	r, err := ds.DownloadFile(id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSyncFileSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxSyncFileSize {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,193 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+
+	"github.com/bobinette/papernet/clients/paper"
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/users"
+)
+
+// fakeDrive is a drive with a single Papernet folder, listing its files two
+// by two.
+type fakeDrive struct {
+	files    []DriveFile
+	contents map[string][]byte
+}
+
+func (d *fakeDrive) UserHasAllowedDrive() (bool, error)      { return true, nil }
+func (d *fakeDrive) GetFolderID(name string) (string, error) { return "folder", nil }
+func (d *fakeDrive) CreateFolder(name string) (string, error) {
+	return "", fmt.Errorf("the folder exists")
+}
+func (d *fakeDrive) DeleteFile(id string) error { return nil }
+
+func (d *fakeDrive) ListFiles(folderID, name, pageToken string) ([]DriveFile, string, error) {
+	start := 0
+	if pageToken != "" {
+		fmt.Sscanf(pageToken, "%d", &start)
+	}
+
+	end := start + 2
+	if end >= len(d.files) {
+		return d.files[start:], "", nil
+	}
+	return d.files[start:end], fmt.Sprintf("%d", end), nil
+}
+
+func (d *fakeDrive) CreateFile(name, typ, folderID string, data []byte) (DriveFile, error) {
+	file := DriveFile{ID: fmt.Sprintf("created-%d", len(d.files)), Name: name, MimeType: typ}
+	d.files = append(d.files, file)
+	return file, nil
+}
+
+func (d *fakeDrive) DownloadFile(id string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(d.contents[id])), nil
+}
+
+// fakePapers records the papers created, giving them increasing ids.
+type fakePapers struct {
+	mu      sync.Mutex
+	papers  []paper.Paper
+	userIDs []int
+}
+
+func (p *fakePapers) Insert(ctx context.Context, pp paper.Paper) (paper.Paper, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return paper.Paper{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pp.ID = len(p.papers) + 1
+	p.papers = append(p.papers, pp)
+	p.userIDs = append(p.userIDs, user.ID)
+	return pp, nil
+}
+
+type fakeExtractor struct{}
+
+func (fakeExtractor) ExtractDraft(data []byte) (papernet.PaperDraft, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return papernet.PaperDraft{}, fmt.Errorf("not a pdf")
+	} else if bytes.Equal(data, []byte("%PDF-panic")) {
+		panic("malformed pdf")
+	}
+
+	return papernet.PaperDraft{
+		Paper: papernet.Paper{
+			Title:      string(bytes.TrimPrefix(data, []byte("%PDF-"))),
+			Authors:    []string{"Ada Lovelace"},
+			References: []string{"https://arxiv.org/abs/1234.5678"},
+		},
+	}, nil
+}
+
+func createSyncService(t *testing.T, ds *fakeDrive) (*Service, *fakePapers) {
+	repo := NewInmemRepository()
+	err := repo.Upsert(User{ID: 1, GoogleID: "1", Tokens: map[string]*oauth2.Token{drive.DriveFileScope: &oauth2.Token{AccessToken: "access"}}})
+	require.NoError(t, err)
+	err = repo.Upsert(User{ID: 2, GoogleID: "2"})
+	require.NoError(t, err)
+
+	papers := &fakePapers{}
+	service := &Service{
+		repository: repo,
+		driveServiceFactory: func(*http.Client) (DriveService, error) {
+			return ds, nil
+		},
+
+		files:     NewInmemDriveFileRepository(),
+		papers:    papers,
+		extractor: fakeExtractor{},
+	}
+	return service, papers
+}
+
+func TestSyncDrive(t *testing.T) {
+	ds := &fakeDrive{
+		files: []DriveFile{
+			{ID: "a", Name: "notes.txt", MimeType: "text/plain", URL: "http://drive/a"},
+			{ID: "b", Name: "sub", MimeType: folderMimeType},
+			{ID: "c", Name: "scan.pdf", MimeType: pdfMimeType, URL: "http://drive/c"},
+			{ID: "d", Name: "broken.pdf", MimeType: pdfMimeType, URL: "http://drive/d"},
+			{ID: "e", Name: "phone.pdf", MimeType: pdfMimeType},
+		},
+		contents: map[string][]byte{
+			"c": []byte("%PDF-Attention Is All You Need"),
+			"d": []byte("garbage"),
+			"e": []byte("%PDF-"),
+		},
+	}
+	service, papers := createSyncService(t, ds)
+
+	created, err := service.SyncDrive(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []paper.Paper{
+		{ID: 1, Title: "notes", References: []string{"http://drive/a"}},
+		{ID: 2, Title: "Attention Is All You Need", Authors: []string{"Ada Lovelace"}, References: []string{"http://drive/c", "https://arxiv.org/abs/1234.5678"}},
+		{ID: 3, Title: "broken", References: []string{"http://drive/d"}},
+		{ID: 4, Title: "phone", Authors: []string{"Ada Lovelace"}, References: []string{"https://arxiv.org/abs/1234.5678"}},
+	}, created)
+	assert.Equal(t, []int{1, 1, 1, 1}, papers.userIDs, "the papers should be created for the user")
+
+	// The files are synced only once
+	created, err = service.SyncDrive(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	// The files uploaded through Papernet are not synced
+	_, err = service.addFile(1, 4, "attachment.pdf", pdfMimeType, []byte("%PDF-attachment"))
+	require.NoError(t, err)
+	ds.files = append(ds.files, DriveFile{ID: "f", Name: "new.pdf", MimeType: pdfMimeType})
+	ds.contents["f"] = []byte("%PDF-New")
+
+	created, err = service.SyncDrive(context.Background(), 1)
+	require.NoError(t, err)
+	if assert.Len(t, created, 1) {
+		assert.Equal(t, "New", created[0].Title)
+	}
+
+	// Users without drive cannot sync
+	_, err = service.SyncDrive(context.Background(), 2)
+	assert.Error(t, err)
+}
+
+func TestSyncDrive_Panic(t *testing.T) {
+	ds := &fakeDrive{
+		files: []DriveFile{
+			{ID: "a", Name: "panic.pdf", MimeType: pdfMimeType},
+			{ID: "b", Name: "fine.pdf", MimeType: pdfMimeType},
+		},
+		contents: map[string][]byte{
+			"a": []byte("%PDF-panic"),
+			"b": []byte("%PDF-Fine"),
+		},
+	}
+	service, _ := createSyncService(t, ds)
+
+	// The other files are still synced
+	created, err := service.SyncDrive(context.Background(), 1)
+	assert.Error(t, err, "the panic should be returned")
+	if assert.Len(t, created, 1) {
+		assert.Equal(t, "Fine", created[0].Title)
+	}
+
+	// The file that panicked is not synced again
+	created, err = service.SyncDrive(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+}
@@ -2,6 +2,7 @@ package google
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,4 +23,98 @@ func TestRepository(t *testing.T, repo UserRepository) {
 	u, err = repo.GetByGoogleID(user.GoogleID)
 	assert.NoError(t, err)
 	assert.Equal(t, user, u)
+
+	other := User{
+		ID:       2,
+		GoogleID: "456",
+	}
+	err = repo.Upsert(other)
+	assert.NoError(t, err)
+
+	users, err := repo.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []User{user, other}, users)
+
+	// Linking another Google account replaces the previous one
+	user.GoogleID = "789"
+	err = repo.Upsert(user)
+	assert.NoError(t, err)
+
+	u, err = repo.GetByGoogleID("123")
+	assert.NoError(t, err)
+	assert.Equal(t, User{}, u)
+
+	u, err = repo.GetByGoogleID("789")
+	assert.NoError(t, err)
+	assert.Equal(t, user, u)
+
+	err = repo.Delete(user.ID)
+	assert.NoError(t, err)
+
+	u, err = repo.GetByID(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, User{}, u)
+
+	u, err = repo.GetByGoogleID(user.GoogleID)
+	assert.NoError(t, err)
+	assert.Equal(t, User{}, u)
+
+	users, err = repo.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []User{other}, users)
+}
+
+func TestDriveFileRepository(t *testing.T, repo DriveFileRepository) {
+	records := []DriveFileRecord{
+		{UserID: 1, FileID: "a", PaperID: 3},
+		{UserID: 1, FileID: "b"},
+		{UserID: 2, FileID: "c", PaperID: 4},
+	}
+	for _, record := range records {
+		err := repo.Save(record)
+		assert.NoError(t, err)
+	}
+
+	seen, err := repo.Seen(1, []string{"a", "b", "c", "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, seen)
+
+	seen, err = repo.Seen(2, []string{"a", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"c": true}, seen)
+
+	seen, err = repo.Seen(3, []string{"a"})
+	assert.NoError(t, err)
+	assert.Empty(t, seen)
+}
+
+func TestStateStore(t *testing.T, store StateStore) {
+	state := OAuthState{FromURL: "/papers", Scopes: []string{"a", "b"}}
+
+	err := store.Save("key", state, time.Minute)
+	assert.NoError(t, err)
+	err = store.Save("expired", state, -time.Second)
+	assert.NoError(t, err)
+
+	// A pending state is not replaced
+	err = store.Save("key", OAuthState{FromURL: "/other"}, time.Minute)
+	assert.Equal(t, ErrStateExists, err)
+
+	s, ok, err := store.Pop("key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, state, s)
+
+	// A state can only be used once
+	_, ok, err = store.Pop("key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = store.Pop("expired")
+	assert.NoError(t, err)
+	assert.False(t, ok, "an expired state should not be returned")
+
+	_, ok, err = store.Pop("unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
 }
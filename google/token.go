@@ -0,0 +1,150 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+// reauthorizationRequired is the reason returned along with the errors of
+// the tokens that cannot be refreshed anymore.
+const reauthorizationRequired = "reauthorization_required"
+
+// revokeURL is the endpoint revoking the Google tokens.
+var revokeURL = "https://oauth2.googleapis.com/revoke"
+
+// reauthorizationError is returned when a token cannot be refreshed, because
+// the user revoked it or it expired. The user has to grant access again.
+type reauthorizationError struct {
+	err errors.Error
+}
+
+func errReauthorizationRequired(cause error) error {
+	err := errors.New(
+		"access to Google has expired, it has to be granted again",
+		errors.WithCause(cause),
+		errors.Forbidden(),
+	)
+	return reauthorizationError{err: err.(errors.Error)}
+}
+
+func (e reauthorizationError) Error() string   { return e.err.Error() }
+func (e reauthorizationError) Code() int       { return e.err.Code() }
+func (e reauthorizationError) Message() string { return e.err.Message() }
+func (e reauthorizationError) Cause() error    { return e.err.Cause() }
+
+// isInvalidGrant returns whether the token endpoint refused to refresh a token
+// because it was revoked or expired. The other errors, the endpoint being
+// unavailable for instance, do not mean that the access is lost.
+//
+// The vendored oauth2 package does not have a typed error for the failures of
+// the token endpoint, it only adds the response to the message of the error.
+func isInvalidGrant(err error) bool {
+	const responsePrefix = "\nResponse: "
+
+	msg := err.Error()
+	i := strings.Index(msg, responsePrefix)
+	if i == -1 {
+		return false
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(msg[i+len(responsePrefix):]), &body); err != nil {
+		return false
+	}
+	return body.Error == "invalid_grant"
+}
+
+// persistingTokenSource writes the tokens refreshed back to the repository, so
+// that the refreshes are not lost when the service restarts.
+type persistingTokenSource struct {
+	source     oauth2.TokenSource
+	repository UserRepository
+	userID     int
+
+	mu      sync.Mutex
+	current *oauth2.Token
+}
+
+// tokenSource returns a token source refreshing the token of a user when it
+// expires.
+func (s *Service) tokenSource(userID int, token *oauth2.Token, scopes ...string) oauth2.TokenSource {
+	return &persistingTokenSource{
+		source:     s.config(scopes...).TokenSource(oauth2.NoContext, token),
+		repository: s.repository,
+		userID:     userID,
+		current:    token,
+	}
+}
+
+func (ts *persistingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.source.Token()
+	if err != nil && isInvalidGrant(err) {
+		return nil, errReauthorizationRequired(err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if tok.AccessToken == ts.current.AccessToken {
+		return tok, nil
+	}
+
+	err = ts.persist(tok)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.current = tok
+	return tok, nil
+}
+
+// persist replaces the token refreshed, which may be stored for several
+// scopes, by the new one.
+func (ts *persistingTokenSource) persist(tok *oauth2.Token) error {
+	user, err := ts.repository.GetByID(ts.userID)
+	if err != nil {
+		return err
+	} else if user.ID == 0 {
+		return nil
+	}
+
+	for scope, t := range user.Tokens {
+		if t != nil && t.AccessToken == ts.current.AccessToken {
+			user.Tokens[scope] = tok
+		}
+	}
+
+	return ts.repository.Upsert(user)
+}
+
+// revoke revokes a token at Google. Revoking a token that is not valid
+// anymore is not an error.
+func revoke(client *http.Client, token *oauth2.Token) error {
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	res, err := client.PostForm(revokeURL, url.Values{"token": {value}})
+	if err != nil {
+		return errors.New("could not revoke token", errors.WithCause(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusBadRequest {
+		return errors.New(fmt.Sprintf("could not revoke token: status %d", res.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+)
+
+// fakeTokenServer refreshes the token "refresh", fails temporarily for the
+// token "unavailable", and revokes any token.
+func fakeTokenServer(t *testing.T, revoked *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		switch r.URL.Path {
+		case "/token":
+			if r.PostForm.Get("refresh_token") == "unavailable" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			} else if r.PostForm.Get("refresh_token") != "refresh" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "refreshed",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		case "/revoke":
+			*revoked = append(*revoked, r.PostForm.Get("token"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func createTokenService(t *testing.T, ts *httptest.Server, tokens map[string]*oauth2.Token) (*Service, *InmemRepository) {
+	repo := NewInmemRepository()
+	err := repo.Upsert(User{ID: 1, GoogleID: "1", Tokens: tokens})
+	require.NoError(t, err)
+
+	ds := &fakeDrive{}
+	service := &Service{
+		repository: repo,
+		driveServiceFactory: func(*http.Client) (DriveService, error) {
+			return ds, nil
+		},
+		files: NewInmemDriveFileRepository(),
+
+		endpoint: oauth2.Endpoint{TokenURL: ts.URL + "/token"},
+	}
+	return service, repo
+}
+
+func TestDriveService_RefreshPersisted(t *testing.T) {
+	ts := fakeTokenServer(t, nil)
+	defer ts.Close()
+
+	expired := &oauth2.Token{AccessToken: "expired", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	service, repo := createTokenService(t, ts, map[string]*oauth2.Token{
+		drive.DriveFileScope: expired,
+		userInfoScope:        expired,
+	})
+
+	_, err := service.driveService(1)
+	require.NoError(t, err)
+
+	user, err := repo.GetByID(1)
+	require.NoError(t, err)
+	for _, scope := range []string{drive.DriveFileScope, userInfoScope} {
+		assert.Equal(t, "refreshed", user.Tokens[scope].AccessToken, "the token of %s should be replaced", scope)
+		assert.Equal(t, "refresh", user.Tokens[scope].RefreshToken, "the refresh token should be kept")
+	}
+}
+
+func TestDriveService_ReauthorizationRequired(t *testing.T) {
+	ts := fakeTokenServer(t, nil)
+	defer ts.Close()
+
+	revoked := &oauth2.Token{AccessToken: "expired", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Hour)}
+	service, _ := createTokenService(t, ts, map[string]*oauth2.Token{
+		drive.DriveFileScope:     revoked,
+		drive.DriveReadonlyScope: revoked,
+	})
+
+	_, err := service.driveService(1)
+	require.Error(t, err)
+	assert.IsType(t, reauthorizationError{}, err)
+
+	has, err := service.hasDrive(1)
+	assert.NoError(t, err)
+	assert.False(t, has, "the access to the drive is lost")
+
+	w := httptest.NewRecorder()
+	_, err = service.driveService(1)
+	encodeError(context.Background(), err, w)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, reauthorizationRequired, body["reason"])
+}
+
+func TestDriveService_RefreshUnavailable(t *testing.T) {
+	ts := fakeTokenServer(t, nil)
+	defer ts.Close()
+
+	token := &oauth2.Token{AccessToken: "expired", RefreshToken: "unavailable", Expiry: time.Now().Add(-time.Hour)}
+	service, repo := createTokenService(t, ts, map[string]*oauth2.Token{
+		drive.DriveFileScope:     token,
+		drive.DriveReadonlyScope: token,
+	})
+
+	_, err := service.driveService(1)
+	require.Error(t, err)
+	_, ok := err.(reauthorizationError)
+	assert.False(t, ok, "a failure of the token endpoint should not require to grant access again")
+
+	_, err = service.hasDrive(1)
+	assert.Error(t, err, "the access to the drive is not known to be lost")
+
+	user, err := repo.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, token, user.Tokens[drive.DriveFileScope], "the token should be kept")
+}
+
+func TestDisconnectDrive(t *testing.T) {
+	var revoked []string
+	ts := fakeTokenServer(t, &revoked)
+	defer ts.Close()
+
+	defer func(u string) { revokeURL = u }(revokeURL)
+	revokeURL = ts.URL + "/revoke"
+
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+	other := &oauth2.Token{AccessToken: "other"}
+	service, repo := createTokenService(t, ts, map[string]*oauth2.Token{
+		drive.DriveFileScope: token,
+		userInfoScope:        token,
+		"other":              other,
+	})
+
+	err := service.disconnectDrive(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refresh"}, revoked)
+
+	user, err := repo.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*oauth2.Token{"other": other}, user.Tokens)
+
+	_, err = service.driveService(1)
+	assert.Error(t, err, "the drive should not be accessible anymore")
+
+	// Disconnecting twice is fine
+	err = service.disconnectDrive(1)
+	assert.NoError(t, err)
+}
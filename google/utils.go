@@ -2,9 +2,9 @@ package google
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
-	"math/rand"
 	"net/http"
 
 	"github.com/bobinette/papernet/errors"
@@ -14,10 +14,13 @@ var (
 	errInvalidRequest = errors.New("invalid request")
 )
 
+// randToken generates a random url safe token. The tokens are the states of
+// the logins, that identify the user linking their Google account, so they
+// have to be unpredictable, including across restarts.
 func randToken(size int) string {
 	b := make([]byte, size)
 	rand.Read(b)
-	return base64.StdEncoding.EncodeToString(b)
+	return base64.RawURLEncoding.EncodeToString(b)
 }
 
 // Server defines the interface to register the http handlers.
@@ -32,8 +35,13 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	}
 	w.WriteHeader(statusCode)
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"error": err.Error(),
-	})
+	}
+	if _, ok := err.(reauthorizationError); ok {
+		body["reason"] = reauthorizationRequired
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(body)
 }
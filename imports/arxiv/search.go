@@ -24,10 +24,14 @@ var (
 	)
 
 	refRegexp *regexp.Regexp
+	// idRegexp matches the queries that are arXiv identifiers, looked up
+	// directly instead of searched.
+	idRegexp *regexp.Regexp
 )
 
 func init() {
 	refRegexp = regexp.MustCompile("http://arxiv.org/abs/([0-9.]*)(v[0-9]+)?")
+	idRegexp = regexp.MustCompile(`^(?:[0-9]{4}\.[0-9]{4,5}|[a-z][a-z.-]*/[0-9]{7})(?:v[0-9]+)?$`)
 
 	// Check if arxiv URL is valid
 	_, err := url.Parse(apiURLStr)
@@ -40,7 +44,10 @@ type responseEntry struct {
 	Title   string `xml:"title"`
 	ID      string `xml:"id"`
 	Summary string `xml:"summary"`
-	Links   []struct {
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Links []struct {
 		HRef string `xml:"href,attr"`
 		Type string `xml:"type,attr"`
 	} `xml:"link"`
@@ -145,7 +152,9 @@ func craftURL(q string, limit, offset int) *url.URL {
 	u, _ := url.Parse(apiURLStr)
 	query := u.Query()
 
-	if q != "" {
+	if q := strings.TrimSpace(q); idRegexp.MatchString(q) {
+		query.Add("id_list", q)
+	} else if q != "" {
 		re, _ := regexp.Compile("[A-Za-z0-9]+")
 		matches := re.FindAllStringSubmatch(q, -1)
 		qs := make([]string, len(matches))
@@ -186,6 +195,11 @@ func (i *Importer) parsePapers(r response) []imports.Paper {
 			}
 		}
 
+		authors := make([]string, len(entry.Authors))
+		for i, author := range entry.Authors {
+			authors[i] = strings.TrimSpace(author.Name)
+		}
+
 		papers[n] = imports.Paper{
 			Source:    i.source,
 			Reference: extractReference(entry.ID),
@@ -193,7 +207,7 @@ func (i *Importer) parsePapers(r response) []imports.Paper {
 			Title:   entry.Title,
 			Summary: summaryPipe(entry.Summary),
 			Tags:    tags,
-			// @TODO: authors
+			Authors: authors,
 			References: []string{
 				entry.Links[0].HRef, // link to arXiv
 				entry.Links[1].HRef, // PDF
@@ -38,6 +38,7 @@ func TestImporter_Search(t *testing.T) {
 			paper := res.Papers[i]
 			assert.Equal(t, ref, paper.Reference)
 			assert.Equal(t, "arxiv", paper.Source)
+			assert.NotEmpty(t, paper.Authors)
 		}
 	}
 }
@@ -84,6 +85,30 @@ func TestCraftURL(t *testing.T) {
 				"sortOrder":    []string{"descending"},
 			},
 		},
+		"arxiv id": {
+			q:      "1706.03762v5",
+			limit:  1,
+			offset: 0,
+			expected: map[string][]string{
+				"id_list":     []string{"1706.03762v5"},
+				"max_results": []string{"1"},
+				"start":       []string{"0"},
+				"sortBy":      []string{"submittedDate"},
+				"sortOrder":   []string{"descending"},
+			},
+		},
+		"old style arxiv id": {
+			q:      "hep-th/9901001",
+			limit:  1,
+			offset: 0,
+			expected: map[string][]string{
+				"id_list":     []string{"hep-th/9901001"},
+				"max_results": []string{"1"},
+				"start":       []string{"0"},
+				"sortBy":      []string{"submittedDate"},
+				"sortOrder":   []string{"descending"},
+			},
+		},
 	}
 
 	for name, tt := range tts {
@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"encoding/json"
-	"io/ioutil"
-
 	"github.com/bobinette/papernet/clients/auth"
 	"github.com/bobinette/papernet/clients/paper"
+	"github.com/bobinette/papernet/jwt"
 	"github.com/bobinette/papernet/log"
 
 	"github.com/bobinette/papernet/imports"
@@ -21,19 +19,10 @@ type Configuration struct {
 }
 
 func Start(srv imports.HTTPServer, conf Configuration, logger log.Logger, paperClient *paper.Client, authClient *auth.Client) {
-	// Load key from file
-	keyData, err := ioutil.ReadFile(conf.KeyPath)
-	if err != nil {
-		logger.Fatal("could not open key file:", err)
-	}
-
-	// Extract key from data
-	var key struct {
-		Key string `json:"k"`
-	}
-	err = json.Unmarshal(keyData, &key)
+	// Load keys from file. The services verifying the tokens only need the public keys.
+	keys, err := jwt.LoadKeySet(conf.KeyPath)
 	if err != nil {
-		logger.Fatal("could not read key file:", err)
+		logger.Fatal("could not load key file:", err)
 	}
 
 	driver := &bolt.Driver{}
@@ -47,5 +36,5 @@ func Start(srv imports.HTTPServer, conf Configuration, logger log.Logger, paperC
 	arxivSearcher := arxiv.NewSearcher()
 
 	service := imports.NewService(repo, paperClient, arxivSearcher)
-	service.RegisterHTTP(srv, []byte(key.Key), authClient)
+	service.RegisterHTTP(srv, keys, authClient)
 }
@@ -57,7 +57,7 @@ func extractUserID(ctx context.Context) (int, error) {
 	return ppnClaims.UserID, nil
 }
 
-func (s *Service) RegisterHTTP(srv HTTPServer, jwtKey []byte, ac *auth.Client) {
+func (s *Service) RegisterHTTP(srv HTTPServer, keys *jwt.KeySet, ac *auth.Client) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
@@ -65,8 +65,8 @@ func (s *Service) RegisterHTTP(srv HTTPServer, jwtKey []byte, ac *auth.Client) {
 
 	authenticator := users.NewAuthenticator(ac)
 
-	authenticationMiddleware := jwt.Middleware(jwtKey)
-	optionalAuthenticationMiddleware := jwt.OptionalMiddleware(jwtKey)
+	authenticationMiddleware := jwt.Middleware(keys, jwt.ScopeImports)
+	optionalAuthenticationMiddleware := jwt.OptionalMiddleware(keys, jwt.ScopeImports)
 
 	searchHandler := kithttp.NewServer(
 		optionalAuthenticationMiddleware(makeSearchEndpoint(s)),
@@ -76,7 +76,7 @@ func (s *Service) RegisterHTTP(srv HTTPServer, jwtKey []byte, ac *auth.Client) {
 	)
 
 	sourcesHandler := kithttp.NewServer(
-		authenticationMiddleware(makeSourcesEndpoint(s)),
+		authenticationMiddleware(authenticator.Valid(makeSourcesEndpoint(s))),
 		decodeSourcesRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
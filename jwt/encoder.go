@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -8,49 +10,137 @@ import (
 	"github.com/bobinette/papernet/errors"
 )
 
+// AccessTokenTTL is the validity of the access tokens. They are short lived so
+// that a revoked session does not stay usable for long in the services that
+// cannot check the revocations: a new access token is obtained with a refresh
+// token.
+const AccessTokenTTL = 15 * time.Minute
+
 type EncodeDecoder struct {
-	key []byte
+	keys *KeySet
 }
 
 type Claims struct {
 	UserID  int  `json:"user_id"`
 	IsAdmin bool `json:"is_admin"`
+
+	// TokenID and Scopes are only set for the personal API tokens. The tokens
+	// issued on login have all the scopes.
+	TokenID int      `json:"token_id,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+
 	jwt.StandardClaims
 }
 
+// NewEncodeDecoder creates an encoder signing the tokens with a single HS256 key.
 func NewEncodeDecoder(key []byte) *EncodeDecoder {
+	return NewKeySetEncodeDecoder(NewHS256KeySet(key))
+}
+
+// NewKeySetEncodeDecoder creates an encoder signing the tokens with the current
+// key of the set, and verifying them with any key of the set.
+func NewKeySetEncodeDecoder(keys *KeySet) *EncodeDecoder {
 	return &EncodeDecoder{
-		key: key,
+		keys: keys,
 	}
 }
 
+// Encode issues an access token for a user. Each token has a unique id (jti)
+// so that it can be revoked.
 func (e *EncodeDecoder) Encode(userID int, isAdmin bool) (string, error) {
+	now := time.Now()
 	claims := Claims{
 		UserID:  userID,
 		IsAdmin: isAdmin,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().AddDate(0, 2, 0).Unix(),
+			Id:        newTokenID(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(AccessTokenTTL).Unix(),
+			Issuer:    "papernet",
+		},
+	}
+
+	return e.keys.sign(claims)
+}
+
+// EncodeAPIToken issues a personal API token. API tokens are never admin, and
+// only give access to the endpoints accepting one of their scopes.
+func (e *EncodeDecoder) EncodeAPIToken(userID, tokenID int, scopes []string, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		TokenID: tokenID,
+		Scopes:  scopes,
+		StandardClaims: jwt.StandardClaims{
+			Id:        newTokenID(),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
 			Issuer:    "papernet",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(e.key)
+	return e.keys.sign(claims)
 }
 
+// Decode returns the user of an access token. The API tokens are rejected: they
+// are only accepted by the endpoints checking their scopes, see Middleware.
 func (e *EncodeDecoder) Decode(bearer string) (int, bool, error) {
 	claims := Claims{}
 
-	token, err := jwt.ParseWithClaims(bearer, &claims, func(token *jwt.Token) (interface{}, error) {
-		return e.key, nil
-	})
+	token, err := jwt.ParseWithClaims(bearer, &claims, e.keys.keyfunc)
 	if err != nil {
 		return 0, false, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if claims.IsAPIToken() {
+			return 0, false, errors.New("api tokens cannot be used on this endpoint")
+		}
 		return claims.UserID, claims.IsAdmin, nil
 	}
 
 	return 0, false, errors.New("could not get claims")
 }
+
+// InvitationClaims are the claims of the signed token sent in team invitation
+// links.
+type InvitationClaims struct {
+	InvitationID int `json:"invitation_id"`
+	jwt.StandardClaims
+}
+
+const invitationAudience = "invitation"
+
+func (e *EncodeDecoder) EncodeInvitation(invitationID int, expiresAt time.Time) (string, error) {
+	claims := InvitationClaims{
+		InvitationID: invitationID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  invitationAudience,
+			ExpiresAt: expiresAt.Unix(),
+			Issuer:    "papernet",
+		},
+	}
+
+	return e.keys.sign(claims)
+}
+
+func (e *EncodeDecoder) DecodeInvitation(tokenString string) (int, error) {
+	claims := InvitationClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims, e.keys.keyfunc)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims, ok := token.Claims.(*InvitationClaims); ok && token.Valid && claims.VerifyAudience(invitationAudience, true) {
+		return claims.InvitationID, nil
+	}
+
+	return 0, errors.New("invalid invitation token")
+}
+
+// newTokenID generates a random id for a token.
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
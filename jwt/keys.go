@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+// Algorithms supported for the signing keys
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Key is a key used to sign and verify tokens. HS256 keys are symmetric: every
+// service verifying the tokens can also issue them. With RS256 and EdDSA keys,
+// only the service issuing the tokens needs the private key, the other ones
+// only need the public key.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds the keys the tokens can be verified with, selected by the kid
+// header of the tokens. New tokens are signed with the current key.
+//
+// To rotate the keys, add a new key to the set and make it the current one.
+// The tokens signed with the previous key stay valid until they expire, after
+// which the previous key can be removed from the set.
+type KeySet struct {
+	current string
+	keys    map[string]Key
+}
+
+// NewHS256KeySet creates a key set with a single HS256 key, without id.
+func NewHS256KeySet(secret []byte) *KeySet {
+	return &KeySet{
+		current: "",
+		keys: map[string]Key{
+			"": {ID: "", Algorithm: HS256, signKey: secret, verifyKey: secret},
+		},
+	}
+}
+
+type jsonKey struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+
+	// Secret of the HS256 keys
+	Secret string `json:"k"`
+
+	// PEM encoded keys for RS256, base64 encoded keys for EdDSA
+	Private string `json:"private"`
+	Public  string `json:"public"`
+}
+
+// LoadKeySet reads a key set from a file. See ParseKeySet for the format.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet reads a key set from its JSON representation:
+//
+//	{
+//	  "current": "2017-10",
+//	  "keys": [
+//	    {"kid": "2017-10", "alg": "EdDSA", "private": "<base64>", "public": "<base64>"},
+//	    {"kid": "2017-09", "alg": "RS256", "public": "-----BEGIN PUBLIC KEY-----..."},
+//	    {"kid": "2017-08", "alg": "HS256", "k": "secret"}
+//	  ]
+//	}
+//
+// The private keys can be omitted for the services that only verify tokens. The
+// former format, {"k": "secret"}, is read as a single HS256 key without id.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var file struct {
+		Secret  string    `json:"k"`
+		Current string    `json:"current"`
+		Keys    []jsonKey `json:"keys"`
+	}
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Keys) == 0 {
+		if file.Secret == "" {
+			return nil, errors.New("no key in key set")
+		}
+		return NewHS256KeySet([]byte(file.Secret)), nil
+	}
+
+	ks := &KeySet{
+		current: file.Current,
+		keys:    make(map[string]Key, len(file.Keys)),
+	}
+	for _, jk := range file.Keys {
+		if _, ok := ks.keys[jk.ID]; ok {
+			return nil, errors.New(fmt.Sprintf("duplicate key id %q", jk.ID))
+		}
+
+		key, err := parseKey(jk)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid key %q", jk.ID), errors.WithCause(err))
+		}
+		ks.keys[key.ID] = key
+	}
+
+	if len(ks.keys) == 1 && ks.current == "" {
+		ks.current = file.Keys[0].ID
+	}
+	if _, ok := ks.keys[ks.current]; !ok {
+		return nil, errors.New(fmt.Sprintf("current key %q not found", ks.current))
+	}
+
+	return ks, nil
+}
+
+func parseKey(jk jsonKey) (Key, error) {
+	key := Key{ID: jk.ID, Algorithm: jk.Algorithm}
+
+	switch jk.Algorithm {
+	case HS256:
+		if jk.Secret == "" {
+			return Key{}, errors.New("missing secret")
+		}
+		key.signKey = []byte(jk.Secret)
+		key.verifyKey = []byte(jk.Secret)
+	case RS256:
+		if jk.Private != "" {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(jk.Private))
+			if err != nil {
+				return Key{}, err
+			}
+			key.signKey = private
+			key.verifyKey = &private.PublicKey
+		}
+		if jk.Public != "" {
+			public, err := jwt.ParseRSAPublicKeyFromPEM([]byte(jk.Public))
+			if err != nil {
+				return Key{}, err
+			}
+			key.verifyKey = public
+		}
+	case EdDSA:
+		if jk.Private != "" {
+			private, err := base64.StdEncoding.DecodeString(jk.Private)
+			if err != nil {
+				return Key{}, err
+			} else if len(private) != ed25519.PrivateKeySize {
+				return Key{}, errors.New("invalid ed25519 private key size")
+			}
+			key.signKey = ed25519.PrivateKey(private)
+			key.verifyKey = ed25519.PrivateKey(private).Public()
+		}
+		if jk.Public != "" {
+			public, err := base64.StdEncoding.DecodeString(jk.Public)
+			if err != nil {
+				return Key{}, err
+			} else if len(public) != ed25519.PublicKeySize {
+				return Key{}, errors.New("invalid ed25519 public key size")
+			}
+			key.verifyKey = ed25519.PublicKey(public)
+		}
+	default:
+		return Key{}, errors.New(fmt.Sprintf("unsupported algorithm %q", jk.Algorithm))
+	}
+
+	if key.verifyKey == nil {
+		return Key{}, errors.New("missing key")
+	}
+	return key, nil
+}
+
+// sign signs the claims with the current key of the set.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[ks.current]
+	if key.signKey == nil {
+		return "", errors.New(fmt.Sprintf("no private key for key %q", key.ID))
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
+}
+
+// keyfunc selects the key verifying a token from its kid header. The algorithm
+// of the token has to be the one of the key, so that a public key cannot be
+// used as an HMAC secret.
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown key %q", kid))
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case RS256:
+		return jwt.SigningMethodRS256
+	case EdDSA:
+		return SigningMethodEdDSA
+	}
+	return jwt.SigningMethodHS256
+}
@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+)
+
+func rsaKeys(t *testing.T) (string, string) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "generating rsa key must not fail")
+
+	public, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(t, err, "marshalling rsa public key must not fail")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+	return string(privatePEM), string(publicPEM)
+}
+
+func ed25519Keys(t *testing.T) (string, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, "generating ed25519 key must not fail")
+	return base64.StdEncoding.EncodeToString(private), base64.StdEncoding.EncodeToString(public)
+}
+
+func keySet(t *testing.T, current string, keys ...jsonKey) *KeySet {
+	data, err := json.Marshal(map[string]interface{}{"current": current, "keys": keys})
+	require.NoError(t, err, "marshalling key set must not fail")
+
+	ks, err := ParseKeySet(data)
+	require.NoError(t, err, "parsing key set must not fail")
+	return ks
+}
+
+func TestParseKeySet_Legacy(t *testing.T) {
+	ks, err := ParseKeySet([]byte(`{"k": "secret"}`))
+	require.NoError(t, err, "parsing legacy key must not fail")
+
+	token, err := NewKeySetEncodeDecoder(ks).Encode(1, true)
+	require.NoError(t, err, "encoding must not fail")
+
+	// Tokens are compatible with the ones of a plain HS256 secret
+	userID, isAdmin, err := NewEncodeDecoder([]byte("secret")).Decode(token)
+	if assert.NoError(t, err, "decoding should not fail") {
+		assert.Equal(t, 1, userID)
+		assert.True(t, isAdmin)
+	}
+
+	_, err = ParseKeySet([]byte(`{}`))
+	assert.Error(t, err, "parsing an empty key set should fail")
+}
+
+func TestParseKeySet_Errors(t *testing.T) {
+	tts := map[string]string{
+		"unknown algorithm": `{"keys": [{"kid": "1", "alg": "none"}]}`,
+		"missing key":       `{"keys": [{"kid": "1", "alg": "RS256"}]}`,
+		"invalid key":       `{"keys": [{"kid": "1", "alg": "EdDSA", "public": "cGl6emE="}]}`,
+		"duplicate key":     `{"current": "1", "keys": [{"kid": "1", "alg": "HS256", "k": "a"}, {"kid": "1", "alg": "HS256", "k": "b"}]}`,
+		"unknown current":   `{"current": "3", "keys": [{"kid": "1", "alg": "HS256", "k": "a"}, {"kid": "2", "alg": "HS256", "k": "b"}]}`,
+	}
+
+	for name, data := range tts {
+		_, err := ParseKeySet([]byte(data))
+		assert.Error(t, err, "%s: parsing should fail", name)
+	}
+}
+
+func TestKeySet_Algorithms(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaKeys(t)
+	edPrivate, edPublic := ed25519Keys(t)
+
+	tts := map[string]jsonKey{
+		HS256: {ID: "hs", Algorithm: HS256, Secret: "secret"},
+		RS256: {ID: "rs", Algorithm: RS256, Private: rsaPrivate, Public: rsaPublic},
+		EdDSA: {ID: "ed", Algorithm: EdDSA, Private: edPrivate, Public: edPublic},
+	}
+
+	for alg, key := range tts {
+		encoder := NewKeySetEncodeDecoder(keySet(t, key.ID, key))
+
+		token, err := encoder.Encode(1, false)
+		require.NoError(t, err, "%s: encoding must not fail", alg)
+
+		userID, _, err := encoder.Decode(token)
+		if assert.NoError(t, err, "%s: decoding should not fail", alg) {
+			assert.Equal(t, 1, userID, "%s: invalid user id", alg)
+		}
+
+		invitation, err := encoder.EncodeInvitation(2, time.Now().Add(time.Hour))
+		require.NoError(t, err, "%s: encoding invitation must not fail", alg)
+
+		invitationID, err := encoder.DecodeInvitation(invitation)
+		if assert.NoError(t, err, "%s: decoding invitation should not fail", alg) {
+			assert.Equal(t, 2, invitationID, "%s: invalid invitation id", alg)
+		}
+
+		// The access tokens are not invitations
+		_, err = encoder.DecodeInvitation(token)
+		assert.Error(t, err, "%s: decoding an access token as an invitation should fail", alg)
+	}
+}
+
+func TestKeySet_PublicOnly(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaKeys(t)
+
+	signer := NewKeySetEncodeDecoder(keySet(t, "rs", jsonKey{ID: "rs", Algorithm: RS256, Private: rsaPrivate}))
+	verifier := NewKeySetEncodeDecoder(keySet(t, "rs", jsonKey{ID: "rs", Algorithm: RS256, Public: rsaPublic}))
+
+	token, err := signer.Encode(1, false)
+	require.NoError(t, err, "encoding must not fail")
+
+	userID, _, err := verifier.Decode(token)
+	if assert.NoError(t, err, "decoding with the public key should not fail") {
+		assert.Equal(t, 1, userID)
+	}
+
+	_, err = verifier.Encode(1, false)
+	assert.Error(t, err, "encoding without private key should fail")
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	edPrivate, edPublic := ed25519Keys(t)
+	oldKey := jsonKey{ID: "old", Algorithm: HS256, Secret: "secret"}
+	newKey := jsonKey{ID: "new", Algorithm: EdDSA, Private: edPrivate, Public: edPublic}
+
+	before := NewKeySetEncodeDecoder(keySet(t, "old", oldKey))
+	during := NewKeySetEncodeDecoder(keySet(t, "new", oldKey, newKey))
+	after := NewKeySetEncodeDecoder(keySet(t, "new", newKey))
+
+	oldToken, err := before.Encode(1, false)
+	require.NoError(t, err, "encoding with the old key must not fail")
+	newToken, err := during.Encode(1, false)
+	require.NoError(t, err, "encoding with the new key must not fail")
+
+	_, _, err = during.Decode(oldToken)
+	assert.NoError(t, err, "tokens signed with the old key should be valid during the rotation")
+	_, _, err = during.Decode(newToken)
+	assert.NoError(t, err, "tokens signed with the new key should be valid during the rotation")
+
+	_, _, err = after.Decode(oldToken)
+	assert.Error(t, err, "tokens signed with a removed key should be invalid")
+	_, _, err = after.Decode(newToken)
+	assert.NoError(t, err, "tokens signed with the new key should be valid after the rotation")
+}
+
+func TestKeySet_AlgorithmMismatch(t *testing.T) {
+	_, rsaPublic := rsaKeys(t)
+	ks := keySet(t, "rs", jsonKey{ID: "rs", Algorithm: RS256, Public: rsaPublic})
+
+	// A token signed with HS256 using the public key as secret must not be
+	// accepted
+	forger := &KeySet{
+		current: "rs",
+		keys: map[string]Key{
+			"rs": {ID: "rs", Algorithm: HS256, signKey: []byte(rsaPublic), verifyKey: []byte(rsaPublic)},
+		},
+	}
+	token, err := NewKeySetEncodeDecoder(forger).Encode(1, true)
+	require.NoError(t, err, "encoding must not fail")
+
+	_, _, err = NewKeySetEncodeDecoder(ks).Decode(token)
+	assert.Error(t, err, "decoding a token with an unexpected algorithm should fail")
+}
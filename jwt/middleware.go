@@ -2,21 +2,74 @@ package jwt
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/dgrijalva/jwt-go"
 
 	kitjwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/endpoint"
+
+	"github.com/bobinette/papernet/errors"
 )
 
-func Middleware(key []byte) endpoint.Middleware {
-	claims := Claims{}
-	return kitjwt.NewParser(func(token *jwt.Token) (interface{}, error) {
-		return key, nil
-	}, jwt.SigningMethodHS256, &claims)
+// Revoker tells whether a token has been revoked before its expiration, for
+// instance because its user logged out.
+type Revoker interface {
+	IsRevoked(claims *Claims) (bool, error)
+}
+
+// Middleware checks the token stored in the context by the transport, and adds
+// its claims to the context. The personal API tokens are only accepted if they
+// have one of scopes: without scopes, the endpoint is restricted to the tokens
+// issued on login.
+func Middleware(keys *KeySet, scopes ...string) endpoint.Middleware {
+	return RevocableMiddleware(keys, nil, scopes...)
+}
+
+// RevocableMiddleware is Middleware, also rejecting the tokens revoked according
+// to revoker. Only the auth service knows about the revocations, the other
+// services rely on the short validity of the access tokens.
+func RevocableMiddleware(keys *KeySet, revoker Revoker, scopes ...string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			// tokenString is stored in the context from the transport handlers.
+			tokenString, ok := ctx.Value(kitjwt.JWTTokenContextKey).(string)
+			if !ok {
+				return nil, errors.New("no token", errors.WithCode(http.StatusUnauthorized))
+			}
+
+			claims := &Claims{}
+			token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyfunc)
+			if err != nil {
+				if e, ok := err.(*jwt.ValidationError); ok && e.Errors&jwt.ValidationErrorExpired != 0 {
+					return nil, errors.New("token expired", errors.WithCode(http.StatusUnauthorized))
+				}
+				return nil, errors.New("invalid token", errors.WithCode(http.StatusUnauthorized), errors.WithCause(err))
+			} else if !token.Valid {
+				return nil, errors.New("invalid token", errors.WithCode(http.StatusUnauthorized))
+			}
+
+			err = checkScope(claims, scopes)
+			if err != nil {
+				return nil, err
+			}
+
+			if revoker != nil {
+				revoked, err := revoker.IsRevoked(claims)
+				if err != nil {
+					return nil, err
+				} else if revoked {
+					return nil, errors.New("token revoked", errors.WithCode(http.StatusUnauthorized))
+				}
+			}
+
+			ctx = context.WithValue(ctx, kitjwt.JWTClaimsContextKey, claims)
+			return next(ctx, request)
+		}
+	}
 }
 
-func OptionalMiddleware(key []byte) endpoint.Middleware {
+func OptionalMiddleware(keys *KeySet, scopes ...string) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 			// tokenString is stored in the context from the transport handlers.
@@ -25,7 +78,7 @@ func OptionalMiddleware(key []byte) endpoint.Middleware {
 				return next(ctx, request)
 			}
 
-			return Middleware(key)(next)(ctx, request)
+			return Middleware(keys, scopes...)(next)(ctx, request)
 		}
 	}
 }
@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+// Scopes of the personal API tokens
+const (
+	ScopePapersRead  = "papers:read"
+	ScopePapersWrite = "papers:write"
+	ScopeImports     = "imports"
+	ScopeCrons       = "crons"
+)
+
+// Scopes lists all the scopes an API token can be given.
+var Scopes = []string{ScopePapersRead, ScopePapersWrite, ScopeImports, ScopeCrons}
+
+// IsAPIToken tells whether the claims are the ones of a personal API token.
+func (c *Claims) IsAPIToken() bool {
+	return c.TokenID != 0
+}
+
+// HasScope tells whether the token gives access to an endpoint accepting any of
+// scopes. The tokens issued on login have all the scopes, whereas the API
+// tokens can only be used on the endpoints accepting one of their scopes.
+func (c *Claims) HasScope(scopes ...string) bool {
+	if !c.IsAPIToken() {
+		return true
+	}
+
+	for _, scope := range scopes {
+		for _, s := range c.Scopes {
+			if s == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidScope tells whether scope is one of the scopes an API token can have.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScope rejects the claims that do not have any of the scopes.
+func checkScope(claims *Claims, scopes []string) error {
+	if claims.HasScope(scopes...) {
+		return nil
+	}
+
+	if len(scopes) == 0 {
+		return errors.New("api tokens cannot be used on this endpoint", errors.Forbidden())
+	}
+	return errors.New(fmt.Sprintf("insufficient scope: %v required", scopes), errors.Forbidden())
+}
+
+// ClaimsFromContext returns the claims stored in the context by the middleware.
+func ClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(kitjwt.JWTClaimsContextKey).(*Claims)
+	if !ok {
+		return nil, errors.New("no claims", errors.WithCode(http.StatusUnauthorized))
+	}
+	return claims, nil
+}
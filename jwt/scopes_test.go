@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+func TestMiddleware_Scopes(t *testing.T) {
+	keys := NewHS256KeySet([]byte("secret"))
+	encoder := NewKeySetEncodeDecoder(keys)
+
+	session, err := encoder.Encode(1, false)
+	require.NoError(t, err, "encoding session token must not fail")
+	reader, err := encoder.EncodeAPIToken(1, 1, []string{ScopePapersRead}, time.Now().Add(time.Hour))
+	require.NoError(t, err, "encoding api token must not fail")
+	importer, err := encoder.EncodeAPIToken(1, 2, []string{ScopeImports}, time.Now().Add(time.Hour))
+	require.NoError(t, err, "encoding api token must not fail")
+
+	next := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return ClaimsFromContext(ctx)
+	}
+
+	tts := map[string]struct {
+		token  string
+		scopes []string
+		code   int
+	}{
+		"session on session only endpoint":   {token: session, code: 200},
+		"session on scoped endpoint":         {token: session, scopes: []string{ScopeCrons}, code: 200},
+		"api token on session only endpoint": {token: reader, code: 403},
+		"api token with scope":               {token: reader, scopes: []string{ScopePapersRead, ScopePapersWrite}, code: 200},
+		"api token without scope":            {token: importer, scopes: []string{ScopePapersRead, ScopePapersWrite}, code: 403},
+	}
+
+	for name, tt := range tts {
+		ctx := context.WithValue(context.Background(), kitjwt.JWTTokenContextKey, tt.token)
+		res, err := Middleware(keys, tt.scopes...)(next)(ctx, nil)
+		if tt.code != 200 {
+			if assert.Error(t, err, "%s: middleware should fail", name) {
+				errors.AssertCode(t, err, tt.code)
+			}
+			continue
+		}
+
+		if assert.NoError(t, err, "%s: middleware should not fail", name) {
+			assert.Equal(t, 1, res.(*Claims).UserID, "%s: claims should be in the context", name)
+		}
+	}
+
+	// Optional authentication also checks the scopes
+	ctx := context.WithValue(context.Background(), kitjwt.JWTTokenContextKey, reader)
+	_, err = OptionalMiddleware(keys, ScopeImports)(next)(ctx, nil)
+	if assert.Error(t, err, "api token without scope should be rejected by the optional middleware") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// API tokens cannot be decoded as access tokens
+	_, _, err = encoder.Decode(reader)
+	assert.Error(t, err, "decoding an api token should fail")
+}
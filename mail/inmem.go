@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemTransport keeps the emails in memory. It is used in tests to check
+// what would have been sent.
+type InMemTransport struct {
+	mu       sync.Locker
+	messages []Message
+}
+
+func NewInMemTransport() *InMemTransport {
+	return &InMemTransport{
+		mu:       &sync.Mutex{},
+		messages: make([]Message, 0),
+	}
+}
+
+func (t *InMemTransport) Send(ctx context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = append(t.messages, msg)
+	return nil
+}
+
+// Messages returns the emails sent so far.
+func (t *InMemTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	messages := make([]Message, len(t.messages))
+	copy(messages, t.messages)
+	return messages
+}
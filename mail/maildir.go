@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirTransport writes the emails in a local maildir instead of sending
+// them. It is meant for development: the directory can be opened with any
+// mail client supporting the maildir format.
+type MaildirTransport struct {
+	dir string
+
+	hostname string
+	counter  uint64
+}
+
+// NewMaildirTransport creates the maildir structure (tmp, new and cur) under
+// dir if it does not exist yet.
+func NewMaildirTransport(dir string) (*MaildirTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &MaildirTransport{
+		dir:      dir,
+		hostname: hostname,
+	}, nil
+}
+
+// Send writes the message in tmp and then moves it to new, so readers never
+// see a partially written email.
+func (t *MaildirTransport) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf(
+		"%d.M%dP%dQ%d.%s",
+		now.Unix(), now.Nanosecond()/1000, os.Getpid(), atomic.AddUint64(&t.counter, 1), t.hostname,
+	)
+
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(t.dir, "new", name))
+}
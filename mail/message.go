@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Message is an email with an HTML body and its plain text alternative.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+
+	HTML string
+	Text string
+}
+
+// Bytes formats the message as a MIME multipart/alternative email, ready to
+// be sent. The plain text part comes first so that clients that cannot
+// display HTML fall back to it.
+func (m Message) Bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	headers := []struct {
+		key   string
+		value string
+	}{
+		{key: "From", value: fmt.Sprintf("\"Papernet\" <%s>", m.From)},
+		{key: "To", value: strings.Join(m.To, ", ")},
+		{key: "Subject", value: mime.QEncoding.Encode("utf-8", m.Subject)},
+		{key: "Date", value: time.Now().Format(time.RFC1123Z)},
+		{key: "MIME-Version", value: "1.0"},
+		{key: "Content-Type", value: fmt.Sprintf("multipart/alternative; boundary=%q", w.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=\"UTF-8\"", content: m.Text},
+		{contentType: "text/html; charset=\"UTF-8\"", content: m.HTML},
+	}
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := pw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,151 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+// Transport defines the interface to send emails.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// TLSMode defines how the connection to the SMTP server is secured.
+type TLSMode string
+
+const (
+	// TLSNone sends the emails in clear text. It should only be used with a
+	// local relay.
+	TLSNone TLSMode = "none"
+	// TLSStartTLS connects in clear text and upgrades the connection with
+	// the STARTTLS command. It is the default.
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit opens a TLS connection from the start, usually on port 465.
+	TLSImplicit TLSMode = "tls"
+)
+
+var dialTimeout = 30 * time.Second
+
+// SMTPTransport sends the emails through an SMTP server.
+type SMTPTransport struct {
+	server   string
+	port     int
+	username string
+	password string
+	tlsMode  TLSMode
+}
+
+// NewSMTPTransport creates a transport connecting to server. When port is 0,
+// the default port for the TLS mode is used.
+func NewSMTPTransport(server string, port int, username, password string, tlsMode TLSMode) (*SMTPTransport, error) {
+	if tlsMode == "" {
+		tlsMode = TLSStartTLS
+	}
+
+	defaultPort := 0
+	switch tlsMode {
+	case TLSNone:
+		defaultPort = 25
+	case TLSStartTLS:
+		defaultPort = 587
+	case TLSImplicit:
+		defaultPort = 465
+	default:
+		return nil, errors.New("unknown tls mode " + string(tlsMode))
+	}
+
+	if port == 0 {
+		port = defaultPort
+	}
+
+	return &SMTPTransport{
+		server:   server,
+		port:     port,
+		username: username,
+		password: password,
+		tlsMode:  tlsMode,
+	}, nil
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	tlsConfig := &tls.Config{ServerName: t.server}
+	if t.tlsMode == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if t.password != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.username, t.password, t.server)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (t *SMTPTransport) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(t.server, strconv.Itoa(t.port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if t.tlsMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: t.server})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, t.server)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -18,7 +18,9 @@ type Server interface {
 	RegisterHandler(path, method string, f http.Handler)
 }
 
-func Start(srv Server, cfg Configuration, logger log.Logger) {
+// Start registers the route listing the login providers. The providers of the
+// other services, such as the OpenID Connect ones, are passed in providers.
+func Start(srv Server, cfg Configuration, logger log.Logger, providers ...string) {
 	providerService := oauth.NewProviderService()
 
 	// Basic email / password
@@ -31,5 +33,9 @@ func Start(srv Server, cfg Configuration, logger log.Logger) {
 		providerService.Register("google")
 	}
 
+	for _, provider := range providers {
+		providerService.Register(provider)
+	}
+
 	oauth.RegisterProviderHTTPRoutes(srv, providerService)
 }
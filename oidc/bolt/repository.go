@@ -0,0 +1,58 @@
+package bolt
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/oidc"
+)
+
+var identityBucket = []byte("identities")
+
+type IdentityRepository struct {
+	driver *Driver
+}
+
+func NewIdentityRepository(driver *Driver) *IdentityRepository {
+	return &IdentityRepository{
+		driver: driver,
+	}
+}
+
+func (r *IdentityRepository) Get(provider, subject string) (oidc.Identity, error) {
+	var identity oidc.Identity
+	err := r.driver.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(identityBucket)
+
+		data := bucket.Get(identityKey(provider, subject))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &identity)
+	})
+	if err != nil {
+		return oidc.Identity{}, err
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepository) Upsert(identity oidc.Identity) error {
+	return r.driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(identityBucket)
+		data, err := json.Marshal(identity)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(identityKey(identity.Provider, identity.Subject), data)
+	})
+}
+
+// identityKey builds the key of an identity. The subjects are only unique for
+// a provider.
+func identityKey(provider, subject string) []byte {
+	return []byte(provider + "\x00" + subject)
+}
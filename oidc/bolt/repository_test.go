@@ -0,0 +1,27 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/oidc"
+)
+
+func TestRepository(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+
+	filename := tmpFile.Name()
+	defer os.Remove(filename)
+
+	driver := Driver{}
+	err = driver.Open(filename)
+	require.NoError(t, err)
+	defer driver.Close()
+
+	repo := NewIdentityRepository(&driver)
+	oidc.TestRepository(t, repo)
+}
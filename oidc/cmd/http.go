@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bobinette/papernet/log"
+
+	"github.com/bobinette/papernet/clients/auth"
+
+	"github.com/bobinette/papernet/oidc"
+	"github.com/bobinette/papernet/oidc/bolt"
+)
+
+type Configuration struct {
+	Bolt      string                `toml:"bolt"`
+	Providers []oidc.ProviderConfig `toml:"providers"`
+}
+
+// Start registers the login routes of the configured providers, and returns
+// the names of the providers available. The providers that cannot be
+// discovered are skipped.
+func Start(srv oidc.Server, cfg Configuration, logger log.Logger, authClient *auth.Client) []string {
+	if len(cfg.Providers) == 0 {
+		return nil
+	}
+
+	boltDriver := &bolt.Driver{}
+	if err := boltDriver.Open(cfg.Bolt); err != nil {
+		logger.Fatal("could not open bolt driver", err)
+	}
+	repository := bolt.NewIdentityRepository(boltDriver)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	services := make([]*oidc.Service, 0, len(cfg.Providers))
+	names := make([]string, 0, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		service, err := oidc.NewService(provider, client, repository, authClient)
+		if err != nil {
+			logger.Error("could not start oidc provider:", err)
+			continue
+		}
+
+		services = append(services, service)
+		names = append(names, service.Name())
+	}
+
+	oidc.RegisterHTTPRoutes(srv, services)
+	return names
+}
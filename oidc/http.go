@@ -0,0 +1,127 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+// RegisterHTTPRoutes registers the login routes of the providers, under
+// /oidc/:provider/login.
+func RegisterHTTPRoutes(srv Server, services []*Service) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	providers := make(map[string]*Service, len(services))
+	for _, s := range services {
+		providers[s.Name()] = s
+	}
+
+	loginURLHandler := kithttp.NewServer(
+		makeLoginURLEndpoint(providers),
+		decodeLoginURLRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	loginHandler := kithttp.NewServer(
+		makeLoginEndpoint(providers),
+		decodeLoginRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	srv.RegisterHandler("/oidc/:provider/login", "GET", loginURLHandler)
+	srv.RegisterHandler("/oidc/:provider/login", "POST", loginHandler)
+}
+
+type LoginURLRequest struct {
+	Provider string
+	FromURL  string
+}
+
+func makeLoginURLEndpoint(providers map[string]*Service) endpoint.Endpoint {
+	return func(_ context.Context, r interface{}) (interface{}, error) {
+		req, ok := r.(LoginURLRequest)
+		if !ok {
+			return nil, errInvalidRequest
+		}
+
+		s, err := provider(providers, req.Provider)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"url": s.LoginURL(req.FromURL)}, nil
+	}
+}
+
+func decodeLoginURLRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	return LoginURLRequest{
+		Provider: params["provider"],
+		FromURL:  r.URL.Query().Get("from"),
+	}, nil
+}
+
+type LoginRequest struct {
+	Provider string `json:"-"`
+	State    string `json:"state"`
+	Code     string `json:"code"`
+}
+
+func makeLoginEndpoint(providers map[string]*Service) endpoint.Endpoint {
+	return func(_ context.Context, r interface{}) (interface{}, error) {
+		req, ok := r.(LoginRequest)
+		if !ok {
+			return nil, errInvalidRequest
+		}
+
+		s, err := provider(providers, req.Provider)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens, fromURL, err := s.Login(req.State, req.Code)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"fromURL":       fromURL,
+		}, nil
+	}
+}
+
+func decodeLoginRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	params := ctx.Value("params").(map[string]string)
+	req.Provider = params["provider"]
+	return req, nil
+}
+
+func provider(providers map[string]*Service, name string) (*Service, error) {
+	s, ok := providers[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown provider %s", name), errors.NotFound())
+	}
+	return s, nil
+}
@@ -0,0 +1,32 @@
+package oidc
+
+type InmemRepository struct {
+	identities []Identity
+}
+
+func NewInmemRepository() *InmemRepository {
+	return &InmemRepository{
+		identities: make([]Identity, 0),
+	}
+}
+
+func (r InmemRepository) Get(provider, subject string) (Identity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return Identity{}, nil
+}
+
+func (r *InmemRepository) Upsert(identity Identity) error {
+	for i, id := range r.identities {
+		if id.Provider == identity.Provider && id.Subject == identity.Subject {
+			r.identities[i] = identity
+			return nil
+		}
+	}
+
+	r.identities = append(r.identities, identity)
+	return nil
+}
@@ -0,0 +1,10 @@
+package oidc
+
+import (
+	"testing"
+)
+
+func TestInmemRepository(t *testing.T) {
+	repo := NewInmemRepository()
+	TestRepository(t, repo)
+}
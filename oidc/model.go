@@ -0,0 +1,35 @@
+package oidc
+
+import (
+	"github.com/bobinette/papernet/clients/auth"
+)
+
+// ProviderConfig configures an OpenID Connect provider. The endpoints of the
+// provider are discovered from its issuer.
+type ProviderConfig struct {
+	// Name identifies the provider in the urls and the list of providers
+	Name         string   `toml:"name"`
+	Issuer       string   `toml:"issuer"`
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	RedirectURL  string   `toml:"redirect_url"`
+	Scopes       []string `toml:"scopes"`
+}
+
+// Identity links the subject of a provider to a papernet user.
+type Identity struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	UserID   int    `json:"userID"`
+}
+
+type IdentityRepository interface {
+	Get(provider, subject string) (Identity, error)
+	Upsert(Identity) error
+}
+
+// UserClient creates the users in the auth service and issues their tokens.
+type UserClient interface {
+	Upsert(auth.User) (auth.User, error)
+	Token(id int) (auth.Tokens, error)
+}
@@ -0,0 +1,351 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/errors"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// discovery holds the parts of the provider metadata used by the service.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+}
+
+// audience is the aud claim of an id token, either a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var aud string
+	if err := json.Unmarshal(data, &aud); err == nil {
+		*a = audience{aud}
+		return nil
+	}
+
+	var auds []string
+	if err := json.Unmarshal(data, &auds); err != nil {
+		return err
+	}
+	*a = audience(auds)
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+type idTokenClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	Nonce     string   `json:"nonce"`
+
+	userInfo
+}
+
+type userInfo struct {
+	Subject       string `json:"sub"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// Service logs users in with an OpenID Connect provider, using the
+// authorization code flow with PKCE. The users are linked to papernet users by
+// their verified email on their first login, and by their subject afterwards.
+type Service struct {
+	config    ProviderConfig
+	discovery discovery
+
+	client     HTTPClient
+	repository IdentityRepository
+	userClient UserClient
+
+	states *stateStore
+}
+
+// NewService creates a service for a provider, discovering its endpoints.
+func NewService(config ProviderConfig, client HTTPClient, repo IdentityRepository, userClient UserClient) (*Service, error) {
+	if config.Name == "" {
+		return nil, errors.New("provider without name")
+	}
+
+	s := &Service{
+		config: config,
+
+		client:     client,
+		repository: repo,
+		userClient: userClient,
+
+		states: newStateStore(),
+	}
+
+	err := s.discover()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("could not discover provider %s", config.Name), errors.WithCause(err))
+	}
+
+	return s, nil
+}
+
+// Name returns the name of the provider.
+func (s *Service) Name() string {
+	return s.config.Name
+}
+
+// LoginURL returns the url the user is redirected to in order to log in with
+// the provider. The user is sent back to fromURL after logging in.
+func (s *Service) LoginURL(fromURL string) string {
+	state := loginState{
+		fromURL:  fromURL,
+		verifier: randToken(32),
+		nonce:    randToken(16),
+	}
+	key := s.states.add(state)
+
+	scopes := append([]string{"openid", "email", "profile"}, s.config.Scopes...)
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.config.ClientID},
+		"redirect_uri":          {s.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {key},
+		"nonce":                 {state.nonce},
+		"code_challenge":        {codeChallenge(state.verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(s.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return s.discovery.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Login completes the login of a user coming back from the provider, and
+// returns their papernet tokens and the url they started from.
+func (s *Service) Login(state, code string) (auth.Tokens, string, error) {
+	ls, ok := s.states.pop(state)
+	if !ok {
+		return auth.Tokens{}, "", errors.New("invalid or expired state", errors.BadRequest())
+	}
+
+	tok, err := s.exchange(code, ls.verifier)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	claims, err := s.verifyIDToken(tok.IDToken, ls.nonce)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	info := claims.userInfo
+	if info.Email == "" && s.discovery.UserInfoEndpoint != "" {
+		info, err = s.fetchUserInfo(tok.AccessToken)
+		if err != nil {
+			return auth.Tokens{}, "", err
+		} else if info.Subject != claims.Subject {
+			return auth.Tokens{}, "", errors.New("user info of another subject", errors.WithCode(http.StatusUnauthorized))
+		}
+	}
+
+	identity, err := s.repository.Get(s.config.Name, claims.Subject)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	if identity.UserID == 0 {
+		userID, err := s.link(info)
+		if err != nil {
+			return auth.Tokens{}, "", err
+		}
+
+		identity = Identity{Provider: s.config.Name, Subject: claims.Subject, UserID: userID}
+		err = s.repository.Upsert(identity)
+		if err != nil {
+			return auth.Tokens{}, "", err
+		}
+	}
+
+	tokens, err := s.userClient.Token(identity.UserID)
+	if err != nil {
+		return auth.Tokens{}, "", err
+	}
+
+	return tokens, ls.fromURL, nil
+}
+
+// link finds or creates the papernet user of an identity from its email. Only
+// the emails verified by the provider are trusted, otherwise anyone could take
+// over an account by registering its email with the provider. The other way
+// around, the auth service refuses to hand over an account with a password
+// whose email was never verified.
+func (s *Service) link(info userInfo) (int, error) {
+	if info.Email == "" {
+		return 0, errors.New("the provider did not return an email", errors.BadRequest())
+	} else if !info.EmailVerified {
+		return 0, errors.New("the email has not been verified by the provider", errors.Forbidden())
+	}
+
+	name := info.Name
+	if name == "" {
+		name = info.Email
+	}
+
+	user, err := s.userClient.Upsert(auth.User{
+		Name:          name,
+		Email:         info.Email,
+		EmailVerified: true,
+	})
+	if err != nil {
+		return 0, err
+	} else if user.ID == 0 {
+		return 0, errors.New("user got no id")
+	}
+
+	return user.ID, nil
+}
+
+func (s *Service) discover() error {
+	issuer := strings.TrimSuffix(s.config.Issuer, "/")
+	req, err := http.NewRequest("GET", issuer+discoveryPath, nil)
+	if err != nil {
+		return err
+	}
+
+	var d discovery
+	err = s.do(req, &d)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return errors.New(fmt.Sprintf("issuer mismatch: got %s, expected %s", d.Issuer, issuer))
+	} else if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" {
+		return errors.New("missing endpoints in provider metadata")
+	}
+
+	s.discovery = d
+	return nil
+}
+
+// exchange exchanges the authorization code for tokens, proving with the PKCE
+// verifier that the code was requested by this service.
+func (s *Service) exchange(code, verifier string) (tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.RedirectURL},
+		"client_id":     {s.config.ClientID},
+		"client_secret": {s.config.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest("POST", s.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var tok tokenResponse
+	err = s.do(req, &tok)
+	if err != nil {
+		return tokenResponse{}, errors.New("could not exchange code", errors.WithCode(http.StatusUnauthorized), errors.WithCause(err))
+	} else if tok.IDToken == "" {
+		return tokenResponse{}, errors.New("no id token returned by the provider", errors.WithCode(http.StatusUnauthorized))
+	}
+
+	return tok, nil
+}
+
+// verifyIDToken reads the claims of an id token and checks that it was issued
+// by the provider, for this client and this login. The id token is received
+// directly from the token endpoint, so its signature does not need to be
+// checked: TLS authenticates the provider (OpenID Connect Core, 3.1.3.7).
+func (s *Service) verifyIDToken(idToken, nonce string) (idTokenClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return idTokenClaims{}, errInvalidIDToken("malformed token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return idTokenClaims{}, errInvalidIDToken("malformed payload")
+	}
+
+	var claims idTokenClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return idTokenClaims{}, errInvalidIDToken("malformed claims")
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(s.discovery.Issuer, "/") {
+		return idTokenClaims{}, errInvalidIDToken("unexpected issuer")
+	} else if !claims.Audience.contains(s.config.ClientID) {
+		return idTokenClaims{}, errInvalidIDToken("unexpected audience")
+	} else if !time.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return idTokenClaims{}, errInvalidIDToken("expired")
+	} else if claims.Nonce != nonce {
+		return idTokenClaims{}, errInvalidIDToken("unexpected nonce")
+	} else if claims.Subject == "" {
+		return idTokenClaims{}, errInvalidIDToken("no subject")
+	}
+
+	return claims, nil
+}
+
+func (s *Service) fetchUserInfo(accessToken string) (userInfo, error) {
+	req, err := http.NewRequest("GET", s.discovery.UserInfoEndpoint, nil)
+	if err != nil {
+		return userInfo{}, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	var info userInfo
+	err = s.do(req, &info)
+	if err != nil {
+		return userInfo{}, err
+	}
+	return info, nil
+}
+
+// do sends a request to the provider and decodes its JSON response in v.
+func (s *Service) do(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("%s %s: unexpected status %d", req.Method, req.URL, res.StatusCode))
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func errInvalidIDToken(reason string) error {
+	return errors.New(fmt.Sprintf("invalid id token: %s", reason), errors.WithCode(http.StatusUnauthorized))
+}
@@ -0,0 +1,201 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/errors"
+)
+
+// fakeUserClient mimics the auth service: users are found by email.
+type fakeUserClient struct {
+	users []auth.User
+}
+
+func (c *fakeUserClient) Upsert(user auth.User) (auth.User, error) {
+	for i, u := range c.users {
+		if u.Email == user.Email {
+			user.ID = u.ID
+			c.users[i] = user
+			return user, nil
+		}
+	}
+
+	user.ID = len(c.users) + 1
+	c.users = append(c.users, user)
+	return user, nil
+}
+
+func (c *fakeUserClient) Token(id int) (auth.Tokens, error) {
+	return auth.Tokens{AccessToken: fmt.Sprintf("token-%d", id)}, nil
+}
+
+// fakeProvider is an OpenID Connect provider issuing id tokens for the user
+// set in it. It checks the PKCE verifier of the code exchanges.
+type fakeProvider struct {
+	server *httptest.Server
+
+	// User logging in
+	user map[string]interface{}
+
+	// Parameters of the last authorization request
+	challenge string
+	nonce     string
+	audience  string
+}
+
+func newFakeProvider() *fakeProvider {
+	p := &fakeProvider{audience: "papernet"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "code" || codeChallenge(r.FormValue("code_verifier")) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		claims := map[string]interface{}{
+			"iss":   p.server.URL,
+			"aud":   p.audience,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.user {
+			claims[k] = v
+		}
+		payload, _ := json.Marshal(claims)
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"id_token":     "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".signature",
+		})
+	})
+	p.server = httptest.NewServer(mux)
+
+	return p
+}
+
+// authorize mimics the redirection of the user to the provider, and returns
+// the state to send back.
+func (p *fakeProvider) authorize(t *testing.T, loginURL string) string {
+	u, err := url.Parse(loginURL)
+	require.NoError(t, err, "login url must be valid")
+
+	q := u.Query()
+	assert.Equal(t, p.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path, "user should be sent to the authorization endpoint")
+	assert.Equal(t, "S256", q.Get("code_challenge_method"), "PKCE should use S256")
+	assert.Equal(t, "papernet", q.Get("client_id"))
+
+	p.challenge = q.Get("code_challenge")
+	p.nonce = q.Get("nonce")
+	return q.Get("state")
+}
+
+func TestService(t *testing.T) {
+	provider := newFakeProvider()
+	defer provider.server.Close()
+
+	repo := NewInmemRepository()
+	users := &fakeUserClient{users: []auth.User{{ID: 1, Name: "Pizza", Email: "pizza@paper.net"}}}
+	config := ProviderConfig{
+		Name:        "keycloak",
+		Issuer:      provider.server.URL,
+		ClientID:    "papernet",
+		RedirectURL: "http://papernet/login",
+	}
+
+	_, err := NewService(ProviderConfig{Name: "keycloak", Issuer: provider.server.URL + "/other"}, http.DefaultClient, repo, users)
+	assert.Error(t, err, "creating a service for an issuer that cannot be discovered should fail")
+
+	service, err := NewService(config, http.DefaultClient, repo, users)
+	require.NoError(t, err, "creating service must not fail")
+
+	// Unknown state
+	_, _, err = service.Login("unknown", "code")
+	if assert.Error(t, err, "login with an unknown state should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// Emails that are not verified are not trusted
+	provider.user = map[string]interface{}{"sub": "123", "email": "pizza@paper.net", "email_verified": false}
+	state := provider.authorize(t, service.LoginURL("/papers"))
+	_, _, err = service.Login(state, "code")
+	if assert.Error(t, err, "login with an unverified email should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	// The identity is linked to the user with the same email
+	provider.user = map[string]interface{}{"sub": "123", "email": "pizza@paper.net", "email_verified": true}
+	state = provider.authorize(t, service.LoginURL("/papers"))
+	tokens, fromURL, err := service.Login(state, "code")
+	if assert.NoError(t, err, "login should not fail") {
+		assert.Equal(t, "token-1", tokens.AccessToken, "user should be logged in as the user with the same email")
+		assert.Equal(t, "/papers", fromURL)
+		assert.True(t, users.users[0].EmailVerified, "email should be marked as verified")
+	}
+
+	// States can only be used once
+	_, _, err = service.Login(state, "code")
+	if assert.Error(t, err, "reusing a state should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+
+	// Once linked, the identity is found by its subject
+	provider.user = map[string]interface{}{"sub": "123", "email": "yolo@paper.net", "email_verified": false}
+	state = provider.authorize(t, service.LoginURL(""))
+	tokens, _, err = service.Login(state, "code")
+	if assert.NoError(t, err, "login should not fail") {
+		assert.Equal(t, "token-1", tokens.AccessToken, "user should be found by subject")
+	}
+
+	// New users are created
+	provider.user = map[string]interface{}{"sub": "456", "email": "new@paper.net", "email_verified": true}
+	state = provider.authorize(t, service.LoginURL(""))
+	tokens, _, err = service.Login(state, "code")
+	if assert.NoError(t, err, "login should not fail") {
+		assert.Equal(t, "token-2", tokens.AccessToken, "a user should be created")
+	}
+
+	// The PKCE verifier of the state has to be the one of the challenge
+	state = provider.authorize(t, service.LoginURL(""))
+	otherState := provider.authorize(t, service.LoginURL(""))
+	_, _, err = service.Login(state, "code")
+	if assert.Error(t, err, "login with the verifier of another state should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+
+	// The id token has to be issued for this client
+	provider.audience = "other"
+	_, _, err = service.Login(otherState, "code")
+	if assert.Error(t, err, "login with an id token for another client should fail") {
+		errors.AssertCode(t, err, 401)
+	}
+	provider.audience = "papernet"
+
+	// States expire
+	state = provider.authorize(t, service.LoginURL(""))
+	ls := service.states.states[state]
+	ls.expiresAt = time.Now().Add(-time.Second)
+	service.states.states[state] = ls
+	_, _, err = service.Login(state, "code")
+	if assert.Error(t, err, "login with an expired state should fail") {
+		errors.AssertCode(t, err, 400)
+	}
+}
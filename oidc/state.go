@@ -0,0 +1,67 @@
+package oidc
+
+import (
+	"sync"
+	"time"
+)
+
+// stateTTL is the time a user has to log in with the provider once the login
+// url has been generated.
+const stateTTL = 10 * time.Minute
+
+// loginState is what is remembered between the redirection to the provider and
+// the callback.
+type loginState struct {
+	fromURL  string
+	verifier string
+	nonce    string
+
+	expiresAt time.Time
+}
+
+// stateStore keeps the states of the logins in progress. A state can only be
+// used once, and not after it expired.
+type stateStore struct {
+	mu     sync.Locker
+	states map[string]loginState
+}
+
+func newStateStore() *stateStore {
+	return &stateStore{
+		mu:     &sync.Mutex{},
+		states: make(map[string]loginState),
+	}
+}
+
+// add stores a login state and returns the key sent to the provider as state.
+func (s *stateStore) add(state loginState) string {
+	key := randToken(32)
+	now := time.Now()
+	state.expiresAt = now.Add(stateTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget the logins that were never completed
+	for k, st := range s.states {
+		if !now.Before(st.expiresAt) {
+			delete(s.states, k)
+		}
+	}
+
+	s.states[key] = state
+	return key
+}
+
+// pop returns and forgets the login state stored under key.
+func (s *stateStore) pop(key string) (loginState, bool) {
+	s.mu.Lock()
+	state, ok := s.states[key]
+	delete(s.states, key)
+	s.mu.Unlock()
+
+	if !ok || !time.Now().Before(state.expiresAt) {
+		return loginState{}, false
+	}
+	return state, true
+}
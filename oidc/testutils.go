@@ -0,0 +1,38 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository(t *testing.T, repo IdentityRepository) {
+	identities := []Identity{
+		{Provider: "keycloak", Subject: "123", UserID: 1},
+		{Provider: "gitlab", Subject: "123", UserID: 2},
+	}
+
+	for _, identity := range identities {
+		err := repo.Upsert(identity)
+		assert.NoError(t, err)
+	}
+
+	for _, identity := range identities {
+		i, err := repo.Get(identity.Provider, identity.Subject)
+		assert.NoError(t, err)
+		assert.Equal(t, identity, i)
+	}
+
+	i, err := repo.Get("keycloak", "456")
+	assert.NoError(t, err)
+	assert.Equal(t, Identity{}, i)
+
+	// Update
+	identities[0].UserID = 3
+	err = repo.Upsert(identities[0])
+	assert.NoError(t, err)
+
+	i, err = repo.Get("keycloak", "123")
+	assert.NoError(t, err)
+	assert.Equal(t, identities[0], i)
+}
@@ -0,0 +1,53 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bobinette/papernet/errors"
+)
+
+var (
+	errInvalidRequest = errors.New("invalid request")
+)
+
+// randToken generates a random url safe token. The tokens are used as state,
+// nonce and PKCE verifier, so they have to be unpredictable.
+func randToken(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// codeChallenge computes the S256 PKCE challenge of a verifier.
+func codeChallenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// HTTPClient is the client used to call the provider.
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Server defines the interface to register the http handlers.
+type Server interface {
+	RegisterHandler(path, method string, f http.Handler)
+}
+
+func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+	statusCode := http.StatusInternalServerError
+	if err, ok := err.(errors.Error); ok {
+		statusCode = err.Code()
+	}
+	w.WriteHeader(statusCode)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,71 @@
+package papernet
+
+import (
+	"time"
+)
+
+// ReadingStatus is where a user is in the reading of a paper.
+type ReadingStatus string
+
+const (
+	StatusToRead  ReadingStatus = "to_read"
+	StatusReading ReadingStatus = "reading"
+	StatusRead    ReadingStatus = "read"
+
+	// StatusNone is used in the searches and the facets for the papers a user
+	// has not set any status on. It is never stored.
+	StatusNone ReadingStatus = "none"
+	// StatusUnread is a search filter matching all the papers not read yet,
+	// with or without a status.
+	StatusUnread ReadingStatus = "unread"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+
+	// MinTeamRatings is the number of ratings below which the average rating
+	// of a team is withheld, so that it does not reveal the rating of a
+	// member.
+	MinTeamRatings = 3
+)
+
+// Annotation is what a user thinks of a paper. It is personal: the
+// annotations of a user are never shown to the other users, and they are
+// only aggregated within teams.
+type Annotation struct {
+	UserID  int `json:"userId"`
+	PaperID int `json:"paperId"`
+
+	Status ReadingStatus `json:"status"`
+	// ReadAt is set when the status goes to read.
+	ReadAt *time.Time `json:"readAt"`
+	// Rating goes from MinRating to MaxRating, 0 means the paper has not
+	// been rated.
+	Rating int `json:"rating"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsEmpty returns whether the annotation holds neither a status nor a
+// rating.
+func (a Annotation) IsEmpty() bool {
+	return a.Status == "" && a.Rating == 0
+}
+
+type AnnotationRepository interface {
+	// Get returns the annotation of a user on a paper. The annotation has an
+	// empty status and a rating of 0 if there is none.
+	Get(userID, paperID int) (Annotation, error)
+	ListForPaper(paperID int) ([]Annotation, error)
+	Upsert(Annotation) error
+	Delete(userID, paperID int) error
+}
+
+// RatingSummary aggregates the ratings given to a paper by a group of users.
+// The average is 0 when there are fewer than MinTeamRatings ratings.
+type RatingSummary struct {
+	PaperID int     `json:"paperId"`
+	Count   int     `json:"count"`
+	Average float64 `json:"average"`
+}
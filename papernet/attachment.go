@@ -0,0 +1,73 @@
+package papernet
+
+import (
+	"io"
+	"time"
+)
+
+// Attachment is a file, typically the PDF of a paper, linked to a paper. The
+// content of the file is kept by the storage the attachment was uploaded to.
+type Attachment struct {
+	ID      int `json:"id"`
+	PaperID int `json:"paperId"`
+	UserID  int `json:"userId"`
+
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int    `json:"size"`
+
+	// Storage is the name of the storage holding the file, and Key the
+	// reference of the file in this storage.
+	Storage string `json:"storage"`
+	Key     string `json:"-"`
+	// URL is set by the storages that can display the file themselves.
+	URL string `json:"url,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AttachmentRepository interface {
+	Get(id int) (Attachment, error)
+	ListForPaper(paperID int) ([]Attachment, error)
+	Insert(*Attachment) error
+	Delete(id int) error
+}
+
+// AttachmentStorage stores the content of the attachments.
+type AttachmentStorage interface {
+	Name() string
+
+	// Save stores data and sets the key, and possibly the url, of the
+	// attachment.
+	Save(a *Attachment, data []byte) error
+	Open(a Attachment) (io.ReadCloser, error)
+	Delete(a Attachment) error
+}
+
+// AttachmentText is the text of an attachment, page by page.
+type AttachmentText struct {
+	AttachmentID int      `json:"attachmentId"`
+	PaperID      int      `json:"paperId"`
+	Pages        []string `json:"pages"`
+}
+
+type AttachmentTextRepository interface {
+	// ListForPaper returns the texts of the attachments of a paper, ordered
+	// by attachment id.
+	ListForPaper(paperID int) ([]AttachmentText, error)
+	Upsert(AttachmentText) error
+	Delete(attachmentID int) error
+}
+
+// TextExtractor extracts the text of the pages of a file.
+type TextExtractor interface {
+	ExtractText(data []byte) ([]string, error)
+}
+
+// AttachmentMatch is a page of an attachment matching a search. Pages start
+// at 1.
+type AttachmentMatch struct {
+	AttachmentID int    `json:"attachmentId"`
+	Page         int    `json:"page"`
+	Snippet      string `json:"snippet"`
+}
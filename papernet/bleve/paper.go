@@ -1,20 +1,45 @@
 package bleve
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/blevesearch/bleve"
 	_ "github.com/blevesearch/bleve/analysis/analyzer/keyword"
 	"github.com/blevesearch/bleve/analysis/analyzer/simple"
 	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/search"
 	"github.com/blevesearch/bleve/search/query"
 
 	"github.com/bobinette/papernet/papernet"
 )
 
+const (
+	// maxMatches is the maximum number of attachment pages returned for a
+	// paper.
+	maxMatches = 3
+	// snippetContext is the number of characters kept around a match in the
+	// snippets.
+	snippetContext = 60
+)
+
 type PaperIndex struct {
 	index bleve.Index
+
+	// Texts, when set, is used to index the text of the attachments of the
+	// papers.
+	Texts papernet.AttachmentTextRepository
+
+	// Annotations, when set, is used to index the reading statuses and the
+	// ratings of the users, so the searches can be filtered and faceted on
+	// them.
+	Annotations papernet.AnnotationRepository
+
+	// Notes, when set, is used to index the notes on the papers, so the
+	// users can search the notes they can read.
+	Notes papernet.NoteRepository
 }
 
 func (s *PaperIndex) Open(path string) error {
@@ -46,6 +71,67 @@ func (s *PaperIndex) Index(paper *papernet.Paper) error {
 		"updatedAt":    paper.UpdatedAt,
 	}
 
+	if s.Texts != nil {
+		texts, err := s.Texts.ListForPaper(paper.ID)
+		if err != nil {
+			return err
+		}
+
+		// The pages of all the attachments are indexed in the same array,
+		// the matches are mapped back to the pages with the array positions.
+		pages := make([]string, 0)
+		for _, text := range texts {
+			pages = append(pages, text.Pages...)
+		}
+		if len(pages) > 0 {
+			data["attachments"] = pages
+		}
+	}
+
+	if s.Annotations != nil {
+		annotations, err := s.Annotations.ListForPaper(paper.ID)
+		if err != nil {
+			return err
+		}
+
+		// The annotations are indexed under the id of their user, in the
+		// fields annotations.<userID>.status and annotations.<userID>.rating.
+		byUser := make(map[string]interface{})
+		for _, annotation := range annotations {
+			fields := make(map[string]interface{})
+			if annotation.Status != "" {
+				fields["status"] = string(annotation.Status)
+			}
+			if annotation.Rating != 0 {
+				fields["rating"] = annotation.Rating
+			}
+			if len(fields) > 0 {
+				byUser[strconv.Itoa(annotation.UserID)] = fields
+			}
+		}
+		if len(byUser) > 0 {
+			data["annotations"] = byUser
+		}
+	}
+
+	if s.Notes != nil {
+		notes, err := s.Notes.ListForPaper(paper.ID)
+		if err != nil {
+			return err
+		}
+
+		// The notes are indexed by audience, in the fields notes.u<userID>
+		// for the private notes and notes.t<teamID> for the shared ones.
+		byAudience := make(map[string][]string)
+		for _, note := range notes {
+			audience := noteAudience(note.AuthorID, note.TeamID)
+			byAudience[audience] = append(byAudience[audience], note.Content)
+		}
+		if len(byAudience) > 0 {
+			data["notes"] = byAudience
+		}
+	}
+
 	return s.index.Index(strconv.Itoa(paper.ID), data)
 }
 
@@ -56,13 +142,16 @@ func (s *PaperIndex) Delete(id int) error {
 func (s *PaperIndex) Search(search papernet.SearchParams) (papernet.SearchResults, error) {
 	q := andQ(
 		query.NewMatchAllQuery(),
-		s.searchQ(search.Q),
+		s.searchQ(search.Q, search.UserID, search.TeamIDs),
 		s.searchIDs(search.IDs),
 		s.searchTags(search.Tags),
+		s.searchStatuses(search.UserID, search.Statuses),
+		s.searchMinRating(search.UserID, search.MinRating),
 	)
 
 	searchRequest := bleve.NewSearchRequest(q)
 	searchRequest.SortBy([]string{"id"})
+	searchRequest.IncludeLocations = search.Q != "" && s.Texts != nil
 
 	if search.Limit > 0 {
 		searchRequest.Size = int(search.Limit)
@@ -72,17 +161,40 @@ func (s *PaperIndex) Search(search papernet.SearchParams) (papernet.SearchResult
 	tagsFacet := bleve.NewFacetRequest("tags_keyword", 10)
 	searchRequest.AddFacet("tags", tagsFacet)
 
+	if search.UserID != 0 {
+		searchRequest.AddFacet("statuses", bleve.NewFacetRequest(statusField(search.UserID), 3))
+
+		ratingsFacet := bleve.NewFacetRequest(ratingField(search.UserID), papernet.MaxRating)
+		for rating := papernet.MinRating; rating <= papernet.MaxRating; rating++ {
+			min, max := float64(rating), float64(rating+1)
+			ratingsFacet.AddNumericRange(strconv.Itoa(rating), &min, &max)
+		}
+		searchRequest.AddFacet("ratings", ratingsFacet)
+	}
+
 	searchResults, err := s.index.Search(searchRequest)
 	if err != nil {
 		return papernet.SearchResults{}, err
 	}
 
 	ids := make([]int, len(searchResults.Hits))
+	matches := make(map[int][]papernet.AttachmentMatch)
 	for i, hit := range searchResults.Hits {
 		ids[i], err = strconv.Atoi(hit.ID)
 		if err != nil {
 			return papernet.SearchResults{}, err
 		}
+
+		locations := hit.Locations["attachments"]
+		if len(locations) == 0 {
+			continue
+		}
+
+		m, err := s.matches(ids[i], locations)
+		if err != nil {
+			return papernet.SearchResults{}, err
+		}
+		matches[ids[i]] = m
 	}
 
 	facets := papernet.Facets{
@@ -92,10 +204,15 @@ func (s *PaperIndex) Search(search papernet.SearchParams) (papernet.SearchResult
 		facets.Tags[i].Tag = term.Term
 		facets.Tags[i].Count = term.Count
 	}
+	if search.UserID != 0 {
+		facets.Statuses = statusesFacet(searchResults.Facets["statuses"])
+		facets.Ratings = ratingsFacet(searchResults.Facets["ratings"])
+	}
 
 	return papernet.SearchResults{
-		IDs:    ids,
-		Facets: facets,
+		IDs:     ids,
+		Facets:  facets,
+		Matches: matches,
 		Pagination: papernet.Pagination{
 			Total:  searchResults.Total,
 			Limit:  search.Limit,
@@ -132,7 +249,7 @@ func orQ(qs ...query.Query) query.Query {
 	return query.NewDisjunctionQuery(ors)
 }
 
-func (s *PaperIndex) searchQ(queryString string) query.Query {
+func (s *PaperIndex) searchQ(queryString string, userID int, teamIDs []int) query.Query {
 	words := strings.Fields(queryString)
 
 	ands := make([]query.Query, 0, len(words))
@@ -141,6 +258,8 @@ func (s *PaperIndex) searchQ(queryString string) query.Query {
 			s.searchTitleQ(word),
 			s.searchTagsQ(word),
 			s.searchAuthorsQ(word),
+			s.searchAttachmentsQ(word),
+			s.searchNotesQ(word, userID, teamIDs),
 		))
 	}
 
@@ -201,6 +320,143 @@ func (s *PaperIndex) searchAuthorsQ(queryString string) query.Query {
 	return query.NewConjunctionQuery(conjuncts)
 }
 
+func (s *PaperIndex) searchAttachmentsQ(queryString string) query.Query {
+	if s.Texts == nil {
+		return nil
+	}
+
+	analyzer := s.index.Mapping().AnalyzerNamed(en.AnalyzerName)
+	tokens := analyzer.Analyze([]byte(queryString))
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	conjuncts := make([]query.Query, len(tokens))
+	for i, token := range tokens {
+		conjuncts[i] = &query.PrefixQuery{
+			Prefix:   string(token.Term),
+			FieldVal: "attachments",
+		}
+	}
+
+	return query.NewConjunctionQuery(conjuncts)
+}
+
+// searchNotesQ searches the notes the user can read: their private notes and
+// the notes shared with their teams.
+func (s *PaperIndex) searchNotesQ(queryString string, userID int, teamIDs []int) query.Query {
+	if s.Notes == nil || userID == 0 {
+		return nil
+	}
+
+	analyzer := s.index.Mapping().AnalyzerNamed(en.AnalyzerName)
+	tokens := analyzer.Analyze([]byte(queryString))
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	fields := make([]string, 0, len(teamIDs)+1)
+	fields = append(fields, "notes."+noteAudience(userID, 0))
+	for _, teamID := range teamIDs {
+		fields = append(fields, "notes."+noteAudience(0, teamID))
+	}
+
+	ors := make([]query.Query, len(fields))
+	for i, field := range fields {
+		conjuncts := make([]query.Query, len(tokens))
+		for j, token := range tokens {
+			conjuncts[j] = &query.PrefixQuery{
+				Prefix:   string(token.Term),
+				FieldVal: field,
+			}
+		}
+		ors[i] = query.NewConjunctionQuery(conjuncts)
+	}
+
+	return orQ(ors...)
+}
+
+// noteAudience returns the name of the field holding the notes shared with a
+// team, or the private notes of their author when teamID is 0.
+func noteAudience(authorID, teamID int) string {
+	if teamID != 0 {
+		return fmt.Sprintf("t%d", teamID)
+	}
+	return fmt.Sprintf("u%d", authorID)
+}
+
+// matches returns the pages of the attachments of a paper where the terms
+// of the search were found, in the order of the attachments.
+func (s *PaperIndex) matches(paperID int, locations search.TermLocationMap) ([]papernet.AttachmentMatch, error) {
+	texts, err := s.Texts.ListForPaper(paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	// First location found on each page of the indexed array
+	first := make(map[uint64]*search.Location)
+	for _, locs := range locations {
+		for _, loc := range locs {
+			if len(loc.ArrayPositions) == 0 {
+				continue
+			}
+
+			pos := loc.ArrayPositions[0]
+			if f, ok := first[pos]; !ok || loc.Start < f.Start {
+				first[pos] = loc
+			}
+		}
+	}
+
+	matches := make([]papernet.AttachmentMatch, 0, maxMatches)
+	var pos uint64
+	for _, text := range texts {
+		for i, page := range text.Pages {
+			loc, ok := first[pos]
+			pos++
+			if !ok || len(matches) == maxMatches {
+				continue
+			}
+
+			matches = append(matches, papernet.AttachmentMatch{
+				AttachmentID: text.AttachmentID,
+				Page:         i + 1,
+				Snippet:      snippet(page, int(loc.Start), int(loc.End)),
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// snippet cuts the text around the bytes [start, end), on rune boundaries.
+func snippet(text string, start, end int) string {
+	if start > len(text) || end > len(text) || start > end {
+		return ""
+	}
+
+	from := start
+	for n := 0; from > 0 && n < snippetContext; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+
+	to := end
+	for n := 0; to < len(text) && n < snippetContext; n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	res := strings.Replace(text[from:to], "\n", " ", -1)
+	if from > 0 {
+		res = "…" + res
+	}
+	if to < len(text) {
+		res = res + "…"
+	}
+	return res
+}
+
 func (*PaperIndex) searchIDs(ids []int) query.Query {
 	docIDs := make([]string, len(ids))
 	for i, id := range ids {
@@ -209,6 +465,122 @@ func (*PaperIndex) searchIDs(ids []int) query.Query {
 	return query.NewDocIDQuery(docIDs)
 }
 
+func statusField(userID int) string {
+	return fmt.Sprintf("annotations.%d.status", userID)
+}
+
+func ratingField(userID int) string {
+	return fmt.Sprintf("annotations.%d.rating", userID)
+}
+
+func (*PaperIndex) searchStatuses(userID int, statuses []papernet.ReadingStatus) query.Query {
+	if userID == 0 || len(statuses) == 0 {
+		return nil
+	}
+
+	field := statusField(userID)
+	statusQ := func(status papernet.ReadingStatus) query.Query {
+		q := query.NewTermQuery(string(status))
+		q.SetField(field)
+		return q
+	}
+
+	// The must not clauses filter out papers from all the indexed ones
+	notQ := func(qs ...query.Query) query.Query {
+		return query.NewBooleanQuery([]query.Query{query.NewMatchAllQuery()}, nil, qs)
+	}
+
+	ors := make([]query.Query, len(statuses))
+	for i, status := range statuses {
+		switch status {
+		case papernet.StatusNone:
+			ors[i] = notQ(statusQ(papernet.StatusToRead), statusQ(papernet.StatusReading), statusQ(papernet.StatusRead))
+		case papernet.StatusUnread:
+			ors[i] = notQ(statusQ(papernet.StatusRead))
+		default:
+			ors[i] = statusQ(status)
+		}
+	}
+
+	return orQ(ors...)
+}
+
+func (*PaperIndex) searchMinRating(userID, minRating int) query.Query {
+	if userID == 0 || minRating <= 0 {
+		return nil
+	}
+
+	min := float64(minRating)
+	inclusive := true
+	q := query.NewNumericRangeInclusiveQuery(&min, nil, &inclusive, nil)
+	q.SetField(ratingField(userID))
+	return q
+}
+
+// statusesFacet orders the counts of the reading statuses, the papers
+// without status coming first.
+func statusesFacet(result *search.FacetResult) papernet.StatusesFacet {
+	if result == nil {
+		return nil
+	}
+
+	counts := map[papernet.ReadingStatus]int{
+		papernet.StatusNone: result.Missing,
+	}
+	for _, term := range result.Terms {
+		counts[papernet.ReadingStatus(term.Term)] = term.Count
+	}
+
+	order := []papernet.ReadingStatus{
+		papernet.StatusNone,
+		papernet.StatusToRead,
+		papernet.StatusReading,
+		papernet.StatusRead,
+	}
+
+	facet := make(papernet.StatusesFacet, 0, len(order))
+	for _, status := range order {
+		if counts[status] == 0 {
+			continue
+		}
+
+		facet = append(facet, struct {
+			Status papernet.ReadingStatus `json:"status"`
+			Count  int                    `json:"count"`
+		}{Status: status, Count: counts[status]})
+	}
+	return facet
+}
+
+// ratingsFacet orders the counts of the ratings by rating.
+func ratingsFacet(result *search.FacetResult) papernet.RatingsFacet {
+	if result == nil {
+		return nil
+	}
+
+	counts := make(map[int]int)
+	for _, r := range result.NumericRanges {
+		rating, err := strconv.Atoi(r.Name)
+		if err != nil {
+			continue
+		}
+		counts[rating] = r.Count
+	}
+
+	facet := make(papernet.RatingsFacet, 0, papernet.MaxRating)
+	for rating := papernet.MinRating; rating <= papernet.MaxRating; rating++ {
+		if counts[rating] == 0 {
+			continue
+		}
+
+		facet = append(facet, struct {
+			Rating int `json:"rating"`
+			Count  int `json:"count"`
+		}{Rating: rating, Count: counts[rating]})
+	}
+	return facet
+}
+
 func (*PaperIndex) searchTags(tags []string) query.Query {
 	if len(tags) == 0 {
 		return nil
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/blevesearch/bleve"
@@ -316,3 +317,243 @@ func TestFind(t *testing.T) {
 		}
 	}
 }
+
+func TestSnippet(t *testing.T) {
+	text := "Résumé: the quick brown fox\njumps over the lazy dog"
+	start := strings.Index(text, "fox")
+
+	if s := snippet(text, start, start+len("fox")); s != text[:start+len("fox")]+" jumps over the lazy dog" {
+		t.Errorf("the whole text should be kept: got %q", s)
+	}
+
+	long := strings.Repeat("é", 100) + " match " + strings.Repeat("à", 100)
+	start = strings.Index(long, "match")
+	s := snippet(long, start, start+len("match"))
+	expected := "…" + strings.Repeat("é", 59) + " match " + strings.Repeat("à", 59) + "…"
+	if s != expected {
+		t.Errorf("incorrect snippet: expected %q got %q", expected, s)
+	}
+}
+
+type annotationRepository map[int][]papernet.Annotation
+
+func (r annotationRepository) Get(userID, paperID int) (papernet.Annotation, error) {
+	for _, annotation := range r[paperID] {
+		if annotation.UserID == userID {
+			return annotation, nil
+		}
+	}
+	return papernet.Annotation{UserID: userID, PaperID: paperID}, nil
+}
+
+func (r annotationRepository) ListForPaper(paperID int) ([]papernet.Annotation, error) {
+	return r[paperID], nil
+}
+
+func (annotationRepository) Upsert(papernet.Annotation) error { return nil }
+func (annotationRepository) Delete(userID, paperID int) error { return nil }
+
+func TestFindAnnotations(t *testing.T) {
+	index, f := createIndex(t)
+	defer f()
+
+	index.Annotations = annotationRepository{
+		1: {
+			{UserID: 1, PaperID: 1, Status: papernet.StatusRead, Rating: 5},
+			{UserID: 2, PaperID: 1, Status: papernet.StatusToRead},
+		},
+		2: {{UserID: 1, PaperID: 2, Status: papernet.StatusReading, Rating: 3}},
+		3: {{UserID: 1, PaperID: 3, Status: papernet.StatusToRead}},
+		4: {{UserID: 1, PaperID: 4, Rating: 4}},
+	}
+
+	papers := []*papernet.Paper{
+		&papernet.Paper{ID: 1, Title: "Attention is all you need", Tags: []string{"nlp"}},
+		&papernet.Paper{ID: 2, Title: "Word embeddings", Tags: []string{"nlp"}},
+		&papernet.Paper{ID: 3, Title: "Pizza yolo", Tags: []string{"nlp"}},
+		&papernet.Paper{ID: 4, Title: "Monte carlo", Tags: []string{"rl"}},
+		&papernet.Paper{ID: 5, Title: "Sequence to sequence", Tags: []string{"nlp"}},
+	}
+	ids := make([]int, len(papers))
+	for i, paper := range papers {
+		if err := index.Index(paper); err != nil {
+			t.Fatal("error indexing", paper.ID, err)
+		}
+		ids[i] = paper.ID
+	}
+
+	var tts = map[string]struct {
+		Search   papernet.SearchParams
+		Expected []int
+	}{
+		"unread nlp": {
+			Search: papernet.SearchParams{
+				IDs:      ids,
+				Tags:     []string{"nlp"},
+				UserID:   1,
+				Statuses: []papernet.ReadingStatus{papernet.StatusUnread},
+				Limit:    10,
+			},
+			Expected: []int{2, 3, 5},
+		},
+		"without status": {
+			Search: papernet.SearchParams{
+				IDs:      ids,
+				UserID:   1,
+				Statuses: []papernet.ReadingStatus{papernet.StatusNone},
+				Limit:    10,
+			},
+			Expected: []int{4, 5},
+		},
+		"to read or reading": {
+			Search: papernet.SearchParams{
+				IDs:      ids,
+				UserID:   1,
+				Statuses: []papernet.ReadingStatus{papernet.StatusToRead, papernet.StatusReading},
+				Limit:    10,
+			},
+			Expected: []int{2, 3},
+		},
+		"statuses of another user": {
+			Search: papernet.SearchParams{
+				IDs:      ids,
+				UserID:   2,
+				Statuses: []papernet.ReadingStatus{papernet.StatusToRead},
+				Limit:    10,
+			},
+			Expected: []int{1},
+		},
+		"min rating": {
+			Search: papernet.SearchParams{
+				IDs:       ids,
+				UserID:    1,
+				MinRating: 4,
+				Limit:     10,
+			},
+			Expected: []int{1, 4},
+		},
+		"filters ignored without user": {
+			Search: papernet.SearchParams{
+				IDs:       ids,
+				Statuses:  []papernet.ReadingStatus{papernet.StatusRead},
+				MinRating: 4,
+				Limit:     10,
+			},
+			Expected: ids,
+		},
+	}
+
+	for name, tt := range tts {
+		res, err := index.Search(tt.Search)
+		if err != nil {
+			t.Errorf("%s - search failed with error: %v", name, err)
+		} else if !reflect.DeepEqual(tt.Expected, res.IDs) {
+			t.Errorf("%s - got wrong ids: expected %v got %v", name, tt.Expected, res.IDs)
+		}
+	}
+
+	res, err := index.Search(papernet.SearchParams{IDs: ids, UserID: 1, Limit: 10})
+	if err != nil {
+		t.Fatal("search failed with error:", err)
+	}
+
+	statuses := map[papernet.ReadingStatus]int{}
+	for _, s := range res.Facets.Statuses {
+		statuses[s.Status] = s.Count
+	}
+	expectedStatuses := map[papernet.ReadingStatus]int{
+		papernet.StatusNone:    2,
+		papernet.StatusToRead:  1,
+		papernet.StatusReading: 1,
+		papernet.StatusRead:    1,
+	}
+	if !reflect.DeepEqual(expectedStatuses, statuses) {
+		t.Errorf("wrong statuses facet: expected %v got %v", expectedStatuses, statuses)
+	}
+
+	ratings := map[int]int{}
+	for _, r := range res.Facets.Ratings {
+		ratings[r.Rating] = r.Count
+	}
+	expectedRatings := map[int]int{3: 1, 4: 1, 5: 1}
+	if !reflect.DeepEqual(expectedRatings, ratings) {
+		t.Errorf("wrong ratings facet: expected %v got %v", expectedRatings, ratings)
+	}
+}
+
+type noteRepository map[int][]papernet.Note
+
+func (r noteRepository) Get(id int) (papernet.Note, error) {
+	for _, notes := range r {
+		for _, note := range notes {
+			if note.ID == id {
+				return note, nil
+			}
+		}
+	}
+	return papernet.Note{}, nil
+}
+
+func (r noteRepository) ListForPaper(paperID int) ([]papernet.Note, error) {
+	return r[paperID], nil
+}
+
+func (noteRepository) Insert(*papernet.Note) error { return nil }
+func (noteRepository) Update(*papernet.Note) error { return nil }
+func (noteRepository) Delete(id int) error         { return nil }
+
+func TestFindNotes(t *testing.T) {
+	index, f := createIndex(t)
+	defer f()
+
+	index.Notes = noteRepository{
+		1: {{ID: 1, PaperID: 1, AuthorID: 1, Content: "Great **benchmark**"}},
+		2: {{ID: 2, PaperID: 2, AuthorID: 2, TeamID: 3, Content: "The benchmark is flawed"}},
+		3: {{ID: 3, PaperID: 3, AuthorID: 2, Content: "benchmark of user 2"}},
+	}
+
+	papers := []*papernet.Paper{
+		&papernet.Paper{ID: 1, Title: "Title 1"},
+		&papernet.Paper{ID: 2, Title: "Title 2"},
+		&papernet.Paper{ID: 3, Title: "Title 3"},
+		&papernet.Paper{ID: 4, Title: "Benchmarks"},
+	}
+	ids := make([]int, len(papers))
+	for i, paper := range papers {
+		if err := index.Index(paper); err != nil {
+			t.Fatal("error indexing", paper.ID, err)
+		}
+		ids[i] = paper.ID
+	}
+
+	var tts = map[string]struct {
+		Search   papernet.SearchParams
+		Expected []int
+	}{
+		"private notes": {
+			Search:   papernet.SearchParams{IDs: ids, Q: "benchmark", UserID: 1, Limit: 10},
+			Expected: []int{1, 4},
+		},
+		"team notes": {
+			Search:   papernet.SearchParams{IDs: ids, Q: "benchmark", UserID: 1, TeamIDs: []int{3}, Limit: 10},
+			Expected: []int{1, 2, 4},
+		},
+		"author of team note": {
+			Search:   papernet.SearchParams{IDs: ids, Q: "flawed", UserID: 2, Limit: 10},
+			Expected: []int{},
+		},
+		"no user": {
+			Search:   papernet.SearchParams{IDs: ids, Q: "benchmark", Limit: 10},
+			Expected: []int{4},
+		},
+	}
+
+	for name, tt := range tts {
+		res, err := index.Search(tt.Search)
+		if err != nil {
+			t.Errorf("%s - search failed with error: %v", name, err)
+		} else if !reflect.DeepEqual(tt.Expected, res.IDs) {
+			t.Errorf("%s - got wrong ids: expected %v got %v", name, tt.Expected, res.IDs)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+var annotationBucket = []byte("annotations")
+
+// AnnotationRepository stores the annotations of the users in a bolt
+// database, keyed by user id then paper id.
+type AnnotationRepository struct {
+	Driver *Driver
+}
+
+func annotationKey(userID, paperID int) []byte {
+	return append(itob(userID), itob(paperID)...)
+}
+
+func (r *AnnotationRepository) Get(userID, paperID int) (papernet.Annotation, error) {
+	annotation := papernet.Annotation{UserID: userID, PaperID: paperID}
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(annotationBucket).Get(annotationKey(userID, paperID))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &annotation)
+	})
+	if err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	return annotation, nil
+}
+
+// ListForPaper returns the annotations of all the users on a paper, ordered
+// by user id.
+func (r *AnnotationRepository) ListForPaper(paperID int) ([]papernet.Annotation, error) {
+	annotations := make([]papernet.Annotation, 0)
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(annotationBucket).Cursor()
+		for key, data := c.First(); key != nil; key, data = c.Next() {
+			if int(binary.BigEndian.Uint64(key[8:])) != paperID {
+				continue
+			}
+
+			var annotation papernet.Annotation
+			if err := json.Unmarshal(data, &annotation); err != nil {
+				return err
+			}
+			annotations = append(annotations, annotation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+func (r *AnnotationRepository) Upsert(annotation papernet.Annotation) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(annotation)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(annotationBucket).Put(annotationKey(annotation.UserID, annotation.PaperID), data)
+	})
+}
+
+func (r *AnnotationRepository) Delete(userID, paperID int) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(annotationBucket).Delete(annotationKey(userID, paperID))
+	})
+}
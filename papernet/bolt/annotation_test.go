@@ -0,0 +1,59 @@
+package bolt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+func TestAnnotationRepository(t *testing.T) {
+	attachments, f := createAttachmentRepository(t)
+	defer f()
+	repo := AnnotationRepository{Driver: attachments.Driver}
+
+	readAt := time.Date(2017, time.October, 12, 0, 0, 0, 0, time.UTC)
+	annotations := []papernet.Annotation{
+		{UserID: 2, PaperID: 1, Status: papernet.StatusRead, ReadAt: &readAt, Rating: 4},
+		{UserID: 1, PaperID: 1, Status: papernet.StatusToRead},
+		{UserID: 1, PaperID: 2, Rating: 2},
+	}
+	for _, annotation := range annotations {
+		if err := repo.Upsert(annotation); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+
+	annotation, err := repo.Get(2, 1)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if !reflect.DeepEqual(annotation, annotations[0]) {
+		t.Fatalf("incorrect annotation retrieved: %+v", annotation)
+	}
+
+	annotation, err = repo.Get(2, 2)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if !reflect.DeepEqual(annotation, papernet.Annotation{UserID: 2, PaperID: 2}) {
+		t.Fatalf("missing annotation should be empty: %+v", annotation)
+	}
+
+	list, err := repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if !reflect.DeepEqual(list, []papernet.Annotation{annotations[1], annotations[0]}) {
+		t.Fatalf("incorrect annotations retrieved, ordered by user id: %+v", list)
+	}
+
+	if err := repo.Delete(1, 1); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+
+	list, err = repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if !reflect.DeepEqual(list, []papernet.Annotation{annotations[0]}) {
+		t.Fatalf("incorrect annotations retrieved after delete: %+v", list)
+	}
+}
@@ -0,0 +1,109 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+var attachmentBucket = []byte("attachments")
+
+// attachmentRecord is the stored version of an attachment. The storage key is
+// not serialized with the attachment so it has to be added.
+type attachmentRecord struct {
+	papernet.Attachment
+	Key string `json:"key"`
+}
+
+// AttachmentRepository is used to store and retrieve attachments from a bolt
+// database.
+type AttachmentRepository struct {
+	Driver *Driver
+}
+
+// Get retrieves the attachment defined by id. If no attachment can be found,
+// the attachment returned has an id of 0.
+func (r *AttachmentRepository) Get(id int) (papernet.Attachment, error) {
+	var attachment papernet.Attachment
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(attachmentBucket).Get(itob(id))
+		if data == nil {
+			return nil
+		}
+
+		var err error
+		attachment, err = decodeAttachment(data)
+		return err
+	})
+	if err != nil {
+		return papernet.Attachment{}, err
+	}
+
+	return attachment, nil
+}
+
+// ListForPaper returns the attachments of a paper, in upload order.
+func (r *AttachmentRepository) ListForPaper(paperID int) ([]papernet.Attachment, error) {
+	attachments := make([]papernet.Attachment, 0)
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(attachmentBucket).Cursor()
+		for id, data := c.First(); id != nil; id, data = c.Next() {
+			attachment, err := decodeAttachment(data)
+			if err != nil {
+				return err
+			}
+
+			if attachment.PaperID == paperID {
+				attachments = append(attachments, attachment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Insert stores a new attachment, setting its id and creation date.
+func (r *AttachmentRepository) Insert(attachment *papernet.Attachment) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(attachmentBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("error incrementing id: %v", err)
+		}
+		attachment.ID = int(id)
+		attachment.CreatedAt = time.Now()
+
+		data, err := json.Marshal(attachmentRecord{Attachment: *attachment, Key: attachment.Key})
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(attachment.ID), data)
+	})
+}
+
+func (r *AttachmentRepository) Delete(id int) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(attachmentBucket).Delete(itob(id))
+	})
+}
+
+func decodeAttachment(data []byte) (papernet.Attachment, error) {
+	var record attachmentRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return papernet.Attachment{}, err
+	}
+
+	attachment := record.Attachment
+	attachment.Key = record.Key
+	return attachment, nil
+}
@@ -0,0 +1,122 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+func createAttachmentRepository(t *testing.T) (*AttachmentRepository, func()) {
+	tmpFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal("could not create tmp file:", err)
+	}
+
+	filename := tmpFile.Name()
+	driver := Driver{}
+	err = driver.Open(filename)
+	if err != nil {
+		os.Remove(filename)
+		t.Fatal("could not create bucket: ", err)
+	}
+	repo := AttachmentRepository{Driver: &driver}
+
+	return &repo, func() {
+		driver.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestAttachmentRepository(t *testing.T) {
+	repo, f := createAttachmentRepository(t)
+	defer f()
+
+	attachments := []*papernet.Attachment{
+		&papernet.Attachment{PaperID: 1, Name: "paper.pdf", Storage: "local", Key: "1/abc"},
+		&papernet.Attachment{PaperID: 2, Name: "other.pdf", Storage: "drive", Key: "xyz", URL: "http://drive/xyz"},
+		&papernet.Attachment{PaperID: 1, Name: "slides.pdf", Storage: "local", Key: "1/def"},
+	}
+	for _, a := range attachments {
+		if err := repo.Insert(a); err != nil {
+			t.Fatal("error inserting:", err)
+		} else if a.ID <= 0 {
+			t.Fatal("inserting should have set the id")
+		} else if a.CreatedAt.IsZero() {
+			t.Fatal("inserting should have set the created at")
+		}
+	}
+
+	a, err := repo.Get(attachments[1].ID)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if a.Name != "other.pdf" || a.URL != "http://drive/xyz" {
+		t.Fatalf("incorrect attachment retrieved: %+v", a)
+	} else if a.Key != "xyz" {
+		t.Fatalf("the key should be stored: expected xyz got %s", a.Key)
+	}
+
+	list, err := repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if len(list) != 2 {
+		t.Fatalf("incorrect number of attachments: expected 2 got %d", len(list))
+	} else if list[0].Name != "paper.pdf" || list[1].Name != "slides.pdf" {
+		t.Fatalf("incorrect attachments retrieved: %+v", list)
+	}
+
+	if err := repo.Delete(attachments[0].ID); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+
+	a, err = repo.Get(attachments[0].ID)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if a.ID != 0 {
+		t.Fatal("attachment should have been deleted")
+	}
+
+	list, err = repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if len(list) != 1 {
+		t.Fatalf("incorrect number of attachments: expected 1 got %d", len(list))
+	}
+}
+
+func TestAttachmentTextRepository(t *testing.T) {
+	attachments, f := createAttachmentRepository(t)
+	defer f()
+	repo := AttachmentTextRepository{Driver: attachments.Driver}
+
+	texts := []papernet.AttachmentText{
+		{AttachmentID: 3, PaperID: 1, Pages: []string{"third"}},
+		{AttachmentID: 1, PaperID: 1, Pages: []string{"first", "page 2"}},
+		{AttachmentID: 2, PaperID: 2, Pages: []string{"other"}},
+	}
+	for _, text := range texts {
+		if err := repo.Upsert(text); err != nil {
+			t.Fatal("error inserting:", err)
+		}
+	}
+
+	list, err := repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if !reflect.DeepEqual(list, []papernet.AttachmentText{texts[1], texts[0]}) {
+		t.Fatalf("incorrect texts retrieved, ordered by attachment id: %+v", list)
+	}
+
+	if err := repo.Delete(1); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+
+	list, err = repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if !reflect.DeepEqual(list, []papernet.AttachmentText{texts[0]}) {
+		t.Fatalf("incorrect texts retrieved after delete: %+v", list)
+	}
+}
@@ -0,0 +1,59 @@
+package bolt
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+var attachmentTextBucket = []byte("attachment_texts")
+
+// AttachmentTextRepository stores the text extracted from the attachments in
+// a bolt database, keyed by attachment id.
+type AttachmentTextRepository struct {
+	Driver *Driver
+}
+
+// ListForPaper returns the texts of the attachments of a paper, ordered by
+// attachment id.
+func (r *AttachmentTextRepository) ListForPaper(paperID int) ([]papernet.AttachmentText, error) {
+	texts := make([]papernet.AttachmentText, 0)
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(attachmentTextBucket).Cursor()
+		for id, data := c.First(); id != nil; id, data = c.Next() {
+			var text papernet.AttachmentText
+			if err := json.Unmarshal(data, &text); err != nil {
+				return err
+			}
+
+			if text.PaperID == paperID {
+				texts = append(texts, text)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return texts, nil
+}
+
+func (r *AttachmentTextRepository) Upsert(text papernet.AttachmentText) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(text)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(attachmentTextBucket).Put(itob(text.AttachmentID), data)
+	})
+}
+
+func (r *AttachmentTextRepository) Delete(attachmentID int) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(attachmentTextBucket).Delete(itob(attachmentID))
+	})
+}
@@ -26,6 +26,10 @@ func (d *Driver) Open(path string) error {
 		buckets := [][]byte{
 			paperBucket,
 			tagBucket,
+			attachmentBucket,
+			attachmentTextBucket,
+			annotationBucket,
+			noteBucket,
 		}
 		for _, bucket := range buckets {
 			_, err := tx.CreateBucketIfNotExists(bucket)
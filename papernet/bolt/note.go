@@ -0,0 +1,108 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+var noteBucket = []byte("notes")
+
+// NoteRepository stores the notes on the papers in a bolt database, keyed by
+// note id.
+type NoteRepository struct {
+	Driver *Driver
+}
+
+// Get retrieves the note defined by id. If no note can be found, the note
+// returned has an id of 0.
+func (r *NoteRepository) Get(id int) (papernet.Note, error) {
+	var note papernet.Note
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(noteBucket).Get(itob(id))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &note)
+	})
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	return note, nil
+}
+
+// ListForPaper returns the notes of a paper, in creation order.
+func (r *NoteRepository) ListForPaper(paperID int) ([]papernet.Note, error) {
+	notes := make([]papernet.Note, 0)
+	err := r.Driver.store.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(noteBucket).Cursor()
+		for id, data := c.First(); id != nil; id, data = c.Next() {
+			var note papernet.Note
+			if err := json.Unmarshal(data, &note); err != nil {
+				return err
+			}
+
+			if note.PaperID == paperID {
+				notes = append(notes, note)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// Insert stores a new note, setting its id and its dates.
+func (r *NoteRepository) Insert(note *papernet.Note) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(noteBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("error incrementing id: %v", err)
+		}
+		note.ID = int(id)
+		note.CreatedAt = time.Now()
+		note.UpdatedAt = note.CreatedAt
+
+		data, err := json.Marshal(note)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(note.ID), data)
+	})
+}
+
+// Update replaces an existing note, setting its update date.
+func (r *NoteRepository) Update(note *papernet.Note) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(noteBucket)
+		if bucket.Get(itob(note.ID)) == nil {
+			return fmt.Errorf("no note for id %d", note.ID)
+		}
+
+		note.UpdatedAt = time.Now()
+		data, err := json.Marshal(note)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(note.ID), data)
+	})
+}
+
+func (r *NoteRepository) Delete(id int) error {
+	return r.Driver.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(noteBucket).Delete(itob(id))
+	})
+}
@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"testing"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+func TestNoteRepository(t *testing.T) {
+	attachments, f := createAttachmentRepository(t)
+	defer f()
+	repo := NoteRepository{Driver: attachments.Driver}
+
+	notes := []*papernet.Note{
+		&papernet.Note{PaperID: 1, AuthorID: 1, Content: "**private**"},
+		&papernet.Note{PaperID: 2, AuthorID: 1, TeamID: 3, Content: "other paper"},
+		&papernet.Note{PaperID: 1, AuthorID: 2, TeamID: 3, Content: "shared"},
+	}
+	for _, n := range notes {
+		if err := repo.Insert(n); err != nil {
+			t.Fatal("error inserting:", err)
+		} else if n.ID <= 0 {
+			t.Fatal("inserting should have set the id")
+		} else if n.CreatedAt.IsZero() || n.UpdatedAt.IsZero() {
+			t.Fatal("inserting should have set the dates")
+		}
+	}
+
+	reply := papernet.Note{PaperID: 1, AuthorID: 1, TeamID: 3, ParentID: notes[2].ID, Content: "reply"}
+	if err := repo.Insert(&reply); err != nil {
+		t.Fatal("error inserting reply:", err)
+	}
+
+	n, err := repo.Get(reply.ID)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if n.ParentID != notes[2].ID || n.TeamID != 3 || n.Content != "reply" {
+		t.Fatalf("incorrect note retrieved: %+v", n)
+	}
+
+	n.Content = "edited reply"
+	if err := repo.Update(&n); err != nil {
+		t.Fatal("error updating:", err)
+	} else if n.UpdatedAt.Before(n.CreatedAt) {
+		t.Fatal("updating should have set the update date")
+	}
+
+	if err := repo.Update(&papernet.Note{ID: 42}); err == nil {
+		t.Fatal("updating a note that does not exist should fail")
+	}
+
+	list, err := repo.ListForPaper(1)
+	if err != nil {
+		t.Fatal("error listing:", err)
+	} else if len(list) != 3 {
+		t.Fatalf("incorrect number of notes: expected 3 got %d", len(list))
+	} else if list[0].ID != notes[0].ID || list[1].ID != notes[2].ID || list[2].Content != "edited reply" {
+		t.Fatalf("incorrect notes retrieved: %+v", list)
+	}
+
+	if err := repo.Delete(notes[0].ID); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+
+	n, err = repo.Get(notes[0].ID)
+	if err != nil {
+		t.Fatal("error getting:", err)
+	} else if n.ID != 0 {
+		t.Fatal("note should have been deleted")
+	}
+}
@@ -1,18 +1,19 @@
 package paper
 
 import (
-	"encoding/json"
-	"io/ioutil"
-
-	// "github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/jwt"
 	"github.com/bobinette/papernet/log"
+	"github.com/bobinette/papernet/pdf"
 
+	"github.com/bobinette/papernet/papernet"
 	"github.com/bobinette/papernet/papernet/bleve"
 	"github.com/bobinette/papernet/papernet/bolt"
+	"github.com/bobinette/papernet/papernet/fs"
 	"github.com/bobinette/papernet/papernet/http"
 	"github.com/bobinette/papernet/papernet/services"
 
 	authClient "github.com/bobinette/papernet/clients/auth"
+	importsClient "github.com/bobinette/papernet/clients/imports"
 )
 
 type Configuration struct {
@@ -23,23 +24,26 @@ type Configuration struct {
 	Bolt struct {
 		Store string `toml:"store"`
 	} `toml:"bolt"`
+	Attachments struct {
+		Dir string `toml:"dir"`
+	} `toml:"attachments"`
 }
 
-// Start registers
-func Start(srv http.Server, conf Configuration, logger log.Logger, au *authClient.Client) *services.PaperService {
-	// Load key from file
-	keyData, err := ioutil.ReadFile(conf.KeyPath)
-	if err != nil {
-		logger.Fatal("could not open key file:", err)
-	}
-
-	// Extract key from data
-	var key struct {
-		Key string `json:"k"`
-	}
-	err = json.Unmarshal(keyData, &key)
+// Start registers the paper endpoints. The attachments are stored on the local
+// file system, or in one of the storages given. The drafts are enriched
+// through the imports service.
+func Start(
+	srv http.Server,
+	conf Configuration,
+	logger log.Logger,
+	au *authClient.Client,
+	ic *importsClient.Client,
+	storages ...papernet.AttachmentStorage,
+) *services.PaperService {
+	// Load keys from file. The services verifying the tokens only need the public keys.
+	keys, err := jwt.LoadKeySet(conf.KeyPath)
 	if err != nil {
-		logger.Fatal("could not read key file:", err)
+		logger.Fatal("could not load key file:", err)
 	}
 
 	// Create repositories
@@ -50,9 +54,17 @@ func Start(srv http.Server, conf Configuration, logger log.Logger, au *authClien
 	}
 	paperRepository := bolt.PaperRepository{Driver: &boltDriver}
 	tagIndex := bolt.TagIndex{Driver: &boltDriver}
+	attachmentRepository := bolt.AttachmentRepository{Driver: &boltDriver}
+	textRepository := bolt.AttachmentTextRepository{Driver: &boltDriver}
+	annotationRepository := bolt.AnnotationRepository{Driver: &boltDriver}
+	noteRepository := bolt.NoteRepository{Driver: &boltDriver}
 
 	// Create index
-	index := bleve.PaperIndex{}
+	index := bleve.PaperIndex{
+		Texts:       &textRepository,
+		Annotations: &annotationRepository,
+		Notes:       &noteRepository,
+	}
 	err = index.Open(conf.Bleve.Store)
 	if err != nil {
 		logger.Fatalf("could not open bleve: %v", err)
@@ -60,11 +72,32 @@ func Start(srv http.Server, conf Configuration, logger log.Logger, au *authClien
 
 	// Create services
 	tagService := services.NewTagService(&tagIndex)
-	paperService := services.NewPaperService(&paperRepository, &index, au, tagService)
+
+	localStorage := &fs.AttachmentStorage{Dir: conf.Attachments.Dir}
+	attachmentService := services.NewAttachmentService(
+		&attachmentRepository,
+		&textRepository,
+		pdf.TextExtractor{},
+		&paperRepository,
+		&index,
+		append([]papernet.AttachmentStorage{localStorage}, storages...)...,
+	)
+
+	annotationService := services.NewAnnotationService(&annotationRepository, &paperRepository, &index, au)
+	noteService := services.NewNoteService(&noteRepository, &paperRepository, &index, au)
+
+	// The attachments, annotations and notes are deleted with their paper
+	paperService := services.NewPaperService(&paperRepository, &index, au, tagService, attachmentService, annotationService, noteService)
+
+	draftService := services.NewDraftService(pdf.DraftExtractor{}, services.NewImportsEnricher(ic))
 
 	// Register paper endpoints
-	http.RegisterPaperEndpoints(srv, paperService, []byte(key.Key), au)
-	http.RegisterTagEndpoints(srv, tagService, []byte(key.Key), au)
+	http.RegisterPaperEndpoints(srv, paperService, keys, au)
+	http.RegisterTagEndpoints(srv, tagService, keys, au)
+	http.RegisterAttachmentEndpoints(srv, attachmentService, keys, au)
+	http.RegisterDraftEndpoints(srv, draftService, keys, au)
+	http.RegisterAnnotationEndpoints(srv, annotationService, keys, au)
+	http.RegisterNoteEndpoints(srv, noteService, keys, au)
 
 	return paperService
 }
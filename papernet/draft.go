@@ -0,0 +1,28 @@
+package papernet
+
+import (
+	"context"
+)
+
+// PaperDraft is a paper pre-filled with the metadata of a file. It is not
+// saved, the user completes it before creating the paper.
+type PaperDraft struct {
+	Paper Paper `json:"paper"`
+
+	ArxivIDs []string `json:"arxivIds"`
+	DOIs     []string `json:"dois"`
+
+	// Source is the imports source the draft was enriched with, if any.
+	Source string `json:"source,omitempty"`
+}
+
+// DraftExtractor reads the metadata of a file to create a draft.
+type DraftExtractor interface {
+	ExtractDraft(data []byte) (PaperDraft, error)
+}
+
+// DraftEnricher completes a draft with the information found by the imports
+// searchers. The context contains the user.
+type DraftEnricher interface {
+	Enrich(ctx context.Context, draft PaperDraft) (PaperDraft, error)
+}
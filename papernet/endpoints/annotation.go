@@ -0,0 +1,93 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/papernet/services"
+	"github.com/bobinette/papernet/users"
+)
+
+type AnnotationEndpoint struct {
+	service *services.AnnotationService
+}
+
+func NewAnnotationEndpoint(service *services.AnnotationService) *AnnotationEndpoint {
+	return &AnnotationEndpoint{
+		service: service,
+	}
+}
+
+func (ep *AnnotationEndpoint) Get(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	annotation, err := ep.service.Get(user, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": annotation,
+	}, nil
+}
+
+type UpdateAnnotationRequest struct {
+	PaperID int                    `json:"-"`
+	Status  papernet.ReadingStatus `json:"status"`
+	Rating  int                    `json:"rating"`
+}
+
+func (ep *AnnotationEndpoint) Update(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(UpdateAnnotationRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	annotation, err := ep.service.Update(user, req.PaperID, req.Status, req.Rating)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": annotation,
+	}, nil
+}
+
+type TeamRatingsRequest struct {
+	PaperID int
+	TeamID  int
+}
+
+func (ep *AnnotationEndpoint) TeamRatings(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(TeamRatingsRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	summary, err := ep.service.TeamRatings(user, req.TeamID, req.PaperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": summary,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package endpoints
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/papernet/services"
+	"github.com/bobinette/papernet/users"
+)
+
+type AttachmentEndpoint struct {
+	service *services.AttachmentService
+}
+
+func NewAttachmentEndpoint(service *services.AttachmentService) *AttachmentEndpoint {
+	return &AttachmentEndpoint{
+		service: service,
+	}
+}
+
+type UploadAttachmentRequest struct {
+	PaperID int    `json:"-"`
+	Storage string `json:"storage"`
+	Name    string `json:"name"`
+	Data    []byte `json:"data"`
+}
+
+func (ep *AttachmentEndpoint) Upload(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(UploadAttachmentRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	attachment, err := ep.service.Upload(user, req.PaperID, req.Storage, req.Name, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": attachment,
+	}, nil
+}
+
+func (ep *AttachmentEndpoint) List(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	attachments, err := ep.service.List(user, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": attachments,
+	}, nil
+}
+
+type AttachmentRequest struct {
+	PaperID      int
+	AttachmentID int
+}
+
+// AttachmentContent is the response of the download endpoint. The transport
+// has to close the content once written.
+type AttachmentContent struct {
+	Attachment papernet.Attachment
+	Content    io.ReadCloser
+}
+
+func (ep *AttachmentEndpoint) Download(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(AttachmentRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	attachment, content, err := ep.service.Open(user, req.PaperID, req.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return AttachmentContent{Attachment: attachment, Content: content}, nil
+}
+
+func (ep *AttachmentEndpoint) Delete(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(AttachmentRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err = ep.service.Delete(user, req.PaperID, req.AttachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return statusCoder{code: http.StatusNoContent}, nil
+}
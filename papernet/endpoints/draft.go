@@ -0,0 +1,38 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/bobinette/papernet/papernet/services"
+)
+
+type DraftEndpoint struct {
+	service *services.DraftService
+}
+
+func NewDraftEndpoint(service *services.DraftService) *DraftEndpoint {
+	return &DraftEndpoint{
+		service: service,
+	}
+}
+
+type DraftRequest struct {
+	Data   []byte `json:"data"`
+	Enrich bool   `json:"enrich"`
+}
+
+func (ep *DraftEndpoint) Draft(ctx context.Context, r interface{}) (interface{}, error) {
+	req, ok := r.(DraftRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	draft, err := ep.service.Draft(ctx, req.Data, req.Enrich)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": draft,
+	}, nil
+}
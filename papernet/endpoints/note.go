@@ -0,0 +1,113 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/papernet/services"
+	"github.com/bobinette/papernet/users"
+)
+
+type NoteEndpoint struct {
+	service *services.NoteService
+}
+
+func NewNoteEndpoint(service *services.NoteService) *NoteEndpoint {
+	return &NoteEndpoint{
+		service: service,
+	}
+}
+
+func (ep *NoteEndpoint) List(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	paperID, ok := r.(int)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	notes, err := ep.service.List(user, paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": notes,
+	}, nil
+}
+
+type CreateNoteRequest struct {
+	PaperID int
+	Note    papernet.Note
+}
+
+func (ep *NoteEndpoint) Create(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(CreateNoteRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	note, err := ep.service.Create(user, req.PaperID, req.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": note,
+	}, nil
+}
+
+type NoteRequest struct {
+	PaperID int    `json:"-"`
+	NoteID  int    `json:"-"`
+	Content string `json:"content"`
+}
+
+func (ep *NoteEndpoint) Update(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(NoteRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	note, err := ep.service.Update(user, req.PaperID, req.NoteID, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": note,
+	}, nil
+}
+
+func (ep *NoteEndpoint) Delete(ctx context.Context, r interface{}) (interface{}, error) {
+	user, err := users.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, ok := r.(NoteRequest)
+	if !ok {
+		return nil, errInvalidRequest
+	}
+
+	err = ep.service.Delete(user, req.PaperID, req.NoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	return statusCoder{code: http.StatusNoContent}, nil
+}
@@ -30,6 +30,9 @@ type SearchPaperRequest struct {
 	Q          string
 	Tags       []string
 	Bookmarked bool
+	List       int
+	Statuses   []papernet.ReadingStatus
+	MinRating  int
 	Limit      int
 	Offset     int
 }
@@ -45,7 +48,7 @@ func (ep *PaperEndpoint) Search(ctx context.Context, r interface{}) (interface{}
 		return nil, errInvalidRequest
 	}
 
-	res, err := ep.service.Search(user, req.Q, req.Tags, req.Bookmarked, req.Offset, req.Limit)
+	res, err := ep.service.Search(user, req.Q, req.Tags, req.Bookmarked, req.List, req.Statuses, req.MinRating, req.Offset, req.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -54,21 +57,27 @@ func (ep *PaperEndpoint) Search(ctx context.Context, r interface{}) (interface{}
 		"data":       res.Papers,
 		"pagination": res.Pagination,
 		"facets":     res.Facets,
+		"matches":    res.Matches,
 	}, nil
 }
 
+type CreatePaperRequest struct {
+	Paper  papernet.Paper
+	TeamID int
+}
+
 func (ep *PaperEndpoint) Create(ctx context.Context, r interface{}) (interface{}, error) {
 	user, err := users.FromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	paper, ok := r.(papernet.Paper)
+	req, ok := r.(CreatePaperRequest)
 	if !ok {
 		return nil, errInvalidRequest
 	}
 
-	paper, err = ep.service.Create(user.ID, paper)
+	paper, err := ep.service.Create(user.ID, req.TeamID, req.Paper)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,63 @@
+package fs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+// AttachmentStorage stores the attachments on the local file system, in a
+// directory per paper.
+type AttachmentStorage struct {
+	Dir string
+}
+
+func (s *AttachmentStorage) Name() string {
+	return "local"
+}
+
+// Save writes data in a new file. The name of the file is random so the name
+// given by the user never ends up in a path.
+func (s *AttachmentStorage) Save(a *papernet.Attachment, data []byte) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%d/%s", a.PaperID, hex.EncodeToString(b))
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	a.Key = key
+	return nil
+}
+
+func (s *AttachmentStorage) Open(a papernet.Attachment) (io.ReadCloser, error) {
+	return os.Open(s.path(a.Key))
+}
+
+// Delete removes the file of an attachment. Deleting a file that does not
+// exist is not an error.
+func (s *AttachmentStorage) Delete(a papernet.Attachment) error {
+	err := os.Remove(s.path(a.Key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *AttachmentStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
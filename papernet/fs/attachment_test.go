@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+func TestAttachmentStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal("could not create tmp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage := AttachmentStorage{Dir: dir}
+
+	a := papernet.Attachment{PaperID: 1, Name: "../../paper.pdf"}
+	if err := storage.Save(&a, []byte("%PDF-1.4")); err != nil {
+		t.Fatal("error saving:", err)
+	} else if a.Key == "" {
+		t.Fatal("saving should have set the key")
+	}
+
+	r, err := storage.Open(a)
+	if err != nil {
+		t.Fatal("error opening:", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal("error reading:", err)
+	} else if string(data) != "%PDF-1.4" {
+		t.Fatalf("incorrect content: %s", data)
+	}
+
+	if err := storage.Delete(a); err != nil {
+		t.Fatal("error deleting:", err)
+	}
+	if _, err := storage.Open(a); !os.IsNotExist(err) {
+		t.Fatal("file should have been deleted, got:", err)
+	}
+	if err := storage.Delete(a); err != nil {
+		t.Fatal("deleting twice should not fail:", err)
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/users"
+
+	"github.com/bobinette/papernet/clients/auth"
+
+	"github.com/bobinette/papernet/papernet/endpoints"
+	"github.com/bobinette/papernet/papernet/services"
+)
+
+func RegisterAnnotationEndpoints(srv Server, service *services.AnnotationService, keys *jwt.KeySet, au *auth.Client) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	authenticator := users.NewAuthenticator(au)
+	readMiddleware := jwt.Middleware(keys, jwt.ScopePapersRead, jwt.ScopePapersWrite)
+	writeMiddleware := jwt.Middleware(keys, jwt.ScopePapersWrite)
+
+	// Create endpoint
+	ep := endpoints.NewAnnotationEndpoint(service)
+
+	// Get annotation handler
+	getAnnotationHandler := kithttp.NewServer(
+		readMiddleware(authenticator.Authenticated(ep.Get)),
+		decodeGetPaperRequest, // Only the paper id is needed
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Update annotation handler
+	updateAnnotationHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Update)),
+		decodeUpdateAnnotationRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Team ratings handler
+	teamRatingsHandler := kithttp.NewServer(
+		readMiddleware(authenticator.Authenticated(ep.TeamRatings)),
+		decodeTeamRatingsRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/paper/v2/papers/:id/annotation", "GET", getAnnotationHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/annotation", "PUT", updateAnnotationHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/ratings", "GET", teamRatingsHandler)
+}
+
+func decodeUpdateAnnotationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var req endpoints.UpdateAnnotationRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	req.PaperID = paperID
+	return req, nil
+}
+
+func decodeTeamRatingsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	teamID, err := strconv.Atoi(r.URL.Query().Get("team"))
+	if err != nil {
+		return nil, errors.New("invalid parameter: team", errors.BadRequest(), errors.WithCause(err))
+	}
+
+	return endpoints.TeamRatingsRequest{
+		PaperID: paperID,
+		TeamID:  teamID,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/users"
+
+	"github.com/bobinette/papernet/clients/auth"
+
+	"github.com/bobinette/papernet/papernet/endpoints"
+	"github.com/bobinette/papernet/papernet/services"
+)
+
+// maxUploadBodySize bounds the body of the upload requests. The files are
+// base64 encoded in the body, hence the margin above the maximum file size.
+const maxUploadBodySize = 48 << 20
+
+func RegisterAttachmentEndpoints(srv Server, service *services.AttachmentService, keys *jwt.KeySet, au *auth.Client) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	authenticator := users.NewAuthenticator(au)
+	readMiddleware := jwt.Middleware(keys, jwt.ScopePapersRead, jwt.ScopePapersWrite)
+	writeMiddleware := jwt.Middleware(keys, jwt.ScopePapersWrite)
+
+	// Create endpoint
+	ep := endpoints.NewAttachmentEndpoint(service)
+
+	// List attachments handler
+	listAttachmentsHandler := kithttp.NewServer(
+		readMiddleware(authenticator.Authenticated(ep.List)),
+		decodeGetPaperRequest, // Only the paper id is needed
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Upload attachment handler
+	uploadAttachmentHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Upload)),
+		decodeUploadAttachmentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Download attachment handler
+	downloadAttachmentHandler := kithttp.NewServer(
+		readMiddleware(authenticator.Authenticated(ep.Download)),
+		decodeAttachmentRequest,
+		encodeAttachmentContent,
+		opts...,
+	)
+
+	// Delete attachment handler
+	deleteAttachmentHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Delete)),
+		decodeAttachmentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/paper/v2/papers/:id/attachments", "GET", listAttachmentsHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/attachments", "POST", uploadAttachmentHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/attachments/:attachmentID", "GET", downloadAttachmentHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/attachments/:attachmentID", "DELETE", deleteAttachmentHandler)
+}
+
+func decodeUploadAttachmentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var req endpoints.UploadAttachmentRequest
+	err = json.NewDecoder(io.LimitReader(r.Body, maxUploadBodySize)).Decode(&req)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	req.PaperID = paperID
+	return req, nil
+}
+
+func decodeAttachmentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentID, err := strconv.Atoi(params["attachmentID"])
+	if err != nil {
+		return nil, errors.New("invalid attachment id", errors.BadRequest(), errors.WithCause(err))
+	}
+
+	return endpoints.AttachmentRequest{
+		PaperID:      paperID,
+		AttachmentID: attachmentID,
+	}, nil
+}
+
+// encodeAttachmentContent writes the content of an attachment, to be displayed
+// by the browser.
+func encodeAttachmentContent(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	res, ok := response.(endpoints.AttachmentContent)
+	if !ok {
+		return errors.New("invalid response")
+	}
+	defer res.Content.Close()
+
+	a := res.Attachment
+	w.Header().Set("Content-Type", a.MimeType)
+	w.Header().Set("Content-Length", strconv.Itoa(a.Size))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	_, err := io.Copy(w, res.Content)
+	return err
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/users"
+
+	"github.com/bobinette/papernet/clients/auth"
+
+	"github.com/bobinette/papernet/papernet/endpoints"
+	"github.com/bobinette/papernet/papernet/services"
+)
+
+func RegisterDraftEndpoints(srv Server, service *services.DraftService, keys *jwt.KeySet, au *auth.Client) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	authenticator := users.NewAuthenticator(au)
+	writeMiddleware := jwt.Middleware(keys, jwt.ScopePapersWrite)
+
+	// Create endpoint
+	ep := endpoints.NewDraftEndpoint(service)
+
+	// Draft handler
+	draftHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Draft)),
+		decodeDraftRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/paper/v2/drafts", "POST", draftHandler)
+}
+
+func decodeDraftRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var req endpoints.DraftRequest
+	err := json.NewDecoder(io.LimitReader(r.Body, maxUploadBodySize)).Decode(&req)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	return req, nil
+}
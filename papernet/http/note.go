@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	kitjwt "github.com/go-kit/kit/auth/jwt"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/jwt"
+	"github.com/bobinette/papernet/users"
+
+	"github.com/bobinette/papernet/clients/auth"
+
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/papernet/endpoints"
+	"github.com/bobinette/papernet/papernet/services"
+)
+
+func RegisterNoteEndpoints(srv Server, service *services.NoteService, keys *jwt.KeySet, au *auth.Client) {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
+	}
+
+	authenticator := users.NewAuthenticator(au)
+	readMiddleware := jwt.Middleware(keys, jwt.ScopePapersRead, jwt.ScopePapersWrite)
+	writeMiddleware := jwt.Middleware(keys, jwt.ScopePapersWrite)
+
+	// Create endpoint
+	ep := endpoints.NewNoteEndpoint(service)
+
+	// List notes handler
+	listNotesHandler := kithttp.NewServer(
+		readMiddleware(authenticator.Authenticated(ep.List)),
+		decodeGetPaperRequest, // Only the paper id is needed
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Create note handler
+	createNoteHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Create)),
+		decodeCreateNoteRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Update note handler
+	updateNoteHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Update)),
+		decodeUpdateNoteRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Delete note handler
+	deleteNoteHandler := kithttp.NewServer(
+		writeMiddleware(authenticator.Authenticated(ep.Delete)),
+		decodeNoteRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	// Register all handlers
+	srv.RegisterHandler("/paper/v2/papers/:id/notes", "GET", listNotesHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/notes", "POST", createNoteHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/notes/:noteID", "PUT", updateNoteHandler)
+	srv.RegisterHandler("/paper/v2/papers/:id/notes/:noteID", "DELETE", deleteNoteHandler)
+}
+
+func decodeCreateNoteRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	var note papernet.Note
+	err = json.NewDecoder(r.Body).Decode(&note)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	return endpoints.CreateNoteRequest{
+		PaperID: paperID,
+		Note:    note,
+	}, nil
+}
+
+func decodeUpdateNoteRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	req, err := noteParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return nil, errors.New("error decoding body", errors.WithCause(err), errors.BadRequest())
+	}
+
+	return req, nil
+}
+
+func decodeNoteRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return noteParams(ctx)
+}
+
+// noteParams reads the ids of the paper and of the note from the url.
+func noteParams(ctx context.Context) (endpoints.NoteRequest, error) {
+	params := ctx.Value("params").(map[string]string)
+	paperID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return endpoints.NoteRequest{}, err
+	}
+
+	noteID, err := strconv.Atoi(params["noteID"])
+	if err != nil {
+		return endpoints.NoteRequest{}, errors.New("invalid note id", errors.BadRequest(), errors.WithCause(err))
+	}
+
+	return endpoints.NoteRequest{
+		PaperID: paperID,
+		NoteID:  noteID,
+	}, nil
+}
@@ -20,21 +20,22 @@ import (
 	"github.com/bobinette/papernet/papernet/services"
 )
 
-func RegisterPaperEndpoints(srv Server, service *services.PaperService, jwtKey []byte, au *auth.Client) {
+func RegisterPaperEndpoints(srv Server, service *services.PaperService, keys *jwt.KeySet, au *auth.Client) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
 
 	authenticator := users.NewAuthenticator(au)
-	jwtMiddleware := jwt.Middleware(jwtKey)
+	readMiddleware := jwt.Middleware(keys, jwt.ScopePapersRead, jwt.ScopePapersWrite)
+	writeMiddleware := jwt.Middleware(keys, jwt.ScopePapersWrite)
 
 	// Create endpoint
 	ep := endpoints.NewPaperEndpoint(service)
 
 	// Search paper handler
 	searchPaperHandler := kithttp.NewServer(
-		jwtMiddleware(authenticator.Authenticated(ep.Search)),
+		readMiddleware(authenticator.Authenticated(ep.Search)),
 		decodeSearchPaperRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -42,7 +43,7 @@ func RegisterPaperEndpoints(srv Server, service *services.PaperService, jwtKey [
 
 	// Create paper handler
 	createPaperHandler := kithttp.NewServer(
-		jwtMiddleware(authenticator.Authenticated(ep.Create)),
+		writeMiddleware(authenticator.Authenticated(ep.Create)),
 		decodeCreatePaperRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -50,7 +51,7 @@ func RegisterPaperEndpoints(srv Server, service *services.PaperService, jwtKey [
 
 	// Get paper handler
 	getPaperHandler := kithttp.NewServer(
-		jwtMiddleware(authenticator.Authenticated(ep.Get)),
+		readMiddleware(authenticator.Authenticated(ep.Get)),
 		decodeGetPaperRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -58,7 +59,7 @@ func RegisterPaperEndpoints(srv Server, service *services.PaperService, jwtKey [
 
 	// Update paper handler
 	updatePaperHandler := kithttp.NewServer(
-		jwtMiddleware(authenticator.Authenticated(ep.Update)),
+		writeMiddleware(authenticator.Authenticated(ep.Update)),
 		decodeUpdatePaperRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -66,7 +67,7 @@ func RegisterPaperEndpoints(srv Server, service *services.PaperService, jwtKey [
 
 	// Update paper handler
 	deletePaperHandler := kithttp.NewServer(
-		jwtMiddleware(authenticator.Authenticated(ep.Delete)),
+		writeMiddleware(authenticator.Authenticated(ep.Delete)),
 		decodeGetPaperRequest, // Decoder is the same as get
 		kithttp.EncodeJSONResponse,
 		opts...,
@@ -110,12 +111,36 @@ func decodeSearchPaperRequest(ctx context.Context, r *http.Request) (interface{}
 		}
 	}
 
+	list := r.URL.Query().Get("list")
+	if list != "" {
+		var err error
+		req.List, err = strconv.Atoi(list)
+		if err != nil {
+			return nil, errors.New("invalid parameter: list", errors.BadRequest(), errors.WithCause(err))
+		}
+	}
+
+	for _, status := range r.URL.Query()["status"] {
+		req.Statuses = append(req.Statuses, papernet.ReadingStatus(status))
+	}
+
+	minRating := r.URL.Query().Get("minRating")
+	if minRating != "" {
+		var err error
+		req.MinRating, err = strconv.Atoi(minRating)
+		if err != nil {
+			return nil, errors.New("invalid parameter: minRating", errors.BadRequest(), errors.WithCause(err))
+		}
+	}
+
 	limit := r.URL.Query().Get("limit")
 	if limit != "" {
 		var err error
 		req.Limit, err = strconv.Atoi(limit)
 		if err != nil {
 			return nil, errors.New("invalid parameter: limit", errors.BadRequest(), errors.WithCause(err))
+		} else if req.Limit < 0 {
+			return nil, errors.New("invalid parameter: limit cannot be negative", errors.BadRequest())
 		}
 	}
 
@@ -125,6 +150,8 @@ func decodeSearchPaperRequest(ctx context.Context, r *http.Request) (interface{}
 		req.Offset, err = strconv.Atoi(offset)
 		if err != nil {
 			return nil, errors.New("invalid parameter: offset", errors.BadRequest(), errors.WithCause(err))
+		} else if req.Offset < 0 {
+			return nil, errors.New("invalid parameter: offset cannot be negative", errors.BadRequest())
 		}
 	}
 
@@ -140,7 +167,18 @@ func decodeCreatePaperRequest(ctx context.Context, r *http.Request) (interface{}
 		return nil, err
 	}
 
-	req := paper
+	req := endpoints.CreatePaperRequest{
+		Paper: paper,
+	}
+
+	team := r.URL.Query().Get("team")
+	if team != "" {
+		req.TeamID, err = strconv.Atoi(team)
+		if err != nil {
+			return nil, errors.New("invalid parameter: team", errors.BadRequest(), errors.WithCause(err))
+		}
+	}
+
 	return req, nil
 }
 
@@ -16,14 +16,14 @@ import (
 	"github.com/bobinette/papernet/papernet/services"
 )
 
-func RegisterTagEndpoints(srv Server, service *services.TagService, jwtKey []byte, au *auth.Client) {
+func RegisterTagEndpoints(srv Server, service *services.TagService, keys *jwt.KeySet, au *auth.Client) {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorEncoder(encodeError),
 		kithttp.ServerBefore(kitjwt.ToHTTPContext()),
 	}
 
 	authenticator := users.NewAuthenticator(au)
-	jwtMiddleware := jwt.Middleware(jwtKey)
+	jwtMiddleware := jwt.Middleware(keys, jwt.ScopePapersRead, jwt.ScopePapersWrite)
 
 	// Create endpoint
 	ep := endpoints.NewTagEndpoint(service)
@@ -0,0 +1,51 @@
+package papernet
+
+import (
+	"time"
+)
+
+// Note is a comment written by a user on a paper, in Markdown. A note is
+// either private to its author or shared with one of their teams. The replies
+// to a note are shared with the same audience.
+type Note struct {
+	ID       int `json:"id"`
+	PaperID  int `json:"paperId"`
+	AuthorID int `json:"authorId"`
+
+	// TeamID is the team the note is shared with, 0 for a private note.
+	TeamID int `json:"teamId"`
+	// ParentID is the note replied to, 0 for the notes starting a thread.
+	ParentID int `json:"parentId"`
+
+	Content string `json:"content"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// VisibleTo returns whether a user, member of the teams teamIDs, can read the
+// note.
+func (n Note) VisibleTo(userID int, teamIDs []int) bool {
+	if n.TeamID == 0 {
+		return n.AuthorID == userID
+	}
+
+	for _, teamID := range teamIDs {
+		if teamID == n.TeamID {
+			return true
+		}
+	}
+	return false
+}
+
+type NoteRepository interface {
+	// Get returns the note defined by id. The note returned has an id of 0 if
+	// there is none.
+	Get(id int) (Note, error)
+	// ListForPaper returns the notes of a paper, in creation order.
+	ListForPaper(paperID int) ([]Note, error)
+	// Insert stores a new note, setting its id and its dates.
+	Insert(*Note) error
+	Update(*Note) error
+	Delete(id int) error
+}
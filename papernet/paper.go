@@ -28,6 +28,18 @@ type SearchParams struct {
 	Q    string   `json:"q"`
 	Tags []string `json:"tags"`
 
+	// UserID is the user whose annotations are used by the status and
+	// rating filters and facets. They are ignored when it is 0.
+	UserID int `json:"userId"`
+	// Statuses restricts the search to the papers having one of the reading
+	// statuses.
+	Statuses []ReadingStatus `json:"statuses"`
+	// MinRating restricts the search to the papers rated at least MinRating.
+	MinRating int `json:"minRating"`
+	// TeamIDs are the teams of the user. The query matches the private notes
+	// of the user and the notes shared with these teams.
+	TeamIDs []int `json:"teamIds"`
+
 	Limit  uint64 `json:"limit"`
 	Offset uint64 `json:"offset"`
 }
@@ -37,14 +49,32 @@ type TagsFacet []struct {
 	Count int    `json:"count"`
 }
 
+// StatusesFacet counts the papers by reading status of the user searching.
+type StatusesFacet []struct {
+	Status ReadingStatus `json:"status"`
+	Count  int           `json:"count"`
+}
+
+// RatingsFacet counts the papers by rating of the user searching.
+type RatingsFacet []struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
 type Facets struct {
-	Tags TagsFacet `json:"tags,omitempty"`
+	Tags     TagsFacet     `json:"tags,omitempty"`
+	Statuses StatusesFacet `json:"statuses,omitempty"`
+	Ratings  RatingsFacet  `json:"ratings,omitempty"`
 }
 
 type SearchResults struct {
 	IDs        []int
 	Facets     Facets
 	Pagination Pagination
+
+	// Matches are the pages of the attachments matching the search, by
+	// paper id.
+	Matches map[int][]AttachmentMatch
 }
 
 type PaperRepository interface {
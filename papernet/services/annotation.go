@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/users"
+)
+
+// AnnotationService manages the reading statuses and the ratings the users
+// give to the papers they can see. The annotations are personal, they are
+// only shared as aggregates within the teams.
+//
+// The annotations are indexed with their paper, so the searches can be
+// filtered on them.
+type AnnotationService struct {
+	repository papernet.AnnotationRepository
+
+	paperRepository papernet.PaperRepository
+	paperIndex      papernet.PaperIndex
+
+	userService UserService
+}
+
+func NewAnnotationService(
+	repo papernet.AnnotationRepository,
+	paperRepo papernet.PaperRepository,
+	paperIndex papernet.PaperIndex,
+	us UserService,
+) *AnnotationService {
+	return &AnnotationService{
+		repository: repo,
+
+		paperRepository: paperRepo,
+		paperIndex:      paperIndex,
+
+		userService: us,
+	}
+}
+
+// Get returns the annotation of the user on a paper.
+func (s *AnnotationService) Get(user users.User, paperID int) (papernet.Annotation, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	return s.repository.Get(user.ID, paperID)
+}
+
+// Update sets the reading status and the rating of the user on a paper. The
+// date the paper was read is set when the status goes to read. Clearing both
+// the status and the rating removes the annotation.
+func (s *AnnotationService) Update(user users.User, paperID int, status papernet.ReadingStatus, rating int) (papernet.Annotation, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	switch status {
+	case "", papernet.StatusToRead, papernet.StatusReading, papernet.StatusRead:
+	default:
+		return papernet.Annotation{}, errors.New(fmt.Sprintf("invalid reading status: %s", status), errors.BadRequest())
+	}
+
+	if rating != 0 && (rating < papernet.MinRating || rating > papernet.MaxRating) {
+		return papernet.Annotation{}, errors.New(
+			fmt.Sprintf("rating must be between %d and %d", papernet.MinRating, papernet.MaxRating),
+			errors.BadRequest(),
+		)
+	}
+
+	annotation, err := s.repository.Get(user.ID, paperID)
+	if err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	now := time.Now().UTC()
+	if status != papernet.StatusRead {
+		annotation.ReadAt = nil
+	} else if annotation.Status != papernet.StatusRead {
+		annotation.ReadAt = &now
+	}
+	annotation.Status = status
+	annotation.Rating = rating
+	annotation.UpdatedAt = now
+
+	if annotation.IsEmpty() {
+		err = s.repository.Delete(user.ID, paperID)
+		annotation.UpdatedAt = time.Time{}
+	} else {
+		err = s.repository.Upsert(annotation)
+	}
+	if err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	err = reindex(s.paperRepository, s.paperIndex, paperID)
+	if err != nil {
+		return papernet.Annotation{}, err
+	}
+
+	return annotation, nil
+}
+
+// TeamRatings aggregates the ratings given to a paper by the members of a
+// team the user is a member of. The average is only given from
+// papernet.MinTeamRatings ratings, otherwise the members could deduce the
+// ratings of each other.
+func (s *AnnotationService) TeamRatings(user users.User, teamID, paperID int) (papernet.RatingSummary, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.RatingSummary{}, err
+	}
+
+	team, err := s.userService.Team(user.ID, teamID)
+	if err != nil {
+		return papernet.RatingSummary{}, err
+	}
+
+	members := make(map[int]bool, len(team.Members))
+	for _, member := range team.Members {
+		members[member.ID] = true
+	}
+
+	annotations, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return papernet.RatingSummary{}, err
+	}
+
+	summary := papernet.RatingSummary{PaperID: paperID}
+	total := 0
+	for _, annotation := range annotations {
+		if !members[annotation.UserID] || annotation.Rating == 0 {
+			continue
+		}
+
+		summary.Count++
+		total += annotation.Rating
+	}
+	if summary.Count >= papernet.MinTeamRatings {
+		summary.Average = float64(total) / float64(summary.Count)
+	}
+
+	return summary, nil
+}
+
+// DeletePaper removes the annotations of a paper that is being deleted. The
+// paper is not reindexed.
+func (s *AnnotationService) DeletePaper(paperID int) error {
+	annotations, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return err
+	}
+
+	for _, annotation := range annotations {
+		err = s.repository.Delete(annotation.UserID, paperID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
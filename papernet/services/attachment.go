@@ -0,0 +1,298 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/users"
+)
+
+// maxAttachmentSize is the maximum size of an uploaded file.
+const maxAttachmentSize = 32 << 20
+
+func errAttachmentNotFound(id int) error {
+	return errors.New(fmt.Sprintf("attachment %d not found", id), errors.NotFound())
+}
+
+// AttachmentService links PDF files to the papers. The permissions on the
+// attachments are the ones on their paper.
+//
+// The text of the attachments is extracted when they are uploaded, and indexed
+// with their paper.
+type AttachmentService struct {
+	repository papernet.AttachmentRepository
+	texts      papernet.AttachmentTextRepository
+	extractor  papernet.TextExtractor
+
+	paperRepository papernet.PaperRepository
+	paperIndex      papernet.PaperIndex
+
+	storages       map[string]papernet.AttachmentStorage
+	defaultStorage string
+}
+
+// NewAttachmentService creates an attachment service storing the files in the
+// given storages. The first storage is used when none is specified.
+func NewAttachmentService(
+	repo papernet.AttachmentRepository,
+	texts papernet.AttachmentTextRepository,
+	extractor papernet.TextExtractor,
+	paperRepo papernet.PaperRepository,
+	paperIndex papernet.PaperIndex,
+	storages ...papernet.AttachmentStorage,
+) *AttachmentService {
+	s := &AttachmentService{
+		repository: repo,
+		texts:      texts,
+		extractor:  extractor,
+
+		paperRepository: paperRepo,
+		paperIndex:      paperIndex,
+
+		storages: make(map[string]papernet.AttachmentStorage, len(storages)),
+	}
+
+	for _, storage := range storages {
+		s.storages[storage.Name()] = storage
+	}
+	if len(storages) > 0 {
+		s.defaultStorage = storages[0].Name()
+	}
+
+	return s
+}
+
+// Upload attaches a PDF file to a paper the user can edit.
+func (s *AttachmentService) Upload(user users.User, paperID int, storageName, name string, data []byte) (papernet.Attachment, error) {
+	if err := aclCanEdit(user, paperID); err != nil {
+		return papernet.Attachment{}, err
+	}
+
+	if name == "" {
+		return papernet.Attachment{}, errors.New("missing name", errors.BadRequest())
+	} else if len(data) == 0 {
+		return papernet.Attachment{}, errors.New("empty file", errors.BadRequest())
+	} else if len(data) > maxAttachmentSize {
+		return papernet.Attachment{}, errors.New("file too large", errors.WithCode(http.StatusRequestEntityTooLarge))
+	}
+
+	// Do not trust the type announced by the client
+	mimeType := http.DetectContentType(data)
+	if mimeType != "application/pdf" {
+		return papernet.Attachment{}, errors.New("only PDF files can be attached", errors.BadRequest())
+	}
+
+	if storageName == "" {
+		storageName = s.defaultStorage
+	}
+	storage, ok := s.storages[storageName]
+	if !ok {
+		return papernet.Attachment{}, errors.New(fmt.Sprintf("unknown storage %s", storageName), errors.BadRequest())
+	}
+
+	attachment := papernet.Attachment{
+		PaperID:  paperID,
+		UserID:   user.ID,
+		Name:     name,
+		MimeType: mimeType,
+		Size:     len(data),
+		Storage:  storage.Name(),
+	}
+	err := storage.Save(&attachment, data)
+	if err != nil {
+		return papernet.Attachment{}, err
+	}
+
+	err = s.repository.Insert(&attachment)
+	if err != nil {
+		// Do not keep a file no attachment refers to
+		storage.Delete(attachment)
+		return papernet.Attachment{}, err
+	}
+
+	err = s.indexText(attachment, data)
+	if err != nil {
+		return papernet.Attachment{}, err
+	}
+
+	return attachment, nil
+}
+
+// List returns the attachments of a paper the user can see.
+func (s *AttachmentService) List(user users.User, paperID int) ([]papernet.Attachment, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return nil, err
+	}
+
+	return s.repository.ListForPaper(paperID)
+}
+
+// Open returns an attachment of a paper the user can see, along with its
+// content. The caller has to close the reader.
+func (s *AttachmentService) Open(user users.User, paperID, id int) (papernet.Attachment, io.ReadCloser, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.Attachment{}, nil, err
+	}
+
+	attachment, storage, err := s.get(paperID, id)
+	if err != nil {
+		return papernet.Attachment{}, nil, err
+	}
+
+	r, err := storage.Open(attachment)
+	if err != nil {
+		return papernet.Attachment{}, nil, err
+	}
+
+	return attachment, r, nil
+}
+
+// Delete removes an attachment of a paper the user can edit.
+func (s *AttachmentService) Delete(user users.User, paperID, id int) error {
+	if err := aclCanEdit(user, paperID); err != nil {
+		return err
+	}
+
+	attachment, storage, err := s.get(paperID, id)
+	if err != nil {
+		return err
+	}
+
+	err = storage.Delete(attachment)
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.Delete(attachment.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.texts.Delete(attachment.ID)
+	if err != nil {
+		return err
+	}
+
+	return reindex(s.paperRepository, s.paperIndex, paperID)
+}
+
+// DeletePaper removes the attachments of a paper that is being deleted, with
+// their files and their text. The paper is not reindexed.
+func (s *AttachmentService) DeletePaper(paperID int) error {
+	attachments, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		storage, ok := s.storages[attachment.Storage]
+		if !ok {
+			return errors.New(fmt.Sprintf("storage %s is not available", attachment.Storage))
+		}
+
+		err = storage.Delete(attachment)
+		if err != nil {
+			return err
+		}
+
+		err = s.repository.Delete(attachment.ID)
+		if err != nil {
+			return err
+		}
+
+		err = s.texts.Delete(attachment.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtractText extracts again the text of the attachments of a paper, and
+// reindexes it. It returns the number of attachments whose text could be
+// extracted.
+func (s *AttachmentService) ExtractText(paperID int) (int, error) {
+	attachments, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return 0, err
+	}
+
+	extracted := 0
+	for _, attachment := range attachments {
+		storage, ok := s.storages[attachment.Storage]
+		if !ok {
+			continue
+		}
+
+		data, err := read(storage, attachment)
+		if err != nil {
+			return extracted, err
+		}
+
+		pages, err := s.extractor.ExtractText(data)
+		if err != nil {
+			// The file is not readable, it will not be searchable
+			continue
+		}
+
+		err = s.texts.Upsert(papernet.AttachmentText{AttachmentID: attachment.ID, PaperID: paperID, Pages: pages})
+		if err != nil {
+			return extracted, err
+		}
+		extracted++
+	}
+
+	return extracted, reindex(s.paperRepository, s.paperIndex, paperID)
+}
+
+// indexText extracts the text of an attachment and reindexes its paper. Files
+// whose text cannot be extracted are still attached, they are just not
+// searchable.
+func (s *AttachmentService) indexText(attachment papernet.Attachment, data []byte) error {
+	pages, err := s.extractor.ExtractText(data)
+	if err != nil {
+		return nil
+	}
+
+	err = s.texts.Upsert(papernet.AttachmentText{
+		AttachmentID: attachment.ID,
+		PaperID:      attachment.PaperID,
+		Pages:        pages,
+	})
+	if err != nil {
+		return err
+	}
+
+	return reindex(s.paperRepository, s.paperIndex, attachment.PaperID)
+}
+
+func read(storage papernet.AttachmentStorage, attachment papernet.Attachment) ([]byte, error) {
+	r, err := storage.Open(attachment)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func (s *AttachmentService) get(paperID, id int) (papernet.Attachment, papernet.AttachmentStorage, error) {
+	attachment, err := s.repository.Get(id)
+	if err != nil {
+		return papernet.Attachment{}, nil, err
+	} else if attachment.ID == 0 || attachment.PaperID != paperID {
+		return papernet.Attachment{}, nil, errAttachmentNotFound(id)
+	}
+
+	storage, ok := s.storages[attachment.Storage]
+	if !ok {
+		return papernet.Attachment{}, nil, errors.New(fmt.Sprintf("storage %s is not available", attachment.Storage))
+	}
+
+	return attachment, storage, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bobinette/papernet/clients/imports"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+)
+
+// DraftService pre-fills papers from the PDF files dropped by the users.
+type DraftService struct {
+	extractor papernet.DraftExtractor
+	enricher  papernet.DraftEnricher
+}
+
+// NewDraftService creates a draft service. The enricher is optional.
+func NewDraftService(extractor papernet.DraftExtractor, enricher papernet.DraftEnricher) *DraftService {
+	return &DraftService{
+		extractor: extractor,
+		enricher:  enricher,
+	}
+}
+
+// Draft reads the metadata of a PDF file, and completes them through the
+// imports searchers if enrich is true. The context has to contain the user.
+func (s *DraftService) Draft(ctx context.Context, data []byte, enrich bool) (papernet.PaperDraft, error) {
+	if len(data) == 0 {
+		return papernet.PaperDraft{}, errors.New("empty file", errors.BadRequest())
+	} else if len(data) > maxAttachmentSize {
+		return papernet.PaperDraft{}, errors.New("file too large", errors.WithCode(http.StatusRequestEntityTooLarge))
+	} else if http.DetectContentType(data) != "application/pdf" {
+		return papernet.PaperDraft{}, errors.New("only PDF files can be read", errors.BadRequest())
+	}
+
+	draft, err := s.extractor.ExtractDraft(data)
+	if err != nil {
+		return papernet.PaperDraft{}, errors.New("could not read the file", errors.WithCause(err), errors.BadRequest())
+	}
+
+	if !enrich || s.enricher == nil {
+		return draft, nil
+	}
+
+	enriched, err := s.enricher.Enrich(ctx, draft)
+	if err != nil {
+		// The metadata of the file are still worth returning
+		return draft, nil
+	}
+	return enriched, nil
+}
+
+// ImportsEnricher completes the drafts having an arXiv identifier with the
+// paper found by the arxiv searcher of the imports service.
+type ImportsEnricher struct {
+	client *imports.Client
+}
+
+func NewImportsEnricher(client *imports.Client) *ImportsEnricher {
+	return &ImportsEnricher{
+		client: client,
+	}
+}
+
+// importedPaper is the paper returned by the imports search.
+type importedPaper struct {
+	Source     string   `json:"source"`
+	Reference  string   `json:"reference"`
+	Title      string   `json:"title"`
+	Summary    string   `json:"summary"`
+	Tags       []string `json:"tags"`
+	Authors    []string `json:"authors"`
+	References []string `json:"references"`
+}
+
+func (e *ImportsEnricher) Enrich(ctx context.Context, draft papernet.PaperDraft) (papernet.PaperDraft, error) {
+	if len(draft.ArxivIDs) == 0 {
+		return draft, nil
+	}
+
+	var res map[string]struct {
+		Papers []importedPaper `json:"papers"`
+	}
+	err := e.client.Search(ctx, draft.ArxivIDs[0], 1, 0, []string{"arxiv"}).Decode(&res)
+	if err != nil {
+		return papernet.PaperDraft{}, err
+	}
+
+	papers := res["arxiv"].Papers
+	if len(papers) == 0 {
+		return draft, nil
+	}
+
+	return mergeDraft(draft, papers[0]), nil
+}
+
+// mergeDraft completes a draft with an imported paper. The data of the source
+// are preferred to the metadata of the file, the tags and references are
+// merged.
+func mergeDraft(draft papernet.PaperDraft, p importedPaper) papernet.PaperDraft {
+	if p.Title != "" {
+		draft.Paper.Title = p.Title
+	}
+	if p.Summary != "" {
+		draft.Paper.Summary = p.Summary
+	}
+	if len(p.Authors) > 0 {
+		draft.Paper.Authors = p.Authors
+	}
+
+	for _, tag := range p.Tags {
+		if !containsString(tag, draft.Paper.Tags) {
+			draft.Paper.Tags = append(draft.Paper.Tags, tag)
+		}
+	}
+	for _, ref := range p.References {
+		if !containsString(ref, draft.Paper.References) {
+			draft.Paper.References = append(draft.Paper.References, ref)
+		}
+	}
+
+	draft.Source = p.Source
+	return draft
+}
+
+func containsString(v string, a []string) bool {
+	for _, s := range a {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+	"github.com/bobinette/papernet/users"
+)
+
+// maxNoteLength is the maximum size of the Markdown content of a note.
+const maxNoteLength = 20000
+
+func errNoteNotFound(id int) error {
+	return errors.New(fmt.Sprintf("note %d not found", id), errors.NotFound())
+}
+
+// NoteService manages the notes on the papers. The users can write notes on
+// the papers they can see, either for themselves or for one of their teams.
+// Only the author of a note can edit or delete it.
+//
+// The notes are indexed with their paper, so the users can search the notes
+// they can read.
+type NoteService struct {
+	repository papernet.NoteRepository
+
+	paperRepository papernet.PaperRepository
+	paperIndex      papernet.PaperIndex
+
+	userService UserService
+}
+
+func NewNoteService(
+	repo papernet.NoteRepository,
+	paperRepo papernet.PaperRepository,
+	paperIndex papernet.PaperIndex,
+	us UserService,
+) *NoteService {
+	return &NoteService{
+		repository: repo,
+
+		paperRepository: paperRepo,
+		paperIndex:      paperIndex,
+
+		userService: us,
+	}
+}
+
+// List returns the notes of a paper the user can read, in creation order.
+func (s *NoteService) List(user users.User, paperID int) ([]papernet.Note, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return nil, err
+	}
+
+	teamIDs, err := teamIDs(s.userService, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]papernet.Note, 0, len(notes))
+	for _, note := range notes {
+		if note.VisibleTo(user.ID, teamIDs) {
+			visible = append(visible, note)
+		}
+	}
+	return visible, nil
+}
+
+// Create writes a note on a paper. A reply is shared with the same audience as
+// the note it replies to, otherwise the user has to be a member of the team
+// the note is shared with.
+func (s *NoteService) Create(user users.User, paperID int, note papernet.Note) (papernet.Note, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.Note{}, err
+	} else if note.ID != 0 {
+		return papernet.Note{}, errors.New("id already set", errors.BadRequest())
+	}
+
+	content, err := validateNoteContent(note.Content)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	if note.ParentID != 0 {
+		parent, err := s.readable(user, paperID, note.ParentID)
+		if err != nil {
+			return papernet.Note{}, err
+		}
+		note.TeamID = parent.TeamID
+	} else if note.TeamID != 0 {
+		// Returns a 404 if the user is not a member of the team
+		_, err := s.userService.Team(user.ID, note.TeamID)
+		if err != nil {
+			return papernet.Note{}, err
+		}
+	}
+
+	note.PaperID = paperID
+	note.AuthorID = user.ID
+	note.Content = content
+	err = s.repository.Insert(&note)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	err = reindex(s.paperRepository, s.paperIndex, paperID)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	return note, nil
+}
+
+// Update changes the content of a note of the user.
+func (s *NoteService) Update(user users.User, paperID, id int, content string) (papernet.Note, error) {
+	note, err := s.authored(user, paperID, id)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	note.Content, err = validateNoteContent(content)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	err = s.repository.Update(&note)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	err = reindex(s.paperRepository, s.paperIndex, paperID)
+	if err != nil {
+		return papernet.Note{}, err
+	}
+
+	return note, nil
+}
+
+// Delete deletes a note of the user, along with the replies to it.
+func (s *NoteService) Delete(user users.User, paperID, id int) error {
+	note, err := s.authored(user, paperID, id)
+	if err != nil {
+		return err
+	}
+
+	notes, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return err
+	}
+
+	// The replies are always created after the note they reply to, so a
+	// single pass in creation order finds the whole thread.
+	thread := map[int]bool{note.ID: true}
+	for _, n := range notes {
+		if thread[n.ParentID] {
+			thread[n.ID] = true
+		}
+	}
+
+	for _, n := range notes {
+		if !thread[n.ID] {
+			continue
+		}
+
+		err := s.repository.Delete(n.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return reindex(s.paperRepository, s.paperIndex, paperID)
+}
+
+// readable returns a note of a paper the user can read, and a 404 otherwise.
+func (s *NoteService) readable(user users.User, paperID, id int) (papernet.Note, error) {
+	note, err := s.repository.Get(id)
+	if err != nil {
+		return papernet.Note{}, err
+	} else if note.ID == 0 || note.PaperID != paperID {
+		return papernet.Note{}, errNoteNotFound(id)
+	}
+
+	teamIDs, err := teamIDs(s.userService, user.ID)
+	if err != nil {
+		return papernet.Note{}, err
+	} else if !note.VisibleTo(user.ID, teamIDs) {
+		return papernet.Note{}, errNoteNotFound(id)
+	}
+
+	return note, nil
+}
+
+// authored returns a note of the user on a paper they can still see, a 404 if
+// they cannot read the note and a 403 if they are not its author.
+func (s *NoteService) authored(user users.User, paperID, id int) (papernet.Note, error) {
+	if err := aclCanSee(user, paperID); err != nil {
+		return papernet.Note{}, err
+	}
+
+	note, err := s.readable(user, paperID, id)
+	if err != nil {
+		return papernet.Note{}, err
+	} else if note.AuthorID != user.ID {
+		return papernet.Note{}, errors.New("only the author can modify a note", errors.Forbidden())
+	}
+
+	return note, nil
+}
+
+// DeletePaper removes the notes of a paper that is being deleted, whoever
+// wrote them. The paper is not reindexed.
+func (s *NoteService) DeletePaper(paperID int) error {
+	notes, err := s.repository.ListForPaper(paperID)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		err = s.repository.Delete(note.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNoteContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", errors.New("note cannot be empty", errors.BadRequest())
+	} else if len(content) > maxNoteLength {
+		return "", errors.New(fmt.Sprintf("note cannot be longer than %d characters", maxNoteLength), errors.BadRequest())
+	}
+	return content, nil
+}
+
+// teamIDs returns the ids of the teams of a user.
+func teamIDs(us UserService, userID int) ([]int, error) {
+	teams, err := us.Teams(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(teams))
+	for i, team := range teams {
+		ids[i] = team.ID
+	}
+	return ids, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 
+	"github.com/bobinette/papernet/clients/auth"
 	"github.com/bobinette/papernet/errors"
 	"github.com/bobinette/papernet/papernet"
 	"github.com/bobinette/papernet/users"
@@ -14,6 +15,16 @@ func errPaperNotFound(id int) error {
 
 type UserService interface {
 	CreatePaper(userID, paperID int) error
+	CreateTeamPaper(userID, teamID, paperID int) error
+	ReadingList(userID, listID int) (auth.ReadingList, error)
+	Team(userID, teamID int) (auth.Team, error)
+	Teams(userID int) ([]auth.Team, error)
+}
+
+// PaperCleaner removes the data linked to a paper, such as its attachments,
+// when the paper is deleted.
+type PaperCleaner interface {
+	DeletePaper(paperID int) error
 }
 
 type PaperService struct {
@@ -22,13 +33,18 @@ type PaperService struct {
 
 	userService UserService
 	tagService  *TagService
+
+	cleaners []PaperCleaner
 }
 
+// NewPaperService creates a paper service. The cleaners are called when a
+// paper is deleted, before the paper itself.
 func NewPaperService(
 	repo papernet.PaperRepository,
 	index papernet.PaperIndex,
 	us UserService,
 	ts *TagService,
+	cleaners ...PaperCleaner,
 ) *PaperService {
 	return &PaperService{
 		repository: repo,
@@ -36,6 +52,8 @@ func NewPaperService(
 
 		userService: us,
 		tagService:  ts,
+
+		cleaners: cleaners,
 	}
 }
 
@@ -58,14 +76,47 @@ type SearchResults struct {
 	Papers     []papernet.Paper    `json:"papers"`
 	Facets     papernet.Facets     `json:"facets"`
 	Pagination papernet.Pagination `json:"pagination"`
+
+	// Matches are the pages of the attachments where the query was found,
+	// by paper id.
+	Matches map[int][]papernet.AttachmentMatch `json:"matches"`
 }
 
-func (s *PaperService) Search(user users.User, q string, tags []string, bookmarked bool, offset, limit int) (SearchResults, error) {
+// Search searches the papers the user can see, and the notes on them the user
+// can read. The papers can be filtered on the reading statuses and the ratings
+// of the user. If listID is not 0, the search is restricted to the papers of
+// the reading list, returned in the order of the list.
+func (s *PaperService) Search(
+	user users.User,
+	q string,
+	tags []string,
+	bookmarked bool,
+	listID int,
+	statuses []papernet.ReadingStatus,
+	minRating int,
+	offset, limit int,
+) (SearchResults, error) {
+	if offset < 0 || limit < 0 {
+		return SearchResults{}, errors.New("offset and limit cannot be negative", errors.BadRequest())
+	}
+
+	for _, status := range statuses {
+		switch status {
+		case papernet.StatusToRead, papernet.StatusReading, papernet.StatusRead, papernet.StatusNone, papernet.StatusUnread:
+		default:
+			return SearchResults{}, errors.New(fmt.Sprintf("invalid reading status: %s", status), errors.BadRequest())
+		}
+	}
+
 	sp := papernet.SearchParams{
 		IDs:  user.CanSee,
 		Q:    q,
 		Tags: tags,
 
+		UserID:    user.ID,
+		Statuses:  statuses,
+		MinRating: minRating,
+
 		Offset: uint64(offset),
 		Limit:  uint64(limit),
 	}
@@ -77,6 +128,19 @@ func (s *PaperService) Search(user users.User, q string, tags []string, bookmark
 		sp.Limit = 20
 	}
 
+	// The query also searches the notes shared with the teams of the user
+	if q != "" {
+		var err error
+		sp.TeamIDs, err = teamIDs(s.userService, user.ID)
+		if err != nil {
+			return SearchResults{}, err
+		}
+	}
+
+	if listID != 0 {
+		return s.searchList(user, sp, listID)
+	}
+
 	res, err := s.index.Search(sp)
 	if err != nil {
 		return SearchResults{}, err
@@ -91,10 +155,90 @@ func (s *PaperService) Search(user users.User, q string, tags []string, bookmark
 		Papers:     papers,
 		Facets:     res.Facets,
 		Pagination: res.Pagination,
+		Matches:    res.Matches,
 	}, nil
 }
 
-func (s *PaperService) Create(callerID int, paper papernet.Paper) (papernet.Paper, error) {
+// searchList searches the papers of a reading list. The index sorts the
+// results by id, so all the matching papers of the list are retrieved and
+// paginated here.
+func (s *PaperService) searchList(user users.User, sp papernet.SearchParams, listID int) (SearchResults, error) {
+	list, err := s.userService.ReadingList(user.ID, listID)
+	if err != nil {
+		return SearchResults{}, err
+	}
+
+	ids := make([]int, 0, len(list.Papers))
+	for _, paperID := range list.Papers {
+		if contains(paperID, sp.IDs) {
+			ids = append(ids, paperID)
+		}
+	}
+
+	offset, limit := int(sp.Offset), int(sp.Limit)
+	pagination := papernet.Pagination{
+		Limit:  sp.Limit,
+		Offset: sp.Offset,
+	}
+	if len(ids) == 0 {
+		return SearchResults{
+			Papers:     []papernet.Paper{},
+			Pagination: pagination,
+			Matches:    map[int][]papernet.AttachmentMatch{},
+		}, nil
+	}
+
+	sp.IDs = ids
+	sp.Offset = 0
+	sp.Limit = uint64(len(ids))
+	res, err := s.index.Search(sp)
+	if err != nil {
+		return SearchResults{}, err
+	}
+
+	// Keep the order of the list
+	found := make([]int, 0, len(res.IDs))
+	for _, paperID := range ids {
+		if contains(paperID, res.IDs) {
+			found = append(found, paperID)
+		}
+	}
+	pagination.Total = uint64(len(found))
+
+	// The offset and the limit are not negative, but can be large enough for
+	// their sum to overflow
+	start, end := offset, len(found)
+	if start > len(found) {
+		start = len(found)
+	}
+	if limit < end-start {
+		end = start + limit
+	}
+	page := found[start:end]
+
+	papers, err := s.repository.Get(page...)
+	if err != nil {
+		return SearchResults{}, err
+	}
+
+	matches := make(map[int][]papernet.AttachmentMatch)
+	for _, paperID := range page {
+		if m, ok := res.Matches[paperID]; ok {
+			matches[paperID] = m
+		}
+	}
+
+	return SearchResults{
+		Papers:     papers,
+		Facets:     res.Facets,
+		Pagination: pagination,
+		Matches:    matches,
+	}, nil
+}
+
+// Create stores a new paper. The paper is owned by the caller, or by the team
+// identified by teamID if it is not 0.
+func (s *PaperService) Create(callerID, teamID int, paper papernet.Paper) (papernet.Paper, error) {
 	if paper.ID != 0 {
 		return papernet.Paper{}, errors.New("id already set", errors.BadRequest())
 	}
@@ -104,8 +248,15 @@ func (s *PaperService) Create(callerID int, paper papernet.Paper) (papernet.Pape
 		return papernet.Paper{}, err
 	}
 
-	err = s.userService.CreatePaper(callerID, paper.ID)
+	if teamID != 0 {
+		err = s.userService.CreateTeamPaper(callerID, teamID, paper.ID)
+	} else {
+		err = s.userService.CreatePaper(callerID, paper.ID)
+	}
 	if err != nil {
+		// Do not keep a paper nobody owns, when the caller cannot add papers
+		// to the team for instance
+		s.repository.Delete(paper.ID)
 		return papernet.Paper{}, err
 	}
 
@@ -140,12 +291,22 @@ func (s *PaperService) Update(user users.User, paper papernet.Paper) (papernet.P
 	return paper, nil
 }
 
+// Delete removes a paper the user can delete, along with the data linked to
+// it. The data is removed first, so that a failure leaves the paper to be
+// deleted again.
 func (s *PaperService) Delete(user users.User, paperID int) error {
 	err := aclCanDelete(user, paperID)
 	if err != nil {
 		return err
 	}
 
+	for _, cleaner := range s.cleaners {
+		err = cleaner.DeletePaper(paperID)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = s.repository.Delete(paperID)
 	if err != nil {
 		return err
@@ -159,6 +320,23 @@ func (s *PaperService) Delete(user users.User, paperID int) error {
 	return nil
 }
 
+// reindex indexes a paper again, after the data indexed with it, such as the
+// text of its attachments, changed.
+func reindex(repo papernet.PaperRepository, index papernet.PaperIndex, paperID int) error {
+	papers, err := repo.Get(paperID)
+	if err != nil {
+		return err
+	}
+
+	for _, paper := range papers {
+		err := index.Index(&paper)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func aclCanSee(user users.User, paperID int) error {
 	if !contains(paperID, user.CanSee) {
 		return errPaperNotFound(paperID)
@@ -186,7 +364,8 @@ func aclCanDelete(user users.User, paperID int) error {
 		return err
 	}
 
-	if !contains(paperID, user.Owns) {
+	// Admins of the team owning a paper hold the owner rights on it
+	if !contains(paperID, user.Owns) && !contains(paperID, user.TeamOwns) {
 		return errors.New("only the owner can delete a paper", errors.Forbidden())
 	}
 	return nil
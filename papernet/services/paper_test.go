@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/clients/auth"
+	"github.com/bobinette/papernet/errors"
+	"github.com/bobinette/papernet/papernet"
+)
+
+// fakePaperRepository keeps the papers in memory.
+type fakePaperRepository struct {
+	papers map[int]papernet.Paper
+	lastID int
+}
+
+func (r *fakePaperRepository) Get(ids ...int) ([]papernet.Paper, error) {
+	papers := make([]papernet.Paper, 0, len(ids))
+	for _, id := range ids {
+		if paper, ok := r.papers[id]; ok {
+			papers = append(papers, paper)
+		}
+	}
+	return papers, nil
+}
+
+func (r *fakePaperRepository) List() ([]papernet.Paper, error) {
+	papers := make([]papernet.Paper, 0, len(r.papers))
+	for _, paper := range r.papers {
+		papers = append(papers, paper)
+	}
+	return papers, nil
+}
+
+func (r *fakePaperRepository) Upsert(paper *papernet.Paper) error {
+	if paper.ID == 0 {
+		r.lastID++
+		paper.ID = r.lastID
+	}
+	r.papers[paper.ID] = *paper
+	return nil
+}
+
+func (r *fakePaperRepository) Delete(id int) error {
+	delete(r.papers, id)
+	return nil
+}
+
+// fakePaperIndex records the ids of the papers indexed.
+type fakePaperIndex struct {
+	indexed map[int]bool
+}
+
+func (i *fakePaperIndex) Index(paper *papernet.Paper) error {
+	i.indexed[paper.ID] = true
+	return nil
+}
+
+func (i *fakePaperIndex) Search(papernet.SearchParams) (papernet.SearchResults, error) {
+	return papernet.SearchResults{}, nil
+}
+
+func (i *fakePaperIndex) Delete(id int) error {
+	delete(i.indexed, id)
+	return nil
+}
+
+// fakeUserService lets the user 1 create papers for themselves and for the
+// team 1 only.
+type fakeUserService struct{}
+
+func (fakeUserService) CreatePaper(userID, paperID int) error {
+	if userID != 1 {
+		return errors.New("user not found", errors.NotFound())
+	}
+	return nil
+}
+
+func (fakeUserService) CreateTeamPaper(userID, teamID, paperID int) error {
+	if userID != 1 || teamID != 1 {
+		return errors.New("cannot add papers to the team", errors.Forbidden())
+	}
+	return nil
+}
+
+func (fakeUserService) ReadingList(userID, listID int) (auth.ReadingList, error) {
+	return auth.ReadingList{}, nil
+}
+
+func (fakeUserService) Team(userID, teamID int) (auth.Team, error) { return auth.Team{}, nil }
+func (fakeUserService) Teams(userID int) ([]auth.Team, error)      { return nil, nil }
+
+func TestPaperService_Create(t *testing.T) {
+	repo := &fakePaperRepository{papers: make(map[int]papernet.Paper)}
+	index := &fakePaperIndex{indexed: make(map[int]bool)}
+	service := NewPaperService(repo, index, fakeUserService{}, nil)
+
+	paper, err := service.Create(1, 0, papernet.Paper{Title: "Pizza"})
+	require.NoError(t, err, "creating a paper must not fail")
+	assert.Contains(t, repo.papers, paper.ID, "the paper should be stored")
+	assert.True(t, index.indexed[paper.ID], "the paper should be indexed")
+
+	paper, err = service.Create(1, 1, papernet.Paper{Title: "Team pizza"})
+	require.NoError(t, err, "creating a team paper must not fail")
+	assert.Contains(t, repo.papers, paper.ID, "the team paper should be stored")
+
+	// The papers nobody owns are not kept
+	_, err = service.Create(1, 2, papernet.Paper{Title: "Not my team"})
+	if assert.Error(t, err, "creating a paper for a team of which the user cannot edit the papers should fail") {
+		errors.AssertCode(t, err, 403)
+	}
+
+	_, err = service.Create(2, 0, papernet.Paper{Title: "Unknown user"})
+	if assert.Error(t, err, "creating a paper for an unknown user should fail") {
+		errors.AssertCode(t, err, 404)
+	}
+
+	assert.Len(t, repo.papers, 2, "the papers that could not be created should not be stored")
+	assert.Len(t, index.indexed, 2, "the papers that could not be created should not be indexed")
+}
@@ -0,0 +1,326 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// maxDepth bounds the recursions following references, page trees and form
+// objects, so that a malformed file cannot loop forever.
+const maxDepth = 32
+
+var (
+	// ErrNotPDF is returned when the data does not start with a PDF header.
+	ErrNotPDF = errors.New("not a PDF file")
+	// ErrEncrypted is returned for the encrypted files, that are not supported.
+	ErrEncrypted = errors.New("encrypted PDF files are not supported")
+
+	objectRegexp = regexp.MustCompile(`(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+obj\b`)
+)
+
+// Document is a parsed PDF file.
+//
+// The objects are found by scanning the file rather than by reading the cross
+// reference tables, which are often broken in the files found in the wild.
+// When an object is defined several times, by incremental updates, the last
+// definition wins.
+type Document struct {
+	objects map[int]interface{}
+	trailer Dict
+
+	// xref is the dictionary of the last cross reference stream of the file,
+	// used as trailer by the files without trailer dictionary.
+	xref Dict
+}
+
+// Open parses a PDF file.
+func Open(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n\x00"), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	d := &Document{
+		objects: make(map[int]interface{}),
+	}
+	d.scanObjects(data)
+	d.readObjectStreams()
+
+	d.trailer = d.findTrailer(data)
+	if d.trailer == nil {
+		return nil, errors.New("no document catalog found")
+	} else if _, ok := d.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+
+	return d, nil
+}
+
+// scanObjects reads all the "num gen obj ... endobj" of the file.
+func (d *Document) scanObjects(data []byte) {
+	end := 0
+	for _, m := range objectRegexp.FindAllSubmatchIndex(data, -1) {
+		// Skip the matches in the data of the previous object, and the ones
+		// that are not at the beginning of a token.
+		if m[0] < end || (m[0] > 0 && isRegular(data[m[0]-1])) {
+			continue
+		}
+
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+
+		l := newLexer(data, true)
+		l.pos = m[1]
+		obj, err := l.readObject()
+		if err != nil {
+			continue
+		}
+
+		if dict, ok := obj.(Dict); ok {
+			if stream, ok := readStreamData(l, dict); ok {
+				obj = stream
+				if dict["Type"] == Name("XRef") && dict["Root"] != nil {
+					d.xref = dict
+				}
+			}
+		}
+
+		d.objects[num] = obj
+		end = l.pos
+	}
+}
+
+// readStreamData reads the data of a stream following its dictionary, if any.
+func readStreamData(l *lexer, dict Dict) (Stream, bool) {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return Stream{}, false
+	}
+
+	start := l.pos + len("stream")
+	if bytes.HasPrefix(l.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if bytes.HasPrefix(l.data[start:], []byte("\n")) || bytes.HasPrefix(l.data[start:], []byte("\r")) {
+		start++
+	}
+
+	// Trust the length only if it is direct and followed by endstream
+	if length, ok := dict["Length"].(int64); ok && length >= 0 && start+int(length) <= len(l.data) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(l.data[end:], " \t\r\n\f\x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			return Stream{Dict: dict, Data: l.data[start:end]}, true
+		}
+	}
+
+	i := bytes.Index(l.data[start:], []byte("endstream"))
+	if i == -1 {
+		l.pos = len(l.data)
+		return Stream{Dict: dict, Data: l.data[start:]}, true
+	}
+
+	end := start + i
+	l.pos = end
+	data := bytes.TrimSuffix(l.data[start:end], []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return Stream{Dict: dict, Data: data}, true
+}
+
+// readObjectStreams reads the objects compressed in object streams. They never
+// replace the objects found in the file.
+func (d *Document) readObjectStreams() {
+	for _, obj := range d.objects {
+		stream, ok := obj.(Stream)
+		if !ok || stream.Dict["Type"] != Name("ObjStm") {
+			continue
+		}
+
+		n, _ := stream.Dict["N"].(int64)
+		first, _ := stream.Dict["First"].(int64)
+		data, err := decodeStream(stream)
+		if err != nil || first < 0 || first > int64(len(data)) {
+			continue
+		}
+
+		header := newLexer(data[:first], false)
+		for i := 0; i < int(n); i++ {
+			num, err1 := header.readObject()
+			offset, err2 := header.readObject()
+			if err1 != nil || err2 != nil {
+				break
+			}
+
+			objNum, ok1 := num.(int64)
+			objOffset, ok2 := offset.(int64)
+			if !ok1 || !ok2 || objOffset < 0 || objOffset >= int64(len(data))-first {
+				continue
+			} else if _, ok := d.objects[int(objNum)]; ok {
+				continue
+			}
+
+			l := newLexer(data, true)
+			l.pos = int(first + objOffset)
+			if obj, err := l.readObject(); err == nil {
+				d.objects[int(objNum)] = obj
+			}
+		}
+	}
+}
+
+// recoverMalformed turns a panic while reading a malformed file into an error,
+// so that a bad file does not bring the caller down.
+func recoverMalformed(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed PDF file: %v", r)
+	}
+}
+
+// findTrailer returns the last trailer of the file referencing the catalog.
+// The trailer is either a trailer dictionary or the dictionary of a cross
+// reference stream. If there is none, a trailer is made up from the first
+// catalog found.
+func (d *Document) findTrailer(data []byte) Dict {
+	var trailer Dict
+
+	for _, i := range allIndexes(data, []byte("trailer")) {
+		l := newLexer(data, true)
+		l.pos = i + len("trailer")
+		if dict, ok := l.readObjectOrNil().(Dict); ok && dict["Root"] != nil {
+			trailer = dict
+		}
+	}
+	if trailer != nil {
+		return trailer
+	}
+
+	if d.xref != nil {
+		return d.xref
+	}
+
+	for num, obj := range d.objects {
+		if dict, ok := obj.(Dict); ok && dict["Type"] == Name("Catalog") {
+			return Dict{"Root": Ref{Num: num}}
+		}
+	}
+	return nil
+}
+
+func (l *lexer) readObjectOrNil() interface{} {
+	obj, err := l.readObject()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+func allIndexes(data, sep []byte) []int {
+	indexes := make([]int, 0)
+	offset := 0
+	for {
+		i := bytes.Index(data[offset:], sep)
+		if i == -1 {
+			return indexes
+		}
+		indexes = append(indexes, offset+i)
+		offset += i + len(sep)
+	}
+}
+
+// resolve follows the references until a direct object is found. Missing
+// objects are null.
+func (d *Document) resolve(obj interface{}) interface{} {
+	for i := 0; i < maxDepth; i++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj
+		}
+		obj = d.objects[ref.Num]
+	}
+	return nil
+}
+
+func (d *Document) dict(obj interface{}) Dict {
+	switch obj := d.resolve(obj).(type) {
+	case Dict:
+		return obj
+	case Stream:
+		return obj.Dict
+	}
+	return nil
+}
+
+func (d *Document) array(obj interface{}) Array {
+	arr, _ := d.resolve(obj).(Array)
+	return arr
+}
+
+func (d *Document) name(obj interface{}) Name {
+	name, _ := d.resolve(obj).(Name)
+	return name
+}
+
+// catalog returns the root of the document.
+func (d *Document) catalog() Dict {
+	return d.dict(d.trailer["Root"])
+}
+
+// Info returns the document information dictionary, or nil if there is none.
+func (d *Document) Info() Dict {
+	return d.dict(d.trailer["Info"])
+}
+
+// pages returns the page dictionaries in order. The inheritable resources are
+// copied to the pages.
+func (d *Document) pages() []Dict {
+	pages := make([]Dict, 0)
+	visited := make(map[int]bool)
+
+	var walk func(node interface{}, resources interface{}, depth int)
+	walk = func(node interface{}, resources interface{}, depth int) {
+		if ref, ok := node.(Ref); ok {
+			if visited[ref.Num] {
+				return
+			}
+			visited[ref.Num] = true
+		}
+
+		dict := d.dict(node)
+		if dict == nil || depth > maxDepth {
+			return
+		}
+
+		if r, ok := dict["Resources"]; ok {
+			resources = r
+		}
+
+		kids, ok := dict["Kids"]
+		if !ok || dict["Type"] == Name("Page") {
+			page := make(Dict, len(dict)+1)
+			for k, v := range dict {
+				page[k] = v
+			}
+			page["Resources"] = resources
+			pages = append(pages, page)
+			return
+		}
+
+		for _, kid := range d.array(kids) {
+			walk(kid, resources, depth+1)
+		}
+	}
+
+	if root := d.catalog(); root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+	return pages
+}
+
+// NumPage returns the number of pages of the document.
+func (d *Document) NumPage() int {
+	return len(d.pages())
+}
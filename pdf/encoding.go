@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// encoding maps the codes of a simple font to unicode. 0 means the code has no
+// character.
+type encoding [256]rune
+
+var (
+	winAnsiEncoding  = newWinAnsiEncoding()
+	macRomanEncoding = newMacRomanEncoding()
+	standardEncoding = newStandardEncoding()
+)
+
+// latin1 returns an encoding where the codes are their unicode value.
+func latin1() *encoding {
+	var enc encoding
+	for i := 32; i < 256; i++ {
+		enc[i] = rune(i)
+	}
+	enc[127] = 0
+	return &enc
+}
+
+func newWinAnsiEncoding() *encoding {
+	enc := latin1()
+	upper := []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ")
+	for i, r := range upper {
+		enc[0x80+i] = r
+	}
+	return enc
+}
+
+func newMacRomanEncoding() *encoding {
+	enc := latin1()
+	upper := []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü" +
+		"†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+		"¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ" +
+		"‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+	for i, r := range upper {
+		enc[0x80+i] = r
+	}
+	return enc
+}
+
+func newStandardEncoding() *encoding {
+	enc := latin1()
+	enc['\''] = '’'
+	enc['`'] = '‘'
+	for i := 0x80; i < 256; i++ {
+		enc[i] = 0
+	}
+
+	upper := map[int]rune{
+		0xa1: '¡', 0xa2: '¢', 0xa3: '£', 0xa4: '⁄', 0xa5: '¥', 0xa6: 'ƒ', 0xa7: '§',
+		0xa8: '¤', 0xa9: '\'', 0xaa: '“', 0xab: '«', 0xac: '‹', 0xad: '›', 0xae: 'ﬁ',
+		0xaf: 'ﬂ', 0xb1: '–', 0xb2: '†', 0xb3: '‡', 0xb4: '·', 0xb6: '¶', 0xb7: '•',
+		0xb8: '‚', 0xb9: '„', 0xba: '”', 0xbb: '»', 0xbc: '…', 0xbd: '‰', 0xbf: '¿',
+		0xc1: '`', 0xc2: '´', 0xc3: 'ˆ', 0xc4: '˜', 0xc5: '¯', 0xc6: '˘', 0xc7: '˙',
+		0xc8: '¨', 0xca: '˚', 0xcb: '¸', 0xcd: '˝', 0xce: '˛', 0xcf: 'ˇ', 0xd0: '—',
+		0xe1: 'Æ', 0xe3: 'ª', 0xe8: 'Ł', 0xe9: 'Ø', 0xea: 'Œ', 0xeb: 'º', 0xf1: 'æ',
+		0xf5: 'ı', 0xf8: 'ł', 0xf9: 'ø', 0xfa: 'œ', 0xfb: 'ß',
+	}
+	for i, r := range upper {
+		enc[i] = r
+	}
+	return enc
+}
+
+// glyphNames maps the glyph names used in the Differences arrays of the font
+// encodings to unicode. The single letters are handled by glyphRune.
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "quoteright": '’',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+', "comma": ',',
+	"hyphen": '-', "minus": '−', "period": '.', "slash": '/', "colon": ':',
+	"semicolon": ';', "less": '<', "equal": '=', "greater": '>', "question": '?',
+	"at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"asciicircum": '^', "underscore": '_', "grave": '`', "quoteleft": '‘',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5',
+	"six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"endash": '–', "emdash": '—', "bullet": '•', "ellipsis": '…', "dagger": '†',
+	"daggerdbl": '‡', "quotedblleft": '“', "quotedblright": '”',
+	"quotesinglbase": '‚', "quotedblbase": '„', "guillemotleft": '«',
+	"guillemotright": '»', "guilsinglleft": '‹', "guilsinglright": '›',
+	"multiply": '×', "divide": '÷', "degree": '°', "plusminus": '±',
+	"section": '§', "paragraph": '¶', "copyright": '©', "registered": '®',
+	"trademark": '™', "periodcentered": '·', "dotlessi": 'ı', "germandbls": 'ß',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ',
+	"AE": 'Æ', "ae": 'æ', "OE": 'Œ', "oe": 'œ', "Oslash": 'Ø', "oslash": 'ø',
+	"Lslash": 'Ł', "lslash": 'ł', "nbspace": ' ',
+}
+
+// accented lists the names of the accented latin letters, followed by their
+// character.
+const accented = `Agrave À Aacute Á Acircumflex Â Atilde Ã Adieresis Ä Aring Å
+	Ccedilla Ç Egrave È Eacute É Ecircumflex Ê Edieresis Ë Igrave Ì Iacute Í
+	Icircumflex Î Idieresis Ï Ntilde Ñ Ograve Ò Oacute Ó Ocircumflex Ô Otilde Õ
+	Odieresis Ö Ugrave Ù Uacute Ú Ucircumflex Û Udieresis Ü Yacute Ý
+	agrave à aacute á acircumflex â atilde ã adieresis ä aring å ccedilla ç
+	egrave è eacute é ecircumflex ê edieresis ë igrave ì iacute í icircumflex î
+	idieresis ï ntilde ñ ograve ò oacute ó ocircumflex ô otilde õ odieresis ö
+	ugrave ù uacute ú ucircumflex û udieresis ü yacute ý ydieresis ÿ
+	Scaron Š scaron š Zcaron Ž zcaron ž Ydieresis Ÿ`
+
+func init() {
+	fields := strings.Fields(accented)
+	for i := 0; i+1 < len(fields); i += 2 {
+		glyphNames[fields[i]] = []rune(fields[i+1])[0]
+	}
+}
+
+// glyphRune returns the character of a glyph name, or 0 if it is unknown.
+func glyphRune(name string) rune {
+	// Suffixes such as .sc or .alt are variants of the same glyph
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+
+	if len(name) == 1 {
+		return rune(name[0])
+	} else if r, ok := glyphNames[name]; ok {
+		return r
+	}
+
+	// uniXXXX and uXXXX[XX]
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if n, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(n)
+		}
+	} else if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if n, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return rune(n)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,84 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// decodeStream applies the filters of a stream to its data. Only the filters
+// used for text, fonts and object streams are supported.
+func decodeStream(s Stream) ([]byte, error) {
+	var filters Array
+	switch f := s.Dict["Filter"].(type) {
+	case Name:
+		filters = Array{f}
+	case Array:
+		filters = f
+	}
+
+	data := s.Data
+	for _, f := range filters {
+		var err error
+		switch f {
+		case Name("FlateDecode"), Name("Fl"):
+			data, err = inflate(data)
+		case Name("ASCIIHexDecode"), Name("AHx"):
+			// Copy the data not to write in the file
+			hex := append(append([]byte{}, data...), '>')
+			data, err = newLexer(hex, false).readHexString()
+		case Name("ASCII85Decode"), Name("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			err = fmt.Errorf("unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// maxDecodedSize bounds the size of a decompressed stream, so that a small
+// file cannot exhaust the memory.
+const maxDecodedSize = 64 << 20
+
+// inflate decompresses zlib data. Some producers write raw deflate data, or
+// truncate the stream, so what could be read is returned in those cases. It
+// fails when the data decompresses to more than maxDecodedSize.
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if len(out) > maxDecodedSize {
+		return nil, fmt.Errorf("decompressed stream larger than %d bytes", maxDecodedSize)
+	} else if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i != -1 {
+		data = data[:i]
+	}
+
+	out := make([]byte, 4*len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
@@ -0,0 +1,257 @@
+package pdf
+
+import (
+	"bytes"
+	"unicode/utf16"
+)
+
+// maxRange bounds the size of the ranges of the CMaps.
+const maxRange = 1 << 16
+
+// codeRange is a range of codes of the same length, from a codespace range.
+type codeRange struct {
+	length    int
+	low, high []byte
+}
+
+func (r codeRange) contains(code []byte) bool {
+	for i := range code {
+		if code[i] < r.low[i] || code[i] > r.high[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// font decodes the strings shown with a font into text.
+type font struct {
+	// Codespace of the font. The codes are one byte long when it is empty.
+	codespace []codeRange
+	// toUnicode maps the codes, keyed by codeKey, to their text.
+	toUnicode map[uint64]string
+	// enc is the encoding of the simple fonts.
+	enc *encoding
+}
+
+// codeKey keys a code in the ToUnicode map. The length is part of the key as
+// <01> and <0001> are different codes.
+func codeKey(code []byte) uint64 {
+	return uint64(len(code))<<32 | uint64(bytesToInt(code))
+}
+
+func (d *Document) loadFont(obj interface{}) *font {
+	dict := d.dict(obj)
+	f := &font{}
+	if dict == nil {
+		f.enc = winAnsiEncoding
+		return f
+	}
+
+	if dict["Subtype"] == Name("Type0") {
+		// Composite fonts use 2 bytes codes, unless their ToUnicode CMap
+		// says otherwise.
+		f.codespace = []codeRange{{length: 2, low: []byte{0, 0}, high: []byte{0xff, 0xff}}}
+	} else {
+		f.enc = d.simpleEncoding(dict["Encoding"])
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(Stream); ok {
+		if data, err := decodeStream(stream); err == nil {
+			f.readCMap(data)
+		}
+	}
+
+	return f
+}
+
+// simpleEncoding returns the encoding of a simple font, applying its
+// differences to its base encoding.
+func (d *Document) simpleEncoding(obj interface{}) *encoding {
+	var base Name
+	var differences Array
+	switch enc := d.resolve(obj).(type) {
+	case Name:
+		base = enc
+	case Dict:
+		base = d.name(enc["BaseEncoding"])
+		differences = d.array(enc["Differences"])
+	}
+
+	var enc encoding
+	switch base {
+	case "MacRomanEncoding":
+		enc = *macRomanEncoding
+	case "StandardEncoding":
+		enc = *standardEncoding
+	default:
+		enc = *winAnsiEncoding
+	}
+
+	code := 0
+	for _, diff := range differences {
+		switch diff := diff.(type) {
+		case int64:
+			code = int(diff)
+		case Name:
+			if code >= 0 && code < 256 {
+				if r := glyphRune(string(diff)); r != 0 {
+					enc[code] = r
+				}
+			}
+			code++
+		}
+	}
+
+	return &enc
+}
+
+// readCMap reads the codespace and mappings of a ToUnicode CMap.
+func (f *font) readCMap(data []byte) {
+	l := newLexer(data, false)
+	operands := make([]interface{}, 0)
+	codespace := make([]codeRange, 0)
+	f.toUnicode = make(map[uint64]string)
+
+	for !l.eof() {
+		obj, err := l.readObject()
+		if err != nil {
+			continue
+		}
+
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, ok1 := operands[i].(String)
+				high, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(low) == len(high) && len(low) > 0 && len(low) <= 4 {
+					codespace = append(codespace, codeRange{length: len(low), low: low, high: high})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(String)
+				dst, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(src) > 0 && len(src) <= 4 {
+					f.toUnicode[codeKey(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, ok1 := operands[i].(String)
+				high, ok2 := operands[i+1].(String)
+				if !ok1 || !ok2 || len(low) != len(high) || len(low) == 0 || len(low) > 4 {
+					continue
+				}
+				f.addRange(low, high, operands[i+2])
+			}
+		}
+
+		operands = operands[:0]
+	}
+
+	if len(codespace) > 0 {
+		f.codespace = codespace
+	}
+}
+
+// addRange adds the mappings of a bfrange. The destination is either the text
+// of the first code, incremented for the next ones, or an array of texts.
+func (f *font) addRange(low, high []byte, dst interface{}) {
+	start := bytesToInt(low)
+	end := bytesToInt(high)
+	if end < start || end-start > maxRange {
+		return
+	}
+
+	code := make([]byte, len(low))
+	for n := start; n <= end; n++ {
+		intToBytes(n, code)
+		offset := int(n - start)
+
+		switch dst := dst.(type) {
+		case String:
+			if len(dst) == 0 {
+				return
+			}
+			// Increment the last byte of the destination
+			text := append([]byte{}, dst...)
+			text[len(text)-1] += byte(offset)
+			f.toUnicode[codeKey(code)] = utf16BE(text)
+		case Array:
+			if offset < len(dst) {
+				if text, ok := dst[offset].(String); ok {
+					f.toUnicode[codeKey(code)] = utf16BE(text)
+				}
+			}
+		}
+	}
+}
+
+func bytesToInt(b []byte) uint32 {
+	n := uint32(0)
+	for _, c := range b {
+		n = n<<8 | uint32(c)
+	}
+	return n
+}
+
+func intToBytes(n uint32, b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+}
+
+// utf16BE decodes a UTF-16BE string.
+func utf16BE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// decode returns the text of a string shown with the font.
+func (f *font) decode(s []byte) string {
+	var buf bytes.Buffer
+	for len(s) > 0 {
+		code := f.nextCode(s)
+		s = s[len(code):]
+
+		if text, ok := f.toUnicode[codeKey(code)]; ok {
+			buf.WriteString(text)
+		} else if f.enc != nil && len(code) == 1 {
+			if r := f.enc[code[0]]; r != 0 {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// nextCode returns the first code of s, according to the codespace.
+func (f *font) nextCode(s []byte) []byte {
+	for _, r := range f.codespace {
+		if r.length <= len(s) && r.contains(s[:r.length]) {
+			return s[:r.length]
+		}
+	}
+
+	// Not in the codespace: use the shortest length
+	length := 0
+	for _, r := range f.codespace {
+		if length == 0 || r.length < length {
+			length = r.length
+		}
+	}
+	if length == 0 || length > len(s) {
+		length = 1
+	}
+	return s[:length]
+}
@@ -0,0 +1,356 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Name is a PDF name object, without its leading slash.
+type Name string
+
+// Dict is a PDF dictionary.
+type Dict map[Name]interface{}
+
+// Array is a PDF array.
+type Array []interface{}
+
+// Ref is a reference to an indirect object.
+type Ref struct {
+	Num int
+	Gen int
+}
+
+// String is a PDF string. Its encoding depends on where it is used, so it is
+// kept as raw bytes.
+type String []byte
+
+// Stream is a stream object. Data is still encoded with the filters of Dict.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// keyword is a bare word: an operator in a content stream, or obj, stream...
+type keyword string
+
+var (
+	errEOF = errors.New("unexpected end of data")
+
+	errEndArray = errors.New("unexpected ]")
+	errEndDict  = errors.New("unexpected >>")
+)
+
+// lexer reads the objects of a PDF file or of a content stream. The
+// references are only parsed in files, as content streams have no indirect
+// objects.
+type lexer struct {
+	data []byte
+	pos  int
+
+	refs bool
+}
+
+func newLexer(data []byte, refs bool) *lexer {
+	return &lexer{data: data, refs: refs}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isSpace(c) && !isDelimiter(c)
+}
+
+// skipSpace skips the white spaces and the comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		} else if !isSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) eof() bool {
+	l.skipSpace()
+	return l.pos >= len(l.data)
+}
+
+// readObject reads the next object.
+func (l *lexer) readObject() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return l.readName(), nil
+	case c == '(':
+		l.pos++
+		return l.readLiteralString()
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return l.readDict()
+	case c == '<':
+		l.pos++
+		return l.readHexString()
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return nil, errEndDict
+	case c == '[':
+		l.pos++
+		return l.readArray()
+	case c == ']':
+		l.pos++
+		return nil, errEndArray
+	case c == '{' || c == '}':
+		// Only found in PostScript functions, read as keywords
+		l.pos++
+		return keyword(c), nil
+	case !isRegular(c):
+		l.pos++
+		return nil, fmt.Errorf("unexpected character %q", c)
+	}
+
+	word := l.readWord()
+	if n, err := strconv.ParseInt(word, 10, 64); err == nil {
+		if l.refs {
+			if ref, ok := l.readRef(n); ok {
+				return ref, nil
+			}
+		}
+		return n, nil
+	} else if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f, nil
+	}
+
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return keyword(word), nil
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset >= len(l.data) {
+		return 0
+	}
+	return l.data[l.pos+offset]
+}
+
+func (l *lexer) readWord() string {
+	start := l.pos
+	for l.pos < len(l.data) && isRegular(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// readRef tries to read the "gen R" following the number of a reference. The
+// position is restored when it is not a reference.
+func (l *lexer) readRef(num int64) (Ref, bool) {
+	pos := l.pos
+	defer func() {
+		if pos != -1 {
+			l.pos = pos
+		}
+	}()
+
+	l.skipSpace()
+	gen, err := strconv.Atoi(l.readWord())
+	if err != nil {
+		return Ref{}, false
+	}
+
+	l.skipSpace()
+	if l.readWord() != "R" {
+		return Ref{}, false
+	}
+
+	pos = -1
+	return Ref{Num: int(num), Gen: gen}, true
+}
+
+func (l *lexer) readName() Name {
+	word := l.readWord()
+	if !bytes.Contains([]byte(word), []byte("#")) {
+		return Name(word)
+	}
+
+	// Decode the #xx escapes
+	var buf bytes.Buffer
+	for i := 0; i < len(word); i++ {
+		if word[i] == '#' && i+2 < len(word) {
+			if b, err := strconv.ParseUint(word[i+1:i+3], 16, 8); err == nil {
+				buf.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(word[i])
+	}
+	return Name(buf.String())
+}
+
+func (l *lexer) readLiteralString() (String, error) {
+	var buf bytes.Buffer
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(buf.Bytes()), nil
+			}
+		case '\\':
+			l.readEscape(&buf)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	return nil, errEOF
+}
+
+func (l *lexer) readEscape(buf *bytes.Buffer) {
+	if l.pos >= len(l.data) {
+		return
+	}
+
+	c := l.data[l.pos]
+	l.pos++
+	switch c {
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case '\r':
+		// Line continuation
+		if l.peek(0) == '\n' {
+			l.pos++
+		}
+	case '\n':
+		// Line continuation
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		b := c - '0'
+		for i := 0; i < 2 && l.peek(0) >= '0' && l.peek(0) <= '7'; i++ {
+			b = b*8 + l.data[l.pos] - '0'
+			l.pos++
+		}
+		buf.WriteByte(b)
+	default:
+		buf.WriteByte(c)
+	}
+}
+
+func (l *lexer) readHexString() (String, error) {
+	var buf bytes.Buffer
+	var b byte
+	odd := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		var v byte
+		switch {
+		case c == '>':
+			if odd {
+				buf.WriteByte(b << 4)
+			}
+			return String(buf.Bytes()), nil
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+
+		if odd {
+			buf.WriteByte(b<<4 | v)
+		} else {
+			b = v
+		}
+		odd = !odd
+	}
+	return nil, errEOF
+}
+
+func (l *lexer) readArray() (Array, error) {
+	arr := make(Array, 0)
+	for {
+		obj, err := l.readObject()
+		if err == errEndArray {
+			return arr, nil
+		} else if err != nil {
+			return nil, err
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *lexer) readDict() (Dict, error) {
+	dict := make(Dict)
+	for {
+		key, err := l.readObject()
+		if err == errEndDict {
+			return dict, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(Name)
+		if !ok {
+			return nil, fmt.Errorf("dictionary key is not a name: %v", key)
+		}
+
+		value, err := l.readObject()
+		if err == errEndDict {
+			// Tolerate a key without value
+			return dict, nil
+		} else if err != nil {
+			return nil, err
+		}
+		dict[name] = value
+	}
+}
@@ -0,0 +1,363 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+// maxAbstract is the maximum length, in bytes, of the abstract read from the
+// first page.
+const maxAbstract = 3000
+
+var (
+	// Only the identifiers explicitly prefixed are kept, the numbers of a page
+	// look too much like old style arXiv ids.
+	arxivRegexp = regexp.MustCompile(`(?i)(?:arxiv:\s?|arxiv\.org/(?:abs|pdf)/)([0-9]{4}\.[0-9]{4,5}|[a-z][a-z.-]*/[0-9]{7})(?:v[0-9]+)?`)
+	doiRegexp   = regexp.MustCompile(`\b10\.[0-9]{4,9}/[-._;()/:A-Za-z0-9]+`)
+
+	abstractRegexp = regexp.MustCompile(`(?i)^abstract\b[\s.:—–-]*`)
+	sectionRegexp  = regexp.MustCompile(`(?i)^(?:(?:1|I)\.?\s+)?(?:introduction|keywords|index terms)\b`)
+
+	// junkTitleRegexp matches the titles set by the tools producing the files
+	// instead of the title of the document.
+	junkTitleRegexp = regexp.MustCompile(`(?i)^(?:untitled|microsoft word|slide ?\d*$)|\.(?:dvi|pdf|docx?|tex|ps)$`)
+	authorsRegexp   = regexp.MustCompile(`\s*(?:;|,|\band\b|&)\s*`)
+)
+
+// Metadata describes the paper contained in a PDF file.
+type Metadata struct {
+	Title    string
+	Authors  []string
+	Subject  string
+	Abstract string
+	Keywords []string
+
+	ArxivIDs []string
+	DOIs     []string
+}
+
+// Metadata returns the metadata of the document. The XMP metadata are
+// preferred to the information dictionary, and the identifiers are looked for
+// in both and in the text of the first page.
+func (d *Document) Metadata() Metadata {
+	var m Metadata
+
+	// Information dictionary
+	info := d.Info()
+	m.Title = d.textString(info["Title"])
+	m.Authors = splitAuthors(d.textString(info["Author"]))
+	m.Subject = d.textString(info["Subject"])
+	m.Keywords = splitKeywords(d.textString(info["Keywords"]))
+
+	// XMP metadata
+	x := d.xmp()
+	if x.title != "" {
+		m.Title = x.title
+	}
+	if len(x.creators) > 0 {
+		m.Authors = x.creators
+	}
+	if x.description != "" {
+		m.Subject = x.description
+	}
+	if len(x.subjects) > 0 {
+		m.Keywords = x.subjects
+	}
+
+	m.Title = strings.Join(strings.Fields(m.Title), " ")
+	if junkTitleRegexp.MatchString(m.Title) {
+		m.Title = ""
+	}
+
+	var firstPage string
+	if pages := d.pages(); len(pages) > 0 {
+		firstPage = d.pageText(pages[0])
+	}
+	m.Abstract = abstract(firstPage)
+
+	ids := strings.Join([]string{x.doi, x.identifier, m.Subject, m.Title, firstPage}, "\n")
+	m.ArxivIDs = FindArxivIDs(ids)
+	m.DOIs = FindDOIs(ids)
+
+	return m
+}
+
+// ExtractMetadata returns the metadata of a PDF file.
+func ExtractMetadata(data []byte) (m Metadata, err error) {
+	defer recoverMalformed(&err)
+
+	d, err := Open(data)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return d.Metadata(), nil
+}
+
+// FindArxivIDs returns the arXiv identifiers, without version, found in a
+// text.
+func FindArxivIDs(text string) []string {
+	ids := make([]string, 0)
+	for _, match := range arxivRegexp.FindAllStringSubmatch(text, -1) {
+		ids = appendUnique(ids, match[1])
+	}
+	return ids
+}
+
+// FindDOIs returns the DOIs found in a text.
+func FindDOIs(text string) []string {
+	dois := make([]string, 0)
+	for _, match := range doiRegexp.FindAllString(text, -1) {
+		// The punctuation ending a sentence is not part of the DOI
+		doi := strings.TrimRight(match, ".,;:")
+		if strings.HasSuffix(doi, ")") && strings.Count(doi, "(") < strings.Count(doi, ")") {
+			doi = doi[:len(doi)-1]
+		}
+		dois = appendUnique(dois, doi)
+	}
+	return dois
+}
+
+// textString decodes a text string, which is either UTF-16BE with a byte
+// order mark, UTF-8 with a byte order mark, or in PDFDocEncoding. The
+// PDFDocEncoding is close enough to WinAnsiEncoding to use it.
+func (d *Document) textString(obj interface{}) string {
+	s, ok := d.resolve(obj).(String)
+	if !ok {
+		return ""
+	}
+
+	var text string
+	switch {
+	case bytes.HasPrefix(s, []byte{0xfe, 0xff}):
+		text = utf16BE(s[2:])
+	case bytes.HasPrefix(s, []byte{0xef, 0xbb, 0xbf}):
+		text = string(s[3:])
+	default:
+		runes := make([]rune, 0, len(s))
+		for _, c := range s {
+			if r := winAnsiEncoding[c]; r != 0 {
+				runes = append(runes, r)
+			} else if c == '\n' || c == '\r' || c == '\t' {
+				runes = append(runes, ' ')
+			}
+		}
+		text = string(runes)
+	}
+	return strings.TrimSpace(text)
+}
+
+// xmpMetadata is the part of the XMP metadata describing the paper.
+type xmpMetadata struct {
+	title       string
+	creators    []string
+	description string
+	subjects    []string
+	doi         string
+	identifier  string
+}
+
+// xmp reads the XMP metadata of the catalog. The elements are matched on their
+// local name only, the prefixes used by the producers vary.
+func (d *Document) xmp() xmpMetadata {
+	var x xmpMetadata
+
+	stream, ok := d.resolve(d.catalog()["Metadata"]).(Stream)
+	if !ok {
+		return x
+	}
+	data, err := decodeStream(stream)
+	if err != nil {
+		return x
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	// property is the dc or prism element being read, items its values
+	var property string
+	var items []string
+	var text bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "title", "creator", "description", "subject", "doi", "identifier":
+				if property == "" {
+					property = t.Name.Local
+					items = nil
+				}
+			}
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := strings.TrimSpace(text.String())
+			text.Reset()
+
+			if property == "" {
+				continue
+			}
+			if value != "" && (t.Name.Local == "li" || t.Name.Local == property) {
+				items = append(items, value)
+			}
+			if t.Name.Local != property {
+				continue
+			}
+
+			switch property {
+			case "title":
+				x.title = first(items)
+			case "creator":
+				x.creators = items
+			case "description":
+				x.description = first(items)
+			case "subject":
+				for _, item := range items {
+					x.subjects = append(x.subjects, splitKeywords(item)...)
+				}
+			case "doi":
+				x.doi = first(items)
+			case "identifier":
+				x.identifier = strings.Join(items, "\n")
+			}
+			property = ""
+		}
+	}
+
+	return x
+}
+
+// abstract returns the paragraph following the "Abstract" heading of the
+// first page, up to the introduction.
+func abstract(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		loc := abstractRegexp.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+
+		parts := make([]string, 0)
+		if rest := strings.TrimSpace(line[loc[1]:]); rest != "" {
+			parts = append(parts, rest)
+		}
+		for _, next := range lines[i+1:] {
+			if sectionRegexp.MatchString(next) {
+				break
+			}
+			parts = append(parts, next)
+		}
+
+		return truncate(joinLines(parts), maxAbstract)
+	}
+	return ""
+}
+
+// joinLines joins the lines of a paragraph, merging the hyphenated words.
+func joinLines(lines []string) string {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 {
+			if b := buf.Bytes(); b[len(b)-1] == '-' {
+				buf.Truncate(buf.Len() - 1)
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString(line)
+	}
+	return buf.String()
+}
+
+// truncate cuts a text to at most n bytes, on a rune boundary.
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+func splitAuthors(authors string) []string {
+	res := make([]string, 0)
+	for _, author := range authorsRegexp.Split(authors, -1) {
+		if author != "" {
+			res = append(res, author)
+		}
+	}
+	return res
+}
+
+func splitKeywords(keywords string) []string {
+	res := make([]string, 0)
+	for _, keyword := range strings.FieldsFunc(keywords, func(r rune) bool { return r == ',' || r == ';' }) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			res = appendUnique(res, keyword)
+		}
+	}
+	return res
+}
+
+func first(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return items[0]
+}
+
+func appendUnique(a []string, s string) []string {
+	for _, e := range a {
+		if e == s {
+			return a
+		}
+	}
+	return append(a, s)
+}
+
+// DraftExtractor creates paper drafts from PDF files.
+type DraftExtractor struct{}
+
+func (DraftExtractor) ExtractDraft(data []byte) (papernet.PaperDraft, error) {
+	m, err := ExtractMetadata(data)
+	if err != nil {
+		return papernet.PaperDraft{}, err
+	}
+
+	summary := m.Abstract
+	if summary == "" {
+		summary = m.Subject
+	}
+
+	references := make([]string, 0, len(m.ArxivIDs)+len(m.DOIs))
+	for _, id := range m.ArxivIDs {
+		references = append(references, "https://arxiv.org/abs/"+id)
+	}
+	for _, doi := range m.DOIs {
+		references = append(references, "https://doi.org/"+doi)
+	}
+
+	return papernet.PaperDraft{
+		Paper: papernet.Paper{
+			Title:      m.Title,
+			Summary:    summary,
+			Authors:    m.Authors,
+			Tags:       m.Keywords,
+			References: references,
+		},
+		ArxivIDs: m.ArxivIDs,
+		DOIs:     m.DOIs,
+	}, nil
+}
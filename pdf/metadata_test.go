@@ -0,0 +1,89 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bobinette/papernet/papernet"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	page := "BT /F1 12 Tf 72 750 Td (Attention Is All You Need) Tj 0 -14 Td (Abstract) Tj " +
+		"0 -14 Td (The dominant sequence trans-) Tj 0 -14 Td (duction models are complex.) Tj " +
+		"0 -14 Td (1 Introduction) Tj 0 -14 Td (See doi:10.1000/xyz\\(1\\).) Tj " +
+		"0 -14 Td (arXiv:1706.03762v5 [cs.CL] 6 Dec 2017) Tj ET"
+
+	data := buildPDF("/Root 1 0 R /Info 5 0 R",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("", []byte(page)),
+		"<< /Title (Microsoft Word - paper.docx) /Author <FEFF0056006100730077006100690020006E00E9> /Keywords (nlp, attention; nlp) >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	)
+
+	m, err := ExtractMetadata(data)
+	require.NoError(t, err, "extracting metadata must not fail")
+	assert.Equal(t, Metadata{
+		Title:    "",
+		Authors:  []string{"Vaswai né"},
+		Abstract: "The dominant sequence transduction models are complex.",
+		Keywords: []string{"nlp", "attention"},
+		ArxivIDs: []string{"1706.03762"},
+		DOIs:     []string{"10.1000/xyz(1)"},
+	}, m)
+}
+
+func TestExtractMetadata_XMP(t *testing.T) {
+	xmp := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:prism="http://prismstandard.org/namespaces/basic/2.0/">
+<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Deep   Residual Learning</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>Kaiming He</rdf:li><rdf:li>Xiangyu Zhang</rdf:li></rdf:Seq></dc:creator>
+<dc:description><rdf:Alt><rdf:li xml:lang="x-default">Residual networks.</rdf:li></rdf:Alt></dc:description>
+<dc:subject><rdf:Bag><rdf:li>vision</rdf:li><rdf:li>resnet</rdf:li></rdf:Bag></dc:subject>
+<prism:doi>10.1109/CVPR.2016.90</prism:doi>
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+	data := buildPDF("/Root 1 0 R /Info 4 0 R",
+		"<< /Type /Catalog /Pages 2 0 R /Metadata 3 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		stream("/Type /Metadata /Subtype /XML", []byte(xmp)),
+		"<< /Title (Other title) /Author (Someone) /Subject (Other subject) >>",
+	)
+
+	m, err := ExtractMetadata(data)
+	require.NoError(t, err, "extracting metadata must not fail")
+	assert.Equal(t, Metadata{
+		Title:    "Deep Residual Learning",
+		Authors:  []string{"Kaiming He", "Xiangyu Zhang"},
+		Subject:  "Residual networks.",
+		Keywords: []string{"vision", "resnet"},
+		ArxivIDs: []string{},
+		DOIs:     []string{"10.1109/CVPR.2016.90"},
+	}, m)
+
+	draft, err := DraftExtractor{}.ExtractDraft(data)
+	require.NoError(t, err, "extracting a draft must not fail")
+	assert.Equal(t, papernet.Paper{
+		Title:      "Deep Residual Learning",
+		Summary:    "Residual networks.",
+		Authors:    []string{"Kaiming He", "Xiangyu Zhang"},
+		Tags:       []string{"vision", "resnet"},
+		References: []string{"https://doi.org/10.1109/CVPR.2016.90"},
+	}, draft.Paper)
+}
+
+func TestFindIdentifiers(t *testing.T) {
+	text := "arXiv:hep-th/9901001v2, see https://arxiv.org/abs/1512.03385 and arXiv: 1512.03385. 2017.1234"
+	assert.Equal(t, []string{"hep-th/9901001", "1512.03385"}, FindArxivIDs(text))
+
+	text = "(doi: 10.1145/3065386), https://doi.org/10.1038/nature14539."
+	assert.Equal(t, []string{"10.1145/3065386", "10.1038/nature14539"}, FindDOIs(text))
+}
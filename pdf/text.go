@@ -0,0 +1,296 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// kerningSpace is the adjustment of a TJ array, in thousandths of em, above
+// which words are considered to be separated.
+const kerningSpace = 200
+
+// normalizer replaces the ligatures, so that the words can be searched.
+var normalizer = strings.NewReplacer(
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+	"\u00a0", " ", "\u00ad", "",
+)
+
+// Text returns the text of the pages of the document. The lines of a page are
+// separated by new lines. The pages that cannot be read are empty.
+func (d *Document) Text() []string {
+	pages := d.pages()
+	texts := make([]string, len(pages))
+	for i, page := range pages {
+		texts[i] = d.pageText(page)
+	}
+	return texts
+}
+
+// ExtractText returns the text of each page of a PDF file.
+func ExtractText(data []byte) (pages []string, err error) {
+	defer recoverMalformed(&err)
+
+	d, err := Open(data)
+	if err != nil {
+		return nil, err
+	}
+	return d.Text(), nil
+}
+
+// TextExtractor extracts the text of PDF files.
+type TextExtractor struct{}
+
+func (TextExtractor) ExtractText(data []byte) ([]string, error) {
+	return ExtractText(data)
+}
+
+func (d *Document) pageText(page Dict) string {
+	w := textWriter{doc: d}
+	w.run(d.contents(page["Contents"]), d.dict(page["Resources"]), 0)
+	return clean(w.buf.String())
+}
+
+// contents returns the content stream of a page, which may be split in
+// several streams.
+func (d *Document) contents(obj interface{}) []byte {
+	var streams []interface{}
+	switch obj := d.resolve(obj).(type) {
+	case Stream:
+		streams = []interface{}{obj}
+	case Array:
+		streams = obj
+	}
+
+	var buf bytes.Buffer
+	for _, s := range streams {
+		stream, ok := d.resolve(s).(Stream)
+		if !ok {
+			continue
+		}
+
+		data, err := decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// textWriter interprets the content streams, keeping only the text. The
+// position of the text is only used to guess the spaces and line breaks.
+type textWriter struct {
+	doc *Document
+	buf bytes.Buffer
+
+	font *font
+	// y is the vertical position of the text matrix, to detect new lines.
+	y float64
+}
+
+func (w *textWriter) run(content []byte, resources Dict, depth int) {
+	if depth > maxDepth {
+		return
+	}
+
+	fonts := make(map[Name]*font)
+	l := newLexer(content, false)
+	operands := make([]interface{}, 0)
+
+	for !l.eof() {
+		obj, err := l.readObject()
+		if err != nil {
+			operands = operands[:0]
+			continue
+		}
+
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BI":
+			skipInlineImage(l)
+		case "Tf":
+			if len(operands) >= 2 {
+				name, _ := operands[0].(Name)
+				f, ok := fonts[name]
+				if !ok {
+					f = w.doc.loadFont(w.doc.dict(resources["Font"])[name])
+					fonts[name] = f
+				}
+				w.font = f
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				w.show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			w.newLine()
+			if len(operands) >= 1 {
+				w.show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				arr, _ := operands[len(operands)-1].(Array)
+				for _, item := range arr {
+					if number(item) < -kerningSpace {
+						w.space()
+					}
+					w.show(item)
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if number(operands[1]) != 0 {
+					w.newLine()
+				} else if number(operands[0]) > 0 {
+					w.space()
+				}
+				w.y += number(operands[1])
+			}
+		case "T*":
+			w.newLine()
+		case "Tm":
+			if len(operands) >= 6 {
+				y := number(operands[5])
+				if math.Abs(y-w.y) > 1 {
+					w.newLine()
+				} else {
+					w.space()
+				}
+				w.y = y
+			}
+		case "ET":
+			w.space()
+		case "Do":
+			if len(operands) >= 1 {
+				name, _ := operands[0].(Name)
+				w.form(w.doc.dict(resources["XObject"])[name], resources, depth)
+			}
+		}
+
+		operands = operands[:0]
+	}
+}
+
+// form writes the text of a form XObject.
+func (w *textWriter) form(obj interface{}, resources Dict, depth int) {
+	stream, ok := w.doc.resolve(obj).(Stream)
+	if !ok || stream.Dict["Subtype"] != Name("Form") {
+		return
+	}
+
+	data, err := decodeStream(stream)
+	if err != nil {
+		return
+	}
+
+	if r := w.doc.dict(stream.Dict["Resources"]); r != nil {
+		resources = r
+	}
+
+	current := w.font
+	w.run(data, resources, depth+1)
+	w.font = current
+}
+
+func (w *textWriter) show(obj interface{}) {
+	s, ok := obj.(String)
+	if !ok {
+		return
+	}
+
+	if w.font == nil {
+		w.font = w.doc.loadFont(nil)
+	}
+	w.buf.WriteString(w.font.decode(s))
+}
+
+func (w *textWriter) space() {
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	last := w.buf.Bytes()[w.buf.Len()-1]
+	if last != ' ' && last != '\n' {
+		w.buf.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newLine() {
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	// Replace the trailing space
+	if w.buf.Bytes()[w.buf.Len()-1] == ' ' {
+		w.buf.Truncate(w.buf.Len() - 1)
+	}
+	if w.buf.Len() > 0 && w.buf.Bytes()[w.buf.Len()-1] != '\n' {
+		w.buf.WriteByte('\n')
+	}
+}
+
+// skipInlineImage skips the data of an inline image, up to the EI operator.
+func skipInlineImage(l *lexer) {
+	i := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if i == -1 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += i + len("ID")
+
+	for {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i == -1 {
+			l.pos = len(l.data)
+			return
+		}
+
+		start := l.pos + i
+		end := start + len("EI")
+		l.pos = end
+		if isSpace(l.data[start-1]) && (end == len(l.data) || isSpace(l.data[end])) {
+			return
+		}
+	}
+}
+
+func number(obj interface{}) float64 {
+	switch n := obj.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// clean normalizes the text of a page: ligatures, control characters and
+// spaces.
+func clean(text string) string {
+	text = normalizer.Replace(text)
+
+	lines := strings.Split(text, "\n")
+	cleaned := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || unicode.IsSpace(r) {
+				return ' '
+			}
+			return r
+		}, line)
+
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			cleaned = append(cleaned, line)
+		}
+	}
+	return strings.Join(cleaned, "\n")
+}
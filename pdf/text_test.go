@@ -0,0 +1,201 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF writes a PDF file with the objects given, numbered from 1. The
+// first object has to be the catalog.
+func buildPDF(trailer string, objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	if trailer == "" {
+		trailer = "/Root 1 0 R"
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return buf.Bytes()
+}
+
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func compress(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestExtractText(t *testing.T) {
+	data := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("", []byte("BT /F1 12 Tf 72 700 Td (Hello World) Tj 0 -14 Td [(Pa) -50 (per) -500 (net)] TJ ET")),
+		"<< /Type /Page /Parent 2 0 R /Contents [7 0 R 8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		stream("/Filter /FlateDecode", compress("BT /F1 12 Tf 1 0 0 1 72 700 Tm (Second) Tj")),
+		stream("", []byte("1 0 0 1 150 700 Tm (page \\(2\\)) Tj 1 0 0 1 72 680 Tm (caf\\351) Tj ET")),
+	)
+
+	pages, err := ExtractText(data)
+	require.NoError(t, err, "extracting text must not fail")
+	assert.Equal(t, []string{"Hello World\nPaper net", "Second page (2)\ncafé"}, pages)
+}
+
+func TestExtractText_ToUnicode(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0004> <FB01>
+endbfchar
+1 beginbfrange
+<0002> <0003> <0069>
+endbfrange
+endcmap
+end end`
+
+	data := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		stream("", []byte("BT /F1 12 Tf <000100020003> Tj 0 -14 Td <00040003000400FF> Tj ET")),
+		"<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		stream("", []byte(cmap)),
+	)
+
+	pages, err := ExtractText(data)
+	require.NoError(t, err, "extracting text must not fail")
+	assert.Equal(t, []string{"Hij\nfijfi"}, pages, "unknown codes should be skipped")
+}
+
+func TestExtractText_Differences(t *testing.T) {
+	data := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> /XObject << /X1 6 0 R >> >> >>",
+		stream("", []byte("BT /F1 12 Tf (AB) Tj ET /X1 Do")),
+		"<< /Type /Font /Subtype /Type1 /Encoding << /BaseEncoding /StandardEncoding /Differences [65 /fi /eacute] >> >>",
+		stream("/Type /XObject /Subtype /Form", []byte("BT /F1 12 Tf 0 -14 Td (in form') Tj ET")),
+	)
+
+	pages, err := ExtractText(data)
+	require.NoError(t, err, "extracting text must not fail")
+	assert.Equal(t, []string{"fié\nin form’"}, pages)
+}
+
+func TestExtractText_ObjectStream(t *testing.T) {
+	// Objects 2 and 3 are compressed in the object stream 5
+	objects := "<< /Type /Pages /Kids [3 0 R] /Count 1 >> << /Type /Page /Parent 2 0 R /Contents 4 0 R >>"
+	header := fmt.Sprintf("2 0 3 %d ", len("<< /Type /Pages /Kids [3 0 R] /Count 1 >> "))
+	objStm := compress(header + objects)
+
+	data := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"null",
+		"null",
+		stream("", []byte("BT (compressed) Tj ET")),
+		stream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), objStm),
+	)
+	// The objects defined in the file take precedence: remove the null ones
+	data = bytes.Replace(data, []byte("2 0 obj\nnull\nendobj\n"), []byte{}, 1)
+	data = bytes.Replace(data, []byte("3 0 obj\nnull\nendobj\n"), []byte{}, 1)
+
+	pages, err := ExtractText(data)
+	require.NoError(t, err, "extracting text must not fail")
+	assert.Equal(t, []string{"compressed"}, pages)
+}
+
+func TestExtractText_MalformedObjectStream(t *testing.T) {
+	header := "2 -100 3 -1000000 "
+	objects := "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"
+	objStm := compress(header + objects)
+
+	dicts := []string{
+		"/Type /ObjStm /N 1 /First -5 /Filter /FlateDecode",
+		fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)),
+	}
+	for _, dict := range dicts {
+		data := buildPDF("",
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			stream(dict, objStm),
+		)
+		_, err := ExtractText(data)
+		assert.NoError(t, err, "%s: invalid offsets should be ignored", dict)
+	}
+}
+
+func TestInflate_TooLarge(t *testing.T) {
+	data := compress(string(make([]byte, maxDecodedSize+1)))
+	_, err := inflate(data)
+	assert.Error(t, err, "inflating past the limit should fail")
+
+	data = compress(string(make([]byte, maxDecodedSize)))
+	out, err := inflate(data)
+	assert.NoError(t, err, "inflating up to the limit should not fail")
+	assert.Len(t, out, maxDecodedSize)
+}
+
+func TestOpen_Errors(t *testing.T) {
+	_, err := Open([]byte("not a pdf"))
+	assert.Equal(t, ErrNotPDF, err)
+
+	data := buildPDF("/Root 1 0 R /Encrypt 2 0 R",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Filter /Standard >>",
+	)
+	_, err = Open(data)
+	assert.Equal(t, ErrEncrypted, err)
+
+	// Missing pages are not an error
+	data = buildPDF("", "<< /Type /Catalog >>")
+	pages, err := ExtractText(data)
+	assert.NoError(t, err)
+	assert.Empty(t, pages)
+}
+
+func TestLexer(t *testing.T) {
+	l := newLexer([]byte(`<< /A#20B (a\(b\)\n\101) /C [1 -2.5 3 0 R true null <48 65>] >>`), true)
+	obj, err := l.readObject()
+	require.NoError(t, err)
+
+	assert.Equal(t, Dict{
+		"A B": String("a(b)\nA"),
+		"C":   Array{int64(1), -2.5, Ref{Num: 3}, true, nil, String("He")},
+	}, obj)
+}
+
+func TestEncodings(t *testing.T) {
+	assert.Equal(t, 'é', macRomanEncoding[0x8e])
+	assert.Equal(t, 'ˇ', macRomanEncoding[0xff])
+	assert.Equal(t, '€', winAnsiEncoding[0x80])
+	assert.Equal(t, 'Ÿ', winAnsiEncoding[0x9f])
+	assert.Equal(t, 'ﬁ', standardEncoding[0xae])
+	assert.Equal(t, 'É', rune(glyphRune("Eacute")))
+	assert.Equal(t, 'ŝ', rune(glyphRune("uni015D")))
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"net/http"
 
-	kitjwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/endpoint"
 
 	"github.com/bobinette/papernet/errors"
@@ -25,6 +24,9 @@ type User struct {
 	CanSee    []int
 	CanEdit   []int
 	Bookmarks []int
+
+	// Papers owned by the teams the user is an admin of
+	TeamOwns []int
 }
 
 func AddToContext(ctx context.Context, user User) context.Context {
@@ -45,20 +47,6 @@ func FromContext(ctx context.Context) (User, error) {
 	return user, nil
 }
 
-func extractUserID(ctx context.Context) (int, bool, error) {
-	claims := ctx.Value(kitjwt.JWTClaimsContextKey)
-	if claims == nil {
-		return 0, false, errors.New("no user", errors.WithCode(http.StatusUnauthorized))
-	}
-
-	ppnClaims, ok := claims.(*jwt.Claims)
-	if !ok {
-		return 0, false, errors.New("invalid claims", errors.WithCode(http.StatusUnauthorized))
-	}
-
-	return ppnClaims.UserID, ppnClaims.IsAdmin, nil
-}
-
 type Authenticator struct {
 	client *auth.Client
 }
@@ -69,6 +57,27 @@ func NewAuthenticator(c *auth.Client) *Authenticator {
 	}
 }
 
+// extractUserID returns the user of the token stored in the context. The API
+// tokens are checked against the auth service, so that the revoked ones are
+// rejected as soon as they are revoked.
+func (a *Authenticator) extractUserID(ctx context.Context) (int, bool, error) {
+	claims, err := jwt.ClaimsFromContext(ctx)
+	if err != nil {
+		return 0, false, errors.New("no user", errors.WithCode(http.StatusUnauthorized))
+	}
+
+	if claims.IsAPIToken() {
+		err := a.client.CheckAPIToken(claims.UserID, claims.TokenID)
+		if e, ok := err.(errors.Error); ok && e.Code() == http.StatusNotFound {
+			return 0, false, errors.New("token revoked", errors.WithCode(http.StatusUnauthorized))
+		} else if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return claims.UserID, claims.IsAdmin, nil
+}
+
 func (a *Authenticator) get(id int) (User, error) {
 	user, err := a.client.User(id)
 	if err != nil {
@@ -83,12 +92,14 @@ func (a *Authenticator) get(id int) (User, error) {
 		CanSee:    user.CanSee,
 		CanEdit:   user.CanEdit,
 		Bookmarks: user.Bookmarks,
+
+		TeamOwns: user.TeamOwns,
 	}, nil
 }
 
 func (a *Authenticator) Valid(next endpoint.Endpoint) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
-		userID, isAdmin, err := extractUserID(ctx)
+		userID, isAdmin, err := a.extractUserID(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -100,7 +111,7 @@ func (a *Authenticator) Valid(next endpoint.Endpoint) endpoint.Endpoint {
 
 func (a *Authenticator) Authenticated(next endpoint.Endpoint) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
-		userID, _, err := extractUserID(ctx)
+		userID, _, err := a.extractUserID(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -117,7 +128,7 @@ func (a *Authenticator) Authenticated(next endpoint.Endpoint) endpoint.Endpoint
 
 func (a *Authenticator) Admin(next endpoint.Endpoint) endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
-		userID, isAdmin, err := extractUserID(ctx)
+		userID, isAdmin, err := a.extractUserID(ctx)
 		if err != nil {
 			return nil, err
 		}